@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"github.com/ofkm/goobrew/internal/homebrew"
+	"github.com/ofkm/goobrew/internal/logger"
+	"github.com/ofkm/goobrew/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// auditDays is how far ahead disable dates are reported, set via --days.
+var auditDays int
+
+// auditCmd represents the audit command.
+// It checks every installed formula and cask against Homebrew's JSON API
+// and lists those that are disabled, will be disabled soon, are deprecated
+// or were renamed, with the reason and a replacement where there is one.
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Find installed packages that are deprecated or disabled",
+	Long: `Find installed formulae and casks that Homebrew has disabled, will disable
+within --days days, has deprecated, or has renamed.
+
+Each package is shown with the reason and dates Homebrew gives, and with the
+formula to use instead when it was renamed.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
+		logger.Log.Info("auditing installed packages", "days", auditDays)
+
+		findings, err := client.Audit(ctx, homebrew.AuditOptions{Days: auditDays})
+		if err != nil {
+			logger.Log.Error("failed to audit installed packages", "error", err)
+			exitWithError(cmd, "Failed to audit installed packages", err)
+		}
+
+		if machineOutput() {
+			emit(cmd, findings)
+			return
+		}
+
+		ui.PrintAudit(findings, auditDays)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+	auditCmd.Flags().IntVarP(&auditDays, "days", "d", 90, "report packages that will be disabled within this many days")
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ofkm/goobrew/internal/logger"
+	"github.com/ofkm/goobrew/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	autoremoveDryRun bool
+	autoremoveYes    bool
+)
+
+// removalResult is the machine-readable result of the autoremove command.
+type removalResult struct {
+	Packages        []removedPackage `json:"packages"`         // Packages are the formulae removed, or to be removed
+	FreedBytes      int64            `json:"freed_bytes"`      // FreedBytes is the disk space removal frees
+	DryRun          bool             `json:"dry_run"`          // DryRun is true if nothing was removed
+	DurationSeconds float64          `json:"duration_seconds"` // DurationSeconds is the elapsed wall time
+}
+
+// removedPackage is one formula in a removalResult.
+type removedPackage struct {
+	Name      string `json:"name"`              // Name is the formula name
+	Version   string `json:"version,omitempty"` // Version is the newest installed version
+	SizeBytes int64  `json:"size_bytes"`        // SizeBytes is the disk space its kegs use
+}
+
+// autoremoveCmd represents the autoremove command.
+// It removes the orphaned dependencies reported by the orphans command.
+// The formulae and the disk space they use are listed first, and nothing
+// is removed until the user confirms, or with --dry-run at all.
+var autoremoveCmd = &cobra.Command{
+	Use:   "autoremove",
+	Short: "Remove dependencies that are no longer needed",
+	Long: `Remove formulae installed as dependencies that no formula installed
+on request needs any more.
+
+The formulae to remove and the disk space they free are shown first and
+removal only starts after confirmation. Use --dry-run to only show them.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		start := time.Now()
+
+		logger.Log.Info("computing autoremove set")
+
+		orphans, err := client.Orphans(ctx)
+		if err != nil {
+			logger.Log.Error("failed to get orphans", "error", err)
+			exitWithError(cmd, "Failed to get orphans", err)
+		}
+
+		result := removalResult{Packages: []removedPackage{}, DryRun: autoremoveDryRun}
+		if len(orphans) == 0 {
+			if machineOutput() {
+				emit(cmd, result)
+				return
+			}
+			ui.PrintSuccess("Nothing to remove")
+			return
+		}
+
+		names := make([]string, len(orphans))
+		for i, f := range orphans {
+			names[i] = f.Name
+		}
+		sizes, err := client.KegSizes(ctx, names)
+		if err != nil {
+			logger.Log.Warn("could not measure disk usage", "error", err)
+		}
+
+		for _, f := range orphans {
+			pkg := removedPackage{Name: f.Name, SizeBytes: sizes[f.Name]}
+			if len(f.Installed) > 0 {
+				pkg.Version = f.Installed[len(f.Installed)-1].Version
+			}
+			result.Packages = append(result.Packages, pkg)
+			result.FreedBytes += pkg.SizeBytes
+		}
+
+		if !machineOutput() {
+			ui.PrintRemovalPlan(orphans, sizes)
+		}
+
+		if autoremoveDryRun {
+			if machineOutput() {
+				emit(cmd, result)
+				return
+			}
+			ui.PrintInfo("Dry run: nothing was removed")
+			fmt.Println()
+			return
+		}
+
+		if !autoremoveYes {
+			if machineOutput() {
+				exitWithError(cmd, "Confirmation required", errors.New("use --yes to remove without a prompt"))
+			}
+			if !ui.Confirm(os.Stdin, fmt.Sprintf("Remove %d formulae?", len(orphans))) {
+				ui.PrintInfo("Aborted, nothing was removed")
+				return
+			}
+			fmt.Println()
+		}
+
+		logger.Log.Info("removing orphans", "packages", names)
+		if err := client.Uninstall(ctx, names); err != nil {
+			logger.Log.Error("autoremove failed", "error", err)
+			exitWithError(cmd, "Failed to remove formulae", err)
+		}
+
+		result.DurationSeconds = time.Since(start).Seconds()
+		if machineOutput() {
+			emit(cmd, result)
+			return
+		}
+		fmt.Printf("\n%s Removed %d formulae and freed %s%s%s\n\n",
+			ui.IconSuccess, len(orphans), ui.Green, ui.FormatSize(result.FreedBytes), ui.Reset)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(autoremoveCmd)
+	autoremoveCmd.Flags().BoolVarP(&autoremoveDryRun, "dry-run", "n", false, "show what would be removed without removing it")
+	autoremoveCmd.Flags().BoolVarP(&autoremoveYes, "yes", "y", false, "remove without asking for confirmation")
+}
@@ -0,0 +1,357 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ofkm/goobrew/internal/brewfile"
+	"github.com/ofkm/goobrew/internal/homebrew"
+	"github.com/ofkm/goobrew/internal/logger"
+	"github.com/ofkm/goobrew/internal/output"
+	"github.com/ofkm/goobrew/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	bundleFilePath  string
+	bundleJobs      int
+	bundleLocked    bool
+	bundleDumpForce bool
+)
+
+// bundleCheckResult is the data emitted by bundle check.
+type bundleCheckResult struct {
+	Satisfied bool                  `json:"satisfied"` // Satisfied is true if every entry is installed
+	Items     []homebrew.BundleItem `json:"items"`     // Items are the Brewfile entries with their state
+}
+
+// bundleCmd represents the bundle command.
+// Its install, check and dump subcommands read and write Brewfiles
+// natively; anything else, such as `bundle cleanup`, is passed through to
+// `brew bundle` unchanged.
+var bundleCmd = &cobra.Command{
+	Use:   "bundle [subcommand]",
+	Short: "Install or check the packages in a Brewfile",
+	Long: `Install, check or generate a Brewfile.
+
+The Brewfile is read from --file, $HOMEBREW_BUNDLE_FILE or ./Brewfile.
+Subcommands other than install, check and dump are passed through to
+brew bundle.`,
+	DisableFlagParsing: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := client.ExecuteCommand(cmd.Context(), append([]string{"bundle"}, args...)); err != nil {
+			exitWithError(cmd, "Command failed", err)
+		}
+	},
+}
+
+// bundleCheckCmd represents the bundle check command.
+// It compares the Brewfile against what is installed, listing everything
+// at once instead of asking brew about each entry, and exits non-zero if
+// anything is missing.
+var bundleCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check whether everything in the Brewfile is installed",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		_, items := checkBrewfile(cmd)
+
+		satisfied := true
+		for _, item := range items {
+			satisfied = satisfied && item.Installed
+		}
+
+		if machineOutput() {
+			doc := output.Document{Command: cmd.Name(), Data: bundleCheckResult{Satisfied: satisfied, Items: items}}
+			if !satisfied {
+				doc.Error = output.NewError("The Brewfile's dependencies are not satisfied", nil)
+			}
+			writeDocument(doc)
+		} else {
+			ui.PrintBundleCheck(items)
+		}
+		if !satisfied {
+			os.Exit(1)
+		}
+	},
+}
+
+// bundleInstallCmd represents the bundle install command.
+// It taps missing taps, installs missing formulae and casks through the
+// same parallel pipeline and progress display as install, then installs
+// App Store apps and VS Code extensions, and finally applies the
+// restart_service and link options of formulae.
+var bundleInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install everything in the Brewfile that is missing",
+	Long: `Install everything in the Brewfile that is missing.
+
+Taps are added first. Formulae and casks are then installed in parallel
+with the same progress display as goobrew install (see --jobs), followed by
+App Store apps (with mas) and VS Code extensions (with code). Finally,
+services marked restart_service are restarted and formulae with a link
+option are linked or unlinked.
+
+With --locked, nothing is installed unless the missing formulae and casks
+match goobrew.lock (see goobrew lock).`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		bf, items := checkBrewfile(cmd)
+
+		var (
+			results  []installResult
+			missing  = make(map[brewfile.Kind][]homebrew.BundleItem)
+			packages []string
+			opts     = []homebrew.InstallOption{homebrew.WithJobs(bundleJobs)}
+		)
+		for _, item := range items {
+			if item.Installed {
+				continue
+			}
+			missing[item.Kind] = append(missing[item.Kind], item)
+			switch item.Kind {
+			case brewfile.KindBrew:
+				packages = append(packages, item.Name)
+				opts = append(opts, homebrew.WithPackageArgs(item.Name, bf.InstallArgs(item.Entry)...))
+			case brewfile.KindCask:
+				packages = append(packages, item.Name)
+				opts = append(opts, homebrew.WithPackageArgs(item.Name, append([]string{"--cask"}, bf.InstallArgs(item.Entry)...)...))
+			}
+		}
+
+		if bundleLocked && len(packages) > 0 {
+			requireLocked(cmd, packages)
+		}
+
+		start := time.Now()
+		if len(packages)+len(missing[brewfile.KindTap])+len(missing[brewfile.KindMas])+len(missing[brewfile.KindVSCode]) == 0 && !machineOutput() {
+			fmt.Printf("\n%s The Brewfile's dependencies are satisfied\n", ui.IconSuccess)
+		}
+
+		step := func(kind brewfile.Kind, item homebrew.BundleItem, action string, run func() error) {
+			if !machineOutput() {
+				ui.PrintInfo(fmt.Sprintf("%s %s", action, item.Name))
+			}
+			began := time.Now()
+			err := run()
+			result := installResult{Package: string(kind) + ":" + item.Name, Status: "completed", DurationSeconds: time.Since(began).Seconds()}
+			if err != nil {
+				logger.Log.Error("bundle step failed", "entry", item.Name, "error", err)
+				result.Status, result.Error = "failed", err.Error()
+				if !machineOutput() {
+					ui.PrintError(fmt.Sprintf("Failed to %s %s: %v", strings.ToLower(action), item.Name, err))
+				}
+			}
+			results = append(results, result)
+		}
+
+		if !machineOutput() && len(missing[brewfile.KindTap]) > 0 {
+			fmt.Println()
+		}
+		for _, item := range missing[brewfile.KindTap] {
+			step(brewfile.KindTap, item, "Tap", func() error { return client.Tap(ctx, item.Name, item.URL) })
+		}
+
+		installedNow := make(map[string]bool)
+		if len(packages) > 0 && !wasInterrupted() {
+			if !machineOutput() {
+				fmt.Printf("\n%s %sInstalling packages:%s %s\n\n",
+					ui.IconBeer, ui.Bold, ui.Reset, strings.Join(packages, ", "))
+			}
+
+			statusChan := make(chan homebrew.InstallationStatus, 100)
+			var installErr error
+			go func() {
+				defer close(statusChan)
+				installErr = client.Install(ctx, packages, statusChan, opts...)
+			}()
+			if machineOutput() {
+				results = append(results, collectInstallResults(statusChan)...)
+			} else {
+				showProgress(statusChan, "install")
+			}
+
+			failed := make(map[string]bool)
+			var ie *homebrew.InstallError
+			if errors.As(installErr, &ie) {
+				for _, f := range ie.Failures {
+					failed[f.Package] = true
+				}
+			}
+			for _, pkg := range packages {
+				installedNow[pkg] = !failed[pkg]
+			}
+		}
+
+		for _, item := range missing[brewfile.KindMas] {
+			if wasInterrupted() {
+				break
+			}
+			step(brewfile.KindMas, item, "Install", func() error { return client.InstallAppStoreApp(ctx, item.ID) })
+		}
+		for _, item := range missing[brewfile.KindVSCode] {
+			if wasInterrupted() {
+				break
+			}
+			step(brewfile.KindVSCode, item, "Install", func() error { return client.InstallVSCodeExtension(ctx, item.Name) })
+		}
+
+		for _, item := range items {
+			if item.Kind != brewfile.KindBrew || wasInterrupted() || !item.Installed && !installedNow[item.Name] {
+				continue
+			}
+			if item.Link != nil {
+				action := "Unlink"
+				if *item.Link {
+					action = "Link"
+				}
+				step(brewfile.KindBrew, item, action, func() error { return client.SetLinked(ctx, item.Name, *item.Link) })
+			}
+			if item.RestartService == brewfile.RestartAlways || item.RestartService == brewfile.RestartChanged && installedNow[item.Name] {
+				step(brewfile.KindBrew, item, "Restart service", func() error {
+					_, err := client.RestartService(ctx, item.Name)
+					return err
+				})
+			}
+		}
+
+		if machineOutput() {
+			writeInstallResults(cmd, results)
+			return
+		}
+
+		failed := 0
+		for _, r := range results {
+			if r.Status != "completed" {
+				failed++
+			}
+		}
+		for _, pkg := range packages {
+			if !installedNow[pkg] {
+				failed++
+			}
+		}
+
+		elapsed := time.Since(start)
+		switch {
+		case wasInterrupted():
+			fmt.Printf("\n%s Bundle install interrupted after %s%s%s\n\n",
+				ui.IconWarning, ui.Yellow, ui.FormatDuration(elapsed), ui.Reset)
+			os.Exit(failureExitCode())
+		case failed > 0:
+			fmt.Printf("\n%s Bundle install finished with %d errors in %s%s%s\n\n",
+				ui.IconError, failed, ui.Red, ui.FormatDuration(elapsed), ui.Reset)
+			os.Exit(1)
+		}
+		fmt.Printf("\n%s Bundle install completed in %s%s%s\n\n",
+			ui.IconSparkles, ui.Green, ui.FormatDuration(elapsed), ui.Reset)
+	},
+}
+
+// bundleDumpCmd represents the bundle dump command.
+// It writes a Brewfile describing the current installation: taps,
+// formulae installed on request, casks, App Store apps and VS Code
+// extensions.
+var bundleDumpCmd = &cobra.Command{
+	Use:   "dump",
+	Short: "Write a Brewfile of everything installed",
+	Long: `Write a Brewfile listing every tap, formula installed on request, cask,
+App Store app and VS Code extension. Formulae installed only as dependencies
+are left out. Use --file - to print the Brewfile instead of writing it, and
+--force to overwrite an existing file.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		path := bundleFile()
+		if path != "-" && !bundleDumpForce {
+			if _, err := os.Stat(path); err == nil {
+				exitWithError(cmd, "Refusing to overwrite "+path, errors.New("use --force to replace it"))
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				exitWithError(cmd, "Failed to write Brewfile", err)
+			}
+		}
+
+		logger.Log.Info("dumping Brewfile", "file", path)
+		bf, err := client.DumpBundle(cmd.Context())
+		if err != nil {
+			logger.Log.Error("failed to dump Brewfile", "error", err)
+			exitWithError(cmd, "Failed to dump Brewfile", err)
+		}
+
+		switch {
+		case path == "-" && machineOutput():
+		case path == "-":
+			if err := bf.Write(os.Stdout); err != nil {
+				exitWithError(cmd, "Failed to write Brewfile", err)
+			}
+			return
+		default:
+			if err := writeBrewfile(path, bf); err != nil {
+				exitWithError(cmd, "Failed to write Brewfile", err)
+			}
+		}
+
+		if machineOutput() {
+			emit(cmd, bf)
+			return
+		}
+		ui.PrintSuccess(fmt.Sprintf("Wrote %d entries to %s", len(bf.Entries), path))
+	},
+}
+
+// bundleFile returns the Brewfile location: --file, then
+// $HOMEBREW_BUNDLE_FILE, then Brewfile in the working directory.
+func bundleFile() string {
+	if bundleFilePath != "" {
+		return bundleFilePath
+	}
+	if path := os.Getenv("HOMEBREW_BUNDLE_FILE"); path != "" {
+		return path
+	}
+	return "Brewfile"
+}
+
+// checkBrewfile parses the Brewfile and reports which entries are
+// installed, exiting on failure.
+func checkBrewfile(cmd *cobra.Command) (*brewfile.Brewfile, []homebrew.BundleItem) {
+	path := bundleFile()
+	bf, err := brewfile.ParseFile(path)
+	if err != nil {
+		exitWithError(cmd, "Failed to read Brewfile", err)
+	}
+
+	logger.Log.Info("checking Brewfile", "file", path, "entries", len(bf.Entries))
+	items, err := client.CheckBundle(cmd.Context(), bf)
+	if err != nil {
+		logger.Log.Error("failed to check Brewfile", "error", err)
+		exitWithError(cmd, "Failed to check Brewfile", err)
+	}
+	return bf, items
+}
+
+// writeBrewfile writes bf to path.
+func writeBrewfile(path string, bf *brewfile.Brewfile) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := bf.Write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func init() {
+	rootCmd.AddCommand(bundleCmd)
+	bundleCmd.AddCommand(bundleCheckCmd, bundleInstallCmd, bundleDumpCmd)
+	bundleCmd.PersistentFlags().StringVarP(&bundleFilePath, "file", "f", "", "Brewfile to read or write (default $HOMEBREW_BUNDLE_FILE or ./Brewfile)")
+	bundleInstallCmd.Flags().IntVarP(&bundleJobs, "jobs", "j", homebrew.DefaultInstallJobs, "number of packages to install concurrently")
+	bundleInstallCmd.Flags().BoolVar(&bundleLocked, "locked", false, "refuse to install if a bottle differs from goobrew.lock")
+	bundleInstallCmd.Flags().StringVar(&lockfilePath, "lockfile", "", "lockfile to check with --locked (default goobrew.lock next to the Brewfile)")
+	bundleDumpCmd.Flags().BoolVar(&bundleDumpForce, "force", false, "overwrite an existing Brewfile")
+}
@@ -178,7 +178,7 @@ func TestUninstallCommand_NoArgs(t *testing.T) {
 
 func TestCommandsExist(t *testing.T) {
 	// Ensure all commands are registered
-	commands := []string{"search", "list", "info", "install", "uninstall", "update", "upgrade"}
+	commands := []string{"search", "list", "info", "deps", "uses", "leaves", "orphans", "autoremove", "outdated", "install", "uninstall", "update", "upgrade", "pin", "unpin", "bundle", "lock", "link", "unlink", "audit", "services"}
 	for _, cmdName := range commands {
 		found := false
 		for _, cmd := range rootCmd.Commands() {
@@ -192,3 +192,21 @@ func TestCommandsExist(t *testing.T) {
 		}
 	}
 }
+
+func TestOutputFlag(t *testing.T) {
+	flag := rootCmd.PersistentFlags().Lookup("output")
+	if flag == nil {
+		t.Fatal("--output flag not registered")
+	}
+	if flag.DefValue != "text" {
+		t.Errorf("Expected --output to default to text, got %s", flag.DefValue)
+	}
+
+	output, err := executeCommand("--output", "json", "--help")
+	if err != nil {
+		t.Fatalf("Output flag failed: %v", err)
+	}
+	if !strings.Contains(output, "--output") {
+		t.Error("Help should document the --output flag")
+	}
+}
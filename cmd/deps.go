@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ofkm/goobrew/internal/homebrew"
+	"github.com/ofkm/goobrew/internal/logger"
+	"github.com/ofkm/goobrew/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	depsIncludeBuild bool
+	depsIncludeTest  bool
+	depsInstalled    bool
+	depsMissing      bool
+	depsReverse      bool
+	depsGraph        string
+)
+
+// depsCmd represents the deps command.
+// It resolves the full transitive dependency graph of one or more formulae
+// from Homebrew's JSON API and prints it as an indented tree, marking which
+// formulae are installed. The tree can be inverted to show what pulls each
+// dependency in, and the graph can be exported as Graphviz DOT or Mermaid.
+var depsCmd = &cobra.Command{
+	Use:   "deps [formula...]",
+	Short: "Show the dependency tree of formulae",
+	Long: `Show the full transitive dependency tree of one or more formulae.
+
+Runtime and recommended dependencies are always shown; build and test
+dependencies are included on request. Use --graph to export the graph
+as Graphviz DOT or Mermaid instead of printing a tree.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
+		if depsGraph != "" && depsGraph != "dot" && depsGraph != "mermaid" {
+			exitWithError(cmd, "Invalid graph format", fmt.Errorf("unknown format %q (expected dot or mermaid)", depsGraph))
+		}
+
+		logger.Log.Info("resolving dependencies", "formulae", args)
+
+		graph, err := client.ResolveDependencies(ctx, args, homebrew.DepOptions{
+			IncludeBuild: depsIncludeBuild,
+			IncludeTest:  depsIncludeTest,
+		})
+		if err != nil {
+			logger.Log.Error("failed to resolve dependencies", "error", err, "formulae", args)
+			exitWithError(cmd, "Failed to resolve dependencies", err)
+		}
+
+		switch depsGraph {
+		case "dot":
+			err = graph.WriteDOT(cmd.OutOrStdout())
+		case "mermaid":
+			err = graph.WriteMermaid(cmd.OutOrStdout())
+		}
+		if err != nil {
+			exitWithError(cmd, "Failed to write graph", err)
+		}
+		if depsGraph != "" {
+			return
+		}
+
+		if machineOutput() {
+			emit(cmd, graph)
+			return
+		}
+
+		var keep func(*homebrew.DepNode) bool
+		switch {
+		case depsInstalled:
+			keep = func(n *homebrew.DepNode) bool { return n.Installed }
+		case depsMissing:
+			keep = func(n *homebrew.DepNode) bool { return !n.Installed }
+		}
+
+		if depsReverse {
+			ui.PrintReverseDependencyTree(graph, keep)
+		} else {
+			ui.PrintDependencyTree(graph, keep)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(depsCmd)
+	depsCmd.Flags().BoolVar(&depsIncludeBuild, "include-build", false, "include build dependencies")
+	depsCmd.Flags().BoolVar(&depsIncludeTest, "include-test", false, "include test dependencies")
+	depsCmd.Flags().BoolVar(&depsInstalled, "installed", false, "only show installed dependencies")
+	depsCmd.Flags().BoolVar(&depsMissing, "missing", false, "only show dependencies that are not installed")
+	depsCmd.Flags().BoolVar(&depsReverse, "reverse", false, "show what pulls in each dependency instead")
+	depsCmd.Flags().StringVar(&depsGraph, "graph", "", "export the graph instead of a tree (dot, mermaid)")
+	depsCmd.MarkFlagsMutuallyExclusive("installed", "missing")
+}
@@ -1,72 +1,314 @@
 package cmd
 
 import (
-	"context"
+	"errors"
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/ofkm/goobrew/internal/homebrew"
 	"github.com/ofkm/goobrew/internal/logger"
+	"github.com/ofkm/goobrew/internal/output"
 	"github.com/ofkm/goobrew/internal/ui"
 	"github.com/spf13/cobra"
 )
 
+// installJobs is the number of packages installed concurrently, set via --jobs.
+var installJobs int
+
+// installLocked refuses installs that differ from the lockfile, set via --locked.
+var installLocked bool
+
+// installDryRun only shows the install plan, set via --dry-run.
+var installDryRun bool
+
+// installYes installs without asking to confirm the plan, set via --yes.
+var installYes bool
+
 // installCmd represents the install command.
 // It installs one or more packages using Homebrew, displaying real-time progress
-// information including download, installation, and linking stages. The command
-// accepts multiple package names and processes them sequentially.
+// information including download, installation, and linking stages. Independent
+// packages are installed concurrently, with one dashboard row per package when
+// stdout is a terminal and one log line per stage change otherwise.
 var installCmd = &cobra.Command{
 	Use:     "install [package...]",
 	Aliases: []string{"i"},
 	Short:   "Install packages",
-	Long:    `Install one or more Homebrew packages with beautiful progress tracking.`,
-	Args:    cobra.MinimumNArgs(1),
+	Long: `Install one or more Homebrew packages with beautiful progress tracking.
+
+Before anything is installed, the full plan is shown: every package that
+will be installed or upgraded, dependencies included, in install order, with
+the total download size. Deprecated packages are warned about, and disabled
+packages, conflicts with installed formulae and requirements the system does
+not meet stop the install. The plan must be confirmed unless --yes is given
+or HOMEBREW_NO_ASK is set; when there is no terminal to ask on, such as with
+--output json or in a pipeline, the install is refused without one of them.
+The same goes for installing without a plan when none can be computed. Use
+--dry-run to only show the plan.
+
+Independent packages are installed in parallel (see --jobs), and dependencies
+shared by several of them are installed once, before the packages that need them.
+
+With --locked, every package must be in goobrew.lock (see goobrew lock), and
+the install is refused if the version or bottle checksum the API offers for
+it or any of its locked dependencies differs from the locked one.`,
+	Args: cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		ctx := context.Background()
+		ctx := cmd.Context()
 
-		fmt.Printf("\n%s %sInstalling packages:%s %s\n\n",
-			ui.IconBeer, ui.Bold, ui.Reset, strings.Join(args, ", "))
+		if installLocked {
+			requireLocked(cmd, args)
+		}
+		if !confirmInstallPlan(cmd, args) {
+			return
+		}
+
+		if !machineOutput() {
+			fmt.Printf("\n%s %sInstalling packages:%s %s\n\n",
+				ui.IconBeer, ui.Bold, ui.Reset, strings.Join(args, ", "))
+		}
 
 		start := time.Now()
 		statusChan := make(chan homebrew.InstallationStatus, 100)
 
-		// Start installation in background
+		// Start installation in background. Install sends nothing after it
+		// returns, so the channel can be closed right away.
+		var installErr error
 		go func() {
 			defer close(statusChan)
-			if err := client.Install(ctx, args, statusChan); err != nil {
-				logger.Log.Error("installation failed", "error", err)
-			}
+			installErr = client.Install(ctx, args, statusChan, homebrew.WithJobs(installJobs))
 		}()
 
-		// Monitor progress
-		lastPkg := ""
-		for status := range statusChan {
-			if status.Formula != lastPkg {
-				if lastPkg != "" {
-					fmt.Println() // New line for new package
-				}
-				lastPkg = status.Formula
-			}
-
-			ui.PrintInstallProgress(status)
-
-			switch status.Stage {
-			case "completed":
-				fmt.Println() // New line after completion
-				ui.PrintSuccess(fmt.Sprintf("%s installed successfully", status.Formula))
-			case "failed":
-				fmt.Println() // New line after failure
-				ui.PrintError(fmt.Sprintf("Failed to install %s: %v", status.Formula, status.Error))
-			}
+		if machineOutput() {
+			emitInstallResults(cmd, statusChan)
+			return
 		}
 
+		showProgress(statusChan, "install")
+
 		elapsed := time.Since(start)
+		switch {
+		case wasInterrupted():
+			fmt.Printf("\n%s Installation interrupted after %s%s%s\n\n",
+				ui.IconWarning, ui.Yellow, ui.FormatDuration(elapsed), ui.Reset)
+			os.Exit(failureExitCode())
+		case installErr != nil:
+			logger.Log.Error("installation failed", "error", installErr)
+			fmt.Printf("\n%s Installation finished with errors in %s%s%s\n\n",
+				ui.IconError, ui.Red, ui.FormatDuration(elapsed), ui.Reset)
+			os.Exit(1)
+		}
 		fmt.Printf("\n%s Installation completed in %s%s%s\n\n",
 			ui.IconSparkles, ui.Green, ui.FormatDuration(elapsed), ui.Reset)
 	},
 }
 
+// confirmInstallPlan computes the install plan for packages, shows it and
+// asks whether to go ahead, and reports whether the install should start.
+// A blocked plan exits non-zero. If no plan can be computed, the install
+// may still go ahead without one once confirmed, since brew resolves
+// dependencies itself.
+func confirmInstallPlan(cmd *cobra.Command, packages []string) bool {
+	plan, err := client.Plan(cmd.Context(), packages)
+	if err != nil {
+		if installDryRun {
+			exitWithError(cmd, "Failed to compute the install plan", err)
+		}
+		logger.Log.Warn("could not compute an install plan", "error", err)
+		if !machineOutput() {
+			ui.PrintWarning("Could not compute the install plan: " + err.Error())
+		}
+		return askToInstall(cmd, fmt.Sprintf("Install %s without a plan?", strings.Join(packages, ", ")))
+	}
+
+	if machineOutput() {
+		switch {
+		case plan.Blocked():
+			writeDocument(output.Document{
+				Command: cmd.Name(),
+				Data:    plan,
+				Error:   output.NewError("the install plan has conflicts, disabled packages or unmet requirements", nil),
+			})
+			os.Exit(1)
+		case installDryRun:
+			emit(cmd, plan)
+			return false
+		case !installConsent():
+			writeDocument(output.Document{
+				Command: cmd.Name(),
+				Data:    plan,
+				Error:   output.NewError("Confirmation required", errors.New("use --yes or set HOMEBREW_NO_ASK to install without a prompt")),
+			})
+			os.Exit(1)
+		}
+		return true
+	}
+
+	ui.PrintInstallPlan(plan)
+	switch {
+	case plan.Blocked():
+		exitWithError(cmd, "Not installing: the plan has conflicts, disabled packages or unmet requirements", nil)
+	case installDryRun:
+		ui.PrintInfo("Dry run: nothing was installed")
+		fmt.Println()
+		return false
+	case len(plan.Steps) == 0 && len(plan.Unknown) == 0:
+		ui.PrintSuccess("Everything is already installed")
+		fmt.Println()
+		return false
+	}
+	return askToInstall(cmd, fmt.Sprintf("Install %d packages?", len(plan.Steps)+len(plan.Unknown)))
+}
+
+// askToInstall reports whether the install may go ahead, asking question
+// on the terminal unless installConsent allows it. Without a terminal to
+// ask on, it exits non-zero instead.
+func askToInstall(cmd *cobra.Command, question string) bool {
+	if installConsent() {
+		return true
+	}
+	if machineOutput() || !ui.IsTerminal(os.Stdin) {
+		exitWithError(cmd, "Confirmation required", errors.New("there is no terminal to ask on; use --yes or set HOMEBREW_NO_ASK to install without a prompt"))
+	}
+	if !ui.Confirm(os.Stdin, question) {
+		ui.PrintInfo("Aborted, nothing was installed")
+		return false
+	}
+	return true
+}
+
+// installConsent reports whether the plan may be installed without asking,
+// because --yes was given or HOMEBREW_NO_ASK is set.
+func installConsent() bool {
+	return installYes || os.Getenv("HOMEBREW_NO_ASK") != ""
+}
+
+// showProgress renders statuses from statusChan on a dashboard until the
+// channel is closed, then reports every package that failed or was skipped
+// and prints a summary table. action names the brew command in failure
+// messages, such as "install".
+func showProgress(statusChan <-chan homebrew.InstallationStatus, action string) {
+	dashboard := ui.NewDashboard(os.Stdout, ui.IsTerminal(os.Stdout))
+	dashboard.Run(statusChan)
+
+	statuses := dashboard.Statuses()
+	fmt.Println()
+	for _, status := range statuses {
+		switch {
+		case errors.Is(status.Error, homebrew.ErrInterrupted):
+			// Listed as interrupted in the summary
+		case status.Stage == "failed":
+			ui.PrintError(fmt.Sprintf("Failed to %s %s: %v", action, status.Formula, status.Error))
+			printTranscript(status.Transcript)
+		case status.Stage == "skipped":
+			ui.PrintWarning(fmt.Sprintf("Skipped %s: %v", status.Formula, status.Error))
+		}
+	}
+
+	fmt.Println()
+	ui.PrintInstallSummary(statuses)
+}
+
+// transcriptTail is how many lines of brew output are shown for a failed
+// package unless --verbose is set.
+const transcriptTail = 20
+
+// printTranscript shows the end of brew's output for a failed package, or
+// all of it with --verbose.
+func printTranscript(lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	if !verbose && !debug && len(lines) > transcriptTail {
+		fmt.Printf("  %s... %d earlier lines hidden, use --verbose to show all%s\n",
+			ui.Gray, len(lines)-transcriptTail, ui.Reset)
+		lines = lines[len(lines)-transcriptTail:]
+	}
+	for _, line := range lines {
+		fmt.Printf("  %s│%s %s\n", ui.Gray, ui.Reset, line)
+	}
+}
+
+// installResult is the machine-readable outcome of installing one package.
+type installResult struct {
+	Package         string   `json:"package"`          // Package is the requested package name
+	Status          string   `json:"status"`           // Status is "completed", "failed" or "skipped"
+	Error           string   `json:"error,omitempty"`  // Error describes why installation failed
+	Output          []string `json:"output,omitempty"` // Output is brew's transcript for a failed package
+	DurationSeconds float64  `json:"duration_seconds"` // DurationSeconds is the time spent on the package
+}
+
+// emitInstallResults drains statusChan, records the final stage of each
+// package and writes them as a single document. It exits non-zero if any
+// package failed to install or was skipped.
+func emitInstallResults(cmd *cobra.Command, statusChan <-chan homebrew.InstallationStatus) {
+	writeInstallResults(cmd, collectInstallResults(statusChan))
+}
+
+// collectInstallResults drains statusChan and returns the final stage of
+// each package, in the order the packages first finished.
+func collectInstallResults(statusChan <-chan homebrew.InstallationStatus) []installResult {
+	var (
+		results []installResult
+		index   = make(map[string]int)
+	)
+
+	for status := range statusChan {
+		switch status.Stage {
+		case "completed", "failed", "skipped":
+		default:
+			continue
+		}
+
+		result := installResult{
+			Package: status.Formula,
+			Status:  status.Stage,
+		}
+		if !status.EndTime.IsZero() {
+			result.DurationSeconds = status.EndTime.Sub(status.StartTime).Seconds()
+		}
+		if status.Error != nil {
+			result.Error = status.Error.Error()
+			result.Output = status.Transcript
+		}
+
+		if i, ok := index[status.Formula]; ok {
+			results[i] = result
+		} else {
+			index[status.Formula] = len(results)
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// writeInstallResults writes results as a single document, with an error
+// if any package was not installed, and then exits non-zero in that case.
+func writeInstallResults(cmd *cobra.Command, results []installResult) {
+	var failed int
+	for _, r := range results {
+		if r.Status != "completed" {
+			failed++
+		}
+	}
+
+	doc := output.Document{Command: cmd.Name(), Data: results}
+	if failed > 0 {
+		doc.Error = output.NewError(fmt.Sprintf("%d of %d packages were not installed", failed, len(results)), nil)
+	}
+	writeDocument(doc)
+
+	if failed > 0 {
+		os.Exit(failureExitCode())
+	}
+}
+
 func init() {
+	installCmd.Flags().IntVarP(&installJobs, "jobs", "j", homebrew.DefaultInstallJobs, "number of packages to install concurrently")
+	installCmd.Flags().BoolVarP(&installDryRun, "dry-run", "n", false, "show the install plan without installing anything")
+	installCmd.Flags().BoolVarP(&installYes, "yes", "y", false, "install without asking to confirm the plan")
+	installCmd.Flags().BoolVar(&installLocked, "locked", false, "refuse to install if a bottle differs from goobrew.lock")
+	installCmd.Flags().StringVar(&lockfilePath, "lockfile", "", "lockfile to check with --locked (default ./goobrew.lock)")
 	rootCmd.AddCommand(installCmd)
 }
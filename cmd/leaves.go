@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"github.com/ofkm/goobrew/internal/homebrew"
+	"github.com/ofkm/goobrew/internal/logger"
+	"github.com/ofkm/goobrew/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	leavesOnRequest    bool
+	leavesAsDependency bool
+)
+
+// leavesCmd represents the leaves command.
+// It lists the installed formulae that no other installed formula depends
+// on, optionally narrowed to those installed on request or those installed
+// as a dependency, using the installation records brew keeps for each keg.
+var leavesCmd = &cobra.Command{
+	Use:   "leaves",
+	Short: "List installed formulae that nothing depends on",
+	Long:  `List installed formulae that are not dependencies of another installed formula.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
+		logger.Log.Info("fetching leaves")
+
+		leaves, err := client.Leaves(ctx)
+		if err != nil {
+			logger.Log.Error("failed to get leaves", "error", err)
+			exitWithError(cmd, "Failed to get leaves", err)
+		}
+
+		var filtered []homebrew.Formula
+		for _, f := range leaves {
+			onRequest := false
+			for _, info := range f.Installed {
+				onRequest = onRequest || info.InstalledOnRequest
+			}
+			if (leavesOnRequest && !onRequest) || (leavesAsDependency && onRequest) {
+				continue
+			}
+			filtered = append(filtered, f)
+		}
+
+		if machineOutput() {
+			emit(cmd, filtered)
+			return
+		}
+
+		ui.PrintFormulaList("Leaves", "No leaves", filtered)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(leavesCmd)
+	leavesCmd.Flags().BoolVarP(&leavesOnRequest, "installed-on-request", "r", false, "only list leaves installed on request")
+	leavesCmd.Flags().BoolVarP(&leavesAsDependency, "installed-as-dependency", "p", false, "only list leaves installed as a dependency")
+	leavesCmd.MarkFlagsMutuallyExclusive("installed-on-request", "installed-as-dependency")
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ofkm/goobrew/internal/homebrew"
+	"github.com/ofkm/goobrew/internal/logger"
+	"github.com/ofkm/goobrew/internal/output"
+	"github.com/ofkm/goobrew/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	linkDryRun    bool
+	linkForce     bool
+	linkOverwrite bool
+)
+
+// linkCmd represents the link command.
+// It symlinks kegs into the prefix natively, reporting any file in the way
+// together with the keg it belongs to, and with --dry-run only lists the
+// symlinks it would create or remove.
+var linkCmd = &cobra.Command{
+	Use:   "link formula...",
+	Short: "Symlink installed formulae into the Homebrew prefix",
+	Long: `Symlink the newest installed keg of each formula into the Homebrew prefix.
+
+The files in the keg's bin, sbin, include, lib, share, etc and Frameworks
+directories are linked individually. Keg-only formulae are only linked with
+--force. Files in the way are reported together with the formula they
+belong to, and nothing is linked unless they match the formula's
+link_overwrite list or --overwrite is given.
+
+Use --dry-run to list every symlink that would be created or removed.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := linkOptions()
+		if linkForce {
+			opts = append(opts, homebrew.WithLinkForce())
+		}
+		if linkOverwrite {
+			opts = append(opts, homebrew.WithLinkOverwrite())
+		}
+		runLink(cmd, args, func(name string) (*homebrew.LinkResult, error) {
+			return client.Link(cmd.Context(), name, opts...)
+		})
+	},
+}
+
+// unlinkCmd represents the unlink command.
+var unlinkCmd = &cobra.Command{
+	Use:   "unlink formula...",
+	Short: "Remove the symlinks of formulae from the Homebrew prefix",
+	Long: `Remove the symlinks into every installed keg of each formula from the
+Homebrew prefix, and the directories left empty. The formula stays installed
+and its opt link is kept.
+
+Use --dry-run to list every symlink that would be removed.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := linkOptions()
+		runLink(cmd, args, func(name string) (*homebrew.LinkResult, error) {
+			return client.Unlink(cmd.Context(), name, opts...)
+		})
+	},
+}
+
+// linkOptions returns the options shared by link and unlink.
+func linkOptions() []homebrew.LinkOption {
+	if linkDryRun {
+		return []homebrew.LinkOption{homebrew.WithLinkDryRun()}
+	}
+	return nil
+}
+
+// runLink links or unlinks each formula with fn and reports the results,
+// exiting non-zero if any of them failed.
+func runLink(cmd *cobra.Command, names []string, fn func(string) (*homebrew.LinkResult, error)) {
+	var (
+		results []*homebrew.LinkResult
+		errs    []error
+	)
+	for _, name := range names {
+		logger.Log.Info("running "+cmd.Name(), "formula", name, "dry_run", linkDryRun)
+		result, err := fn(name)
+		if err != nil {
+			logger.Log.Error(cmd.Name()+" failed", "formula", name, "error", err)
+			errs = append(errs, err)
+		}
+		if result != nil {
+			results = append(results, result)
+		}
+		if !machineOutput() {
+			if result != nil {
+				ui.PrintLinkResult(cmd.Name(), result, linkDryRun)
+			}
+			var conflict *homebrew.LinkConflictError
+			switch {
+			case errors.As(err, &conflict):
+				ui.PrintError(fmt.Sprintf("Could not link %s. Remove the conflicting files or use --overwrite.", name))
+			case err != nil:
+				ui.PrintError(fmt.Sprintf("Failed to %s %s: %v", cmd.Name(), name, err))
+			}
+		}
+	}
+
+	if machineOutput() {
+		doc := output.Document{Command: cmd.Name(), Data: results}
+		if len(errs) > 0 {
+			doc.Error = output.NewError(fmt.Sprintf("Failed to %s %d of %d formulae", cmd.Name(), len(errs), len(names)), errors.Join(errs...))
+		}
+		writeDocument(doc)
+	}
+	if len(errs) > 0 {
+		os.Exit(failureExitCode())
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(linkCmd)
+	rootCmd.AddCommand(unlinkCmd)
+	for _, c := range []*cobra.Command{linkCmd, unlinkCmd} {
+		c.Flags().BoolVarP(&linkDryRun, "dry-run", "n", false, "list the changes without making them")
+	}
+	linkCmd.Flags().BoolVarP(&linkForce, "force", "f", false, "link keg-only formulae too")
+	linkCmd.Flags().BoolVar(&linkOverwrite, "overwrite", false, "delete files in the way instead of failing")
+}
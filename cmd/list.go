@@ -1,9 +1,6 @@
 package cmd
 
 import (
-	"context"
-	"os"
-
 	"github.com/ofkm/goobrew/internal/logger"
 	"github.com/ofkm/goobrew/internal/ui"
 	"github.com/spf13/cobra"
@@ -19,15 +16,19 @@ var listCmd = &cobra.Command{
 	Short:   "List installed packages",
 	Long:    `List all installed Homebrew packages with detailed information.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		ctx := context.Background()
+		ctx := cmd.Context()
 
 		logger.Log.Info("fetching installed packages")
 
-		formulae, err := client.GetInstalledFormulae(ctx)
+		formulae, err := client.InstalledFormulae(ctx)
 		if err != nil {
-			ui.PrintError("Failed to get installed packages: " + err.Error())
 			logger.Log.Error("failed to get installed packages", "error", err)
-			os.Exit(1)
+			exitWithError(cmd, "Failed to get installed packages", err)
+		}
+
+		if machineOutput() {
+			emit(cmd, formulae)
+			return
 		}
 
 		ui.PrintInstalledList(formulae)
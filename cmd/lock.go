@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/ofkm/goobrew/internal/brewfile"
+	"github.com/ofkm/goobrew/internal/homebrew"
+	"github.com/ofkm/goobrew/internal/lockfile"
+	"github.com/ofkm/goobrew/internal/logger"
+	"github.com/ofkm/goobrew/internal/output"
+	"github.com/ofkm/goobrew/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// lockfilePath overrides the lockfile location when set via --lockfile.
+var lockfilePath string
+
+// lockResult is the data emitted by lock.
+type lockResult struct {
+	File     string             `json:"file"`     // File is the lockfile that was written
+	Lockfile *lockfile.Lockfile `json:"lockfile"` // Lockfile is its content
+}
+
+// lockVerifyResult is the data emitted by lock verify.
+type lockVerifyResult struct {
+	File   string           `json:"file"`   // File is the lockfile that was checked
+	Drifts []lockfile.Drift `json:"drifts"` // Drifts are the differences found
+}
+
+// lockCmd represents the lock command.
+// It records the installed versions, bottle checksums and tap commits of
+// the given packages, the Brewfile's formulae and casks, or everything
+// installed on request, together with their dependencies, in goobrew.lock.
+var lockCmd = &cobra.Command{
+	Use:   "lock [package...]",
+	Short: "Record installed versions in goobrew.lock",
+	Long: `Write goobrew.lock, recording the full name, tap, version, revision,
+bottle checksums and tap commit of packages and their runtime dependencies.
+
+The packages are the arguments, or the formulae and casks in the Brewfile
+(see goobrew bundle), or everything installed on request. The lockfile is
+written next to the Brewfile unless --lockfile is given.
+
+Use "goobrew lock verify" to compare the lockfile with what is installed,
+and "goobrew install --locked" to refuse installs whose bottles changed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
+		names := args
+		if len(names) == 0 {
+			bf, err := brewfile.ParseFile(bundleFile())
+			switch {
+			case errors.Is(err, fs.ErrNotExist):
+				logger.Log.Info("no Brewfile, locking everything installed on request")
+				if bf, err = client.DumpBundle(ctx); err != nil {
+					exitWithError(cmd, "Failed to list installed packages", err)
+				}
+			case err != nil:
+				exitWithError(cmd, "Failed to read Brewfile", err)
+			}
+			for _, e := range bf.Entries {
+				if e.Kind == brewfile.KindBrew || e.Kind == brewfile.KindCask {
+					names = append(names, e.Name)
+				}
+			}
+		}
+
+		logger.Log.Info("locking packages", "packages", names)
+		lock, warnings, err := client.Lock(ctx, names)
+		if err != nil {
+			logger.Log.Error("failed to lock packages", "error", err)
+			exitWithError(cmd, "Failed to lock packages", err)
+		}
+
+		path := lockFile()
+		if err := lockfile.Write(path, lock); err != nil {
+			exitWithError(cmd, "Failed to write lockfile", err)
+		}
+
+		if machineOutput() {
+			emit(cmd, lockResult{File: path, Lockfile: lock}, warnings...)
+			return
+		}
+		for _, w := range warnings {
+			ui.PrintWarning(w)
+		}
+		ui.PrintSuccess(fmt.Sprintf("Locked %d packages in %s", len(lock.Packages), path))
+	},
+}
+
+// lockVerifyCmd represents the lock verify command.
+// It reports every locked package that is missing or installed at another
+// version and exits non-zero if there is any.
+var lockVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Compare goobrew.lock with what is installed",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		path := lockFile()
+		lock := readLockfile(cmd, path)
+
+		drifts, err := client.VerifyLock(cmd.Context(), lock)
+		if err != nil {
+			logger.Log.Error("failed to verify lockfile", "error", err)
+			exitWithError(cmd, "Failed to verify lockfile", err)
+		}
+
+		if machineOutput() {
+			doc := output.Document{Command: cmd.Name(), Data: lockVerifyResult{File: path, Drifts: drifts}}
+			if len(drifts) > 0 {
+				doc.Error = output.NewError(fmt.Sprintf("%d packages differ from %s", len(drifts), path), nil)
+			}
+			writeDocument(doc)
+		} else {
+			ui.PrintLockDrift(path, len(lock.Packages), drifts)
+		}
+		if len(drifts) > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// lockFile returns the lockfile location: --lockfile, or goobrew.lock next
+// to the Brewfile.
+func lockFile() string {
+	if lockfilePath != "" {
+		return lockfilePath
+	}
+	return filepath.Join(filepath.Dir(bundleFile()), lockfile.Name)
+}
+
+// readLockfile reads the lockfile at path, exiting on failure.
+func readLockfile(cmd *cobra.Command, path string) *lockfile.Lockfile {
+	lock, err := lockfile.Read(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		exitWithError(cmd, "No lockfile at "+path, errors.New(`run "goobrew lock" first`))
+	} else if err != nil {
+		exitWithError(cmd, "Failed to read lockfile", err)
+	}
+	return lock
+}
+
+// requireLocked exits unless installing packages would fetch exactly the
+// versions and bottles recorded in the lockfile.
+func requireLocked(cmd *cobra.Command, packages []string) {
+	path := lockFile()
+	lock := readLockfile(cmd, path)
+
+	tag, err := homebrew.BottleTag(cmd.Context())
+	if err != nil {
+		exitWithError(cmd, "Cannot check locked bottles", err)
+	}
+
+	logger.Log.Info("checking packages against lockfile", "file", path, "tag", tag)
+	drifts := client.CheckLocked(cmd.Context(), lock, packages, tag)
+	if len(drifts) == 0 {
+		return
+	}
+	if !machineOutput() {
+		ui.PrintLockDrift(path, len(lock.Packages), drifts)
+	}
+	exitWithError(cmd, "Refusing to install", fmt.Errorf("%d packages differ from %s", len(drifts), path))
+}
+
+func init() {
+	rootCmd.AddCommand(lockCmd)
+	lockCmd.AddCommand(lockVerifyCmd)
+	lockCmd.PersistentFlags().StringVar(&lockfilePath, "lockfile", "", "lockfile to write or read (default goobrew.lock next to the Brewfile)")
+	lockCmd.PersistentFlags().StringVarP(&bundleFilePath, "file", "f", "", "Brewfile to lock (default $HOMEBREW_BUNDLE_FILE or ./Brewfile)")
+}
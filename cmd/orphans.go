@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"github.com/ofkm/goobrew/internal/logger"
+	"github.com/ofkm/goobrew/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// orphansCmd represents the orphans command.
+// It lists the formulae that were installed only as a dependency and that
+// no formula installed on request needs any more, typically because the
+// formula that pulled them in has been uninstalled. These are the formulae
+// autoremove would remove.
+var orphansCmd = &cobra.Command{
+	Use:   "orphans",
+	Short: "List dependencies that are no longer needed",
+	Long:  `List formulae installed as dependencies that no formula installed on request needs any more.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
+		logger.Log.Info("fetching orphans")
+
+		orphans, err := client.Orphans(ctx)
+		if err != nil {
+			logger.Log.Error("failed to get orphans", "error", err)
+			exitWithError(cmd, "Failed to get orphans", err)
+		}
+
+		if machineOutput() {
+			emit(cmd, orphans)
+			return
+		}
+
+		ui.PrintFormulaList("Orphaned Dependencies", "No orphaned dependencies", orphans)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(orphansCmd)
+}
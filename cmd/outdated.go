@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"github.com/ofkm/goobrew/internal/homebrew"
+	"github.com/ofkm/goobrew/internal/logger"
+	"github.com/ofkm/goobrew/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var outdatedGreedy bool
+
+// outdatedCmd represents the outdated command.
+// It compares the installed formulae and casks against the latest versions
+// in Homebrew's JSON API and lists those with an update available, showing
+// the installed and latest versions and whether the update is a major,
+// minor or patch release. Updates blocked by a goobrew hold are marked.
+var outdatedCmd = &cobra.Command{
+	Use:   "outdated",
+	Short: "List packages with a newer version available",
+	Long: `List installed formulae and casks that have a newer version available.
+
+Casks that update themselves or are versioned "latest" are only listed
+with --greedy. Updates that a hold set with "goobrew pin --at/--until"
+does not allow are marked as held.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
+		logger.Log.Info("checking for outdated packages", "greedy", outdatedGreedy)
+
+		outdated, err := client.Outdated(ctx, homebrew.OutdatedOptions{Greedy: outdatedGreedy})
+		if err != nil {
+			logger.Log.Error("failed to check for outdated packages", "error", err)
+			exitWithError(cmd, "Failed to check for outdated packages", err)
+		}
+		markHeld(outdated)
+
+		if machineOutput() {
+			emit(cmd, outdated)
+			return
+		}
+
+		ui.PrintOutdated(outdated)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(outdatedCmd)
+	outdatedCmd.Flags().BoolVarP(&outdatedGreedy, "greedy", "g", false, "include casks that update themselves or are versioned latest")
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/ofkm/goobrew/internal/logger"
+	"github.com/ofkm/goobrew/internal/output"
+	"github.com/ofkm/goobrew/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// outputFlag holds the raw value of the --output flag.
+var outputFlag string
+
+// outputFormat is the parsed --output format used by all commands.
+var outputFormat = output.FormatText
+
+// operationResult is the machine-readable result of commands that delegate
+// their work to brew, such as uninstall, update and upgrade.
+type operationResult struct {
+	Packages        []string `json:"packages,omitempty"` // Packages are the packages operated on
+	DurationSeconds float64  `json:"duration_seconds"`   // DurationSeconds is the elapsed wall time
+}
+
+// machineOutput reports whether commands should emit a JSON or YAML document
+// instead of coloured text.
+func machineOutput() bool {
+	return outputFormat.Machine()
+}
+
+// writeDocument writes doc to stdout in the selected output format.
+func writeDocument(doc output.Document) {
+	if err := output.Write(os.Stdout, outputFormat, doc); err != nil {
+		logger.Log.Error("failed to write output", "error", err)
+	}
+}
+
+// emit writes a successful result document for cmd.
+func emit(cmd *cobra.Command, data any, warnings ...string) {
+	writeDocument(output.Document{
+		Command:  cmd.Name(),
+		Data:     data,
+		Warnings: warnings,
+	})
+}
+
+// exitWithError reports a command failure and exits with status 1, or 130
+// if the user interrupted the command. In text mode the message and error
+// are printed as a single error line; otherwise an error document is
+// written to stdout.
+func exitWithError(cmd *cobra.Command, message string, err error) {
+	if wasInterrupted() {
+		message = "Interrupted: " + message
+	}
+
+	if machineOutput() {
+		writeDocument(output.Document{
+			Command: cmd.Name(),
+			Error:   output.NewError(message, err),
+		})
+	} else if err != nil {
+		ui.PrintError(message + ": " + err.Error())
+	} else {
+		ui.PrintError(message)
+	}
+	os.Exit(failureExitCode())
+}
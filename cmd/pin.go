@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ofkm/goobrew/internal/config"
+	"github.com/ofkm/goobrew/internal/homebrew"
+	"github.com/ofkm/goobrew/internal/logger"
+	"github.com/ofkm/goobrew/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pinAt     string
+	pinUntil  string
+	pinReason string
+)
+
+// pinList is the data emitted by pin without arguments.
+type pinList struct {
+	Pinned []string      `json:"pinned"` // Pinned are the formulae pinned with brew
+	Holds  []config.Hold `json:"holds"`  // Holds are the goobrew-managed holds from the config file
+}
+
+// pinCmd represents the pin command.
+// Without flags it pins formulae with brew, which blocks every upgrade.
+// With --at, --until or --reason it records a goobrew hold in the config
+// file instead, which upgrade enforces and outdated reports. Without
+// arguments it lists the current pins and holds.
+var pinCmd = &cobra.Command{
+	Use:   "pin [package...]",
+	Short: "Pin packages or hold them at a version",
+	Long: `Pin packages so that upgrade leaves them alone.
+
+Without flags, formulae are pinned with brew at their installed version.
+With --at, --until or --reason, a goobrew hold is stored in the config file
+instead. A hold can keep a package within a version series, such as node at
+20, and can expire on a date. Holds apply to casks too.
+
+Without arguments, the pinned formulae and holds are listed.`,
+	Example: `  goobrew pin node
+  goobrew pin node --at 20 --reason "LTS only"
+  goobrew pin postgresql@16 --until 2026-03-01`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
+		if len(args) == 0 {
+			if cmd.Flags().Changed("at") || cmd.Flags().Changed("until") || cmd.Flags().Changed("reason") {
+				exitWithError(cmd, "Cannot add hold", errors.New("no packages given"))
+			}
+			listPins(cmd)
+			return
+		}
+
+		if pinAt == "" && pinUntil == "" && pinReason == "" {
+			logger.Log.Info("pinning formulae", "packages", args)
+			if err := client.Pin(ctx, args); err != nil {
+				logger.Log.Error("pin failed", "error", err)
+				exitWithError(cmd, "Failed to pin", err)
+			}
+			if machineOutput() {
+				emit(cmd, operationResult{Packages: args})
+				return
+			}
+			ui.PrintSuccess("Pinned " + strings.Join(args, ", "))
+			return
+		}
+
+		var holds []config.Hold
+		for _, pkg := range args {
+			hold := config.Hold{Package: pkg, Version: pinAt, Until: pinUntil, Reason: pinReason}
+			if err := hold.Validate(); err != nil {
+				exitWithError(cmd, "Invalid hold", err)
+			}
+			cfg.SetHold(hold)
+			holds = append(holds, hold)
+		}
+		if err := saveConfig(); err != nil {
+			exitWithError(cmd, "Failed to save hold", err)
+		}
+
+		logger.Log.Info("holds saved", "packages", args)
+		if machineOutput() {
+			emit(cmd, holds)
+			return
+		}
+		for _, hold := range holds {
+			ui.PrintSuccess(fmt.Sprintf("%s is %s", hold.Package, hold))
+		}
+	},
+}
+
+// unpinCmd represents the unpin command.
+// It removes goobrew holds for the given packages and unpins the remaining
+// ones, and any that are also pinned with brew, with brew.
+var unpinCmd = &cobra.Command{
+	Use:   "unpin package...",
+	Short: "Remove pins and holds from packages",
+	Long: `Remove goobrew holds and brew pins from packages, so that upgrade
+updates them again.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
+		pinned := make(map[string]bool)
+		if installed, err := client.InstalledFormulae(ctx); err == nil {
+			for _, f := range installed {
+				pinned[f.Name] = f.Pinned
+			}
+		} else {
+			logger.Log.Warn("could not list pinned formulae", "error", err)
+		}
+
+		var released, brewPinned []string
+		for _, pkg := range args {
+			held := cfg.RemoveHold(pkg)
+			if held {
+				released = append(released, pkg)
+			}
+			// Without a hold the package can only be pinned with brew, so
+			// let brew report it if it is not.
+			if pinned[pkg] || !held {
+				brewPinned = append(brewPinned, pkg)
+			}
+		}
+
+		if len(released) > 0 {
+			if err := saveConfig(); err != nil {
+				exitWithError(cmd, "Failed to remove hold", err)
+			}
+			logger.Log.Info("holds removed", "packages", released)
+		}
+		if len(brewPinned) > 0 {
+			logger.Log.Info("unpinning formulae", "packages", brewPinned)
+			if err := client.Unpin(ctx, brewPinned); err != nil {
+				logger.Log.Error("unpin failed", "error", err)
+				exitWithError(cmd, "Failed to unpin", err)
+			}
+		}
+
+		if machineOutput() {
+			emit(cmd, operationResult{Packages: args})
+			return
+		}
+		if len(released) > 0 {
+			ui.PrintSuccess("Removed holds for " + strings.Join(released, ", "))
+		}
+		if len(brewPinned) > 0 {
+			ui.PrintSuccess("Unpinned " + strings.Join(brewPinned, ", "))
+		}
+	},
+}
+
+// listPins shows the formulae pinned with brew and the goobrew holds.
+func listPins(cmd *cobra.Command) {
+	installed, err := client.InstalledFormulae(cmd.Context())
+	if err != nil {
+		logger.Log.Error("failed to list installed formulae", "error", err)
+		exitWithError(cmd, "Failed to list pinned formulae", err)
+	}
+
+	list := pinList{Pinned: []string{}, Holds: cfg.Holds}
+	for _, f := range installed {
+		if f.Pinned {
+			list.Pinned = append(list.Pinned, f.Name)
+		}
+	}
+	if list.Holds == nil {
+		list.Holds = []config.Hold{}
+	}
+
+	if machineOutput() {
+		emit(cmd, list)
+		return
+	}
+	ui.PrintPins(list.Pinned, list.Holds, time.Now())
+}
+
+// saveConfig writes cfg back to the config file.
+func saveConfig() error {
+	path, err := configFile()
+	if err != nil {
+		return fmt.Errorf("no config file location: %w", err)
+	}
+	return config.Save(path, cfg)
+}
+
+// markHeld sets Held on the packages whose latest version is blocked by a
+// goobrew hold.
+func markHeld(packages []homebrew.OutdatedPackage) {
+	now := time.Now()
+	for i := range packages {
+		if hold, ok := cfg.Hold(packages[i].Name); ok && !hold.Allows(packages[i].LatestVersion, now) {
+			packages[i].Held = hold.String()
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(pinCmd)
+	rootCmd.AddCommand(unpinCmd)
+	pinCmd.Flags().StringVar(&pinAt, "at", "", "hold upgrades within a version series, such as 20 or 16.4")
+	pinCmd.Flags().StringVar(&pinUntil, "until", "", "hold upgrades until a date (YYYY-MM-DD)")
+	pinCmd.Flags().StringVar(&pinReason, "reason", "", "explain the hold")
+}
@@ -3,12 +3,13 @@ package cmd
 
 import (
 	"context"
-	"fmt"
 	"log/slog"
 	"os"
 
+	"github.com/ofkm/goobrew/internal/config"
 	"github.com/ofkm/goobrew/internal/homebrew"
 	"github.com/ofkm/goobrew/internal/logger"
+	"github.com/ofkm/goobrew/internal/output"
 	"github.com/ofkm/goobrew/internal/ui"
 	"github.com/ofkm/goobrew/internal/version"
 	"github.com/spf13/cobra"
@@ -23,6 +24,18 @@ var verbose bool
 // debug enables debug logging when set via the --debug flag.
 var debug bool
 
+// cfg is the user configuration loaded from the config file.
+var cfg *config.Config
+
+// configPath overrides the config file location when set via the --config flag.
+var configPath string
+
+// apiDomain overrides the Homebrew JSON API base URL when set via the --api-domain flag.
+var apiDomain string
+
+// apiMirrors lists fallback API base URLs set via the --api-mirror flag.
+var apiMirrors []string
+
 // rootCmd represents the root command of goobrew.
 var rootCmd = &cobra.Command{
 	Use:   "goobrew",
@@ -40,14 +53,30 @@ beautiful, user-friendly interface.`,
 			logger.SetLevel(slog.LevelWarn)
 		}
 
-		// Initialize client
+		// Select output format
 		var err error
-		client, err = homebrew.NewClient()
+		outputFormat, err = output.ParseFormat(outputFlag)
 		if err != nil {
 			ui.PrintError(err.Error())
 			os.Exit(1)
 		}
 
+		// Load configuration
+		cfg, err = loadConfig()
+		if err != nil {
+			exitWithError(cmd, "Failed to load config", err)
+		}
+
+		// Initialize client, keeping stdout clean for machine-readable output
+		opts := []homebrew.ClientOption{apiEndpoints()}
+		if machineOutput() {
+			opts = append(opts, homebrew.WithOutput(os.Stderr))
+		}
+		client, err = homebrew.NewClient(opts...)
+		if err != nil {
+			exitWithError(cmd, err.Error(), nil)
+		}
+
 		logger.Log.Debug("goobrew initialized", "version", version.Version)
 	},
 	Run: func(cmd *cobra.Command, args []string) {
@@ -56,23 +85,74 @@ beautiful, user-friendly interface.`,
 			return
 		}
 		// Pass through to brew for unknown commands
-		ctx := context.Background()
+		ctx := cmd.Context()
 		if err := client.ExecuteCommand(ctx, args); err != nil {
-			ui.PrintError(fmt.Sprintf("Command failed: %v", err))
-			os.Exit(1)
+			exitWithError(cmd, "Command failed", err)
 		}
 	},
 }
 
 // Execute runs the root command and all registered subcommands.
-// This is the main entry point for command execution. It returns an error
-// if command execution fails.
+// This is the main entry point for command execution. Commands run with a
+// context that Ctrl-C cancels (see notifyContext). It returns an error
+// if command execution fails. When --output selects a machine-readable
+// format, the error is written as an error document and the process exits.
 func Execute() error {
-	return rootCmd.Execute()
+	ctx, stop := notifyContext(context.Background())
+	defer stop()
+
+	cmd, err := rootCmd.ExecuteContextC(ctx)
+	if err != nil {
+		if format, ferr := output.ParseFormat(outputFlag); ferr == nil && format.Machine() {
+			outputFormat = format
+			exitWithError(cmd, "Command failed", err)
+		}
+	}
+	return err
+}
+
+// loadConfig reads the config file from --config or the default location.
+func loadConfig() (*config.Config, error) {
+	path, err := configFile()
+	if err != nil {
+		logger.Log.Debug("no config directory available", "error", err)
+		return &config.Config{}, nil
+	}
+	return config.Load(path)
+}
+
+// configFile returns the path of the config file: --config if given,
+// otherwise the default location.
+func configFile() (string, error) {
+	if configPath != "" {
+		return configPath, nil
+	}
+	return config.DefaultPath()
+}
+
+// apiEndpoints resolves the API base URL and mirrors. The base URL comes from
+// --api-domain, then HOMEBREW_API_DOMAIN, then the config file; mirrors from
+// --api-mirror are tried before those listed in the config file.
+func apiEndpoints() homebrew.ClientOption {
+	base := apiDomain
+	if base == "" {
+		base = os.Getenv("HOMEBREW_API_DOMAIN")
+	}
+	if base == "" {
+		base = cfg.APIDomain
+	}
+
+	mirrors := append(append([]string{}, apiMirrors...), cfg.APIMirrors...)
+	logger.Log.Debug("using API endpoints", "base", base, "mirrors", mirrors)
+	return homebrew.WithAPIEndpoints(base, mirrors...)
 }
 
 func init() {
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
 	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "debug output")
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "config file (default $GOOBREW_CONFIG or <user config dir>/goobrew/config.json)")
+	rootCmd.PersistentFlags().StringVar(&apiDomain, "api-domain", "", "Homebrew JSON API base URL (default $HOMEBREW_API_DOMAIN)")
+	rootCmd.PersistentFlags().StringSliceVar(&apiMirrors, "api-mirror", nil, "fallback API base URL, may be repeated")
+	rootCmd.PersistentFlags().StringVarP(&outputFlag, "output", "o", "text", "output format: text, json or yaml")
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
 }
@@ -1,9 +1,7 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
-	"os"
 
 	"github.com/ofkm/goobrew/internal/logger"
 	"github.com/ofkm/goobrew/internal/ui"
@@ -13,7 +11,8 @@ import (
 // searchCmd represents the search command.
 // It searches for packages (both formulae and casks) in Homebrew repositories
 // by matching the search term against package names and descriptions.
-// The search is case-insensitive and displays results with beautiful formatting.
+// The search is case-insensitive, ranks the best matches first and marks
+// packages that are already installed.
 var searchCmd = &cobra.Command{
 	Use:     "search [term]",
 	Aliases: []string{"s"},
@@ -21,21 +20,40 @@ var searchCmd = &cobra.Command{
 	Long:    `Search for packages in Homebrew repositories with beautiful formatting.`,
 	Args:    cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		ctx := context.Background()
+		ctx := cmd.Context()
 		searchTerm := args[0]
 
-		fmt.Printf("\n%s %sSearching for:%s %s\n", ui.IconSearch, ui.Bold, ui.Reset, searchTerm)
+		if !machineOutput() {
+			fmt.Printf("\n%s %sSearching for:%s %s\n", ui.IconSearch, ui.Bold, ui.Reset, searchTerm)
+		}
 
 		logger.Log.Info("searching packages", "term", searchTerm)
 
-		formulae, casks, err := client.Search(ctx, searchTerm)
+		result, err := client.Search(ctx, searchTerm)
 		if err != nil {
-			ui.PrintError("Search failed: " + err.Error())
 			logger.Log.Error("search failed", "error", err, "term", searchTerm)
-			os.Exit(1)
+			exitWithError(cmd, "Search failed", err)
+		}
+
+		if err := client.MarkInstalled(ctx, result); err != nil {
+			logger.Log.Debug("could not determine installed packages", "error", err)
+		}
+
+		var warnings []string
+		if age, stale := client.IndexAge(); stale {
+			warnings = append(warnings, fmt.Sprintf("Offline: showing results from package index cached %s ago", ui.FormatDuration(age)))
+		}
+
+		if machineOutput() {
+			emit(cmd, result, warnings...)
+			return
+		}
+
+		for _, w := range warnings {
+			ui.PrintWarning(w)
 		}
 
-		ui.PrintSearchResults(formulae, casks)
+		ui.PrintSearchResults(result)
 	},
 }
 
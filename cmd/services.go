@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ofkm/goobrew/internal/homebrew"
+	"github.com/ofkm/goobrew/internal/logger"
+	"github.com/ofkm/goobrew/internal/output"
+	"github.com/ofkm/goobrew/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// servicesCmd represents the services command.
+// It manages the background services formulae define natively: services
+// are rendered from the formula's service definition into systemd user
+// units on Linux and launchd agents on macOS, and the ones goobrew starts
+// are recorded in its own directory. Without a subcommand it lists them;
+// other subcommands are passed through to brew services.
+var servicesCmd = &cobra.Command{
+	Use:   "services [subcommand]",
+	Short: "Manage the background services of installed formulae",
+	Long: `Manage the background services that installed formulae define.
+
+Services are written as systemd user units on Linux and as launchd agents
+on macOS, and controlled with systemctl --user or launchctl. Without a
+subcommand, the services of all installed formulae are listed. Subcommands
+other than list, start, stop, restart and info are passed through to brew
+services, as is everything when systemctl or a systemd user manager is not
+available.`,
+	Example: `  goobrew services
+  goobrew services start redis postgresql@16
+  goobrew services info redis
+  goobrew services cleanup`,
+	DisableFlagParsing: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			listServices(cmd)
+			return
+		}
+		if err := client.ExecuteCommand(cmd.Context(), append([]string{"services"}, args...)); err != nil {
+			exitWithError(cmd, "Command failed", err)
+		}
+	},
+}
+
+// servicesListCmd represents the services list command.
+var servicesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the services of installed formulae with their status",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		listServices(cmd)
+	},
+}
+
+// servicesStartCmd represents the services start command.
+var servicesStartCmd = &cobra.Command{
+	Use:   "start formula...",
+	Short: "Start services and run them at login",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runServices(cmd, args, "Started", client.StartService)
+	},
+}
+
+// servicesStopCmd represents the services stop command.
+var servicesStopCmd = &cobra.Command{
+	Use:   "stop formula...",
+	Short: "Stop services and no longer run them at login",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runServices(cmd, args, "Stopped", client.StopService)
+	},
+}
+
+// servicesRestartCmd represents the services restart command.
+var servicesRestartCmd = &cobra.Command{
+	Use:   "restart formula...",
+	Short: "Stop services if they are running and start them again",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runServices(cmd, args, "Restarted", client.RestartService)
+	},
+}
+
+// servicesInfoCmd represents the services info command.
+var servicesInfoCmd = &cobra.Command{
+	Use:   "info formula...",
+	Short: "Show the status and definition of services",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runServices(cmd, args, "", client.ServiceInfo)
+	},
+}
+
+// listServices lists the services of the installed formulae.
+func listServices(cmd *cobra.Command) {
+	services, err := client.ListServices(cmd.Context())
+	if servicesFallback(cmd, "list", nil, err) {
+		return
+	}
+	if err != nil {
+		logger.Log.Error("failed to list services", "error", err)
+		exitWithError(cmd, "Failed to list services", err)
+	}
+
+	if machineOutput() {
+		emit(cmd, services)
+		return
+	}
+	ui.PrintServices(services)
+}
+
+// runServices applies fn to the service of each formula and reports the
+// results, exiting non-zero if any of them failed. Without a verb, as for
+// info, each service is shown in full.
+func runServices(cmd *cobra.Command, names []string, verb string, fn func(context.Context, string) (*homebrew.ServiceInfo, error)) {
+	var (
+		results []*homebrew.ServiceInfo
+		errs    []error
+	)
+	for _, name := range names {
+		logger.Log.Info("running services "+cmd.Name(), "formula", name)
+		info, err := fn(cmd.Context(), name)
+		if servicesFallback(cmd, cmd.Name(), names, err) {
+			return
+		}
+		if err != nil {
+			logger.Log.Error("services "+cmd.Name()+" failed", "formula", name, "error", err)
+			errs = append(errs, err)
+			if !machineOutput() {
+				ui.PrintError(fmt.Sprintf("Failed to %s %s: %v", cmd.Name(), name, err))
+			}
+			continue
+		}
+		results = append(results, info)
+		if machineOutput() {
+			continue
+		}
+		if verb == "" {
+			ui.PrintServiceInfo(info)
+		} else {
+			ui.PrintSuccess(fmt.Sprintf("%s %s (%s)", verb, name, info.Status))
+		}
+	}
+
+	if machineOutput() {
+		doc := output.Document{Command: cmd.Name(), Data: results}
+		if len(errs) > 0 {
+			doc.Error = output.NewError(fmt.Sprintf("Failed to %s %d of %d services", cmd.Name(), len(errs), len(names)), errors.Join(errs...))
+		}
+		writeDocument(doc)
+	}
+	if len(errs) > 0 {
+		os.Exit(failureExitCode())
+	}
+}
+
+// servicesFallback runs brew services subcommand with args instead, and
+// reports whether it did, if err says goobrew cannot manage services on
+// this system. Machine-readable output gets the error instead, since brew
+// services does not produce it.
+func servicesFallback(cmd *cobra.Command, subcommand string, args []string, err error) bool {
+	if !errors.Is(err, homebrew.ErrServicesUnsupported) || machineOutput() {
+		return false
+	}
+	logger.Log.Warn("falling back to brew services", "error", err)
+	if err := client.ExecuteCommand(cmd.Context(), append([]string{"services", subcommand}, args...)); err != nil {
+		exitWithError(cmd, "Command failed", err)
+	}
+	return true
+}
+
+func init() {
+	rootCmd.AddCommand(servicesCmd)
+	servicesCmd.AddCommand(servicesListCmd, servicesStartCmd, servicesStopCmd, servicesRestartCmd, servicesInfoCmd)
+}
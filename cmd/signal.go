@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/ofkm/goobrew/internal/homebrew"
+	"github.com/ofkm/goobrew/internal/ui"
+)
+
+// exitInterrupted is the exit status after the user interrupted goobrew,
+// following the shell convention of 128 + SIGINT.
+const exitInterrupted = 130
+
+// interrupts counts the SIGINT and SIGTERM signals received so far.
+var interrupts atomic.Int32
+
+// notifyContext returns a context that is cancelled by the first SIGINT or
+// SIGTERM, which asks running brew processes to stop after their current
+// step. A SIGINT is taken to come from the terminal, which has delivered it
+// to the brew processes in goobrew's process group too, so the context is
+// cancelled with homebrew.ErrTerminalInterrupt. A second signal force-kills
+// them and a third exits immediately. The returned stop function releases
+// the signal handler.
+func notifyContext(parent context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(parent)
+	sigs := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		for {
+			select {
+			case sig := <-sigs:
+				handleInterrupt(cancel, sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(sigs)
+		close(done)
+		cancel(nil)
+	}
+}
+
+// handleInterrupt escalates with each signal received.
+func handleInterrupt(cancel context.CancelCauseFunc, sig os.Signal) {
+	switch interrupts.Add(1) {
+	case 1:
+		fmt.Fprintf(os.Stderr, "\n%s %sInterrupted, stopping after the current step (press Ctrl-C again to force)%s\n",
+			ui.IconWarning, ui.Yellow, ui.Reset)
+		var cause error
+		if sig == os.Interrupt {
+			cause = homebrew.ErrTerminalInterrupt
+		}
+		cancel(cause)
+	case 2:
+		fmt.Fprintf(os.Stderr, "\n%s %sForce-killing running brew processes%s\n", ui.IconError, ui.Red, ui.Reset)
+		if client != nil {
+			client.Kill()
+		}
+	default:
+		os.Exit(exitInterrupted)
+	}
+}
+
+// wasInterrupted reports whether the user has interrupted goobrew.
+func wasInterrupted() bool {
+	return interrupts.Load() > 0
+}
+
+// failureExitCode returns the exit status for a failed command: 130 if the
+// user interrupted it and 1 otherwise.
+func failureExitCode() int {
+	if wasInterrupted() {
+		return exitInterrupted
+	}
+	return 1
+}
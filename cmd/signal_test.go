@@ -0,0 +1,30 @@
+//go:build unix
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestNotifyContext(t *testing.T) {
+	ctx, stop := notifyContext(context.Background())
+	defer stop()
+	defer interrupts.Store(0)
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGINT); err != nil {
+		t.Skipf("Skipping: cannot signal self: %v", err)
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("First interrupt should cancel the context")
+	}
+	if !wasInterrupted() || failureExitCode() != exitInterrupted {
+		t.Error("Expected the interrupt to be recorded with exit status 130")
+	}
+}
@@ -1,21 +1,24 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
-	"os"
 	"strings"
 	"time"
 
+	"github.com/ofkm/goobrew/internal/homebrew"
 	"github.com/ofkm/goobrew/internal/logger"
 	"github.com/ofkm/goobrew/internal/ui"
 	"github.com/spf13/cobra"
 )
 
+var uninstallIgnoreDependencies bool
+
 // uninstallCmd represents the uninstall command.
 // It removes one or more packages from the system using Homebrew's uninstall
 // functionality. The command accepts multiple package names and displays
-// progress and timing information during the uninstallation process.
+// progress and timing information during the uninstallation process. It
+// refuses to remove formulae that other installed formulae still depend on
+// unless --ignore-dependencies is given.
 var uninstallCmd = &cobra.Command{
 	Use:     "uninstall [package...]",
 	Aliases: []string{"remove", "rm"},
@@ -23,27 +26,70 @@ var uninstallCmd = &cobra.Command{
 	Long:    `Uninstall one or more Homebrew packages.`,
 	Args:    cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		ctx := context.Background()
+		ctx := cmd.Context()
+
+		var opts []homebrew.UninstallOption
+		if uninstallIgnoreDependencies {
+			opts = append(opts, homebrew.WithIgnoreDependencies())
+		} else if err := checkDependents(cmd, args); err != nil {
+			exitWithError(cmd, "Refusing to uninstall", err)
+		}
 
-		fmt.Printf("\n%s %sUninstalling packages:%s %s\n\n",
-			ui.IconTrash, ui.Bold, ui.Reset, strings.Join(args, ", "))
+		if !machineOutput() {
+			fmt.Printf("\n%s %sUninstalling packages:%s %s\n\n",
+				ui.IconTrash, ui.Bold, ui.Reset, strings.Join(args, ", "))
+		}
 
 		start := time.Now()
 		logger.Log.Info("uninstalling packages", "packages", args)
 
-		if err := client.Uninstall(ctx, args); err != nil {
+		if err := client.Uninstall(ctx, args, opts...); err != nil {
 			elapsed := time.Since(start)
-			ui.PrintError(fmt.Sprintf("Uninstallation failed (took %s): %v", ui.FormatDuration(elapsed), err))
 			logger.Log.Error("uninstallation failed", "error", err)
-			os.Exit(1)
+			exitWithError(cmd, fmt.Sprintf("Uninstallation failed (took %s)", ui.FormatDuration(elapsed)), err)
 		}
 
 		elapsed := time.Since(start)
+		if machineOutput() {
+			emit(cmd, operationResult{Packages: args, DurationSeconds: elapsed.Seconds()})
+			return
+		}
 		fmt.Printf("\n%s Uninstallation completed in %s%s%s\n\n",
 			ui.IconSuccess, ui.Green, ui.FormatDuration(elapsed), ui.Reset)
 	},
 }
 
+// checkDependents returns an error naming the installed formulae that still
+// depend on any of packages. Packages that are removed together are not
+// reported. If the installed formulae cannot be listed, the check is skipped
+// and left to brew.
+func checkDependents(cmd *cobra.Command, packages []string) error {
+	dependents, err := client.Uses(cmd.Context(), packages, homebrew.UsesOptions{})
+	if err != nil {
+		logger.Log.Warn("could not check installed dependents", "error", err)
+		return nil
+	}
+	if len(dependents) == 0 {
+		return nil
+	}
+
+	requiredBy := make(map[string][]string)
+	for _, d := range dependents {
+		for _, pkg := range d.Uses {
+			requiredBy[pkg] = append(requiredBy[pkg], d.Name)
+		}
+	}
+
+	var reasons []string
+	for _, pkg := range packages {
+		if names := requiredBy[pkg]; len(names) > 0 {
+			reasons = append(reasons, fmt.Sprintf("%s is required by %s", pkg, strings.Join(names, ", ")))
+		}
+	}
+	return fmt.Errorf("%s (use --ignore-dependencies to remove anyway)", strings.Join(reasons, "; "))
+}
+
 func init() {
 	rootCmd.AddCommand(uninstallCmd)
+	uninstallCmd.Flags().BoolVar(&uninstallIgnoreDependencies, "ignore-dependencies", false, "uninstall even if installed formulae depend on the packages")
 }
@@ -1,9 +1,7 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/ofkm/goobrew/internal/logger"
@@ -21,21 +19,26 @@ var updateCmd = &cobra.Command{
 	Short:   "Update Homebrew and formulae",
 	Long:    `Update Homebrew itself and all formulae from GitHub.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		ctx := context.Background()
+		ctx := cmd.Context()
 
-		fmt.Printf("\n%s %sUpdating Homebrew...%s\n\n", ui.IconUpdate, ui.Bold, ui.Reset)
+		if !machineOutput() {
+			fmt.Printf("\n%s %sUpdating Homebrew...%s\n\n", ui.IconUpdate, ui.Bold, ui.Reset)
+		}
 		start := time.Now()
 
 		logger.Log.Info("updating homebrew")
 
 		if err := client.Update(ctx); err != nil {
 			elapsed := time.Since(start)
-			ui.PrintError(fmt.Sprintf("Update failed (took %s): %v", ui.FormatDuration(elapsed), err))
 			logger.Log.Error("update failed", "error", err)
-			os.Exit(1)
+			exitWithError(cmd, fmt.Sprintf("Update failed (took %s)", ui.FormatDuration(elapsed)), err)
 		}
 
 		elapsed := time.Since(start)
+		if machineOutput() {
+			emit(cmd, operationResult{DurationSeconds: elapsed.Seconds()})
+			return
+		}
 		fmt.Printf("\n%s Update completed in %s%s%s\n\n",
 			ui.IconSuccess, ui.Green, ui.FormatDuration(elapsed), ui.Reset)
 	},
@@ -1,49 +1,239 @@
 package cmd
 
 import (
-	"context"
+	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"time"
 
+	"github.com/ofkm/goobrew/internal/homebrew"
 	"github.com/ofkm/goobrew/internal/logger"
 	"github.com/ofkm/goobrew/internal/ui"
 	"github.com/spf13/cobra"
 )
 
+// upgradeInteractive selects the packages to upgrade in a picker, set via --interactive.
+var upgradeInteractive bool
+
 // upgradeCmd represents the upgrade command.
 // It upgrades installed packages to their latest versions. If no package names
 // are specified, all outdated packages will be upgraded. If package names are
-// provided, only those packages will be upgraded.
+// provided, only those packages will be upgraded. With --interactive, the
+// outdated packages are listed in a picker first and only the selected ones
+// are upgraded, each with its own progress row. Packages held by a goobrew
+// hold are skipped unless the latest version is within the hold, and are
+// pinned with brew while the upgrade runs so that it does not upgrade them
+// as dependencies either.
 var upgradeCmd = &cobra.Command{
 	Use:   "upgrade [package...]",
 	Short: "Upgrade packages",
-	Long:  `Upgrade installed packages to their latest versions.`,
+	Long: `Upgrade installed packages to their latest versions.
+
+With --interactive, outdated packages are listed with their current and new
+versions, homepage and release notes, and only the packages you select are
+upgraded. Package arguments limit the list to those packages.
+
+Packages with a goobrew hold (see "goobrew pin --at/--until") are skipped
+while their latest version is outside the hold. Held formulae are pinned
+with brew for the duration of the upgrade, so that they are not upgraded as
+dependencies of other packages either.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		ctx := context.Background()
+		if upgradeInteractive {
+			runInteractiveUpgrade(cmd, args)
+			return
+		}
 
-		if len(args) == 0 {
+		ctx := cmd.Context()
+
+		switch {
+		case machineOutput():
+		case len(args) == 0:
 			fmt.Printf("\n%s %sUpgrading all packages...%s\n\n", ui.IconRocket, ui.Bold, ui.Reset)
-		} else {
+		default:
 			fmt.Printf("\n%s %sUpgrading packages:%s %v\n\n", ui.IconRocket, ui.Bold, ui.Reset, args)
 		}
 
+		packages, held, warnings := args, []string(nil), []string(nil)
+		if len(cfg.Holds) > 0 {
+			packages, held, warnings = enforceHolds(cmd, args)
+			if len(packages) == 0 {
+				if machineOutput() {
+					emit(cmd, operationResult{}, warnings...)
+					return
+				}
+				fmt.Printf("%s Nothing to upgrade\n\n", ui.IconSuccess)
+				return
+			}
+		}
+
 		start := time.Now()
-		logger.Log.Info("upgrading packages", "packages", args)
+		logger.Log.Info("upgrading packages", "packages", packages)
 
-		if err := client.Upgrade(ctx, args); err != nil {
+		err := client.HoldDuring(ctx, held, func() error {
+			return client.Upgrade(ctx, packages)
+		})
+		if err != nil {
 			elapsed := time.Since(start)
-			ui.PrintError(fmt.Sprintf("Upgrade failed (took %s): %v", ui.FormatDuration(elapsed), err))
 			logger.Log.Error("upgrade failed", "error", err)
-			os.Exit(1)
+			exitWithError(cmd, fmt.Sprintf("Upgrade failed (took %s)", ui.FormatDuration(elapsed)), err)
 		}
 
 		elapsed := time.Since(start)
+		if machineOutput() {
+			emit(cmd, operationResult{Packages: packages, DurationSeconds: elapsed.Seconds()}, warnings...)
+			return
+		}
 		fmt.Printf("\n%s Upgrade completed in %s%s%s\n\n",
 			ui.IconSuccess, ui.Green, ui.FormatDuration(elapsed), ui.Reset)
 	},
 }
 
+// enforceHolds returns the packages to pass to brew upgrade so that no
+// package is upgraded past its goobrew hold, the held formulae to pin while
+// it runs, since brew upgrades the outdated dependencies of the packages it
+// is given, and a warning for each held package. Without args every
+// outdated package that is not held is listed explicitly; otherwise args
+// is filtered. Since brew can only upgrade to the latest version, a package
+// whose latest version is outside its hold is skipped entirely.
+func enforceHolds(cmd *cobra.Command, args []string) ([]string, []string, []string) {
+	outdated, err := client.Outdated(cmd.Context(), homebrew.OutdatedOptions{})
+	if err != nil {
+		logger.Log.Error("failed to check for outdated packages", "error", err)
+		exitWithError(cmd, "Failed to check holds", err)
+	}
+	markHeld(outdated)
+
+	held := make(map[string]string)
+	var allowed, warnings []string
+	for _, pkg := range outdated {
+		if pkg.Held != "" {
+			held[pkg.Name] = pkg.Held
+		} else if !pkg.Pinned {
+			allowed = append(allowed, pkg.Name)
+		}
+	}
+	if len(args) > 0 {
+		allowed = nil
+		for _, name := range args {
+			if _, ok := held[name]; !ok {
+				allowed = append(allowed, name)
+			}
+		}
+	}
+
+	for _, pkg := range outdated {
+		reason, ok := held[pkg.Name]
+		if !ok || len(args) > 0 && !slices.Contains(args, pkg.Name) {
+			continue
+		}
+		warning := fmt.Sprintf("Skipping %s %s, it is %s", pkg.Name, pkg.LatestVersion, reason)
+		warnings = append(warnings, warning)
+		if !machineOutput() {
+			ui.PrintWarning(warning)
+		}
+	}
+	return allowed, heldFormulae(outdated), warnings
+}
+
+// heldFormulae returns the outdated formulae whose latest version is
+// outside their goobrew hold and that brew has not pinned already.
+func heldFormulae(outdated []homebrew.OutdatedPackage) []string {
+	var names []string
+	for _, pkg := range outdated {
+		if pkg.Held != "" && !pkg.Cask && !pkg.Pinned {
+			names = append(names, pkg.Name)
+		}
+	}
+	return names
+}
+
+// runInteractiveUpgrade lets the user pick outdated packages, limited to
+// args if any are given, and upgrades the selection one package at a time.
+// Pinned formulae are not offered since brew will not upgrade them, and
+// neither are packages whose latest version is outside their goobrew hold,
+// which are pinned while the upgrade runs.
+func runInteractiveUpgrade(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+
+	if machineOutput() {
+		exitWithError(cmd, "Cannot upgrade interactively", errors.New("--interactive requires text output"))
+	}
+
+	logger.Log.Info("checking for outdated packages")
+	outdated, err := client.Outdated(ctx, homebrew.OutdatedOptions{})
+	if err != nil {
+		logger.Log.Error("failed to check for outdated packages", "error", err)
+		exitWithError(cmd, "Failed to check for outdated packages", err)
+	}
+	markHeld(outdated)
+
+	var candidates []homebrew.OutdatedPackage
+	for _, pkg := range outdated {
+		if len(args) > 0 && !slices.Contains(args, pkg.Name) {
+			continue
+		}
+		if pkg.Pinned {
+			ui.PrintInfo(fmt.Sprintf("Skipping %s, it is pinned", pkg.Name))
+			continue
+		}
+		if pkg.Held != "" {
+			ui.PrintInfo(fmt.Sprintf("Skipping %s %s, it is %s", pkg.Name, pkg.LatestVersion, pkg.Held))
+			continue
+		}
+		candidates = append(candidates, pkg)
+	}
+	if len(candidates) == 0 {
+		fmt.Printf("\n%s Everything is up to date\n\n", ui.IconSuccess)
+		return
+	}
+
+	selected, ok := ui.PickUpgrades(os.Stdin, os.Stdout, candidates)
+	if !ok {
+		ui.PrintInfo("Upgrade cancelled")
+		return
+	}
+	if len(selected) == 0 {
+		ui.PrintInfo("Nothing selected")
+		return
+	}
+
+	names := make([]string, len(selected))
+	for i, pkg := range selected {
+		names[i] = pkg.Name
+	}
+	fmt.Printf("\n%s %sUpgrading packages:%s %v\n\n", ui.IconRocket, ui.Bold, ui.Reset, names)
+
+	start := time.Now()
+	logger.Log.Info("upgrading selected packages", "packages", names)
+
+	statusChan := make(chan homebrew.InstallationStatus, 100)
+	var upgradeErr error
+	go func() {
+		defer close(statusChan)
+		upgradeErr = client.HoldDuring(ctx, heldFormulae(outdated), func() error {
+			return client.UpgradeEach(ctx, names, statusChan)
+		})
+	}()
+	showProgress(statusChan, "upgrade")
+
+	elapsed := time.Since(start)
+	switch {
+	case wasInterrupted():
+		fmt.Printf("\n%s Upgrade interrupted after %s%s%s\n\n",
+			ui.IconWarning, ui.Yellow, ui.FormatDuration(elapsed), ui.Reset)
+		os.Exit(failureExitCode())
+	case upgradeErr != nil:
+		logger.Log.Error("upgrade failed", "error", upgradeErr)
+		fmt.Printf("\n%s Upgrade finished with errors in %s%s%s\n\n",
+			ui.IconError, ui.Red, ui.FormatDuration(elapsed), ui.Reset)
+		os.Exit(1)
+	}
+	fmt.Printf("\n%s Upgrade completed in %s%s%s\n\n",
+		ui.IconSuccess, ui.Green, ui.FormatDuration(elapsed), ui.Reset)
+}
+
 func init() {
 	rootCmd.AddCommand(upgradeCmd)
+	upgradeCmd.Flags().BoolVarP(&upgradeInteractive, "interactive", "i", false, "choose which outdated packages to upgrade")
 }
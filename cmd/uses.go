@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"github.com/ofkm/goobrew/internal/homebrew"
+	"github.com/ofkm/goobrew/internal/logger"
+	"github.com/ofkm/goobrew/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	usesRecursive bool
+	usesAll       bool
+)
+
+// usesCmd represents the uses command.
+// It lists the formulae that depend on one or more formulae, answering
+// "what breaks if I remove this?". By default only installed formulae are
+// searched, using the runtime dependencies brew recorded when they were
+// installed; --all searches every formula in Homebrew's JSON API instead.
+var usesCmd = &cobra.Command{
+	Use:   "uses [formula...]",
+	Short: "Show formulae that depend on a formula",
+	Long: `Show the formulae that depend on one or more formulae.
+
+Only installed formulae are searched unless --all is given. Use
+--recursive to include formulae that depend on them indirectly.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
+		logger.Log.Info("finding dependents", "formulae", args, "recursive", usesRecursive, "all", usesAll)
+
+		dependents, err := client.Uses(ctx, args, homebrew.UsesOptions{
+			Recursive: usesRecursive,
+			All:       usesAll,
+		})
+		if err != nil {
+			logger.Log.Error("failed to find dependents", "error", err, "formulae", args)
+			exitWithError(cmd, "Failed to find dependents", err)
+		}
+
+		if machineOutput() {
+			emit(cmd, dependents)
+			return
+		}
+
+		ui.PrintDependents(args, dependents)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(usesCmd)
+	usesCmd.Flags().BoolVarP(&usesRecursive, "recursive", "r", false, "include formulae that depend on them indirectly")
+	usesCmd.Flags().BoolVar(&usesAll, "all", false, "search all formulae, not only installed ones")
+}
@@ -15,10 +15,26 @@ var versionCmd = &cobra.Command{
 	Short: "Show version information",
 	Long:  `Display version information including git commit and build time.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		if machineOutput() {
+			emit(cmd, versionInfo{
+				Version:   version.Version,
+				Commit:    version.Commit,
+				BuildTime: version.BuildTime,
+			})
+			return
+		}
+
 		fmt.Println(version.GetFullVersion())
 	},
 }
 
+// versionInfo is the machine-readable form of the version command.
+type versionInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+}
+
 func init() {
 	rootCmd.AddCommand(versionCmd)
 }
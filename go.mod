@@ -5,6 +5,7 @@ go 1.25.3
 require (
 	github.com/lmittmann/tint v1.1.2
 	github.com/spf13/cobra v1.10.1
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
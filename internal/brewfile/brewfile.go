@@ -0,0 +1,361 @@
+// Package brewfile reads and writes Brewfiles, the Ruby DSL used by
+// `brew bundle` to declare taps, formulae, casks, Mac App Store apps and
+// VS Code extensions. Only the declarative subset is supported: one
+// directive per statement with literal arguments, optionally followed by
+// an `if OS.mac?` or `unless OS.linux?` modifier.
+package brewfile
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kind is the directive that declared an entry.
+type Kind string
+
+// Entry kinds, in the order dump writes them.
+const (
+	KindTap    Kind = "tap"    // KindTap is a third-party repository
+	KindBrew   Kind = "brew"   // KindBrew is a formula
+	KindCask   Kind = "cask"   // KindCask is a cask
+	KindMas    Kind = "mas"    // KindMas is a Mac App Store app, installed with mas
+	KindVSCode Kind = "vscode" // KindVSCode is a VS Code extension
+)
+
+// kindOrder ranks kinds for sorting.
+var kindOrder = map[Kind]int{KindTap: 0, KindBrew: 1, KindCask: 2, KindMas: 3, KindVSCode: 4}
+
+// RestartPolicy says when the service of a formula is restarted.
+type RestartPolicy string
+
+// Restart policies.
+const (
+	RestartNever   RestartPolicy = ""        // RestartNever leaves the service alone
+	RestartAlways  RestartPolicy = "always"  // RestartAlways restarts the service on every bundle install
+	RestartChanged RestartPolicy = "changed" // RestartChanged restarts the service when the formula was installed or upgraded
+)
+
+// Entry is one package declared in a Brewfile.
+type Entry struct {
+	Kind           Kind           `json:"kind"`                      // Kind is the directive, such as "brew"
+	Name           string         `json:"name"`                      // Name is the tap, formula, cask, app or extension name
+	Line           int            `json:"line,omitempty"`            // Line is where the entry was declared
+	URL            string         `json:"url,omitempty"`             // URL is the clone URL of a tap
+	Args           []string       `json:"args,omitempty"`            // Args are the install flags, such as "--HEAD" or "--appdir=~/Applications"
+	RestartService RestartPolicy  `json:"restart_service,omitempty"` // RestartService says when to restart the formula's service
+	Link           *bool          `json:"link,omitempty"`            // Link forces the formula to be linked or unlinked after install
+	ID             int64          `json:"id,omitempty"`              // ID is the App Store identifier of a mas entry
+	Options        map[string]any `json:"options,omitempty"`         // Options are any other keyword arguments, kept as written
+}
+
+// String formats the entry as a Brewfile line.
+func (e Entry) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s", e.Kind, strconv.Quote(e.Name))
+	if e.Kind == KindTap && e.URL != "" {
+		fmt.Fprintf(&b, ", %s", strconv.Quote(e.URL))
+	}
+	if len(e.Args) > 0 {
+		if e.Kind == KindCask {
+			b.WriteString(", args: { ")
+			for i, arg := range e.Args {
+				key, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+				key = strings.ReplaceAll(key, "-", "_")
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				if hasValue {
+					fmt.Fprintf(&b, "%s: %s", key, strconv.Quote(value))
+				} else {
+					fmt.Fprintf(&b, "%s: true", key)
+				}
+			}
+			b.WriteString(" }")
+		} else {
+			quoted := make([]string, len(e.Args))
+			for i, arg := range e.Args {
+				quoted[i] = strconv.Quote(strings.TrimPrefix(arg, "--"))
+			}
+			fmt.Fprintf(&b, ", args: [%s]", strings.Join(quoted, ", "))
+		}
+	}
+	switch e.RestartService {
+	case RestartAlways:
+		b.WriteString(", restart_service: true")
+	case RestartChanged:
+		b.WriteString(", restart_service: :changed")
+	}
+	if e.Link != nil {
+		fmt.Fprintf(&b, ", link: %t", *e.Link)
+	}
+	if e.Kind == KindMas {
+		fmt.Fprintf(&b, ", id: %d", e.ID)
+	}
+	return b.String()
+}
+
+// Brewfile is a parsed Brewfile.
+type Brewfile struct {
+	Entries  []Entry  `json:"entries"`             // Entries are the declared packages in file order
+	CaskArgs []string `json:"cask_args,omitempty"` // CaskArgs are the default flags from cask_args, applied to every cask
+}
+
+// Filter returns the entries of the given kind.
+func (b *Brewfile) Filter(kind Kind) []Entry {
+	var entries []Entry
+	for _, e := range b.Entries {
+		if e.Kind == kind {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// InstallArgs returns the flags to install e with. For casks, the defaults
+// from cask_args come first and are overridden by the cask's own args.
+func (b *Brewfile) InstallArgs(e Entry) []string {
+	if e.Kind != KindCask || len(b.CaskArgs) == 0 {
+		return e.Args
+	}
+	own := make(map[string]bool, len(e.Args))
+	for _, arg := range e.Args {
+		key, _, _ := strings.Cut(arg, "=")
+		own[key] = true
+	}
+	var args []string
+	for _, arg := range b.CaskArgs {
+		if key, _, _ := strings.Cut(arg, "="); !own[key] {
+			args = append(args, arg)
+		}
+	}
+	return append(args, e.Args...)
+}
+
+// Sort orders the entries by kind, then by name, the way dump writes them.
+func (b *Brewfile) Sort() {
+	sort.SliceStable(b.Entries, func(i, j int) bool {
+		a, c := b.Entries[i], b.Entries[j]
+		if a.Kind != c.Kind {
+			return kindOrder[a.Kind] < kindOrder[c.Kind]
+		}
+		return a.Name < c.Name
+	})
+}
+
+// Write writes b to w as a Brewfile, one entry per line.
+func (b *Brewfile) Write(w io.Writer) error {
+	if len(b.CaskArgs) > 0 {
+		args := (Entry{Kind: KindCask, Args: b.CaskArgs}).String()
+		_, args, _ = strings.Cut(args, "args: { ")
+		if _, err := fmt.Fprintf(w, "cask_args %s\n", strings.TrimSuffix(args, " }")); err != nil {
+			return err
+		}
+	}
+	for _, e := range b.Entries {
+		if _, err := fmt.Fprintln(w, e.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseError reports a Brewfile statement that could not be understood.
+type ParseError struct {
+	Line int    // Line is the line the statement starts on
+	Msg  string // Msg describes the problem
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// ParseFile reads and parses the Brewfile at path.
+func ParseFile(path string) (*Brewfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	bf, err := Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return bf, nil
+}
+
+// Parse parses the contents of a Brewfile. Entries whose OS modifier does
+// not match the running system are left out. It returns a *ParseError for
+// the first statement that is not a supported directive with literal
+// arguments.
+func Parse(src string) (*Brewfile, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	bf := &Brewfile{Entries: []Entry{}}
+	p := &parser{tokens: tokens}
+	for {
+		stmt, err := p.statement()
+		if err != nil {
+			return nil, err
+		}
+		if stmt == nil {
+			return bf, nil
+		}
+		if !stmt.applies {
+			continue
+		}
+		if err := bf.add(stmt); err != nil {
+			return nil, &ParseError{Line: stmt.line, Msg: err.Error()}
+		}
+	}
+}
+
+// add converts a statement into an entry, or into the cask defaults.
+func (b *Brewfile) add(s *statement) error {
+	if s.directive == "cask_args" {
+		if len(s.positional) > 0 {
+			return fmt.Errorf("cask_args takes only keyword arguments")
+		}
+		args, err := hashArgs(s.options)
+		if err != nil {
+			return err
+		}
+		b.CaskArgs = append(b.CaskArgs, args...)
+		return nil
+	}
+
+	kind := Kind(s.directive)
+	if _, ok := kindOrder[kind]; !ok {
+		return fmt.Errorf("unsupported directive %q", s.directive)
+	}
+	if len(s.positional) == 0 {
+		return fmt.Errorf("%s needs a name", kind)
+	}
+	name, ok := s.positional[0].(string)
+	if !ok || name == "" {
+		return fmt.Errorf("%s name must be a string", kind)
+	}
+	e := Entry{Kind: kind, Name: name, Line: s.line}
+
+	switch {
+	case kind == KindTap && len(s.positional) == 2:
+		if e.URL, ok = s.positional[1].(string); !ok {
+			return fmt.Errorf("tap URL must be a string")
+		}
+	case len(s.positional) > 1:
+		return fmt.Errorf("%s takes one name, got %d arguments", kind, len(s.positional))
+	}
+
+	for _, key := range s.options.keys {
+		value := s.options.values[key]
+		var err error
+		switch {
+		case key == "args" && (kind == KindBrew || kind == KindCask):
+			e.Args, err = installArgs(value)
+		case key == "restart_service" && kind == KindBrew:
+			e.RestartService, err = restartPolicy(value)
+		case key == "link" && kind == KindBrew:
+			link, ok := value.(bool)
+			if !ok {
+				err = fmt.Errorf("link must be true or false")
+			}
+			e.Link = &link
+		case key == "id" && kind == KindMas:
+			if e.ID, ok = value.(int64); !ok {
+				err = fmt.Errorf("id must be a number")
+			}
+		default:
+			if e.Options == nil {
+				e.Options = make(map[string]any)
+			}
+			e.Options[key] = plain(value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if kind == KindMas && e.ID == 0 {
+		return fmt.Errorf("mas %q needs an id", name)
+	}
+
+	b.Entries = append(b.Entries, e)
+	return nil
+}
+
+// installArgs converts the args option into command-line flags. An array
+// such as ["HEAD", "with-foo"] becomes "--HEAD" and "--with-foo"; a hash
+// such as { appdir: "~/Apps" } becomes "--appdir=~/Apps" (see hashArgs).
+func installArgs(value any) ([]string, error) {
+	switch v := value.(type) {
+	case []any:
+		args := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("args must be strings")
+			}
+			if !strings.HasPrefix(s, "-") {
+				s = "--" + s
+			}
+			args = append(args, s)
+		}
+		return args, nil
+	case hash:
+		return hashArgs(v)
+	}
+	return nil, fmt.Errorf("args must be an array or a hash")
+}
+
+// hashArgs converts keyword options into "--key=value" flags, or "--key"
+// for true, with underscores in keys turned into dashes as brew bundle
+// does, so that no_quarantine: true becomes "--no-quarantine".
+func hashArgs(h hash) ([]string, error) {
+	args := make([]string, 0, len(h.keys))
+	for _, key := range h.keys {
+		flag := "--" + strings.ReplaceAll(key, "_", "-")
+		switch v := h.values[key].(type) {
+		case string:
+			args = append(args, flag+"="+v)
+		case bool:
+			if v {
+				args = append(args, flag)
+			}
+		default:
+			return nil, fmt.Errorf("argument %s must be a string or true", key)
+		}
+	}
+	return args, nil
+}
+
+// restartPolicy converts the restart_service option.
+func restartPolicy(value any) (RestartPolicy, error) {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return RestartAlways, nil
+		}
+		return RestartNever, nil
+	case symbol:
+		if v == "changed" {
+			return RestartChanged, nil
+		}
+	}
+	return "", fmt.Errorf("restart_service must be true, false or :changed")
+}
+
+// matchesOS evaluates an OS modifier condition such as "mac?".
+func matchesOS(predicate string) (bool, error) {
+	switch predicate {
+	case "mac?":
+		return runtime.GOOS == "darwin", nil
+	case "linux?":
+		return runtime.GOOS == "linux", nil
+	}
+	return false, fmt.Errorf("unsupported condition OS.%s", predicate)
+}
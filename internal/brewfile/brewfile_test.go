@@ -0,0 +1,155 @@
+package brewfile
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const testBrewfile = `# Team tools
+tap "homebrew/cask-fonts"
+tap "user/tools", "https://example.com/user/homebrew-tools.git"
+
+cask_args appdir: "~/Applications", require_sha: true
+
+brew "git"
+brew "postgresql@16", restart_service: :changed, link: true
+brew "denji/nginx/nginx-full", args: ["with-rtmp-module", "--HEAD"],
+  restart_service: true
+brew("vim", conflicts_with: ["macvim"])
+cask "firefox", args: { appdir: "/Applications" }
+cask 'font-fira-code' # fonts
+mas "Xcode", id: 497_799_835
+vscode "golang.go"
+brew "only-on-mac" if OS.mac?
+brew "only-on-linux" if OS.linux?
+`
+
+func TestParse(t *testing.T) {
+	bf, err := Parse(testBrewfile)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	var names []string
+	for _, e := range bf.Entries {
+		names = append(names, string(e.Kind)+":"+e.Name)
+	}
+	platform := "brew:only-on-linux"
+	if runtime.GOOS == "darwin" {
+		platform = "brew:only-on-mac"
+	}
+	want := []string{
+		"tap:homebrew/cask-fonts", "tap:user/tools",
+		"brew:git", "brew:postgresql@16", "brew:denji/nginx/nginx-full", "brew:vim",
+		"cask:firefox", "cask:font-fira-code", "mas:Xcode", "vscode:golang.go",
+	}
+	if runtime.GOOS == "darwin" || runtime.GOOS == "linux" {
+		want = append(want, platform)
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("Entries = %v, want %v", names, want)
+	}
+
+	if got := bf.Entries[1].URL; got != "https://example.com/user/homebrew-tools.git" {
+		t.Errorf("Tap URL = %q", got)
+	}
+	pg := bf.Entries[3]
+	if pg.RestartService != RestartChanged || pg.Link == nil || !*pg.Link || pg.Line != 8 {
+		t.Errorf("Unexpected postgresql entry: %+v", pg)
+	}
+	nginx := bf.Entries[4]
+	if !reflect.DeepEqual(nginx.Args, []string{"--with-rtmp-module", "--HEAD"}) || nginx.RestartService != RestartAlways {
+		t.Errorf("Unexpected nginx entry: %+v", nginx)
+	}
+	if got := bf.Entries[5].Options["conflicts_with"]; !reflect.DeepEqual(got, []any{"macvim"}) {
+		t.Errorf("Expected unknown options to be kept, got %v", got)
+	}
+	if bf.Entries[8].ID != 497799835 {
+		t.Errorf("mas id = %d", bf.Entries[8].ID)
+	}
+
+	firefox := bf.Entries[6]
+	if got := bf.InstallArgs(firefox); !reflect.DeepEqual(got, []string{"--require-sha", "--appdir=/Applications"}) {
+		t.Errorf("Cask args should override cask_args defaults, got %v", got)
+	}
+	if got := bf.InstallArgs(bf.Entries[7]); !reflect.DeepEqual(got, []string{"--appdir=~/Applications", "--require-sha"}) {
+		t.Errorf("Expected cask_args defaults, got %v", got)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		src  string
+		line int
+		msg  string
+	}{
+		{"brew \"git\"\nwhalebrew \"foo\"", 2, "unsupported directive"},
+		{"brew", 1, "needs a name"},
+		{"brew \"git\", \"extra\"", 1, "takes one name"},
+		{"mas \"Xcode\"", 1, "needs an id"},
+		{"brew \"#{name}\"", 1, "interpolation"},
+		{"brew \"git\" if ENV[\"CI\"]", 1, "conditions"},
+		{"brew \"git\", restart_service: :sometimes", 1, "restart_service"},
+		{"\n\nbrew \"git\", args: [\"a\"", 3, "expected , or ]"},
+		{"brew \"git\" \"vim\"", 1, "unexpected"},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.src)
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("Parse(%q) error = %v, want a *ParseError", tt.src, err)
+			continue
+		}
+		if parseErr.Line != tt.line || !strings.Contains(parseErr.Msg, tt.msg) {
+			t.Errorf("Parse(%q) = %v, want line %d containing %q", tt.src, err, tt.line, tt.msg)
+		}
+	}
+}
+
+func TestWrite_RoundTrip(t *testing.T) {
+	link := false
+	bf := &Brewfile{
+		CaskArgs: []string{"--appdir=~/Applications"},
+		Entries: []Entry{
+			{Kind: KindVSCode, Name: "golang.go"},
+			{Kind: KindBrew, Name: "vim", Args: []string{"--HEAD"}, Link: &link},
+			{Kind: KindMas, Name: "Xcode", ID: 497799835},
+			{Kind: KindCask, Name: "firefox", Args: []string{"--appdir=/Applications", "--no-quarantine"}},
+			{Kind: KindTap, Name: "user/tools", URL: "https://example.com/tools.git"},
+			{Kind: KindBrew, Name: "postgresql@16", RestartService: RestartChanged},
+		},
+	}
+	bf.Sort()
+
+	var buf bytes.Buffer
+	if err := bf.Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	want := `cask_args appdir: "~/Applications"
+tap "user/tools", "https://example.com/tools.git"
+brew "postgresql@16", restart_service: :changed
+brew "vim", args: ["HEAD"], link: false
+cask "firefox", args: { appdir: "/Applications", no_quarantine: true }
+mas "Xcode", id: 497799835
+vscode "golang.go"
+`
+	if buf.String() != want {
+		t.Fatalf("Write =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	parsed, err := Parse(buf.String())
+	if err != nil {
+		t.Fatalf("Written Brewfile does not parse: %v", err)
+	}
+	if len(parsed.Entries) != len(bf.Entries) || !reflect.DeepEqual(parsed.CaskArgs, bf.CaskArgs) {
+		t.Fatalf("Round trip lost entries: %+v", parsed)
+	}
+	if got := parsed.Entries[3].Args; !reflect.DeepEqual(got, bf.Entries[3].Args) {
+		t.Errorf("Cask args = %v after a round trip, want %v", got, bf.Entries[3].Args)
+	}
+}
@@ -0,0 +1,463 @@
+package brewfile
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// tokenKind identifies the lexical class of a token.
+type tokenKind int
+
+const (
+	tokEOF     tokenKind = iota // tokEOF ends the input
+	tokNewline                  // tokNewline ends a statement
+	tokIdent                    // tokIdent is a bare word such as brew, true or OS
+	tokLabel                    // tokLabel is a keyword argument name such as args:
+	tokString                   // tokString is a quoted string
+	tokSymbol                   // tokSymbol is a Ruby symbol such as :changed
+	tokNumber                   // tokNumber is an integer
+	tokPunct                    // tokPunct is one of , [ ] { } ( ) . =>
+)
+
+// token is one lexical element of a Brewfile.
+type token struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+// symbol is a Ruby symbol value, kept apart from strings so that
+// restart_service: :changed can be told from "changed".
+type symbol string
+
+// hash is a Ruby hash literal or a list of keyword arguments, keeping the
+// keys in the order they were written.
+type hash struct {
+	keys   []string
+	values map[string]any
+}
+
+// set adds or replaces a key.
+func (h *hash) set(key string, value any) {
+	if h.values == nil {
+		h.values = make(map[string]any)
+	}
+	if _, ok := h.values[key]; !ok {
+		h.keys = append(h.keys, key)
+	}
+	h.values[key] = value
+}
+
+// plain converts parsed values into types that encode naturally as JSON:
+// symbols become strings and hashes become maps.
+func plain(value any) any {
+	switch v := value.(type) {
+	case symbol:
+		return string(v)
+	case hash:
+		m := make(map[string]any, len(v.keys))
+		for _, key := range v.keys {
+			m[key] = plain(v.values[key])
+		}
+		return m
+	case []any:
+		items := make([]any, len(v))
+		for i, item := range v {
+			items[i] = plain(item)
+		}
+		return items
+	}
+	return value
+}
+
+// lex splits src into tokens, dropping comments and line continuations.
+func lex(src string) ([]token, error) {
+	var tokens []token
+	line := 1
+	runes := []rune(src)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == '\n':
+			tokens = append(tokens, token{kind: tokNewline, line: line})
+			line++
+			i++
+		case r == '\\' && i+1 < len(runes) && runes[i+1] == '\n':
+			line++
+			i += 2
+		case r == ';':
+			tokens = append(tokens, token{kind: tokNewline, line: line})
+			i++
+		case unicode.IsSpace(r):
+			i++
+		case r == '#':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '"' || r == '\'':
+			text, n, err := lexString(runes[i:])
+			if err != nil {
+				return nil, &ParseError{Line: line, Msg: err.Error()}
+			}
+			tokens = append(tokens, token{kind: tokString, text: text, line: line})
+			i += n
+		case r == ':' && i+1 < len(runes) && (runes[i+1] == '"' || runes[i+1] == '\''):
+			text, n, err := lexString(runes[i+1:])
+			if err != nil {
+				return nil, &ParseError{Line: line, Msg: err.Error()}
+			}
+			tokens = append(tokens, token{kind: tokSymbol, text: text, line: line})
+			i += 1 + n
+		case r == ':' && i+1 < len(runes) && isIdentStart(runes[i+1]):
+			word := identAt(runes, i+1)
+			tokens = append(tokens, token{kind: tokSymbol, text: word, line: line})
+			i += 1 + len([]rune(word))
+		case unicode.IsDigit(r) || r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1]):
+			start := i
+			for i++; i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '_'); i++ {
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: strings.ReplaceAll(string(runes[start:i]), "_", ""), line: line})
+		case isIdentStart(r):
+			word := identAt(runes, i)
+			i += len([]rune(word))
+			if i < len(runes) && runes[i] == ':' && (i+1 == len(runes) || runes[i+1] != ':') {
+				tokens = append(tokens, token{kind: tokLabel, text: word, line: line})
+				i++
+				continue
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: word, line: line})
+		case r == '=' && i+1 < len(runes) && runes[i+1] == '>':
+			tokens = append(tokens, token{kind: tokPunct, text: "=>", line: line})
+			i += 2
+		case strings.ContainsRune(",[]{}().", r):
+			tokens = append(tokens, token{kind: tokPunct, text: string(r), line: line})
+			i++
+		default:
+			return nil, &ParseError{Line: line, Msg: fmt.Sprintf("unexpected character %q", r)}
+		}
+	}
+
+	return append(tokens, token{kind: tokEOF, line: line}), nil
+}
+
+// isIdentStart reports whether r can start a Ruby identifier.
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+// identAt returns the identifier starting at runes[i], including a
+// trailing ? or ! as in mac?.
+func identAt(runes []rune, i int) string {
+	start := i
+	for i < len(runes) && (runes[i] == '_' || unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) {
+		i++
+	}
+	if i < len(runes) && (runes[i] == '?' || runes[i] == '!') {
+		i++
+	}
+	return string(runes[start:i])
+}
+
+// lexString reads a quoted string at the start of runes and returns its
+// value and how many runes it spans. Double-quoted strings support the
+// common escapes but not interpolation.
+func lexString(runes []rune) (string, int, error) {
+	quote := runes[0]
+	var b strings.Builder
+	for i := 1; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == quote:
+			return b.String(), i + 1, nil
+		case r == '\n':
+			return "", 0, fmt.Errorf("unterminated string")
+		case r == '#' && quote == '"' && i+1 < len(runes) && runes[i+1] == '{':
+			return "", 0, fmt.Errorf("string interpolation is not supported")
+		case r == '\\' && i+1 < len(runes):
+			i++
+			next := runes[i]
+			switch {
+			case quote == '\'' && next != '\'' && next != '\\':
+				b.WriteRune('\\')
+				b.WriteRune(next)
+			case quote == '"' && next == 'n':
+				b.WriteRune('\n')
+			case quote == '"' && next == 't':
+				b.WriteRune('\t')
+			default:
+				b.WriteRune(next)
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// statement is one directive call with its arguments.
+type statement struct {
+	directive  string
+	line       int
+	positional []any
+	options    hash
+	applies    bool // applies is false if an OS modifier excludes the statement
+}
+
+// parser turns tokens into statements.
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// peek returns the next token without consuming it.
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+// next consumes and returns the next token.
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// isPunct reports whether the next token is the punctuation text.
+func (p *parser) isPunct(text string) bool {
+	t := p.peek()
+	return t.kind == tokPunct && t.text == text
+}
+
+// skipNewlines consumes blank lines.
+func (p *parser) skipNewlines() {
+	for p.peek().kind == tokNewline {
+		p.next()
+	}
+}
+
+// errorf returns a *ParseError at the line of the next token.
+func (p *parser) errorf(format string, args ...any) error {
+	return &ParseError{Line: p.peek().line, Msg: fmt.Sprintf(format, args...)}
+}
+
+// statement parses the next statement, or returns nil at the end of input.
+func (p *parser) statement() (*statement, error) {
+	p.skipNewlines()
+	t := p.next()
+	switch {
+	case t.kind == tokEOF:
+		return nil, nil
+	case t.kind != tokIdent:
+		return nil, &ParseError{Line: t.line, Msg: fmt.Sprintf("expected a directive, got %s", describe(t))}
+	}
+
+	s := &statement{directive: t.text, line: t.line, applies: true}
+	parens := p.isPunct("(")
+	if parens {
+		p.next()
+	}
+	if err := p.arguments(s, parens); err != nil {
+		return nil, err
+	}
+	if parens {
+		p.skipNewlines()
+		if !p.isPunct(")") {
+			return nil, p.errorf("expected ), got %s", describe(p.peek()))
+		}
+		p.next()
+	}
+
+	if t := p.peek(); t.kind == tokIdent && (t.text == "if" || t.text == "unless") {
+		p.next()
+		ok, err := p.condition()
+		if err != nil {
+			return nil, err
+		}
+		s.applies = ok == (t.text == "if")
+	}
+
+	if t := p.peek(); t.kind != tokNewline && t.kind != tokEOF {
+		return nil, p.errorf("unexpected %s after %s arguments", describe(t), s.directive)
+	}
+	return s, nil
+}
+
+// arguments parses the comma-separated arguments of s: positional values
+// followed by keyword options.
+func (p *parser) arguments(s *statement, parens bool) error {
+	for {
+		if parens {
+			p.skipNewlines()
+		}
+		t := p.peek()
+		if t.kind == tokNewline || t.kind == tokEOF || t.kind == tokIdent && (t.text == "if" || t.text == "unless") || p.isPunct(")") {
+			return nil
+		}
+
+		key, isOption, err := p.optionKey()
+		if err != nil {
+			return err
+		}
+		value, err := p.value()
+		if err != nil {
+			return err
+		}
+		switch {
+		case isOption:
+			s.options.set(key, value)
+		case len(s.options.keys) > 0:
+			return &ParseError{Line: t.line, Msg: "positional argument after keyword arguments"}
+		default:
+			s.positional = append(s.positional, value)
+		}
+
+		if !p.isPunct(",") {
+			return nil
+		}
+		p.next()
+		p.skipNewlines()
+	}
+}
+
+// optionKey consumes a keyword argument name, written as key: or
+// :key => or "key" =>, and reports whether there was one.
+func (p *parser) optionKey() (string, bool, error) {
+	t := p.peek()
+	if t.kind == tokLabel {
+		p.next()
+		return t.text, true, nil
+	}
+	if (t.kind == tokSymbol || t.kind == tokString) && p.pos+1 < len(p.tokens) {
+		if after := p.tokens[p.pos+1]; after.kind == tokPunct && after.text == "=>" {
+			p.pos += 2
+			return t.text, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// value parses a literal: a string, symbol, integer, true, false, nil,
+// array or hash.
+func (p *parser) value() (any, error) {
+	t := p.next()
+	switch t.kind {
+	case tokString:
+		return t.text, nil
+	case tokSymbol:
+		return symbol(t.text), nil
+	case tokNumber:
+		n, err := strconv.ParseInt(t.text, 10, 64)
+		if err != nil {
+			return nil, &ParseError{Line: t.line, Msg: fmt.Sprintf("invalid number %s", t.text)}
+		}
+		return n, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "nil":
+			return nil, nil
+		}
+	case tokPunct:
+		switch t.text {
+		case "[":
+			return p.array()
+		case "{":
+			return p.hash()
+		}
+	}
+	return nil, &ParseError{Line: t.line, Msg: fmt.Sprintf("expected a literal value, got %s", describe(t))}
+}
+
+// array parses the rest of an array literal after its opening bracket.
+func (p *parser) array() (any, error) {
+	items := []any{}
+	for {
+		p.skipNewlines()
+		if p.isPunct("]") {
+			p.next()
+			return items, nil
+		}
+		item, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+
+		p.skipNewlines()
+		if p.isPunct(",") {
+			p.next()
+		} else if !p.isPunct("]") {
+			return nil, p.errorf("expected , or ] in array, got %s", describe(p.peek()))
+		}
+	}
+}
+
+// hash parses the rest of a hash literal after its opening brace.
+func (p *parser) hash() (any, error) {
+	var h hash
+	for {
+		p.skipNewlines()
+		if p.isPunct("}") {
+			p.next()
+			return h, nil
+		}
+		key, ok, err := p.optionKey()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, p.errorf("expected a hash key, got %s", describe(p.peek()))
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		h.set(key, value)
+
+		p.skipNewlines()
+		if p.isPunct(",") {
+			p.next()
+		} else if !p.isPunct("}") {
+			return nil, p.errorf("expected , or } in hash, got %s", describe(p.peek()))
+		}
+	}
+}
+
+// condition parses an OS.mac? or OS.linux? modifier and evaluates it.
+func (p *parser) condition() (bool, error) {
+	t := p.next()
+	if t.kind != tokIdent || t.text != "OS" || !p.isPunct(".") {
+		return false, &ParseError{Line: t.line, Msg: "only OS.mac? and OS.linux? conditions are supported"}
+	}
+	p.next()
+	predicate := p.next()
+	ok, err := matchesOS(predicate.text)
+	if err != nil || predicate.kind != tokIdent {
+		return false, &ParseError{Line: predicate.line, Msg: "only OS.mac? and OS.linux? conditions are supported"}
+	}
+	return ok, nil
+}
+
+// describe names a token for error messages.
+func describe(t token) string {
+	switch t.kind {
+	case tokEOF:
+		return "end of file"
+	case tokNewline:
+		return "end of line"
+	case tokString:
+		return strconv.Quote(t.text)
+	case tokSymbol:
+		return ":" + t.text
+	case tokLabel:
+		return t.text + ":"
+	}
+	return t.text
+}
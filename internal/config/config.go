@@ -0,0 +1,75 @@
+// Package config loads goobrew's user configuration file.
+// The file is JSON and lives at $GOOBREW_CONFIG, or goobrew/config.json
+// under the platform user config directory when that is unset.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Config holds user settings read from the goobrew configuration file.
+type Config struct {
+	APIDomain  string   `json:"api_domain,omitempty"`  // APIDomain overrides the Homebrew JSON API base URL
+	APIMirrors []string `json:"api_mirrors,omitempty"` // APIMirrors are fallback API base URLs tried in order
+	Holds      []Hold   `json:"holds,omitempty"`       // Holds restrict which versions packages are upgraded to
+}
+
+// DefaultPath returns the location of the configuration file.
+// It honours GOOBREW_CONFIG and otherwise uses the user config directory.
+func DefaultPath() (string, error) {
+	if path := os.Getenv("GOOBREW_CONFIG"); path != "" {
+		return path, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "goobrew", "config.json"), nil
+}
+
+// Load reads the configuration file at path.
+// A missing file is not an error and yields an empty Config.
+func Load(path string) (*Config, error) {
+	cfg := &Config{}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	for _, h := range cfg.Holds {
+		if err := h.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid hold in config %s: %w", path, err)
+		}
+	}
+
+	return cfg, nil
+}
+
+// Save writes cfg to path as indented JSON, creating the parent directory
+// if needed.
+func Save(path string, cfg *Config) error {
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
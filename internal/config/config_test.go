@@ -0,0 +1,63 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{
+		"api_domain": "https://brew-mirror.internal/api",
+		"api_mirrors": ["https://formulae.brew.sh/api"]
+	}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.APIDomain != "https://brew-mirror.internal/api" {
+		t.Errorf("Expected api_domain to be loaded, got '%s'", cfg.APIDomain)
+	}
+	if len(cfg.APIMirrors) != 1 {
+		t.Errorf("Expected 1 mirror, got %d", len(cfg.APIMirrors))
+	}
+}
+
+func TestLoad_Missing(t *testing.T) {
+	cfg, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("Missing config should not be an error: %v", err)
+	}
+	if cfg == nil || cfg.APIDomain != "" {
+		t.Error("Expected empty config for missing file")
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	if _, err := Load(path); err == nil {
+		t.Error("Expected error for invalid config")
+	}
+}
+
+func TestDefaultPath_Env(t *testing.T) {
+	t.Setenv("GOOBREW_CONFIG", "/etc/goobrew.json")
+
+	path, err := DefaultPath()
+	if err != nil {
+		t.Fatalf("DefaultPath failed: %v", err)
+	}
+	if path != "/etc/goobrew.json" {
+		t.Errorf("Expected GOOBREW_CONFIG to be honoured, got '%s'", path)
+	}
+}
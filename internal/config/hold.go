@@ -0,0 +1,105 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ofkm/goobrew/internal/pkgversion"
+)
+
+// holdDateLayout is the format of Hold.Until.
+const holdDateLayout = "2006-01-02"
+
+// Hold is a goobrew-managed upgrade constraint for a formula or cask.
+// Unlike a brew pin, a hold can allow upgrades within a version series,
+// such as "node at 20", or expire on a date. A hold with neither Version
+// nor Until blocks every upgrade.
+type Hold struct {
+	Package string `json:"package"`           // Package is the formula name or cask token
+	Version string `json:"version,omitempty"` // Version is the series upgrades must stay in, such as "20" or "16.4"
+	Until   string `json:"until,omitempty"`   // Until is the date, as YYYY-MM-DD, on which the hold expires
+	Reason  string `json:"reason,omitempty"`  // Reason explains the hold to other users
+}
+
+// Validate checks that the hold names a package and has a valid date.
+func (h Hold) Validate() error {
+	if h.Package == "" {
+		return errors.New("hold has no package")
+	}
+	if h.Until != "" {
+		if _, err := time.Parse(holdDateLayout, h.Until); err != nil {
+			return fmt.Errorf("hold for %s has an invalid date %q, expected YYYY-MM-DD", h.Package, h.Until)
+		}
+	}
+	return nil
+}
+
+// Active reports whether the hold still applies at now. A hold without a
+// date never expires; one with a date expires at the start of that day.
+func (h Hold) Active(now time.Time) bool {
+	if h.Until == "" {
+		return true
+	}
+	until, err := time.ParseInLocation(holdDateLayout, h.Until, now.Location())
+	return err != nil || now.Before(until)
+}
+
+// Allows reports whether upgrading to version is permitted at now: either
+// the hold has expired, or it has a version series and version is in it.
+func (h Hold) Allows(version string, now time.Time) bool {
+	if !h.Active(now) {
+		return true
+	}
+	if h.Version == "" {
+		return false
+	}
+	return pkgversion.ParsePkgVersion(version).Version.HasPrefix(pkgversion.Parse(h.Version))
+}
+
+// String describes the hold, such as "held at 20 until 2026-03-01".
+func (h Hold) String() string {
+	s := "held"
+	if h.Version != "" {
+		s += " at " + h.Version
+	}
+	if h.Until != "" {
+		s += " until " + h.Until
+	}
+	if h.Reason != "" {
+		s += " (" + h.Reason + ")"
+	}
+	return s
+}
+
+// Hold returns the hold for pkg, if there is one.
+func (c *Config) Hold(pkg string) (Hold, bool) {
+	for _, h := range c.Holds {
+		if h.Package == pkg {
+			return h, true
+		}
+	}
+	return Hold{}, false
+}
+
+// SetHold adds h, replacing any existing hold for the same package.
+func (c *Config) SetHold(h Hold) {
+	for i := range c.Holds {
+		if c.Holds[i].Package == h.Package {
+			c.Holds[i] = h
+			return
+		}
+	}
+	c.Holds = append(c.Holds, h)
+}
+
+// RemoveHold deletes the hold for pkg and reports whether there was one.
+func (c *Config) RemoveHold(pkg string) bool {
+	for i, h := range c.Holds {
+		if h.Package == pkg {
+			c.Holds = append(c.Holds[:i], c.Holds[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHold_Allows(t *testing.T) {
+	now := time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		hold    Hold
+		version string
+		want    bool
+	}{
+		{"Series allows same major", Hold{Package: "node", Version: "20"}, "20.11.1", true},
+		{"Series blocks new major", Hold{Package: "node", Version: "20"}, "22.1.0", false},
+		{"Series ignores revision", Hold{Package: "node", Version: "20.11"}, "20.11.1_1", true},
+		{"Full hold", Hold{Package: "jq"}, "1.7.1", false},
+		{"Active date", Hold{Package: "postgresql@16", Until: "2026-02-01"}, "16.5", false},
+		{"Expired date", Hold{Package: "postgresql@16", Until: "2026-01-15"}, "16.5", true},
+		{"Series until date", Hold{Package: "node", Version: "20", Until: "2026-02-01"}, "22.1.0", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hold.Allows(tt.version, now); got != tt.want {
+				t.Errorf("Allows(%q) = %v, want %v", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHold_String(t *testing.T) {
+	h := Hold{Package: "node", Version: "20", Until: "2026-03-01", Reason: "LTS"}
+	if got := h.String(); got != "held at 20 until 2026-03-01 (LTS)" {
+		t.Errorf("Unexpected description %q", got)
+	}
+}
+
+func TestConfig_Holds(t *testing.T) {
+	cfg := &Config{}
+	cfg.SetHold(Hold{Package: "node", Version: "20"})
+	cfg.SetHold(Hold{Package: "jq"})
+	cfg.SetHold(Hold{Package: "node", Version: "22"})
+
+	if len(cfg.Holds) != 2 {
+		t.Fatalf("Expected SetHold to replace the node hold, got %+v", cfg.Holds)
+	}
+	if h, ok := cfg.Hold("node"); !ok || h.Version != "22" {
+		t.Errorf("Expected node held at 22, got %+v", h)
+	}
+	if !cfg.RemoveHold("jq") || cfg.RemoveHold("jq") {
+		t.Error("Expected jq to be removed exactly once")
+	}
+}
+
+func TestSaveLoad_Holds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "goobrew", "config.json")
+	cfg := &Config{APIDomain: "https://brew-mirror.internal/api"}
+	cfg.SetHold(Hold{Package: "postgresql@16", Until: "2026-06-30", Reason: "migration"})
+
+	if err := Save(path, cfg); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.APIDomain != cfg.APIDomain || len(loaded.Holds) != 1 || loaded.Holds[0] != cfg.Holds[0] {
+		t.Errorf("Expected the config to round-trip, got %+v", loaded)
+	}
+}
+
+func TestLoad_InvalidHold(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"holds":[{"package":"node","until":"next week"}]}`), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	if _, err := Load(path); err == nil {
+		t.Error("Expected error for a hold with an invalid date")
+	}
+}
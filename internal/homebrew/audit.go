@@ -0,0 +1,202 @@
+package homebrew
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ofkm/goobrew/internal/logger"
+)
+
+// deprecationMessages maps the reason codes Homebrew uses for deprecating
+// and disabling packages to the text brew shows for them. Any other reason
+// is free text written by the maintainers.
+var deprecationMessages = map[string]string{
+	"does_not_build":           "does not build",
+	"no_license":               "has no license",
+	"repo_archived":            "has an archived upstream repository",
+	"repo_removed":             "has a removed upstream repository",
+	"unmaintained":             "is not maintained upstream",
+	"unsupported":              "is not supported upstream",
+	"deprecated_upstream":      "is deprecated upstream",
+	"versioned_formula":        "is a versioned formula",
+	"checksum_mismatch":        "was built with an initially released source file that had a different checksum than the current one",
+	"discontinued":             "is discontinued upstream",
+	"no_longer_available":      "is no longer available upstream",
+	"no_longer_meets_criteria": "no longer meets the criteria for acceptable casks",
+	"unsigned":                 "is unsigned or does not meet signature requirements",
+	"fails_gatekeeper_check":   "does not pass the macOS Gatekeeper check",
+}
+
+// DeprecationMessage returns the text for a deprecation or disable reason,
+// such as "is not maintained upstream" for "unmaintained".
+func DeprecationMessage(reason string) string {
+	if message, ok := deprecationMessages[reason]; ok {
+		return message
+	}
+	return reason
+}
+
+// AuditStatus is why Audit reports an installed package.
+type AuditStatus string
+
+// Audit statuses, from most to least urgent.
+const (
+	AuditDisabled   AuditStatus = "disabled"   // AuditDisabled packages can no longer be installed or upgraded
+	AuditScheduled  AuditStatus = "scheduled"  // AuditScheduled packages will be disabled within the audited window
+	AuditDeprecated AuditStatus = "deprecated" // AuditDeprecated packages are deprecated but not about to be disabled
+	AuditRenamed    AuditStatus = "renamed"    // AuditRenamed formulae are installed under a name they no longer have
+)
+
+// auditOrder ranks the statuses for sorting.
+var auditOrder = map[AuditStatus]int{AuditDisabled: 0, AuditScheduled: 1, AuditDeprecated: 2, AuditRenamed: 3}
+
+// AuditOptions configures Audit.
+type AuditOptions struct {
+	Days int       // Days is how far ahead a disable date counts as scheduled
+	Now  time.Time // Now is the time to audit at, the current time if zero
+}
+
+// AuditFinding is an installed package that is deprecated, disabled or
+// renamed.
+type AuditFinding struct {
+	Name            string      `json:"name"`                       // Name is the formula name or cask token
+	Cask            bool        `json:"cask,omitempty"`             // Cask is true for casks
+	Status          AuditStatus `json:"status"`                     // Status is why the package is reported
+	Version         string      `json:"version,omitempty"`          // Version is the installed version
+	Reason          string      `json:"reason,omitempty"`           // Reason explains the deprecation or disable
+	DeprecationDate string      `json:"deprecation_date,omitempty"` // DeprecationDate is when the package was deprecated
+	DisableDate     string      `json:"disable_date,omitempty"`     // DisableDate is when the package is or will be disabled
+	DaysLeft        int         `json:"days_left,omitempty"`        // DaysLeft is how many days remain until a scheduled disable
+	Replacement     string      `json:"replacement,omitempty"`      // Replacement is the formula it was renamed to
+}
+
+// Audit returns the installed formulae and casks that are disabled,
+// scheduled to be disabled within opts.Days, deprecated, or installed
+// under a name the formula has since been renamed from, as recorded in
+// the API index. A finding has a Replacement when the index lists its name
+// as an old name of another formula. Findings are sorted by status, then
+// by disable date and name.
+func (c *Client) Audit(ctx context.Context, opts AuditOptions) ([]AuditFinding, error) {
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	formulae, casks, err := c.indexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	installed, err := c.InstalledFormulae(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]FormulaListItem, len(formulae))
+	renames := make(map[string]string)
+	for _, item := range formulae {
+		index[item.Name] = item
+		for _, old := range append([]string{item.OldName}, item.OldNames...) {
+			if old != "" && old != item.Name {
+				renames[old] = item.Name
+			}
+		}
+	}
+
+	findings := []AuditFinding{}
+	for _, f := range installed {
+		version := ""
+		if len(f.Installed) > 0 {
+			version = f.Installed[len(f.Installed)-1].Version
+		}
+		item, ok := index[f.Name]
+		if !ok {
+			if to, renamed := renames[f.Name]; renamed {
+				findings = append(findings, AuditFinding{Name: f.Name, Status: AuditRenamed, Version: version, Replacement: to})
+			}
+			continue
+		}
+		finding, ok := auditLifecycle(now, opts.Days, item.Deprecated, item.Disabled,
+			item.DeprecationReason, item.DisableReason, item.DeprecationDate, item.DisableDate)
+		if !ok {
+			continue
+		}
+		finding.Name, finding.Version, finding.Replacement = f.Name, version, renames[f.Name]
+		findings = append(findings, finding)
+	}
+
+	caskIndex := make(map[string]CaskListItem, len(casks))
+	for _, item := range casks {
+		caskIndex[item.Token] = item
+	}
+	installedCasks, err := c.installedCaskVersions(ctx)
+	if err != nil {
+		logger.Log.Debug("auditing formulae only", "error", err)
+	}
+	for token, versions := range installedCasks {
+		item, ok := caskIndex[token]
+		if !ok {
+			continue
+		}
+		finding, ok := auditLifecycle(now, opts.Days, item.Deprecated, item.Disabled,
+			item.DeprecationReason, item.DisableReason, item.DeprecationDate, item.DisableDate)
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(versions)
+		if len(fields) > 0 {
+			finding.Version = fields[len(fields)-1]
+		}
+		finding.Name, finding.Cask = token, true
+		findings = append(findings, finding)
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Status != b.Status {
+			return auditOrder[a.Status] < auditOrder[b.Status]
+		}
+		if a.DisableDate != b.DisableDate {
+			return a.DisableDate < b.DisableDate
+		}
+		return a.Name < b.Name
+	})
+	return findings, nil
+}
+
+// auditLifecycle classifies a package from its deprecation and disable
+// data, and reports false if it is neither deprecated nor disabled. A
+// disable date that has passed counts as disabled even if the index has
+// not caught up yet.
+func auditLifecycle(now time.Time, days int, deprecated, disabled bool, deprecationReason, disableReason, deprecationDate, disableDate string) (AuditFinding, bool) {
+	finding := AuditFinding{DeprecationDate: deprecationDate, DisableDate: disableDate}
+	left, scheduled := daysUntil(now, disableDate)
+	switch {
+	case disabled || scheduled && left <= 0:
+		finding.Status = AuditDisabled
+		finding.Reason = DeprecationMessage(disableReason)
+	case scheduled && left <= days:
+		finding.Status = AuditScheduled
+		finding.DaysLeft = left
+		finding.Reason = DeprecationMessage(disableReason)
+	case deprecated:
+		finding.Status = AuditDeprecated
+	default:
+		return finding, false
+	}
+	if finding.Reason == "" {
+		finding.Reason = DeprecationMessage(deprecationReason)
+	}
+	return finding, true
+}
+
+// daysUntil returns the number of days from now's date to date, given as
+// YYYY-MM-DD, and false if date is empty or invalid.
+func daysUntil(now time.Time, date string) (int, bool) {
+	t, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return 0, false
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return int(t.Sub(today).Hours() / 24), true
+}
@@ -0,0 +1,79 @@
+package homebrew
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAudit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/formula.json":
+			_, _ = w.Write([]byte(`[
+ {"name":"python@3.8","versions":{"stable":"3.8.20"},"deprecated":true,"disabled":true,"disable_date":"2025-01-01","disable_reason":"unsupported"},
+ {"name":"node@18","versions":{"stable":"18.20.4"},"deprecated":true,"deprecation_date":"2025-04-30","deprecation_reason":"unsupported","disable_date":"2026-11-01","disable_reason":"unsupported"},
+ {"name":"node@20","versions":{"stable":"20.18.0"},"deprecated":true,"deprecation_date":"2026-01-01","disable_date":"2027-06-01"},
+ {"name":"icu4c@74","versions":{"stable":"74.2"},"deprecated":true,"disable_date":"2026-10-10"},
+ {"name":"yt-dlp","versions":{"stable":"2024.10.22"},"oldnames":["youtube-dl"]},
+ {"name":"jq","versions":{"stable":"1.7.1"}}
+]`))
+		case "/cask.json":
+			_, _ = w.Write([]byte(`[
+ {"token":"old-app","version":"1.0","deprecated":true,"deprecation_date":"2026-01-01","deprecation_reason":"discontinued"},
+ {"token":"iterm2","version":"3.5.4"}
+]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	receipt := `{"source":{"tap":"homebrew/core"}}`
+	client := &Client{httpClient: &http.Client{Timeout: 5 * time.Second}}
+	WithAPIEndpoints(server.URL)(client)
+	WithPaths(writeCellar(t, map[string]string{
+		"Cellar/python@3.8/3.8.20/INSTALL_RECEIPT.json":     receipt,
+		"Cellar/node@18/18.20.4/INSTALL_RECEIPT.json":       receipt,
+		"Cellar/node@20/20.18.0/INSTALL_RECEIPT.json":       receipt,
+		"Cellar/icu4c@74/74.2/INSTALL_RECEIPT.json":         receipt,
+		"Cellar/youtube-dl/2021.12.17/INSTALL_RECEIPT.json": receipt,
+		"Cellar/jq/1.7.1/INSTALL_RECEIPT.json":              receipt,
+		"Caskroom/old-app/.metadata/1.0/20240101/x.json":    "",
+		"Caskroom/iterm2/.metadata/3.5.4/20240101/x.json":   "",
+	}))(client)
+
+	now := time.Date(2026, 10, 16, 15, 0, 0, 0, time.UTC)
+	findings, err := client.Audit(context.Background(), AuditOptions{Days: 30, Now: now})
+	if err != nil {
+		t.Fatalf("Audit failed: %v", err)
+	}
+
+	want := []AuditFinding{
+		{Name: "python@3.8", Status: AuditDisabled, Version: "3.8.20", DisableDate: "2025-01-01", Reason: "is not supported upstream"},
+		{Name: "icu4c@74", Status: AuditDisabled, Version: "74.2", DisableDate: "2026-10-10"},
+		{Name: "node@18", Status: AuditScheduled, Version: "18.20.4", DeprecationDate: "2025-04-30", DisableDate: "2026-11-01", DaysLeft: 16, Reason: "is not supported upstream"},
+		{Name: "old-app", Cask: true, Status: AuditDeprecated, Version: "1.0", DeprecationDate: "2026-01-01", Reason: "is discontinued upstream"},
+		{Name: "node@20", Status: AuditDeprecated, Version: "20.18.0", DeprecationDate: "2026-01-01", DisableDate: "2027-06-01"},
+		{Name: "youtube-dl", Status: AuditRenamed, Version: "2021.12.17", Replacement: "yt-dlp"},
+	}
+	if len(findings) != len(want) {
+		t.Fatalf("Expected %d findings, got %+v", len(want), findings)
+	}
+	for i := range want {
+		if findings[i] != want[i] {
+			t.Errorf("Finding %d:\n got %+v\nwant %+v", i, findings[i], want[i])
+		}
+	}
+}
+
+func TestDeprecationMessage(t *testing.T) {
+	if got := DeprecationMessage("repo_archived"); got != "has an archived upstream repository" {
+		t.Errorf("Unexpected message for a known reason: %q", got)
+	}
+	if got := DeprecationMessage("Use the new tool"); got != "Use the new tool" {
+		t.Errorf("Expected free text to be kept, got %q", got)
+	}
+}
@@ -34,7 +34,7 @@ func BenchmarkSearch(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, _, _ = client.Search(ctx, "package")
+		_, _ = client.Search(ctx, "package")
 	}
 }
 
@@ -83,6 +83,6 @@ func BenchmarkSearchLargeDataset(b *testing.B) {
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		term := searches[i%len(searches)]
-		_, _, _ = client.Search(ctx, term)
+		_, _ = client.Search(ctx, term)
 	}
 }
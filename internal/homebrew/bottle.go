@@ -0,0 +1,100 @@
+package homebrew
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// macOSCodenames maps macOS major versions to the names Homebrew uses in
+// bottle tags.
+var macOSCodenames = map[string]string{
+	"11": "big_sur",
+	"12": "monterey",
+	"13": "ventura",
+	"14": "sonoma",
+	"15": "sequoia",
+	"26": "tahoe",
+}
+
+// BottleTag returns the bottle tag of the running system, such as
+// "arm64_sonoma" or "x86_64_linux". On macOS it asks sw_vers for the
+// system version.
+func BottleTag(ctx context.Context) (string, error) {
+	if runtime.GOOS != "darwin" {
+		return linuxBottleTag(runtime.GOARCH), nil
+	}
+
+	output, err := exec.CommandContext(ctx, "sw_vers", "-productVersion").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read the macOS version: %w", err)
+	}
+	return macOSBottleTag(runtime.GOARCH, strings.TrimSpace(string(output)))
+}
+
+// linuxBottleTag returns the Linux bottle tag for a Go architecture name.
+func linuxBottleTag(arch string) string {
+	if arch == "amd64" {
+		return "x86_64_linux"
+	}
+	return arch + "_linux"
+}
+
+// macOSBottleTag returns the macOS bottle tag for a Go architecture name
+// and a product version such as "14.5".
+func macOSBottleTag(arch, version string) (string, error) {
+	major, _, _ := strings.Cut(version, ".")
+	name, ok := macOSCodenames[major]
+	if !ok {
+		return "", fmt.Errorf("unsupported macOS version %s", version)
+	}
+	if arch == "arm64" {
+		return "arm64_" + name, nil
+	}
+	return name, nil
+}
+
+// File returns the bottle for tag, and the tag it was found under. On
+// macOS a bottle built for an older release of the same architecture runs
+// too, so those are tried newest first before falling back to the
+// platform-independent "all" bottle.
+func (b Bottle) File(tag string) (BottleFile, string, bool) {
+	for _, t := range append(bottleTagFallbacks(tag), "all") {
+		if file, ok := b.Files[t]; ok {
+			return file, t, true
+		}
+	}
+	return BottleFile{}, "", false
+}
+
+// bottleTagFallbacks returns tag followed by the tags of the older macOS
+// releases in macOSCodenames for the same architecture, newest first, such
+// as "arm64_sonoma" and "arm64_ventura" after "arm64_sequoia".
+func bottleTagFallbacks(tag string) []string {
+	tags := []string{tag}
+	name, arm := strings.CutPrefix(tag, "arm64_")
+	major := macOSMajor(name)
+	if major == 0 {
+		return tags
+	}
+
+	var older []int
+	for v := range macOSCodenames {
+		if n, _ := strconv.Atoi(v); n < major {
+			older = append(older, n)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(older)))
+	for _, n := range older {
+		t := macOSCodenames[strconv.Itoa(n)]
+		if arm {
+			t = "arm64_" + t
+		}
+		tags = append(tags, t)
+	}
+	return tags
+}
@@ -0,0 +1,309 @@
+package homebrew
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ofkm/goobrew/internal/brewfile"
+	"github.com/ofkm/goobrew/internal/logger"
+)
+
+// builtinTaps are always available, even when `brew tap` does not list them
+// because formulae and casks come from the JSON API.
+var builtinTaps = map[string]bool{"homebrew/core": true, "homebrew/cask": true}
+
+// BundleItem is a Brewfile entry together with its installation state.
+type BundleItem struct {
+	brewfile.Entry
+	Installed bool   `json:"installed"`         // Installed is true if the entry is satisfied
+	Version   string `json:"version,omitempty"` // Version is the installed version, if known
+}
+
+// CheckBundle reports which entries of bf are installed. It lists the
+// installed taps, formulae, casks, App Store apps and VS Code extensions
+// once each instead of asking about every entry. Formulae match by name,
+// full name, alias or old name. App Store apps and extensions are reported
+// missing when mas or code is not available.
+func (c *Client) CheckBundle(ctx context.Context, bf *brewfile.Brewfile) ([]BundleItem, error) {
+	present := make(map[brewfile.Kind]map[string]string)
+	needs := make(map[brewfile.Kind]bool)
+	for _, e := range bf.Entries {
+		needs[e.Kind] = true
+	}
+
+	if needs[brewfile.KindTap] {
+		taps, err := c.Taps(ctx)
+		if err != nil {
+			return nil, err
+		}
+		present[brewfile.KindTap] = make(map[string]string, len(taps))
+		for _, tap := range taps {
+			present[brewfile.KindTap][tap] = ""
+		}
+	}
+
+	if needs[brewfile.KindBrew] {
+		installed, err := c.InstalledFormulae(ctx)
+		if err != nil {
+			return nil, err
+		}
+		formulae := make(map[string]string)
+		for _, f := range installed {
+			if len(f.Installed) == 0 {
+				continue
+			}
+			version := f.Installed[len(f.Installed)-1].Version
+			for _, name := range append([]string{f.Name, f.FullName, f.OldName}, f.Aliases...) {
+				if name != "" {
+					formulae[name] = version
+				}
+			}
+		}
+		present[brewfile.KindBrew] = formulae
+	}
+
+	if needs[brewfile.KindCask] {
+		casks, err := c.installedCaskVersions(ctx)
+		if err != nil {
+			return nil, err
+		}
+		present[brewfile.KindCask] = casks
+	}
+
+	if needs[brewfile.KindMas] {
+		apps, err := c.masApps(ctx)
+		if err != nil {
+			logger.Log.Warn("could not list App Store apps", "error", err)
+		}
+		present[brewfile.KindMas] = apps
+	}
+
+	if needs[brewfile.KindVSCode] {
+		extensions, err := c.vscodeExtensions(ctx)
+		if err != nil {
+			logger.Log.Warn("could not list VS Code extensions", "error", err)
+		}
+		present[brewfile.KindVSCode] = extensions
+	}
+
+	items := make([]BundleItem, len(bf.Entries))
+	for i, e := range bf.Entries {
+		items[i] = BundleItem{Entry: e}
+		found := present[e.Kind]
+		switch e.Kind {
+		case brewfile.KindTap:
+			_, items[i].Installed = found[strings.ToLower(e.Name)]
+			items[i].Installed = items[i].Installed || builtinTaps[strings.ToLower(e.Name)]
+			continue
+		case brewfile.KindMas:
+			items[i].Version, items[i].Installed = found[strconv.FormatInt(e.ID, 10)]
+			continue
+		case brewfile.KindVSCode:
+			items[i].Version, items[i].Installed = found[strings.ToLower(e.Name)]
+			continue
+		}
+
+		version, ok := found[e.Name]
+		if !ok {
+			version, ok = found[shortFormulaName(e.Name)]
+		}
+		items[i].Version, items[i].Installed = version, ok
+	}
+	return items, nil
+}
+
+// Taps returns the names of the tapped repositories, in lowercase.
+func (c *Client) Taps(ctx context.Context) ([]string, error) {
+	output, err := c.output(c.command(ctx, "tap"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list taps: %w", err)
+	}
+	var taps []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if tap := strings.TrimSpace(line); tap != "" {
+			taps = append(taps, strings.ToLower(tap))
+		}
+	}
+	return taps, nil
+}
+
+// Tap adds a tap, cloning it from url if one is given. It executes `brew
+// tap` and streams the output to stdout (see WithOutput) and stderr.
+func (c *Client) Tap(ctx context.Context, name, url string) error {
+	args := []string{"tap", name}
+	if url != "" {
+		args = append(args, url)
+	}
+	cmd := c.interactiveCommand(ctx, args...)
+	cmd.Stdout = c.brewStdout()
+	cmd.Stderr = os.Stderr
+	return c.run(cmd)
+}
+
+// InstallAppStoreApp installs a Mac App Store app by its identifier with
+// mas, streaming the output like Tap.
+func (c *Client) InstallAppStoreApp(ctx context.Context, id int64) error {
+	cmd, err := c.toolCommand(ctx, "mas", "install", strconv.FormatInt(id, 10))
+	if err != nil {
+		return err
+	}
+	cmd.Stdout = c.brewStdout()
+	cmd.Stderr = os.Stderr
+	return c.run(cmd)
+}
+
+// InstallVSCodeExtension installs a VS Code extension with the code
+// command, streaming the output like Tap.
+func (c *Client) InstallVSCodeExtension(ctx context.Context, name string) error {
+	cmd, err := c.toolCommand(ctx, "code", "--install-extension", name)
+	if err != nil {
+		return err
+	}
+	cmd.Stdout = c.brewStdout()
+	cmd.Stderr = os.Stderr
+	return c.run(cmd)
+}
+
+// SetLinked links a formula into the Homebrew prefix with `brew link`, or
+// unlinks it with `brew unlink`, streaming the output like Tap.
+func (c *Client) SetLinked(ctx context.Context, name string, linked bool) error {
+	verb := "unlink"
+	if linked {
+		verb = "link"
+	}
+	cmd := c.interactiveCommand(ctx, verb, name)
+	cmd.Stdout = c.brewStdout()
+	cmd.Stderr = os.Stderr
+	return c.run(cmd)
+}
+
+// DumpBundle builds a Brewfile from what is installed: every tap, the
+// formulae installed on request with the options they were built with,
+// every cask, and the App Store apps and VS Code extensions if mas and
+// code are available. Entries are sorted by kind and name.
+func (c *Client) DumpBundle(ctx context.Context) (*brewfile.Brewfile, error) {
+	bf := &brewfile.Brewfile{Entries: []brewfile.Entry{}}
+
+	taps, err := c.Taps(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, tap := range taps {
+		bf.Entries = append(bf.Entries, brewfile.Entry{Kind: brewfile.KindTap, Name: tap})
+	}
+
+	installed, err := c.InstalledFormulae(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range installed {
+		if len(f.Installed) == 0 || !installedOnRequest(f) {
+			continue
+		}
+		name := f.FullName
+		if name == "" {
+			name = f.Name
+		}
+		latest := f.Installed[len(f.Installed)-1]
+		args := append([]string(nil), latest.UsedOptions...)
+		if strings.HasPrefix(latest.Version, "HEAD") {
+			args = append(args, "--HEAD")
+		}
+		bf.Entries = append(bf.Entries, brewfile.Entry{Kind: brewfile.KindBrew, Name: name, Args: args})
+	}
+
+	casks, err := c.installedCaskVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for token := range casks {
+		bf.Entries = append(bf.Entries, brewfile.Entry{Kind: brewfile.KindCask, Name: token})
+	}
+
+	if apps, err := c.masAppNames(ctx); err == nil {
+		for id, name := range apps {
+			bf.Entries = append(bf.Entries, brewfile.Entry{Kind: brewfile.KindMas, Name: name, ID: id})
+		}
+	} else {
+		logger.Log.Debug("skipping App Store apps", "error", err)
+	}
+
+	if extensions, err := c.vscodeExtensions(ctx); err == nil {
+		for name := range extensions {
+			bf.Entries = append(bf.Entries, brewfile.Entry{Kind: brewfile.KindVSCode, Name: name})
+		}
+	} else {
+		logger.Log.Debug("skipping VS Code extensions", "error", err)
+	}
+
+	bf.Sort()
+	return bf, nil
+}
+
+// masAppNames lists the installed App Store apps with `mas list`, whose
+// lines look like "497799835  Xcode  (15.0)", mapping each identifier to
+// the app name.
+func (c *Client) masAppNames(ctx context.Context) (map[int64]string, error) {
+	cmd, err := c.toolCommand(ctx, "mas", "list")
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.output(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list App Store apps: %w", err)
+	}
+
+	apps := make(map[int64]string)
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		id, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		name := fields[1:]
+		if last := name[len(name)-1]; len(name) > 1 && strings.HasPrefix(last, "(") {
+			name = name[:len(name)-1]
+		}
+		apps[id] = strings.Join(name, " ")
+	}
+	return apps, nil
+}
+
+// masApps returns the installed App Store apps keyed by identifier, with
+// no version. The map is empty, not nil, if mas is unavailable.
+func (c *Client) masApps(ctx context.Context) (map[string]string, error) {
+	names, err := c.masAppNames(ctx)
+	apps := make(map[string]string, len(names))
+	for id := range names {
+		apps[strconv.FormatInt(id, 10)] = ""
+	}
+	return apps, err
+}
+
+// vscodeExtensions lists the installed VS Code extensions in lowercase,
+// mapped to their versions.
+func (c *Client) vscodeExtensions(ctx context.Context) (map[string]string, error) {
+	extensions := make(map[string]string)
+	cmd, err := c.toolCommand(ctx, "code", "--list-extensions", "--show-versions")
+	if err != nil {
+		return extensions, err
+	}
+	output, err := c.output(cmd)
+	if err != nil {
+		return extensions, fmt.Errorf("failed to list VS Code extensions: %w", err)
+	}
+
+	for _, line := range strings.Split(string(output), "\n") {
+		name, version, _ := strings.Cut(strings.TrimSpace(line), "@")
+		if name != "" {
+			extensions[strings.ToLower(name)] = version
+		}
+	}
+	return extensions, nil
+}
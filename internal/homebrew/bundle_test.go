@@ -0,0 +1,148 @@
+package homebrew
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ofkm/goobrew/internal/brewfile"
+)
+
+// bundleBrew is a fake brew with the denji/nginx tap, git and nginx-full
+// installed on request, curl installed as a dependency, and the firefox cask.
+const bundleBrew = `case "$1" in
+info) echo '[
+ {"name":"git","full_name":"git","aliases":["git-scm"],"installed":[{"version":"2.47.0","installed_on_request":true}]},
+ {"name":"nginx-full","full_name":"denji/nginx/nginx-full","installed":[{"version":"1.25.3","used_options":["--with-rtmp-module"],"installed_on_request":true}]},
+ {"name":"curl","full_name":"curl","installed":[{"version":"8.10.1","installed_as_dependency":true}]}
+]' ;;
+tap) echo "Denji/nginx" ;;
+list) echo "firefox 120.0" ;;
+esac`
+
+// withFakeTools replaces PATH with a directory holding fake mas and code
+// commands that print the given output.
+func withFakeTools(t *testing.T, mas, code string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, out := range map[string]string{"mas": mas, "code": code} {
+		script := "#!/bin/sh\necho '" + out + "'\n"
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0o755); err != nil { //nolint:gosec // test script must be executable
+			t.Fatalf("Failed to write fake %s: %v", name, err)
+		}
+	}
+	t.Setenv("PATH", dir)
+}
+
+func TestCheckBundle(t *testing.T) {
+	client := &Client{brewPath: writeFakeBrew(t, bundleBrew)}
+	withFakeTools(t, "497799835  Xcode  (15.0)", "golang.Go@0.42.0")
+
+	bf, err := brewfile.Parse(`tap "denji/nginx"
+tap "homebrew/core"
+tap "user/tools"
+brew "git-scm"
+brew "denji/nginx/nginx-full"
+brew "homebrew/core/curl"
+brew "jq"
+cask "firefox"
+cask "slack"
+mas "Xcode", id: 497799835
+mas "Keynote", id: 409183694
+vscode "golang.go"
+vscode "ms-python.python"
+`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	items, err := client.CheckBundle(context.Background(), bf)
+	if err != nil {
+		t.Fatalf("CheckBundle failed: %v", err)
+	}
+
+	var installed, missing []string
+	for _, item := range items {
+		if item.Installed {
+			installed = append(installed, item.Name)
+		} else {
+			missing = append(missing, item.Name)
+		}
+	}
+	if got := strings.Join(missing, " "); got != "user/tools jq slack Keynote ms-python.python" {
+		t.Errorf("Missing = %q", got)
+	}
+	if got := strings.Join(installed, " "); got != "denji/nginx homebrew/core git-scm denji/nginx/nginx-full homebrew/core/curl firefox Xcode golang.go" {
+		t.Errorf("Installed = %q", got)
+	}
+	if items[3].Version != "2.47.0" || items[7].Version != "120.0" || items[11].Version != "0.42.0" {
+		t.Errorf("Expected installed versions, got %+v", items)
+	}
+}
+
+func TestCheckBundle_MissingTools(t *testing.T) {
+	client := &Client{brewPath: writeFakeBrew(t, bundleBrew)}
+	t.Setenv("PATH", t.TempDir())
+
+	bf := &brewfile.Brewfile{Entries: []brewfile.Entry{
+		{Kind: brewfile.KindMas, Name: "Xcode", ID: 497799835},
+		{Kind: brewfile.KindVSCode, Name: "golang.go"},
+	}}
+	items, err := client.CheckBundle(context.Background(), bf)
+	if err != nil {
+		t.Fatalf("CheckBundle failed: %v", err)
+	}
+	for _, item := range items {
+		if item.Installed {
+			t.Errorf("Expected %s to be missing without its tool", item.Name)
+		}
+	}
+}
+
+func TestDumpBundle(t *testing.T) {
+	client := &Client{brewPath: writeFakeBrew(t, bundleBrew)}
+	withFakeTools(t, "497799835  Xcode  (15.0)", "golang.Go@0.42.0")
+
+	bf, err := client.DumpBundle(context.Background())
+	if err != nil {
+		t.Fatalf("DumpBundle failed: %v", err)
+	}
+
+	var buf strings.Builder
+	if err := bf.Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	want := `tap "denji/nginx"
+brew "denji/nginx/nginx-full", args: ["with-rtmp-module"]
+brew "git"
+cask "firefox"
+mas "Xcode", id: 497799835
+vscode "golang.go"
+`
+	if buf.String() != want {
+		t.Errorf("DumpBundle wrote\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestInstall_PackageArgs(t *testing.T) {
+	argsFile := filepath.Join(t.TempDir(), "args")
+	client := &Client{brewPath: writeFakeBrew(t, `echo "$@" >> `+argsFile)}
+
+	statusChan := make(chan InstallationStatus, 100)
+	err := client.Install(context.Background(), []string{"firefox"}, statusChan,
+		WithPackageArgs("firefox", "--cask", "--appdir=/Applications"))
+	close(statusChan)
+	if err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatalf("Failed to read brew arguments: %v", err)
+	}
+	if got := strings.TrimSpace(string(args)); got != "install --cask --appdir=/Applications firefox" {
+		t.Errorf("Unexpected brew arguments: %q", got)
+	}
+}
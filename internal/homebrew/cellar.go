@@ -0,0 +1,253 @@
+package homebrew
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ofkm/goobrew/internal/logger"
+	"github.com/ofkm/goobrew/internal/pkgversion"
+)
+
+// ErrUnexpectedLayout is returned by the Cellar and Caskroom scanners when
+// the installation does not look the way brew leaves it, in which case
+// brew itself should be asked.
+var ErrUnexpectedLayout = errors.New("unexpected Homebrew layout")
+
+// kegReceipt is the part of a keg's install receipt that is not already
+// in InstalledInfo.
+type kegReceipt struct {
+	InstalledInfo
+	Source struct {
+		Tap      string `json:"tap"`
+		Versions struct {
+			Stable        string `json:"stable"`
+			VersionScheme *int   `json:"version_scheme"`
+		} `json:"versions"`
+	} `json:"source"`
+}
+
+// InstalledFormulae returns the installed formulae like
+// GetInstalledFormulae, but reads them from the Cellar with ScanCellar
+// instead of running brew, which is much faster. If the Cellar does not
+// look the way brew leaves it, brew is asked instead.
+func (c *Client) InstalledFormulae(ctx context.Context) ([]Formula, error) {
+	formulae, err := c.ScanCellar(ctx)
+	if err == nil {
+		return formulae, nil
+	}
+	logger.Log.Debug("falling back to brew for installed formulae", "error", err)
+	return c.GetInstalledFormulae(ctx)
+}
+
+// ScanCellar reads every installed formula from the Cellar without running
+// brew. Each keg's INSTALL_RECEIPT.json supplies its InstalledInfo, the
+// pinned and linked-keg records in the prefix supply Pinned and LinkedKeg,
+// and the rest of the formula, such as its description and latest version,
+// comes from the API index if it is already loaded or cached on disk. The
+// index is never downloaded: without it those fields come from the receipt
+// or are left empty. Outdated is set when the latest version is newer than
+// the newest keg. Formulae are sorted by name. It returns an error wrapping
+// ErrUnexpectedLayout if the Cellar is missing or a keg has no readable
+// receipt.
+func (c *Client) ScanCellar(_ context.Context) ([]Formula, error) {
+	paths, err := c.Paths()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnexpectedLayout, err)
+	}
+	racks, err := os.ReadDir(paths.Cellar)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnexpectedLayout, err)
+	}
+
+	var formulae []Formula
+	for _, rack := range racks {
+		if !rack.IsDir() || strings.HasPrefix(rack.Name(), ".") {
+			continue
+		}
+		f, err := scanRack(paths, rack.Name())
+		if err != nil {
+			return nil, err
+		}
+		if len(f.Installed) > 0 {
+			formulae = append(formulae, *f)
+		}
+	}
+
+	index := c.cachedIndex()
+	if index == nil {
+		logger.Log.Debug("no formula index at hand, describing installed formulae from their receipts only")
+	}
+	items := make(map[string]FormulaListItem, len(index))
+	for _, item := range index {
+		items[item.Name] = item
+	}
+	for i := range formulae {
+		f := &formulae[i]
+		if item, ok := items[f.Name]; ok {
+			joinIndex(f, item)
+		}
+		latest := pkgversion.NewPkgVersion(f.Versions.Stable, f.Revision, f.VersionScheme)
+		if current, ok := newestInstalled(*f); ok && f.Versions.Stable != "" {
+			f.Outdated = current.Compare(latest) < 0
+		}
+	}
+	sort.Slice(formulae, func(i, j int) bool { return formulae[i].Name < formulae[j].Name })
+	return formulae, nil
+}
+
+// scanRack reads the kegs of one formula from its rack in the Cellar. A
+// rack without kegs gives a formula with no Installed entries.
+func scanRack(paths Paths, name string) (*Formula, error) {
+	f := &Formula{Name: name, FullName: name}
+	versions, err := kegVersions(paths.Cellar, name)
+	if err != nil {
+		return f, nil
+	}
+
+	for _, version := range versions {
+		data, err := os.ReadFile(filepath.Join(paths.Cellar, name, version, receiptFile)) //nolint:gosec // the receipt is in the Cellar
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s %s has no install receipt: %w", ErrUnexpectedLayout, name, version, err)
+		}
+		var receipt kegReceipt
+		if err := json.Unmarshal(data, &receipt); err != nil {
+			return nil, fmt.Errorf("%w: %s %s has an invalid install receipt: %w", ErrUnexpectedLayout, name, version, err)
+		}
+
+		info := receipt.InstalledInfo
+		info.Version = version
+		info.VersionScheme = receipt.Source.Versions.VersionScheme
+		f.Installed = append(f.Installed, info)
+		if tap := receipt.Source.Tap; tap != "" {
+			f.Tap = tap
+		}
+		if stable := receipt.Source.Versions.Stable; stable != "" {
+			f.Versions.Stable = stable
+			if scheme := receipt.Source.Versions.VersionScheme; scheme != nil {
+				f.VersionScheme = *scheme
+			}
+		}
+	}
+	if f.Tap != "" && f.Tap != "homebrew/core" {
+		f.FullName = f.Tap + "/" + name
+	}
+
+	if _, err := os.Lstat(filepath.Join(paths.Prefix, "var", "homebrew", "pinned", name)); err == nil {
+		f.Pinned = true
+	}
+	if target, err := os.Readlink(filepath.Join(paths.Prefix, "var", "homebrew", "linked", name)); err == nil {
+		f.LinkedKeg = filepath.Base(target)
+	}
+	return f, nil
+}
+
+// joinIndex fills the fields of an installed formula that only the API
+// index knows.
+func joinIndex(f *Formula, item FormulaListItem) {
+	f.Desc = item.Desc
+	f.Homepage = item.Homepage
+	f.Aliases = item.Aliases
+	f.OldName = item.OldName
+	f.Urls = item.Urls
+	f.Deprecated = item.Deprecated
+	f.Disabled = item.Disabled
+	f.DeprecationDate = item.DeprecationDate
+	f.DeprecationReason = item.DeprecationReason
+	f.DisableDate = item.DisableDate
+	f.DisableReason = item.DisableReason
+	f.Dependencies = item.Dependencies
+	if item.Versions.Stable != "" {
+		f.Versions = item.Versions
+		f.Revision = item.Revision
+		f.VersionScheme = item.VersionScheme
+	}
+}
+
+// localInstallInfo returns the installed kegs of the named formula from
+// the Cellar, or from brew if the Cellar does not look the way brew
+// leaves it.
+func (c *Client) localInstallInfo(ctx context.Context, name string) ([]InstalledInfo, error) {
+	if paths, err := c.Paths(); err == nil {
+		if info, err := os.Stat(paths.Cellar); err == nil && info.IsDir() {
+			f, err := scanRack(paths, name)
+			if err == nil {
+				return f.Installed, nil
+			}
+			logger.Log.Debug("falling back to brew for install info", "formula", name, "error", err)
+		}
+	}
+	return c.getLocalInstallInfo(ctx, name)
+}
+
+// scanCaskroom returns the installed version of each cask from the
+// Caskroom, keyed by token, without running brew. The version is the one
+// of the most recent install recorded in the cask's .metadata directory.
+// It returns an error wrapping ErrUnexpectedLayout if there is no Cellar,
+// which brew always creates, or a cask has no install metadata.
+func (c *Client) scanCaskroom() (map[string]string, error) {
+	paths, err := c.Paths()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnexpectedLayout, err)
+	}
+	if info, err := os.Stat(paths.Cellar); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("%w: no Cellar at %s", ErrUnexpectedLayout, paths.Cellar)
+	}
+
+	caskroom := filepath.Join(paths.Prefix, "Caskroom")
+	tokens, err := os.ReadDir(caskroom)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnexpectedLayout, err)
+	}
+
+	versions := make(map[string]string, len(tokens))
+	for _, token := range tokens {
+		if !token.IsDir() || strings.HasPrefix(token.Name(), ".") {
+			continue
+		}
+		version, err := caskInstalledVersion(filepath.Join(caskroom, token.Name(), ".metadata"))
+		if err != nil {
+			return nil, fmt.Errorf("%w: cask %s: %w", ErrUnexpectedLayout, token.Name(), err)
+		}
+		if version != "" {
+			versions[token.Name()] = version
+		}
+	}
+	return versions, nil
+}
+
+// caskInstalledVersion returns the version of the newest install recorded
+// in a cask's metadata directory, which holds a directory per version with
+// a directory per install timestamp, or "" if there is none.
+func caskInstalledVersion(metadata string) (string, error) {
+	versionDirs, err := os.ReadDir(metadata)
+	if err != nil {
+		return "", err
+	}
+
+	var newest, version string
+	for _, v := range versionDirs {
+		if !v.IsDir() || strings.HasPrefix(v.Name(), ".") {
+			continue
+		}
+		stamps, err := os.ReadDir(filepath.Join(metadata, v.Name()))
+		if err != nil {
+			return "", err
+		}
+		for _, stamp := range stamps {
+			if stamp.IsDir() && stamp.Name() > newest {
+				newest, version = stamp.Name(), v.Name()
+			}
+		}
+	}
+	return version, nil
+}
@@ -0,0 +1,183 @@
+package homebrew
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCellar creates a Homebrew prefix with the given files, relative to
+// the prefix, and returns its paths.
+func writeCellar(t *testing.T, files map[string]string) Paths {
+	t.Helper()
+	prefix := t.TempDir()
+	for name, body := range files {
+		path := filepath.Join(prefix, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(prefix, "Cellar"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return Paths{Prefix: prefix, Cellar: filepath.Join(prefix, "Cellar"), Repository: prefix}
+}
+
+func TestScanCellar(t *testing.T) {
+	// The index must never be downloaded to list what is installed
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Unexpected API request for %s", r.URL.Path)
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	paths := writeCellar(t, map[string]string{
+		"Cellar/jq/1.6/INSTALL_RECEIPT.json":          `{"installed_on_request":true,"time":1600000000,"source":{"tap":"homebrew/core"}}`,
+		"Cellar/jq/1.7/INSTALL_RECEIPT.json":          `{"installed_on_request":true,"poured_from_bottle":true,"runtime_dependencies":[{"full_name":"oniguruma","version":"6.9.9"}],"source":{"tap":"homebrew/core"}}`,
+		"Cellar/oniguruma/6.9.9/INSTALL_RECEIPT.json": `{"installed_as_dependency":true,"source":{"tap":"homebrew/core"}}`,
+		"Cellar/mytool/1.9/INSTALL_RECEIPT.json":      `{"installed_on_request":true,"source":{"tap":"me/tools","versions":{"stable":"2.0"}}}`,
+		"Cellar/.keepme":                              "",
+		"var/homebrew/pinned/.keepme":                 "",
+	})
+	if err := os.MkdirAll(filepath.Join(paths.Cellar, "empty"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../../../Cellar/jq/1.7", filepath.Join(paths.Prefix, "var", "homebrew", "pinned", "jq")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(paths.Prefix, "var", "homebrew", "linked"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../../../Cellar/jq/1.7", filepath.Join(paths.Prefix, "var", "homebrew", "linked", "jq")); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &Client{httpClient: &http.Client{Timeout: 5 * time.Second}}
+	WithAPIEndpoints(server.URL)(client)
+	WithPaths(paths)(client)
+
+	formulae, err := client.InstalledFormulae(context.Background())
+	if err != nil {
+		t.Fatalf("InstalledFormulae failed: %v", err)
+	}
+	if len(formulae) != 3 || formulae[0].Desc != "" || !formulae[0].Pinned || formulae[0].Outdated {
+		t.Fatalf("Expected the kegs without the index, got %+v", formulae)
+	}
+
+	client.formulaeCache = []FormulaListItem{
+		{Name: "jq", Desc: "Lightweight JSON processor", Versions: Versions{Stable: "1.7.1"}, Dependencies: []string{"oniguruma"}},
+		{Name: "oniguruma", Desc: "Regular expressions library", Versions: Versions{Stable: "6.9.9"}},
+	}
+	formulae, err = client.InstalledFormulae(context.Background())
+	if err != nil {
+		t.Fatalf("InstalledFormulae failed: %v", err)
+	}
+	if len(formulae) != 3 {
+		t.Fatalf("Expected 3 formulae, got %+v", formulae)
+	}
+
+	jq, mytool, oniguruma := formulae[0], formulae[1], formulae[2]
+	if jq.Name != "jq" || mytool.Name != "mytool" || oniguruma.Name != "oniguruma" {
+		t.Fatalf("Unexpected order: %s, %s, %s", jq.Name, mytool.Name, oniguruma.Name)
+	}
+	if jq.FullName != "jq" || jq.Tap != "homebrew/core" || jq.Desc != "Lightweight JSON processor" {
+		t.Errorf("Expected jq to be joined with the index, got %+v", jq)
+	}
+	if len(jq.Installed) != 2 || jq.Installed[0].Version != "1.6" || jq.Installed[1].Version != "1.7" {
+		t.Fatalf("Expected both jq kegs in version order, got %+v", jq.Installed)
+	}
+	if !jq.Installed[1].PouredFromBottle || len(jq.Installed[1].RuntimeDependencies) != 1 {
+		t.Errorf("Expected the receipt to be read, got %+v", jq.Installed[1])
+	}
+	if !jq.Pinned || jq.LinkedKeg != "1.7" || !jq.Outdated {
+		t.Errorf("Expected jq to be pinned, linked to 1.7 and outdated, got pinned=%v linked=%q outdated=%v", jq.Pinned, jq.LinkedKeg, jq.Outdated)
+	}
+	if mytool.FullName != "me/tools/mytool" || mytool.Versions.Stable != "2.0" || !mytool.Outdated {
+		t.Errorf("Expected mytool to be described by its receipt, got %+v", mytool)
+	}
+	if oniguruma.Outdated || oniguruma.Pinned || oniguruma.LinkedKeg != "" || !oniguruma.Installed[0].InstalledAsDependency {
+		t.Errorf("Unexpected oniguruma: %+v", oniguruma)
+	}
+
+	installed, err := client.localInstallInfo(context.Background(), "oniguruma")
+	if err != nil || len(installed) != 1 || installed[0].Version != "6.9.9" {
+		t.Errorf("Expected the oniguruma keg, got %+v (%v)", installed, err)
+	}
+	installed, err = client.localInstallInfo(context.Background(), "wget")
+	if err != nil || len(installed) != 0 {
+		t.Errorf("Expected wget not to be installed, got %+v (%v)", installed, err)
+	}
+}
+
+func TestScanCellarFallsBackToBrew(t *testing.T) {
+	brew := writeFakeBrew(t, `
+case "$1" in
+info) echo '[{"name":"wget","installed":[{"version":"1.24.5"}]}]' ;;
+list) echo 'iterm2 3.5.4' ;;
+esac`)
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		cask  bool
+	}{
+		{name: "missing receipt", files: map[string]string{"Cellar/wget/1.24.5/bin/wget": ""}},
+		{name: "invalid receipt", files: map[string]string{"Cellar/wget/1.24.5/INSTALL_RECEIPT.json": "{"}},
+		{name: "missing cask metadata", files: map[string]string{"Caskroom/iterm2/3.5.4/iTerm.app": ""}, cask: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &Client{brewPath: brew, httpClient: &http.Client{Timeout: 5 * time.Second}}
+			WithAPIEndpoints("http://127.0.0.1:0")(client)
+			WithPaths(writeCellar(t, tt.files))(client)
+
+			if !tt.cask {
+				formulae, err := client.InstalledFormulae(context.Background())
+				if err != nil || len(formulae) != 1 || formulae[0].Name != "wget" {
+					t.Errorf("Expected brew's wget, got %+v (%v)", formulae, err)
+				}
+				return
+			}
+			casks, err := client.installedCaskVersions(context.Background())
+			if err != nil || casks["iterm2"] != "3.5.4" {
+				t.Errorf("Expected iterm2 3.5.4, got %v (%v)", casks, err)
+			}
+		})
+	}
+
+	client := &Client{paths: &Paths{Prefix: t.TempDir(), Cellar: "/nonexistent/Cellar"}}
+	if _, err := client.ScanCellar(context.Background()); !errors.Is(err, ErrUnexpectedLayout) {
+		t.Errorf("Expected ErrUnexpectedLayout for a missing Cellar, got %v", err)
+	}
+}
+
+func TestScanCaskroom(t *testing.T) {
+	paths := writeCellar(t, map[string]string{
+		"Caskroom/firefox/.metadata/119.0/20231101120000.000/Casks/firefox.rb": "",
+		"Caskroom/firefox/.metadata/120.0/20231201120000.000/Casks/firefox.rb": "",
+		"Caskroom/firefox/120.0/Firefox.app/Contents/Info.plist":               "",
+		"Caskroom/iterm2/.metadata/3.5.4/20240101120000.000/Casks/iterm2.rb":   "",
+	})
+	client := &Client{paths: &paths}
+
+	casks, err := client.installedCaskVersions(context.Background())
+	if err != nil {
+		t.Fatalf("installedCaskVersions failed: %v", err)
+	}
+	if len(casks) != 2 || casks["firefox"] != "120.0" || casks["iterm2"] != "3.5.4" {
+		t.Errorf("Unexpected casks: %v", casks)
+	}
+
+	client = &Client{paths: &Paths{Prefix: t.TempDir(), Cellar: paths.Cellar}}
+	if casks, err := client.scanCaskroom(); err != nil || len(casks) != 0 {
+		t.Errorf("Expected no casks without a Caskroom, got %v (%v)", casks, err)
+	}
+}
@@ -4,11 +4,13 @@ package homebrew
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -17,12 +19,8 @@ import (
 )
 
 const (
-	// HomebrewAPIBase is the base URL for Homebrew's JSON API.
+	// HomebrewAPIBase is the default base URL for Homebrew's JSON API.
 	HomebrewAPIBase = "https://formulae.brew.sh/api"
-	// HomebrewAPIFormulae is the URL for the complete list of formulae.
-	HomebrewAPIFormulae = "https://formulae.brew.sh/api/formula.json"
-	// HomebrewAPICasks is the URL for the complete list of casks.
-	HomebrewAPICasks = "https://formulae.brew.sh/api/cask.json"
 	// cacheExpiry defines how long cached data remains valid.
 	cacheExpiry = 1 * time.Hour
 	// installedCacheKey is the cache key for installed formulae.
@@ -38,20 +36,95 @@ type Client struct {
 	formulaeCache  []FormulaListItem // Cache of all formulae names and descriptions
 	casksCache     []CaskListItem    // Cache of all cask names and descriptions
 	cacheMutex     sync.RWMutex
-	cacheTimestamp time.Time
+	cacheTimestamp time.Time      // When both indexes were last loaded together
+	indexCache     *indexCache    // On-disk snapshot of the formula and cask indexes
+	indexState     indexState     // Age and origin of the loaded formula index
+	caskIndexState indexState     // Age and origin of the loaded cask index
+	apiBases       []string       // API base URLs tried in order, primary first
+	stdout         io.Writer      // Destination for streamed brew output, os.Stdout if nil
+	procs          processes      // Running brew processes, for Kill
+	downloadDir    string         // Content-addressed bottle cache, empty if unavailable
+	registryTokens sync.Map       // Anonymous registry tokens by repository URL
+	paths          *Paths         // Homebrew locations set via WithPaths, found from brewPath if nil
+	services       serviceManager // Service manager for this platform, chosen on first use if nil
+	serviceDir     string         // Directory recording started services, DefaultServiceDir if empty
+}
+
+// ClientOption configures optional Client behaviour in NewClient.
+type ClientOption func(*Client)
+
+// WithAPIEndpoints points the client at a Homebrew JSON API base URL other
+// than HomebrewAPIBase, followed by mirrors that are tried in order when a
+// request to an earlier endpoint fails. An empty base keeps the default.
+func WithAPIEndpoints(base string, mirrors ...string) ClientOption {
+	return func(c *Client) {
+		if base == "" {
+			base = HomebrewAPIBase
+		}
+		c.apiBases = []string{base}
+		for _, m := range mirrors {
+			if m != "" && m != base {
+				c.apiBases = append(c.apiBases, m)
+			}
+		}
+	}
+}
+
+// indexState describes the origin of a loaded index.
+type indexState struct {
+	fetchedAt time.Time // fetchedAt is when the index was last confirmed current
+	stale     bool      // stale is true when the API could not be reached
 }
 
 // FormulaListItem represents a minimal formula entry for listing and searching.
 type FormulaListItem struct {
-	Name string `json:"name"` // Name is the formula name
-	Desc string `json:"desc"` // Desc is the formula description
+	Name          string   `json:"name"`                     // Name is the formula name
+	Desc          string   `json:"desc"`                     // Desc is the formula description
+	Aliases       []string `json:"aliases,omitempty"`        // Aliases are alternative names for the formula
+	OldName       string   `json:"oldname,omitempty"`        // OldName is the formula's previous name
+	OldNames      []string `json:"oldnames,omitempty"`       // OldNames lists all previous names
+	Versions      Versions `json:"versions"`                 // Versions holds the latest available versions
+	Revision      int      `json:"revision,omitempty"`       // Revision is the formula revision
+	Deprecated    bool     `json:"deprecated,omitempty"`     // Deprecated indicates the formula is deprecated
+	Disabled      bool     `json:"disabled,omitempty"`       // Disabled indicates the formula is disabled
+	Dependencies  []string `json:"dependencies,omitempty"`   // Dependencies are the formula's runtime dependencies
+	VersionScheme int      `json:"version_scheme,omitempty"` // VersionScheme is bumped when the formula's versioning restarts
+	Homepage      string   `json:"homepage,omitempty"`       // Homepage is the project's website
+	Urls          URLs     `json:"urls,omitempty"`           // Urls holds the source download URLs
+
+	RecommendedDeps      []string            `json:"recommended_dependencies,omitempty"` // RecommendedDeps are installed unless disabled
+	UsesFromMacos        []json.RawMessage   `json:"uses_from_macos,omitempty"`          // UsesFromMacos are dependencies macOS provides
+	UsesFromMacosBounds  []map[string]string `json:"uses_from_macos_bounds,omitempty"`   // UsesFromMacosBounds limit UsesFromMacos to some macOS releases
+	Requirements         []Requirement       `json:"requirements,omitempty"`             // Requirements are the system requirements
+	ConflictsWith        []string            `json:"conflicts_with,omitempty"`           // ConflictsWith names formulae that cannot be installed alongside
+	ConflictsWithReasons []string            `json:"conflicts_with_reasons,omitempty"`   // ConflictsWithReasons explains each conflict
+	Bottle               Bottle              `json:"bottle"`                             // Bottle describes the prebuilt bottles
+	KegOnly              bool                `json:"keg_only,omitempty"`                 // KegOnly is true if the formula is not linked into the prefix
+	LinkOverwrite        []string            `json:"link_overwrite,omitempty"`           // LinkOverwrite are prefix paths linking may replace
+	Service              *Service            `json:"service,omitempty"`                  // Service is the background service the formula defines
+
+	DeprecationDate   string `json:"deprecation_date,omitempty"`   // DeprecationDate is when the formula was deprecated
+	DeprecationReason string `json:"deprecation_reason,omitempty"` // DeprecationReason says why the formula is deprecated
+	DisableDate       string `json:"disable_date,omitempty"`       // DisableDate is when the formula is or will be disabled
+	DisableReason     string `json:"disable_reason,omitempty"`     // DisableReason says why the formula is or will be disabled
 }
 
 // CaskListItem represents a minimal cask entry for listing and searching.
 type CaskListItem struct {
-	Token string   `json:"token"` // Token is the cask identifier
-	Name  []string `json:"name"`  // Name contains the display names for the cask
-	Desc  string   `json:"desc"`  // Desc is the cask description
+	Token       string   `json:"token"`                  // Token is the cask identifier
+	Name        []string `json:"name"`                   // Name contains the display names for the cask
+	Desc        string   `json:"desc"`                   // Desc is the cask description
+	Version     string   `json:"version,omitempty"`      // Version is the latest cask version
+	Deprecated  bool     `json:"deprecated,omitempty"`   // Deprecated indicates the cask is deprecated
+	Disabled    bool     `json:"disabled,omitempty"`     // Disabled indicates the cask is disabled
+	AutoUpdates bool     `json:"auto_updates,omitempty"` // AutoUpdates indicates the app updates itself
+	Homepage    string   `json:"homepage,omitempty"`     // Homepage is the app's website
+	URL         string   `json:"url,omitempty"`          // URL is the download URL of the latest version
+
+	DeprecationDate   string `json:"deprecation_date,omitempty"`   // DeprecationDate is when the cask was deprecated
+	DeprecationReason string `json:"deprecation_reason,omitempty"` // DeprecationReason says why the cask is deprecated
+	DisableDate       string `json:"disable_date,omitempty"`       // DisableDate is when the cask is or will be disabled
+	DisableReason     string `json:"disable_reason,omitempty"`     // DisableReason says why the cask is or will be disabled
 }
 
 // cacheEntry holds cached data with a timestamp for expiration.
@@ -60,11 +133,21 @@ type cacheEntry struct {
 	timestamp time.Time
 }
 
+// WithOutput redirects the output that Uninstall, Update and Upgrade stream
+// from brew, for example to os.Stderr when stdout is reserved for a
+// machine-readable document.
+func WithOutput(w io.Writer) ClientOption {
+	return func(c *Client) {
+		c.stdout = w
+	}
+}
+
 // NewClient creates and initializes a new Homebrew client.
 // It verifies that brew is installed and available in PATH, then
 // pre-loads the formulae and casks list in the background for faster searches.
+// The lists are persisted under DefaultCacheDir so later runs can reuse them.
 // Returns an error if Homebrew is not installed.
-func NewClient() (*Client, error) {
+func NewClient(opts ...ClientOption) (*Client, error) {
 	brewPath, err := exec.LookPath("brew")
 	if err != nil {
 		return nil, fmt.Errorf("homebrew is not installed. Please install it from https://brew.sh")
@@ -77,6 +160,17 @@ func NewClient() (*Client, error) {
 		brewPath: brewPath,
 	}
 
+	for _, opt := range opts {
+		opt(client)
+	}
+
+	if dir, err := DefaultCacheDir(); err == nil {
+		client.indexCache = newIndexCache(dir)
+		client.downloadDir = filepath.Join(dir, "downloads")
+	} else {
+		logger.Log.Debug("on-disk index cache disabled", "error", err)
+	}
+
 	// Pre-load formulae and casks list in background for faster searches
 	go client.loadFormulaeAndCasks(context.Background())
 
@@ -91,14 +185,22 @@ func (c *Client) loadFormulaeAndCasks(ctx context.Context) {
 	logger.Log.Debug("loading formulae and casks from API in parallel")
 
 	// Use channels to load formulae and casks concurrently
-	formulaeChan := make(chan []FormulaListItem)
-	casksChan := make(chan []CaskListItem)
+	type formulaeResult struct {
+		items []FormulaListItem
+		state indexState
+	}
+	type casksResult struct {
+		items []CaskListItem
+		state indexState
+	}
+	formulaeChan := make(chan *formulaeResult)
+	casksChan := make(chan *casksResult)
 
 	// Load formulae in parallel
 	go func() {
-		if formulae, err := c.fetchFormulaeList(ctx); err == nil {
-			logger.Log.Debug("loaded formulae from API", "count", len(formulae))
-			formulaeChan <- formulae
+		if formulae, state, err := c.fetchFormulaeList(ctx); err == nil {
+			logger.Log.Debug("loaded formulae", "count", len(formulae), "stale", state.stale)
+			formulaeChan <- &formulaeResult{items: formulae, state: state}
 		} else {
 			logger.Log.Warn("failed to load formulae from API", "error", err)
 			formulaeChan <- nil
@@ -107,103 +209,214 @@ func (c *Client) loadFormulaeAndCasks(ctx context.Context) {
 
 	// Load casks in parallel
 	go func() {
-		if casks, err := c.fetchCasksList(ctx); err == nil {
-			logger.Log.Debug("loaded casks from API", "count", len(casks))
-			casksChan <- casks
+		if casks, state, err := c.fetchCasksList(ctx); err == nil {
+			logger.Log.Debug("loaded casks", "count", len(casks), "stale", state.stale)
+			casksChan <- &casksResult{items: casks, state: state}
 		} else {
 			logger.Log.Warn("failed to load casks from API", "error", err)
 			casksChan <- nil
 		}
 	}()
 
-	// Wait for both to complete and update cache atomically
+	// Wait for both to complete and update cache atomically. The timestamp
+	// only moves when both loaded, so a failed index is retried next time.
 	formulae := <-formulaeChan
 	casks := <-casksChan
 
 	c.cacheMutex.Lock()
 	if formulae != nil {
-		c.formulaeCache = formulae
-		c.cacheTimestamp = time.Now()
+		c.formulaeCache = formulae.items
+		c.indexState = formulae.state
 	}
 	if casks != nil {
-		c.casksCache = casks
+		c.casksCache = casks.items
+		c.caskIndexState = casks.state
+	}
+	if formulae != nil && casks != nil {
+		c.cacheTimestamp = time.Now()
 	}
 	c.cacheMutex.Unlock()
 }
 
 // fetchFormulaeList fetches the complete list of formulae from the API
-func (c *Client) fetchFormulaeList(ctx context.Context) ([]FormulaListItem, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, HomebrewAPIFormulae, nil)
+func (c *Client) fetchFormulaeList(ctx context.Context) ([]FormulaListItem, indexState, error) {
+	var formulae []FormulaListItem
+	state, err := c.fetchIndex(ctx, "formula.json", &formulae)
 	if err != nil {
-		return nil, err
+		return nil, state, err
 	}
+	return formulae, state, nil
+}
 
-	resp, err := c.httpClient.Do(req)
+// fetchCasksList fetches the complete list of casks from the API
+func (c *Client) fetchCasksList(ctx context.Context) ([]CaskListItem, indexState, error) {
+	var casks []CaskListItem
+	state, err := c.fetchIndex(ctx, "cask.json", &casks)
 	if err != nil {
-		return nil, err
+		return nil, state, err
 	}
-	defer func() {
-		if resp != nil && resp.Body != nil {
-			resp.Body.Close()
-		}
-	}()
+	return casks, state, nil
+}
+
+// indexes returns the formula and cask lists from the API index, loading
+// them if they have not been loaded yet or have expired. It fails only if
+// the formula list cannot be loaded; the cask list may be empty.
+func (c *Client) indexes(ctx context.Context) ([]FormulaListItem, []CaskListItem, error) {
+	c.cacheMutex.RLock()
+	formulae, casks := c.formulaeCache, c.casksCache
+	expired := time.Since(c.cacheTimestamp) > cacheExpiry
+	c.cacheMutex.RUnlock()
+
+	if expired || len(formulae) == 0 || len(casks) == 0 {
+		logger.Log.Debug("index expired or empty, reloading")
+		c.loadFormulaeAndCasks(ctx)
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned status %d", resp.StatusCode)
+		c.cacheMutex.RLock()
+		formulae, casks = c.formulaeCache, c.casksCache
+		c.cacheMutex.RUnlock()
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	if len(formulae) == 0 {
+		return nil, nil, errors.New("failed to load the formula index")
 	}
+	return formulae, casks, nil
+}
 
-	var formulae []FormulaListItem
-	if err := json.Unmarshal(body, &formulae); err != nil {
-		return nil, err
+// cachedIndex returns the formula list without touching the network: the
+// one already loaded, or else the on-disk snapshot however old it is. It
+// returns nil if neither is available.
+func (c *Client) cachedIndex() []FormulaListItem {
+	c.cacheMutex.RLock()
+	formulae := c.formulaeCache
+	c.cacheMutex.RUnlock()
+	if len(formulae) > 0 || c.indexCache == nil {
+		return formulae
 	}
 
-	return formulae, nil
+	body, _, err := c.indexCache.load("formula.json")
+	if err != nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, &formulae); err != nil {
+		logger.Log.Debug("ignoring unreadable on-disk index", "error", err)
+		return nil
+	}
+	return formulae
 }
 
-// fetchCasksList fetches the complete list of casks from the API
-func (c *Client) fetchCasksList(ctx context.Context) ([]CaskListItem, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, HomebrewAPICasks, nil)
-	if err != nil {
-		return nil, err
+// fetchIndex retrieves the named index from the API and decodes it into v.
+// An on-disk snapshot younger than cacheExpiry is used without touching the
+// network; an older one is revalidated with If-None-Match/If-Modified-Since.
+// If the API cannot be reached, the snapshot is used and reported as stale.
+// A snapshot downloaded from another API base is ignored, since its
+// validators mean nothing to this one.
+func (c *Client) fetchIndex(ctx context.Context, name string, v interface{}) (indexState, error) {
+	var (
+		cached []byte
+		meta   indexMeta
+	)
+
+	if c.indexCache != nil {
+		body, m, err := c.indexCache.load(name)
+		if err == nil && m.Base != c.endpoints()[0] {
+			logger.Log.Debug("ignoring on-disk index from another API", "index", name, "base", m.Base)
+		} else if err == nil {
+			cached, meta = body, m
+			if time.Since(meta.FetchedAt) < cacheExpiry {
+				if err := json.Unmarshal(body, v); err == nil {
+					logger.Log.Debug("using on-disk index", "index", name, "fetched_at", meta.FetchedAt)
+					return indexState{fetchedAt: meta.FetchedAt}, nil
+				}
+				cached = nil
+			}
+		}
 	}
 
-	resp, err := c.httpClient.Do(req)
+	body, fresh, err := c.downloadIndex(ctx, name, meta, cached != nil)
 	if err != nil {
-		return nil, err
+		if cached == nil || json.Unmarshal(cached, v) != nil {
+			return indexState{}, err
+		}
+		logger.Log.Warn("using cached index, API unreachable", "index", name, "error", err)
+		return indexState{fetchedAt: meta.FetchedAt, stale: true}, nil
 	}
-	defer func() {
-		if resp != nil && resp.Body != nil {
-			resp.Body.Close()
+
+	notModified := body == nil
+	if notModified {
+		logger.Log.Debug("index not modified", "index", name)
+		body = cached
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return indexState{}, err
+	}
+
+	if c.indexCache != nil {
+		var storeErr error
+		if notModified {
+			fresh.Sum = checksum(body)
+			storeErr = c.indexCache.storeMeta(name, fresh)
+		} else {
+			storeErr = c.indexCache.store(name, body, fresh)
 		}
-	}()
+		if storeErr != nil {
+			logger.Log.Debug("failed to write index cache", "index", name, "error", storeErr)
+		}
+	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned status %d", resp.StatusCode)
+	return indexState{fetchedAt: fresh.FetchedAt}, nil
+}
+
+// downloadIndex performs a GET for path, sending the validators in meta when
+// conditional is true. It returns a nil body when the server answers 304.
+// The index is recorded as coming from the primary API base, as mirrors
+// serve the same API.
+func (c *Client) downloadIndex(ctx context.Context, path string, meta indexMeta, conditional bool) ([]byte, indexMeta, error) {
+	header := http.Header{}
+	if conditional {
+		if meta.ETag != "" {
+			header.Set("If-None-Match", meta.ETag)
+		}
+		if meta.LastModified != "" {
+			header.Set("If-Modified-Since", meta.LastModified)
+		}
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	resp, err := c.apiGet(ctx, path, header)
 	if err != nil {
-		return nil, err
+		return nil, meta, err
 	}
+	defer resp.Body.Close()
 
-	var casks []CaskListItem
-	if err := json.Unmarshal(body, &casks); err != nil {
-		return nil, err
+	switch {
+	case resp.StatusCode == http.StatusNotModified && conditional:
+		meta.FetchedAt = time.Now()
+		return nil, meta, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, meta, fmt.Errorf("API returned status %d", resp.StatusCode)
 	}
 
-	return casks, nil
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, meta, err
+	}
+
+	return body, indexMeta{
+		Base:         c.endpoints()[0],
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		FetchedAt:    time.Now(),
+		Sum:          checksum(body),
+	}, nil
 }
 
-// GetFormula retrieves detailed information about a specific formula or cask.
-// It first checks the cache, then queries Homebrew's JSON API. If the package
-// is not found as a formula, it tries to fetch it as a cask. Local installation
-// information is merged into the result if the package is installed.
-// Returns an error if the package is not found as either a formula or cask.
+// ErrNotFound is returned when the API has no formula or cask by that name.
+var ErrNotFound = errors.New("not found")
+
+// GetFormula retrieves detailed information about a specific formula.
+// It first checks the cache, then queries Homebrew's JSON API. Local
+// installation information is merged into the result if the formula is
+// installed. Returns an error wrapping ErrNotFound if no such formula exists.
 func (c *Client) GetFormula(ctx context.Context, name string) (*Formula, error) {
 	// Check cache first
 	if cached, ok := c.getFromCache(name); ok {
@@ -214,25 +427,16 @@ func (c *Client) GetFormula(ctx context.Context, name string) (*Formula, error)
 	}
 
 	// Fetch from web API
-	url := fmt.Sprintf("%s/formula/%s.json", HomebrewAPIBase, name)
-	logger.Log.Debug("fetching formula from web API", "url", url)
+	path := fmt.Sprintf("formula/%s.json", name)
+	logger.Log.Debug("fetching formula from web API", "path", path)
 
-	formula, err := c.fetchFormula(ctx, url)
+	formula, err := c.fetchFormula(ctx, path)
 	if err != nil {
-		// Try as a cask if formula fetch failed
-		caskURL := fmt.Sprintf("%s/cask/%s.json", HomebrewAPIBase, name)
-		logger.Log.Debug("trying as cask", "url", caskURL)
-
-		if caskFormula, caskErr := c.fetchFormula(ctx, caskURL); caskErr == nil {
-			c.cache.Store(name, cacheEntry{data: caskFormula, timestamp: time.Now()})
-			return caskFormula, nil
-		}
-
-		return nil, fmt.Errorf("package not found: %s (tried both formula and cask)", name)
+		return nil, err
 	}
 
 	// Merge with local installation info
-	if installed, err := c.getLocalInstallInfo(ctx, name); err == nil && len(installed) > 0 {
+	if installed, err := c.localInstallInfo(ctx, formula.Name); err == nil && len(installed) > 0 {
 		formula.Installed = installed
 	}
 
@@ -240,11 +444,64 @@ func (c *Client) GetFormula(ctx context.Context, name string) (*Formula, error)
 	return formula, nil
 }
 
+// GetCask retrieves detailed information about a specific cask, including
+// its artifacts, dependencies and conflicts, from Homebrew's JSON API. The
+// installed version is filled in if the cask is installed. Returns an error
+// wrapping ErrNotFound if no such cask exists.
+func (c *Client) GetCask(ctx context.Context, token string) (*Cask, error) {
+	key := "cask/" + token
+	if cached, ok := c.getFromCache(key); ok {
+		if cask, ok := cached.(*Cask); ok {
+			logger.Log.Debug("using cached cask data", "cask", token)
+			return cask, nil
+		}
+	}
+
+	path := fmt.Sprintf("cask/%s.json", token)
+	logger.Log.Debug("fetching cask from web API", "path", path)
+
+	var cask Cask
+	if err := c.fetchJSON(ctx, path, &cask); err != nil {
+		return nil, err
+	}
+
+	if versions, err := c.installedCaskVersions(ctx); err == nil {
+		if version, ok := versions[cask.Token]; ok {
+			cask.Installed = version
+		}
+	}
+
+	c.cache.Store(key, cacheEntry{data: &cask, timestamp: time.Now()})
+	return &cask, nil
+}
+
+// GetPackage retrieves a formula by name or, if no formula exists with that
+// name, a cask by token. Returns an error wrapping ErrNotFound if neither
+// exists.
+func (c *Client) GetPackage(ctx context.Context, name string) (*Package, error) {
+	formula, err := c.GetFormula(ctx, name)
+	if err == nil {
+		return &Package{Kind: KindFormula, Formula: formula}, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	logger.Log.Debug("formula not found, trying as cask", "name", name)
+	cask, err := c.GetCask(ctx, name)
+	if err == nil {
+		return &Package{Kind: KindCask, Cask: cask}, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	return nil, fmt.Errorf("package %s %w (tried both formula and cask)", name, ErrNotFound)
+}
+
 // getLocalInstallInfo gets installation info for a formula from local brew
 func (c *Client) getLocalInstallInfo(ctx context.Context, name string) ([]InstalledInfo, error) {
-	//nolint:gosec // brewPath is validated at client creation
-	cmd := exec.CommandContext(ctx, c.brewPath, "info", "--json=v1", name)
-	output, err := cmd.Output()
+	output, err := c.output(c.command(ctx, "info", "--json=v1", name))
 	if err != nil {
 		return nil, err
 	}
@@ -266,9 +523,7 @@ func (c *Client) getLocalInstallInfo(ctx context.Context, name string) ([]Instal
 // each installed formula including version, dependencies, and installation metadata.
 // Returns an empty slice if no packages are installed, or an error if the command fails.
 func (c *Client) GetInstalledFormulae(ctx context.Context) ([]Formula, error) {
-	//nolint:gosec // brewPath is validated at client creation
-	cmd := exec.CommandContext(ctx, c.brewPath, "info", "--json=v1", "--installed")
-	output, err := cmd.Output()
+	output, err := c.output(c.command(ctx, "info", "--json=v1", "--installed"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get installed formulae: %w", err)
 	}
@@ -281,167 +536,409 @@ func (c *Client) GetInstalledFormulae(ctx context.Context) ([]Formula, error) {
 	return formulae, nil
 }
 
-// Search performs a case-insensitive search for packages matching the given term.
-// It searches both formulae and casks in parallel using cached API data for performance.
-// The search matches against package names and descriptions. If the cache is expired or
-// empty, it triggers a reload in the background. Returns two slices: matching formulae
-// names and matching cask names, plus any error encountered.
-func (c *Client) Search(ctx context.Context, term string) ([]string, []string, error) {
-	c.cacheMutex.RLock()
-	formulaeCache := c.formulaeCache
-	casksCache := c.casksCache
-	cacheAge := time.Since(c.cacheTimestamp)
-	c.cacheMutex.RUnlock()
-
-	// Reload cache if it's too old or empty
-	if cacheAge > cacheExpiry || len(formulaeCache) == 0 || len(casksCache) == 0 {
-		logger.Log.Debug("cache expired or empty, reloading")
-		c.loadFormulaeAndCasks(ctx)
+// Search performs a ranked, case-insensitive search for packages matching the
+// given term. It searches both formulae and casks in parallel using cached API
+// data for performance. Exact name matches rank first, followed by prefix,
+// alias and old-name matches, substring and typo-tolerant name matches, and
+// finally description matches. If the cache is expired or empty, it triggers a
+// reload. Returns the matching formulae and casks, each sorted by score, with
+// their descriptions, latest versions and deprecation state. Installation
+// state is not filled in; see MarkInstalled.
+func (c *Client) Search(ctx context.Context, term string) (*SearchResult, error) {
+	formulaeCache, casksCache, err := c.indexes(ctx)
+	if err != nil {
+		logger.Log.Warn("searching without a package index", "error", err)
+	}
 
-		// Wait a bit for the reload to complete
-		time.Sleep(100 * time.Millisecond)
+	result := &SearchResult{Term: term, Formulae: []SearchHit{}, Casks: []SearchHit{}}
 
-		c.cacheMutex.RLock()
-		formulaeCache = c.formulaeCache
-		casksCache = c.casksCache
-		c.cacheMutex.RUnlock()
+	lowerTerm := strings.ToLower(strings.TrimSpace(term))
+	if lowerTerm == "" {
+		return result, nil
 	}
 
-	lowerTerm := strings.ToLower(term)
-
 	// Use channels for concurrent search results
-	formulaeChan := make(chan []string)
-	casksChan := make(chan []string)
+	formulaeChan := make(chan []SearchHit)
+	casksChan := make(chan []SearchHit)
 
 	// Search formulae in parallel
 	go func() {
-		var results []string
+		var results []SearchHit
 		for _, f := range formulaeCache {
-			if strings.Contains(strings.ToLower(f.Name), lowerTerm) ||
-				strings.Contains(strings.ToLower(f.Desc), lowerTerm) {
-				results = append(results, f.Name)
+			oldNames := f.OldNames
+			if f.OldName != "" {
+				oldNames = append([]string{f.OldName}, oldNames...)
+			}
+			candidate := searchCandidate{name: f.Name, desc: f.Desc, aliases: f.Aliases, oldNames: oldNames}
+			if hit, ok := scoreCandidate(candidate, lowerTerm); ok {
+				hit.Kind = KindFormula
+				hit.Version = f.Versions.Stable
+				hit.Deprecated = f.Deprecated
+				hit.Disabled = f.Disabled
+				results = append(results, hit)
 			}
 		}
+		rankHits(results)
 		formulaeChan <- results
 	}()
 
-	// Search casks in parallel
+	// Search casks in parallel, treating display names as aliases
 	go func() {
-		var results []string
+		var results []SearchHit
 		for _, c := range casksCache {
-			if strings.Contains(strings.ToLower(c.Token), lowerTerm) ||
-				strings.Contains(strings.ToLower(c.Desc), lowerTerm) {
-				results = append(results, c.Token)
-			} else {
-				// Also search in cask names
-				for _, name := range c.Name {
-					if strings.Contains(strings.ToLower(name), lowerTerm) {
-						results = append(results, c.Token)
-						break
-					}
-				}
+			candidate := searchCandidate{name: c.Token, desc: c.Desc, aliases: c.Name}
+			if hit, ok := scoreCandidate(candidate, lowerTerm); ok {
+				hit.Kind = KindCask
+				hit.Version = c.Version
+				hit.Deprecated = c.Deprecated
+				hit.Disabled = c.Disabled
+				results = append(results, hit)
 			}
 		}
+		rankHits(results)
 		casksChan <- results
 	}()
 
 	// Wait for both searches to complete
-	formulaeResults := <-formulaeChan
-	casksResults := <-casksChan
+	if formulaeResults := <-formulaeChan; formulaeResults != nil {
+		result.Formulae = formulaeResults
+	}
+	if casksResults := <-casksChan; casksResults != nil {
+		result.Casks = casksResults
+	}
 
 	logger.Log.Debug("search completed",
 		"term", term,
-		"formulae_results", len(formulaeResults),
-		"casks_results", len(casksResults))
-
-	return formulaeResults, casksResults, nil
-}
-
-// Install installs one or more packages and reports progress via the status channel.
-// For each package, it executes `brew install` and monitors the output to provide
-// real-time status updates (downloading, installing, linking, completed, or failed).
-// The status channel receives InstallationStatus updates throughout the process and
-// is not closed by this function. Returns an error if the installation fails.
-func (c *Client) Install(ctx context.Context, packages []string, statusChan chan<- InstallationStatus) error {
-	for _, pkg := range packages {
-		startTime := time.Now()
-		status := InstallationStatus{
-			Formula:   pkg,
-			Stage:     "starting",
-			StartTime: startTime,
-		}
-		statusChan <- status
+		"formulae_results", len(result.Formulae),
+		"casks_results", len(result.Casks))
 
-		//nolint:gosec // brewPath is validated at client creation
-		cmd := exec.CommandContext(ctx, c.brewPath, "install", pkg)
+	return result, nil
+}
 
-		// Create pipes for stdout and stderr
-		stdout, err := cmd.StdoutPipe()
-		if err != nil {
-			statusChan <- InstallationStatus{
-				Formula:   pkg,
-				Stage:     "failed",
-				StartTime: startTime,
-				Error:     err,
+// MarkInstalled annotates search hits with their local installation state.
+// Formulae are cross-referenced against InstalledFormulae, which also
+// supplies the outdated flag; casks against `brew list --cask --versions`,
+// where a cask is outdated when its installed version differs from the
+// latest one in the index. Both lookups run in parallel.
+func (c *Client) MarkInstalled(ctx context.Context, result *SearchResult) error {
+	var (
+		wg         sync.WaitGroup
+		formulae   []Formula
+		casks      map[string]string
+		formulaErr error
+		caskErr    error
+	)
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		formulae, formulaErr = c.InstalledFormulae(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		casks, caskErr = c.installedCaskVersions(ctx)
+	}()
+	wg.Wait()
+
+	if formulaErr == nil {
+		installed := make(map[string]*Formula, len(formulae))
+		for i := range formulae {
+			installed[formulae[i].Name] = &formulae[i]
+		}
+		for i := range result.Formulae {
+			hit := &result.Formulae[i]
+			if f, ok := installed[hit.Name]; ok && len(f.Installed) > 0 {
+				hit.Installed = true
+				hit.InstalledVersion = f.Installed[len(f.Installed)-1].Version
+				hit.Outdated = f.Outdated
 			}
-			continue
 		}
+	}
 
-		stderr, err := cmd.StderrPipe()
-		if err != nil {
-			statusChan <- InstallationStatus{
-				Formula:   pkg,
-				Stage:     "failed",
-				StartTime: startTime,
-				Error:     err,
+	if caskErr == nil {
+		for i := range result.Casks {
+			hit := &result.Casks[i]
+			if version, ok := casks[hit.Name]; ok {
+				hit.Installed = true
+				hit.InstalledVersion = version
+				hit.Outdated = hit.Version != "" && version != hit.Version
 			}
-			continue
 		}
+	}
 
-		if err := cmd.Start(); err != nil {
-			statusChan <- InstallationStatus{
-				Formula:   pkg,
-				Stage:     "failed",
-				StartTime: startTime,
-				Error:     err,
-			}
+	return errors.Join(formulaErr, caskErr)
+}
+
+// installedCaskVersions returns the installed version of each cask, keyed by
+// token, from the Caskroom, or from brew if the Caskroom does not look the
+// way brew leaves it.
+func (c *Client) installedCaskVersions(ctx context.Context) (map[string]string, error) {
+	versions, err := c.scanCaskroom()
+	if err == nil {
+		return versions, nil
+	}
+	logger.Log.Debug("falling back to brew for installed casks", "error", err)
+	return c.listCaskVersions(ctx)
+}
+
+// listCaskVersions returns the installed version of each cask, keyed by
+// token, as listed by brew.
+func (c *Client) listCaskVersions(ctx context.Context) (map[string]string, error) {
+	output, err := c.output(c.command(ctx, "list", "--cask", "--versions"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list installed casks: %w", err)
+	}
+
+	versions := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
 			continue
 		}
+		versions[fields[0]] = strings.Join(fields[1:], " ")
+	}
 
-		// Monitor output - pass startTime so monitor can set it on status updates
-		go c.monitorInstallation(stdout, stderr, pkg, startTime, statusChan)
+	return versions, nil
+}
+
+// Install installs one or more packages and reports progress via the status
+// channel. Independent packages are installed concurrently by up to
+// DefaultInstallJobs brew processes (see WithJobs). When more than one
+// package is requested, dependencies shared by several of them are looked
+// up in the JSON API and installed once, ahead of the packages that need
+// them, so that parallel brew processes never contend for the same keg
+// lock; such dependencies are reported with Dependency set. Parallel brew
+// processes run with HOMEBREW_NO_AUTO_UPDATE so they do not each try to
+// update Homebrew. For each package, it executes `brew install` and
+// monitors the output to provide real-time status updates (downloading,
+// installing, linking, completed, or failed). A package whose dependency
+// failed, or that had not started when ctx was cancelled, is reported as
+// skipped. Cancelling ctx interrupts running brew processes with SIGINT;
+// errors for packages stopped that way wrap ErrInterrupted. Every package
+// ends with exactly one completed, failed or skipped status, and no status
+// is sent after Install returns, so the caller may close statusChan as soon
+// as it does. Returns an *InstallError listing every package that failed or
+// was skipped.
+func (c *Client) Install(ctx context.Context, packages []string, statusChan chan<- InstallationStatus, opts ...InstallOption) error {
+	cfg := installConfig{jobs: DefaultInstallJobs}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
 
-		if err := cmd.Wait(); err != nil {
+	var nodes []*installNode
+	if len(packages) > 1 && cfg.jobs > 1 {
+		planned, err := c.planInstall(ctx, packages)
+		if err != nil {
+			logger.Log.Warn("installing packages independently, without scheduling shared dependencies", "error", err)
+		}
+		nodes = planned
+	}
+	if nodes == nil {
+		for _, pkg := range packages {
+			nodes = append(nodes, &installNode{name: pkg})
+		}
+	}
+	for _, n := range nodes {
+		n.args = cfg.args[n.name]
+	}
+	return c.runInstalls(ctx, "install", nodes, cfg.jobs, statusChan)
+}
+
+// UpgradeEach upgrades each of packages with its own `brew upgrade` process
+// and reports progress via statusChan exactly like Install: every package
+// ends with one completed, failed or skipped status, and nothing is sent
+// after UpgradeEach returns. Packages are upgraded one at a time unless
+// WithJobs allows more, since brew upgrades outdated dependencies along with
+// each package. Returns an *InstallError listing every package that failed
+// or was skipped.
+func (c *Client) UpgradeEach(ctx context.Context, packages []string, statusChan chan<- InstallationStatus, opts ...InstallOption) error {
+	cfg := installConfig{jobs: 1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	nodes := make([]*installNode, len(packages))
+	for i, pkg := range packages {
+		nodes[i] = &installNode{name: pkg, args: cfg.args[pkg]}
+	}
+	return c.runInstalls(ctx, "upgrade", nodes, cfg.jobs, statusChan)
+}
+
+// runInstalls runs `brew <verb>` for every node of an install schedule, up
+// to jobs at a time, and collects the packages that failed or were skipped.
+func (c *Client) runInstalls(ctx context.Context, verb string, nodes []*installNode, jobs int, statusChan chan<- InstallationStatus) error {
+	parallel := jobs > 1 && len(nodes) > 1
+
+	var (
+		mu       sync.Mutex
+		failures = make(map[string]PackageFailure)
+	)
+	runSchedule(ctx, nodes, jobs,
+		func(n *installNode) error {
+			err := c.installOne(ctx, verb, n, parallel, statusChan)
+			if err != nil {
+				mu.Lock()
+				failures[n.name] = PackageFailure{Package: n.name, Err: err}
+				mu.Unlock()
+			}
+			return err
+		},
+		func(n *installNode, err error) {
+			if ctx.Err() != nil {
+				err = fmt.Errorf("%w before it started", ErrInterrupted)
+			}
+			now := time.Now()
 			statusChan <- InstallationStatus{
-				Formula:   pkg,
-				Stage:     "failed",
-				StartTime: startTime,
-				Error:     err,
+				Formula:    n.name,
+				Stage:      "skipped",
+				StartTime:  now,
+				EndTime:    now,
+				Error:      err,
+				Dependency: n.dependency,
 			}
-			continue
-		}
+			mu.Lock()
+			failures[n.name] = PackageFailure{Package: n.name, Err: err, Skipped: true}
+			mu.Unlock()
+		})
 
-		statusChan <- InstallationStatus{
-			Formula:   pkg,
-			Stage:     "completed",
-			Progress:  100,
-			StartTime: startTime,
+	if len(failures) == 0 {
+		return nil
+	}
+	installErr := &InstallError{action: verb}
+	for _, n := range nodes {
+		if f, ok := failures[n.name]; ok {
+			installErr.Failures = append(installErr.Failures, f)
 		}
 	}
+	return installErr
+}
+
+// installOne runs `brew <verb>` for a single schedule node, sending its
+// progress to statusChan, and returns the error that ended it, if any.
+// The final status is sent only after brew's output has been fully read.
+func (c *Client) installOne(ctx context.Context, verb string, n *installNode, parallel bool, statusChan chan<- InstallationStatus) error {
+	startTime := time.Now()
+	status := InstallationStatus{
+		Formula:    n.name,
+		Stage:      "starting",
+		StartTime:  startTime,
+		Dependency: n.dependency,
+	}
+	statusChan <- status
+
+	fail := func(err error) error {
+		status.Stage = "failed"
+		status.EndTime = time.Now()
+		status.Error = err
+		statusChan <- status
+		return err
+	}
+
+	args := append([]string{verb}, n.args...)
+	cmd := c.interactiveCommand(ctx, append(args, n.name)...)
+	if parallel {
+		cmd.Env = append(os.Environ(), "HOMEBREW_NO_AUTO_UPDATE=1")
+	}
+
+	// Create pipes for stdout and stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fail(err)
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fail(err)
+	}
 
-	return nil
+	if err := c.start(cmd); err != nil {
+		return fail(err)
+	}
+
+	// Monitor output line by line for stage and download progress. The
+	// pipes must be drained before Wait closes them.
+	log := c.monitorInstallation(stdout, stderr, n.name, startTime, n.dependency, statusChan)
+	log.Wait()
+
+	err = c.wait(cmd)
+	if err != nil && ctx.Err() != nil {
+		err = fmt.Errorf("%w: %w", ErrInterrupted, err)
+	}
+	statusChan <- log.Finish(err)
+	return err
+}
+
+// UninstallOption configures a single Uninstall call.
+type UninstallOption func(*uninstallConfig)
+
+// uninstallConfig holds the settings applied by UninstallOptions.
+type uninstallConfig struct {
+	ignoreDependencies bool
+}
+
+// WithIgnoreDependencies lets Uninstall remove formulae that installed
+// formulae still depend on.
+func WithIgnoreDependencies() UninstallOption {
+	return func(cfg *uninstallConfig) {
+		cfg.ignoreDependencies = true
+	}
 }
 
 // Uninstall removes one or more packages from the system.
 // It executes `brew uninstall` with the provided package names and streams
-// the output to stdout and stderr. Returns an error if uninstallation fails.
-func (c *Client) Uninstall(ctx context.Context, packages []string) error {
-	args := append([]string{"uninstall"}, packages...)
-	//nolint:gosec // brewPath is validated at client creation, args are package names
-	cmd := exec.CommandContext(ctx, c.brewPath, args...)
-	cmd.Stdout = os.Stdout
+// the output to stdout (see WithOutput) and stderr. Returns an error if
+// uninstallation fails.
+func (c *Client) Uninstall(ctx context.Context, packages []string, opts ...UninstallOption) error {
+	var cfg uninstallConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	args := []string{"uninstall"}
+	if cfg.ignoreDependencies {
+		args = append(args, "--ignore-dependencies")
+	}
+	cmd := c.interactiveCommand(ctx, append(args, packages...)...)
+	cmd.Stdout = c.brewStdout()
+	cmd.Stderr = os.Stderr
+	return c.run(cmd)
+}
+
+// Pin pins formulae at their installed version so that `brew upgrade`
+// leaves them alone. It executes `brew pin` and streams the output to
+// stdout (see WithOutput) and stderr.
+func (c *Client) Pin(ctx context.Context, formulae []string) error {
+	cmd := c.interactiveCommand(ctx, append([]string{"pin"}, formulae...)...)
+	cmd.Stdout = c.brewStdout()
 	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return c.run(cmd)
+}
+
+// Unpin removes brew pins from formulae. It executes `brew unpin` and
+// streams the output to stdout (see WithOutput) and stderr.
+func (c *Client) Unpin(ctx context.Context, formulae []string) error {
+	cmd := c.interactiveCommand(ctx, append([]string{"unpin"}, formulae...)...)
+	cmd.Stdout = c.brewStdout()
+	cmd.Stderr = os.Stderr
+	return c.run(cmd)
+}
+
+// HoldDuring pins formulae with brew while fn runs and unpins them again
+// afterwards, so that brew upgrade does not upgrade them as outdated
+// dependencies of the packages fn upgrades. The formulae are unpinned even
+// if fn fails or ctx is cancelled. Without formulae, fn just runs.
+func (c *Client) HoldDuring(ctx context.Context, formulae []string, fn func() error) error {
+	if len(formulae) == 0 {
+		return fn()
+	}
+	if err := c.Pin(ctx, formulae); err != nil {
+		return fmt.Errorf("failed to pin held formulae: %w", err)
+	}
+	err := fn()
+	if unpinErr := c.Unpin(context.WithoutCancel(ctx), formulae); unpinErr != nil {
+		err = errors.Join(err, fmt.Errorf("failed to unpin %s: %w", strings.Join(formulae, ", "), unpinErr))
+	}
+	return err
 }
 
 // Update updates Homebrew itself and refreshes the formulae database.
@@ -449,11 +946,10 @@ func (c *Client) Uninstall(ctx context.Context, packages []string) error {
 // and all formulae from GitHub. Output is streamed to stdout and stderr.
 // Returns an error if the update fails.
 func (c *Client) Update(ctx context.Context) error {
-	//nolint:gosec // brewPath is validated at client creation
-	cmd := exec.CommandContext(ctx, c.brewPath, "update")
-	cmd.Stdout = os.Stdout
+	cmd := c.interactiveCommand(ctx, "update")
+	cmd.Stdout = c.brewStdout()
 	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return c.run(cmd)
 }
 
 // Upgrade upgrades one or more installed packages to their latest versions.
@@ -461,111 +957,124 @@ func (c *Client) Update(ctx context.Context) error {
 // `brew upgrade` with the specified package names (if any) and streams output
 // to stdout and stderr. Returns an error if the upgrade fails.
 func (c *Client) Upgrade(ctx context.Context, packages []string) error {
-	args := append([]string{"upgrade"}, packages...)
-	//nolint:gosec // brewPath is validated at client creation, args are package names
-	cmd := exec.CommandContext(ctx, c.brewPath, args...)
-	cmd.Stdout = os.Stdout
+	cmd := c.interactiveCommand(ctx, append([]string{"upgrade"}, packages...)...)
+	cmd.Stdout = c.brewStdout()
 	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return c.run(cmd)
 }
 
 // Helper methods
 
-func (c *Client) fetchFormula(ctx context.Context, url string) (*Formula, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
+func (c *Client) fetchFormula(ctx context.Context, path string) (*Formula, error) {
+	var formula Formula
+	if err := c.fetchJSON(ctx, path, &formula); err != nil {
 		return nil, err
 	}
+	return &formula, nil
+}
 
-	resp, err := c.httpClient.Do(req)
+// fetchJSON fetches path from the API and decodes the response into v.
+// A 404 response is reported as an error wrapping ErrNotFound.
+func (c *Client) fetchJSON(ctx context.Context, path string, v interface{}) error {
+	resp, err := c.apiGet(ctx, path, nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer func() {
-		if resp != nil && resp.Body != nil {
-			resp.Body.Close()
-		}
-	}()
+	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned status %d", resp.StatusCode)
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return fmt.Errorf("%s: %w", path, ErrNotFound)
+	default:
+		return fmt.Errorf("API returned status %d", resp.StatusCode)
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	var formula Formula
-	if err := json.Unmarshal(body, &formula); err != nil {
-		return nil, err
-	}
-
-	return &formula, nil
+	return json.Unmarshal(body, v)
 }
 
-func (c *Client) getFromCache(key string) (interface{}, bool) {
-	if val, ok := c.cache.Load(key); ok {
-		entry := val.(cacheEntry)
-		if time.Since(entry.timestamp) < cacheExpiry {
-			return entry.data, true
-		}
-		c.cache.Delete(key)
+// endpoints returns the API base URLs to try, primary first.
+func (c *Client) endpoints() []string {
+	if len(c.apiBases) == 0 {
+		return []string{HomebrewAPIBase}
 	}
-	return nil, false
+	return c.apiBases
 }
 
-func (c *Client) monitorInstallation(stdout, stderr io.Reader, pkg string, startTime time.Time, statusChan chan<- InstallationStatus) {
-	scanner := func(r io.Reader) {
-		buf := make([]byte, 1024)
-		for {
-			n, err := r.Read(buf)
-			if n > 0 {
-				line := string(buf[:n])
-				status := c.parseInstallOutput(pkg, line, startTime)
-				if status != nil {
-					statusChan <- *status
-				}
-			}
-			if err != nil {
-				break
+// apiGet issues a GET for path against each API endpoint in order, moving on
+// to the next mirror when the request fails or the server answers with a 5xx
+// status. Other responses are returned as-is; the caller closes the body.
+func (c *Client) apiGet(ctx context.Context, path string, header http.Header) (*http.Response, error) {
+	var lastErr error
+	for _, base := range c.endpoints() {
+		url := strings.TrimRight(base, "/") + "/" + strings.TrimLeft(path, "/")
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		for key, values := range header {
+			req.Header[key] = values
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
 			}
+			logger.Log.Debug("API endpoint unreachable, trying next", "url", url, "error", err)
+			lastErr = err
+			continue
 		}
+
+		if resp.StatusCode >= http.StatusInternalServerError {
+			resp.Body.Close()
+			logger.Log.Debug("API endpoint failed, trying next", "url", url, "status", resp.StatusCode)
+			lastErr = fmt.Errorf("API returned status %d", resp.StatusCode)
+			continue
+		}
+
+		return resp, nil
 	}
 
-	go scanner(stdout)
-	go scanner(stderr)
+	return nil, lastErr
 }
 
-func (c *Client) parseInstallOutput(pkg, line string, startTime time.Time) *InstallationStatus {
-	lower := strings.ToLower(line)
-
-	status := &InstallationStatus{
-		Formula:   pkg,
-		StartTime: startTime,
+// brewStdout returns where streamed brew output should be written.
+func (c *Client) brewStdout() io.Writer {
+	if c.stdout == nil {
+		return os.Stdout
 	}
+	return c.stdout
+}
 
-	switch {
-	case strings.Contains(lower, "downloading"):
-		status.Stage = "downloading"
-		status.Progress = 25
-	case strings.Contains(lower, "installing"):
-		status.Stage = "installing"
-		status.Progress = 50
-	case strings.Contains(lower, "pouring"):
-		status.Stage = "installing"
-		status.Progress = 60
-	case strings.Contains(lower, "linking"):
-		status.Stage = "linking"
-		status.Progress = 90
-	case strings.Contains(lower, "installed"):
-		status.Stage = "completed"
-		status.Progress = 100
-	default:
-		return nil
+func (c *Client) getFromCache(key string) (interface{}, bool) {
+	if val, ok := c.cache.Load(key); ok {
+		entry := val.(cacheEntry)
+		if time.Since(entry.timestamp) < cacheExpiry {
+			return entry.data, true
+		}
+		c.cache.Delete(key)
 	}
+	return nil, false
+}
 
-	return status
+// monitorInstallation scans brew's stdout and stderr line by line in the
+// background, sending status updates for pkg to statusChan. The returned
+// log accumulates the transcript and produces the final status; its Wait
+// method blocks until both streams have been read to the end.
+func (c *Client) monitorInstallation(stdout, stderr io.Reader, pkg string, startTime time.Time, dependency bool, statusChan chan<- InstallationStatus) *installLog {
+	log := newInstallLog(pkg, startTime, dependency)
+	log.readers.Add(2)
+	go log.consume(stdout, statusChan)
+	go log.consume(stderr, statusChan)
+	return log
 }
 
 // ExecuteCommand executes an arbitrary brew command with the provided arguments.
@@ -573,10 +1082,27 @@ func (c *Client) parseInstallOutput(pkg, line string, startTime time.Time) *Inst
 // It passes stdin, stdout, and stderr directly to the brew process, allowing
 // interactive commands to work properly. Returns an error if the command fails.
 func (c *Client) ExecuteCommand(ctx context.Context, args []string) error {
-	//nolint:gosec // brewPath is validated at client creation, args are user commands
-	cmd := exec.CommandContext(ctx, c.brewPath, args...)
+	cmd := c.foregroundCommand(ctx, args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	cmd.Stdin = os.Stdin
-	return cmd.Run()
+	return c.run(cmd)
+}
+
+// IndexAge reports how long ago the older of the formula and cask indexes
+// was last confirmed current and whether either is a stale on-disk snapshot
+// used because the API could not be reached. It returns a zero duration if
+// no index has been loaded.
+func (c *Client) IndexAge() (time.Duration, bool) {
+	c.cacheMutex.RLock()
+	defer c.cacheMutex.RUnlock()
+
+	oldest := c.indexState.fetchedAt
+	if cask := c.caskIndexState.fetchedAt; oldest.IsZero() || !cask.IsZero() && cask.Before(oldest) {
+		oldest = cask
+	}
+	if oldest.IsZero() {
+		return 0, false
+	}
+	return time.Since(oldest), c.indexState.stale || c.caskIndexState.stale
 }
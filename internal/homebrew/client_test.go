@@ -3,17 +3,29 @@ package homebrew
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 )
 
+// TestMain keeps the tests away from the Homebrew installation the
+// environment points to, as brew shellenv does, so that clients built
+// around a fake brew find no Cellar and ask it instead.
+func TestMain(m *testing.M) {
+	for _, name := range []string{"HOMEBREW_PREFIX", "HOMEBREW_CELLAR", "HOMEBREW_REPOSITORY"} {
+		_ = os.Unsetenv(name)
+	}
+	os.Exit(m.Run())
+}
+
 func TestNewClient(t *testing.T) {
 	client, err := NewClient()
 	if err != nil {
@@ -107,11 +119,12 @@ func TestGetFormula(t *testing.T) {
 	client := &Client{
 		httpClient: &http.Client{Timeout: 5 * time.Second},
 		brewPath:   "brew", // Assume brew is in PATH
+		apiBases:   []string{server.URL},
 	}
 
 	// Directly fetch from our test server
 	ctx := context.Background()
-	formula, err := client.fetchFormula(ctx, server.URL+"/formula/git.json")
+	formula, err := client.fetchFormula(ctx, "formula/git.json")
 	if err != nil {
 		t.Fatalf("Failed to get formula: %v", err)
 	}
@@ -130,10 +143,11 @@ func TestGetFormula_NotFound(t *testing.T) {
 	client := &Client{
 		httpClient: &http.Client{Timeout: 5 * time.Second},
 		brewPath:   "brew",
+		apiBases:   []string{server.URL},
 	}
 
 	ctx := context.Background()
-	_, err := client.fetchFormula(ctx, server.URL+"/nonexistent.json")
+	_, err := client.fetchFormula(ctx, "nonexistent.json")
 	if err == nil {
 		t.Error("Expected error for nonexistent formula")
 	}
@@ -157,10 +171,11 @@ func TestSearch(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	formulae, casks, err := client.Search(ctx, "git")
+	result, err := client.Search(ctx, "git")
 	if err != nil {
 		t.Fatalf("Search failed: %v", err)
 	}
+	formulae, casks := result.Formulae, result.Casks
 
 	if len(formulae) < 2 {
 		t.Errorf("Expected at least 2 formulae containing 'git', got %d", len(formulae))
@@ -171,12 +186,12 @@ func TestSearch(t *testing.T) {
 	}
 
 	// Test case insensitive search
-	formulae2, _, err := client.Search(ctx, "GIT")
+	result2, err := client.Search(ctx, "GIT")
 	if err != nil {
 		t.Fatalf("Case insensitive search failed: %v", err)
 	}
 
-	if len(formulae2) != len(formulae) {
+	if len(result2.Formulae) != len(formulae) {
 		t.Error("Case insensitive search should return same results")
 	}
 }
@@ -190,7 +205,7 @@ func TestSearchWithExpiredCache(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	_, _, err := client.Search(ctx, "test")
+	_, err := client.Search(ctx, "test")
 	if err != nil {
 		t.Fatalf("Search with expired cache failed: %v", err)
 	}
@@ -318,56 +333,6 @@ func TestUpgrade(t *testing.T) {
 	_ = client.Upgrade(context.Background(), []string{})
 }
 
-func TestParseInstallOutput(t *testing.T) {
-	client := &Client{
-		httpClient: &http.Client{Timeout: 5 * time.Second},
-	}
-
-	tests := []struct {
-		name          string
-		line          string
-		expectedStage string
-		expectedProg  int
-	}{
-		{"Downloading", "==> Downloading git-2.51.1.tar.gz", "downloading", 25},
-		{"Installing", "==> Installing git", "installing", 50},
-		{"Pouring", "==> Pouring git--2.51.1.bottle.tar.gz", "installing", 60},
-		{"Linking", "==> Linking git", "linking", 90},
-		{"Installed", "git 2.51.1 is installed", "completed", 100},
-		{"No match", "Some other output", "", 0},
-	}
-
-	startTime := time.Now()
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			status := client.parseInstallOutput("git", tt.line, startTime)
-
-			if tt.expectedStage == "" {
-				if status != nil {
-					t.Errorf("Expected nil status for line: %s", tt.line)
-				}
-			} else {
-				if status == nil {
-					t.Fatalf("Expected status for line: %s", tt.line)
-				}
-				if status.Stage != tt.expectedStage {
-					t.Errorf("Expected stage '%s', got '%s'", tt.expectedStage, status.Stage)
-				}
-				if status.Progress != tt.expectedProg {
-					t.Errorf("Expected progress %d, got %d", tt.expectedProg, status.Progress)
-				}
-				if status.Formula != "git" {
-					t.Errorf("Expected formula 'git', got '%s'", status.Formula)
-				}
-				if status.StartTime.IsZero() {
-					t.Error("Expected StartTime to be set")
-				}
-			}
-		})
-	}
-}
-
 func TestExecuteCommand(t *testing.T) {
 	if _, err := exec.LookPath("brew"); err != nil {
 		t.Skip("Skipping execute command test: brew not found")
@@ -401,10 +366,11 @@ func TestFetchFormula(t *testing.T) {
 
 	client := &Client{
 		httpClient: &http.Client{Timeout: 5 * time.Second},
+		apiBases:   []string{server.URL},
 	}
 
 	ctx := context.Background()
-	formula, err := client.fetchFormula(ctx, server.URL)
+	formula, err := client.fetchFormula(ctx, "formula/test.json")
 	if err != nil {
 		t.Fatalf("fetchFormula failed: %v", err)
 	}
@@ -475,10 +441,11 @@ func TestHTTPErrors(t *testing.T) {
 
 	client := &Client{
 		httpClient: &http.Client{Timeout: 5 * time.Second},
+		apiBases:   []string{server.URL},
 	}
 
 	ctx := context.Background()
-	_, err := client.fetchFormula(ctx, server.URL)
+	_, err := client.fetchFormula(ctx, "formula/test.json")
 	if err == nil {
 		t.Error("Expected error for 500 status code")
 	}
@@ -493,12 +460,13 @@ func TestContextCancellation(t *testing.T) {
 
 	client := &Client{
 		httpClient: &http.Client{Timeout: 5 * time.Second},
+		apiBases:   []string{server.URL},
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
 	defer cancel()
 
-	_, err := client.fetchFormula(ctx, server.URL)
+	_, err := client.fetchFormula(ctx, "formula/test.json")
 	if err == nil {
 		t.Error("Expected error from context cancellation")
 	}
@@ -559,7 +527,7 @@ func TestMonitorInstallation(t *testing.T) {
 
 	// Start monitoring
 	startTime := time.Now()
-	client.monitorInstallation(stdout, stderr, "git", startTime, statusChan)
+	client.monitorInstallation(stdout, stderr, "git", startTime, false, statusChan)
 
 	// Collect statuses with timeout to avoid hanging
 	statusCount := 0
@@ -583,3 +551,263 @@ collecting:
 		t.Logf("Received %d status updates", statusCount)
 	}
 }
+
+func TestWithAPIEndpoints(t *testing.T) {
+	client := &Client{}
+	WithAPIEndpoints("", "https://mirror.example.com/api", "")(client)
+
+	if len(client.apiBases) != 2 {
+		t.Fatalf("Expected 2 endpoints, got %v", client.apiBases)
+	}
+	if client.apiBases[0] != HomebrewAPIBase {
+		t.Errorf("Expected default base first, got %s", client.apiBases[0])
+	}
+	if client.apiBases[1] != "https://mirror.example.com/api" {
+		t.Errorf("Expected mirror second, got %s", client.apiBases[1])
+	}
+}
+
+func TestAPIMirrorFallback(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer primary.Close()
+
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	var mirrorPath string
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mirrorPath = r.URL.Path
+		_ = json.NewEncoder(w).Encode(Formula{Name: "git"})
+	}))
+	defer mirror.Close()
+
+	client := &Client{httpClient: &http.Client{Timeout: 5 * time.Second}}
+	WithAPIEndpoints(primary.URL, unreachable.URL, mirror.URL+"/api/")(client)
+
+	formula, err := client.fetchFormula(context.Background(), "formula/git.json")
+	if err != nil {
+		t.Fatalf("Expected mirror to serve request, got: %v", err)
+	}
+	if formula.Name != "git" {
+		t.Errorf("Expected name 'git', got '%s'", formula.Name)
+	}
+	if mirrorPath != "/api/formula/git.json" {
+		t.Errorf("Expected mirror path '/api/formula/git.json', got '%s'", mirrorPath)
+	}
+}
+
+func TestAPINotFoundDoesNotFallBack(t *testing.T) {
+	primary := httptest.NewServer(http.NotFoundHandler())
+	defer primary.Close()
+
+	mirrorHit := false
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mirrorHit = true
+	}))
+	defer mirror.Close()
+
+	client := &Client{httpClient: &http.Client{Timeout: 5 * time.Second}}
+	WithAPIEndpoints(primary.URL, mirror.URL)(client)
+
+	if _, err := client.fetchFormula(context.Background(), "formula/missing.json"); err == nil {
+		t.Error("Expected error for missing formula")
+	}
+	if mirrorHit {
+		t.Error("A 404 from the primary endpoint should not fall back to mirrors")
+	}
+}
+
+// writeFakeBrew writes a shell script standing in for brew and returns its path.
+// The script body receives brew's arguments as "$@".
+func writeFakeBrew(t *testing.T, body string) string {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("Skipping: sh not found")
+	}
+
+	path := filepath.Join(t.TempDir(), "brew")
+	script := "#!/bin/sh\n" + body + "\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil { //nolint:gosec // test script must be executable
+		t.Fatalf("Failed to write fake brew: %v", err)
+	}
+	return path
+}
+
+func TestMarkInstalled(t *testing.T) {
+	brew := writeFakeBrew(t, `
+case "$1" in
+info) echo '[{"name":"git","outdated":true,"installed":[{"version":"2.40.0"}]}]' ;;
+list) echo 'firefox 119.0' ; echo 'iterm2 3.5.0' ;;
+esac`)
+
+	client := &Client{brewPath: brew}
+	result := &SearchResult{
+		Formulae: []SearchHit{{Name: "git", Version: "2.51.1"}, {Name: "git-lfs"}},
+		Casks:    []SearchHit{{Name: "firefox", Version: "120.0"}, {Name: "iterm2", Version: "3.5.0"}},
+	}
+
+	if err := client.MarkInstalled(context.Background(), result); err != nil {
+		t.Fatalf("MarkInstalled failed: %v", err)
+	}
+
+	git := result.Formulae[0]
+	if !git.Installed || git.InstalledVersion != "2.40.0" || !git.Outdated {
+		t.Errorf("Expected git to be installed and outdated, got %+v", git)
+	}
+	if result.Formulae[1].Installed {
+		t.Error("git-lfs should not be marked installed")
+	}
+	if firefox := result.Casks[0]; !firefox.Installed || !firefox.Outdated {
+		t.Errorf("Expected firefox to be installed and outdated, got %+v", firefox)
+	}
+	if iterm := result.Casks[1]; !iterm.Installed || iterm.Outdated {
+		t.Errorf("Expected iterm2 to be installed and current, got %+v", iterm)
+	}
+}
+
+func TestGetPackage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/formula/git.json":
+			_ = json.NewEncoder(w).Encode(Formula{Name: "git"})
+		case "/cask/firefox.json":
+			_, _ = w.Write([]byte(`{"token":"firefox","version":"131.0","artifacts":[{"app":["Firefox.app"]}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	brew := writeFakeBrew(t, `
+case "$1" in
+list) echo 'firefox 130.0' ;;
+*) exit 1 ;;
+esac`)
+
+	client := &Client{
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		brewPath:   brew,
+		apiBases:   []string{server.URL},
+	}
+	ctx := context.Background()
+
+	pkg, err := client.GetPackage(ctx, "git")
+	if err != nil {
+		t.Fatalf("GetPackage(git) failed: %v", err)
+	}
+	if pkg.Kind != KindFormula || pkg.Formula.Name != "git" {
+		t.Errorf("Expected formula git, got %+v", pkg)
+	}
+
+	pkg, err = client.GetPackage(ctx, "firefox")
+	if err != nil {
+		t.Fatalf("GetPackage(firefox) failed: %v", err)
+	}
+	if pkg.Kind != KindCask || pkg.Cask.Token != "firefox" {
+		t.Fatalf("Expected cask firefox, got %+v", pkg)
+	}
+	if pkg.Cask.Installed != "130.0" {
+		t.Errorf("Expected installed version 130.0, got '%s'", pkg.Cask.Installed)
+	}
+	if apps := pkg.Cask.ArtifactPaths("app"); len(apps) != 1 {
+		t.Errorf("Expected cask artifacts to be preserved, got %v", apps)
+	}
+
+	_, err = client.GetPackage(ctx, "missing")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestGetPackage_ServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := &Client{
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		apiBases:   []string{server.URL},
+	}
+
+	_, err := client.GetPackage(context.Background(), "git")
+	if err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a server error rather than not found, got %v", err)
+	}
+}
+
+func TestPinUnpin(t *testing.T) {
+	argsFile := filepath.Join(t.TempDir(), "args")
+	brew := writeFakeBrew(t, `echo "$@" >> `+argsFile)
+	client := &Client{brewPath: brew}
+
+	if err := client.Pin(context.Background(), []string{"node", "go"}); err != nil {
+		t.Fatalf("Pin failed: %v", err)
+	}
+	if err := client.Unpin(context.Background(), []string{"node"}); err != nil {
+		t.Fatalf("Unpin failed: %v", err)
+	}
+
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatalf("Failed to read brew arguments: %v", err)
+	}
+	if got := strings.TrimSpace(string(args)); got != "pin node go\nunpin node" {
+		t.Errorf("Unexpected brew arguments: %q", got)
+	}
+}
+
+func TestHoldDuring(t *testing.T) {
+	state := t.TempDir()
+	// Upgrading yarn upgrades its outdated dependency node unless node is
+	// pinned, as brew does
+	brew := writeFakeBrew(t, fmt.Sprintf(`
+echo "$@" >> %[1]s/log
+case "$1" in
+pin) touch %[1]s/pinned ;;
+unpin) rm -f %[1]s/pinned ;;
+upgrade)
+  [ -f %[1]s/pinned ] || echo "upgraded node" >> %[1]s/log
+  [ "$2" = fail ] && exit 1 ;;
+esac
+exit 0`, state))
+	client := &Client{brewPath: brew}
+	ctx := context.Background()
+
+	err := client.HoldDuring(ctx, []string{"node"}, func() error {
+		return client.Upgrade(ctx, []string{"yarn"})
+	})
+	if err != nil {
+		t.Fatalf("HoldDuring failed: %v", err)
+	}
+	if err := client.HoldDuring(ctx, []string{"node"}, func() error {
+		return client.Upgrade(ctx, []string{"fail"})
+	}); err == nil {
+		t.Error("Expected the upgrade error to be returned")
+	}
+	if err := client.HoldDuring(ctx, nil, func() error { return nil }); err != nil {
+		t.Errorf("Expected nothing to hold, got %v", err)
+	}
+
+	log, _ := os.ReadFile(filepath.Join(state, "log"))
+	want := "pin node\nupgrade yarn\nunpin node\npin node\nupgrade fail\nunpin node"
+	if got := strings.TrimSpace(string(log)); got != want {
+		t.Errorf("Expected the held dependency to stay put, got:\n%s", got)
+	}
+}
+
+func TestIndexAge(t *testing.T) {
+	client := &Client{}
+	if age, stale := client.IndexAge(); age != 0 || stale {
+		t.Errorf("Expected no age before loading, got %v and %v", age, stale)
+	}
+
+	client.indexState = indexState{fetchedAt: time.Now().Add(-time.Minute)}
+	client.caskIndexState = indexState{fetchedAt: time.Now().Add(-3 * time.Hour), stale: true}
+	age, stale := client.IndexAge()
+	if age < 3*time.Hour || !stale {
+		t.Errorf("Expected the stale cask index to set the age, got %v and %v", age, stale)
+	}
+}
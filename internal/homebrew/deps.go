@@ -0,0 +1,335 @@
+package homebrew
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ofkm/goobrew/internal/logger"
+)
+
+// DepKind classifies how one formula depends on another.
+type DepKind string
+
+// Dependency kinds, matching the dependency lists of the formula API.
+const (
+	DepRuntime     DepKind = "runtime"     // DepRuntime is a regular dependency
+	DepRecommended DepKind = "recommended" // DepRecommended is installed unless explicitly disabled
+	DepBuild       DepKind = "build"       // DepBuild is only needed to build from source
+	DepTest        DepKind = "test"        // DepTest is only needed to run the formula's tests
+)
+
+// DepOptions selects which dependency kinds ResolveDependencies follows.
+// Runtime and recommended dependencies are always followed.
+type DepOptions struct {
+	IncludeBuild bool // IncludeBuild follows build dependencies
+	IncludeTest  bool // IncludeTest follows test dependencies
+}
+
+// DepEdge is a dependency of a formula in a DependencyGraph.
+type DepEdge struct {
+	Name string  `json:"name"` // Name is the dependency's formula name
+	Kind DepKind `json:"kind"` // Kind is how the formula depends on it
+}
+
+// DepNode is a formula in a DependencyGraph.
+type DepNode struct {
+	Name             string    `json:"name"`                        // Name is the formula name
+	Version          string    `json:"version,omitempty"`           // Version is the latest stable version
+	Installed        bool      `json:"installed"`                   // Installed is true if any version is installed
+	InstalledVersion string    `json:"installed_version,omitempty"` // InstalledVersion is the newest installed version
+	Deps             []DepEdge `json:"dependencies,omitempty"`      // Deps are the formula's followed dependencies
+	Unknown          bool      `json:"unknown,omitempty"`           // Unknown is true if the API has no such formula
+}
+
+// DependencyGraph is the transitive dependency graph of one or more formulae.
+type DependencyGraph struct {
+	Roots  []string            `json:"roots"`            // Roots are the formulae the graph was resolved for
+	Nodes  map[string]*DepNode `json:"nodes"`            // Nodes holds every formula in the graph by name
+	Cycles [][]string          `json:"cycles,omitempty"` // Cycles lists dependency cycles, each closing on its first name
+}
+
+// ResolveDependencies resolves the full transitive dependency graph of the
+// named formulae using the JSON API, following runtime and recommended
+// dependencies plus build and test dependencies if opts asks for them.
+// Each level of the graph is fetched in parallel. Installed state is taken
+// from InstalledFormulae when they can be listed. Dependency cycles are
+// detected and reported in Cycles rather than followed forever. Returns an
+// error wrapping ErrNotFound if a root formula does not exist.
+func (c *Client) ResolveDependencies(ctx context.Context, names []string, opts DepOptions) (*DependencyGraph, error) {
+	graph := &DependencyGraph{Roots: names, Nodes: make(map[string]*DepNode)}
+
+	frontier := append([]string{}, names...)
+	for len(frontier) > 0 {
+		var todo []string
+		for _, name := range frontier {
+			if _, seen := graph.Nodes[name]; !seen {
+				graph.Nodes[name] = &DepNode{Name: name}
+				todo = append(todo, name)
+			}
+		}
+
+		formulae, errs := c.fetchFormulae(ctx, todo)
+		frontier = nil
+		for i, name := range todo {
+			node := graph.Nodes[name]
+			if errs[i] != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				if !errors.Is(errs[i], ErrNotFound) {
+					return nil, fmt.Errorf("failed to resolve %s: %w", name, errs[i])
+				}
+				node.Unknown = true
+				continue
+			}
+
+			f := formulae[i]
+			node.Version = f.Versions.Stable
+			node.Deps = dependencyEdges(f, opts)
+			for _, edge := range node.Deps {
+				frontier = append(frontier, edge.Name)
+			}
+		}
+	}
+
+	for _, name := range names {
+		if graph.Nodes[name].Unknown {
+			return nil, fmt.Errorf("formula %s %w", name, ErrNotFound)
+		}
+	}
+
+	if installed, err := c.InstalledFormulae(ctx); err == nil {
+		for _, f := range installed {
+			if node, ok := graph.Nodes[f.Name]; ok && len(f.Installed) > 0 {
+				node.Installed = true
+				node.InstalledVersion = f.Installed[len(f.Installed)-1].Version
+			}
+		}
+	} else {
+		logger.Log.Debug("could not determine installed formulae", "error", err)
+	}
+
+	graph.Cycles = graph.findCycles()
+	return graph, nil
+}
+
+// fetchFormulae fetches the named formulae from the API in parallel. The
+// results and errors are indexed like names.
+func (c *Client) fetchFormulae(ctx context.Context, names []string) ([]*Formula, []error) {
+	var wg sync.WaitGroup
+	formulae := make([]*Formula, len(names))
+	errs := make([]error, len(names))
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			formulae[i], errs[i] = c.fetchFormula(ctx, fmt.Sprintf("formula/%s.json", name))
+		}()
+	}
+	wg.Wait()
+	return formulae, errs
+}
+
+// dependencyEdges lists the dependencies of f selected by opts. A name that
+// appears in several lists is reported once, with the strongest kind.
+func dependencyEdges(f *Formula, opts DepOptions) []DepEdge {
+	var edges []DepEdge
+	seen := make(map[string]bool)
+	add := func(kind DepKind, names []string) {
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				edges = append(edges, DepEdge{Name: name, Kind: kind})
+			}
+		}
+	}
+
+	add(DepRuntime, f.Dependencies)
+	add(DepRecommended, f.RecommendedDeps)
+	if opts.IncludeBuild {
+		add(DepBuild, f.BuildDependencies)
+	}
+	if opts.IncludeTest {
+		add(DepTest, f.TestDependencies)
+	}
+	return edges
+}
+
+// findCycles returns every dependency cycle found by a depth-first walk
+// from the roots. Each cycle starts and ends with the same name.
+func (g *DependencyGraph) findCycles() [][]string {
+	const (
+		unvisited = iota
+		active
+		finished
+	)
+
+	var (
+		cycles [][]string
+		stack  []string
+		state  = make(map[string]int)
+		visit  func(string)
+	)
+	visit = func(name string) {
+		state[name] = active
+		stack = append(stack, name)
+		if node := g.Nodes[name]; node != nil {
+			for _, edge := range node.Deps {
+				switch state[edge.Name] {
+				case unvisited:
+					visit(edge.Name)
+				case active:
+					start := len(stack) - 1
+					for stack[start] != edge.Name {
+						start--
+					}
+					cycle := append(append([]string{}, stack[start:]...), edge.Name)
+					cycles = append(cycles, cycle)
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = finished
+	}
+
+	for _, root := range g.Roots {
+		if state[root] == unvisited {
+			visit(root)
+		}
+	}
+	return cycles
+}
+
+// Closure returns every formula the roots depend on, directly or
+// indirectly, sorted by name. The roots themselves are not included
+// unless one depends on another.
+func (g *DependencyGraph) Closure() []*DepNode {
+	seen := make(map[string]bool)
+	var visit func(string)
+	visit = func(name string) {
+		for _, edge := range g.Nodes[name].Deps {
+			if !seen[edge.Name] {
+				seen[edge.Name] = true
+				visit(edge.Name)
+			}
+		}
+	}
+	for _, root := range g.Roots {
+		visit(root)
+	}
+
+	nodes := make([]*DepNode, 0, len(seen))
+	for name := range seen {
+		nodes = append(nodes, g.Nodes[name])
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	return nodes
+}
+
+// Dependents returns, for every formula in the graph, the formulae in the
+// graph that depend on it directly, each list sorted by name.
+func (g *DependencyGraph) Dependents() map[string][]DepEdge {
+	dependents := make(map[string][]DepEdge)
+	for _, node := range g.Nodes {
+		for _, edge := range node.Deps {
+			dependents[edge.Name] = append(dependents[edge.Name], DepEdge{Name: node.Name, Kind: edge.Kind})
+		}
+	}
+	for _, edges := range dependents {
+		sort.Slice(edges, func(i, j int) bool { return edges[i].Name < edges[j].Name })
+	}
+	return dependents
+}
+
+// sortedNames returns the names of every node, sorted.
+func (g *DependencyGraph) sortedNames() []string {
+	names := make([]string, 0, len(g.Nodes))
+	for name := range g.Nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WriteDOT writes the graph in Graphviz DOT format. Roots are drawn bold,
+// missing formulae dashed, and build and test dependencies with dashed and
+// dotted edges.
+func (g *DependencyGraph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph dependencies {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+
+	roots := make(map[string]bool, len(g.Roots))
+	for _, r := range g.Roots {
+		roots[r] = true
+	}
+
+	names := g.sortedNames()
+	for _, name := range names {
+		var attrs []string
+		if roots[name] {
+			attrs = append(attrs, "style=bold")
+		} else if !g.Nodes[name].Installed {
+			attrs = append(attrs, "style=dashed")
+		}
+		fmt.Fprintf(&b, "  %q", name)
+		if len(attrs) > 0 {
+			fmt.Fprintf(&b, " [%s]", strings.Join(attrs, ","))
+		}
+		b.WriteString(";\n")
+	}
+
+	for _, name := range names {
+		for _, edge := range g.Nodes[name].Deps {
+			fmt.Fprintf(&b, "  %q -> %q", name, edge.Name)
+			switch edge.Kind {
+			case DepBuild:
+				b.WriteString(` [style=dashed,label="build"]`)
+			case DepTest:
+				b.WriteString(` [style=dotted,label="test"]`)
+			case DepRecommended:
+				b.WriteString(` [label="recommended"]`)
+			}
+			b.WriteString(";\n")
+		}
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteMermaid writes the graph as a Mermaid flowchart. Build and test
+// dependencies are drawn with dotted, labelled edges.
+func (g *DependencyGraph) WriteMermaid(w io.Writer) error {
+	names := g.sortedNames()
+	ids := make(map[string]string, len(names))
+	for i, name := range names {
+		ids[name] = fmt.Sprintf("n%d", i)
+	}
+
+	var b strings.Builder
+	b.WriteString("graph LR\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", ids[name], name)
+	}
+	for _, name := range names {
+		for _, edge := range g.Nodes[name].Deps {
+			switch edge.Kind {
+			case DepBuild, DepTest:
+				fmt.Fprintf(&b, "  %s -.->|%s| %s\n", ids[name], edge.Kind, ids[edge.Name])
+			default:
+				fmt.Fprintf(&b, "  %s --> %s\n", ids[name], ids[edge.Name])
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,148 @@
+package homebrew
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newDepsClient returns a client whose API serves the given formulae and
+// whose brew reports installed as installed.
+func newDepsClient(t *testing.T, formulae map[string]Formula, installed string) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/formula/"), ".json")
+		f, ok := formulae[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		f.Name = name
+		_ = json.NewEncoder(w).Encode(f)
+	}))
+	t.Cleanup(server.Close)
+
+	brew := writeFakeBrew(t, "echo '"+installed+"'")
+	client := &Client{httpClient: &http.Client{Timeout: 5 * time.Second}, brewPath: brew}
+	WithAPIEndpoints(server.URL)(client)
+	return client
+}
+
+func TestResolveDependencies(t *testing.T) {
+	formulae := map[string]Formula{
+		"wget": {
+			Versions:          Versions{Stable: "1.24.5"},
+			Dependencies:      []string{"libidn2", "openssl@3"},
+			BuildDependencies: []string{"pkgconf"},
+		},
+		"libidn2":         {Dependencies: []string{"libunistring"}},
+		"libunistring":    {},
+		"openssl@3":       {Dependencies: []string{"ca-certificates"}},
+		"ca-certificates": {},
+		"pkgconf":         {},
+	}
+	client := newDepsClient(t, formulae, `[{"name":"openssl@3","installed":[{"version":"3.4.0"}]}]`)
+
+	graph, err := client.ResolveDependencies(context.Background(), []string{"wget"}, DepOptions{})
+	if err != nil {
+		t.Fatalf("ResolveDependencies failed: %v", err)
+	}
+
+	var names []string
+	for _, n := range graph.Closure() {
+		names = append(names, n.Name)
+	}
+	want := []string{"ca-certificates", "libidn2", "libunistring", "openssl@3"}
+	if !slices.Equal(names, want) {
+		t.Errorf("Expected closure %v, got %v", want, names)
+	}
+	if graph.Nodes["wget"].Version != "1.24.5" {
+		t.Errorf("Expected wget version 1.24.5, got %q", graph.Nodes["wget"].Version)
+	}
+	if n := graph.Nodes["openssl@3"]; !n.Installed || n.InstalledVersion != "3.4.0" {
+		t.Errorf("Expected openssl@3 3.4.0 to be installed, got %+v", n)
+	}
+	if graph.Nodes["libidn2"].Installed {
+		t.Error("libidn2 should not be installed")
+	}
+
+	graph, err = client.ResolveDependencies(context.Background(), []string{"wget"}, DepOptions{IncludeBuild: true})
+	if err != nil {
+		t.Fatalf("ResolveDependencies failed: %v", err)
+	}
+	if !slices.Contains(graph.Nodes["wget"].Deps, DepEdge{Name: "pkgconf", Kind: DepBuild}) {
+		t.Errorf("Expected a build edge to pkgconf, got %+v", graph.Nodes["wget"].Deps)
+	}
+}
+
+func TestResolveDependencies_Cycle(t *testing.T) {
+	formulae := map[string]Formula{
+		"a": {Dependencies: []string{"b"}},
+		"b": {Dependencies: []string{"c"}},
+		"c": {Dependencies: []string{"a"}},
+	}
+	client := newDepsClient(t, formulae, `[]`)
+
+	graph, err := client.ResolveDependencies(context.Background(), []string{"a"}, DepOptions{})
+	if err != nil {
+		t.Fatalf("ResolveDependencies failed: %v", err)
+	}
+	if len(graph.Cycles) != 1 || !slices.Equal(graph.Cycles[0], []string{"a", "b", "c", "a"}) {
+		t.Errorf("Expected cycle a → b → c → a, got %v", graph.Cycles)
+	}
+}
+
+func TestResolveDependencies_NotFound(t *testing.T) {
+	client := newDepsClient(t, map[string]Formula{"jq": {Dependencies: []string{"oniguruma"}}}, `[]`)
+
+	_, err := client.ResolveDependencies(context.Background(), []string{"nope"}, DepOptions{})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown root, got %v", err)
+	}
+
+	graph, err := client.ResolveDependencies(context.Background(), []string{"jq"}, DepOptions{})
+	if err != nil {
+		t.Fatalf("An unknown dependency should not fail resolution: %v", err)
+	}
+	if !graph.Nodes["oniguruma"].Unknown {
+		t.Error("Expected oniguruma to be marked unknown")
+	}
+}
+
+func TestDependencyGraph_Export(t *testing.T) {
+	graph := &DependencyGraph{
+		Roots: []string{"wget"},
+		Nodes: map[string]*DepNode{
+			"wget":    {Name: "wget", Deps: []DepEdge{{Name: "libidn2", Kind: DepRuntime}, {Name: "pkgconf", Kind: DepBuild}}},
+			"libidn2": {Name: "libidn2", Installed: true},
+			"pkgconf": {Name: "pkgconf"},
+		},
+	}
+
+	var dot strings.Builder
+	if err := graph.WriteDOT(&dot); err != nil {
+		t.Fatalf("WriteDOT failed: %v", err)
+	}
+	for _, want := range []string{"digraph dependencies {", `"wget" [style=bold];`, `"pkgconf" [style=dashed];`, `"wget" -> "libidn2";`, `"wget" -> "pkgconf" [style=dashed,label="build"];`} {
+		if !strings.Contains(dot.String(), want) {
+			t.Errorf("Expected DOT output to contain %q:\n%s", want, dot.String())
+		}
+	}
+
+	var mermaid strings.Builder
+	if err := graph.WriteMermaid(&mermaid); err != nil {
+		t.Fatalf("WriteMermaid failed: %v", err)
+	}
+	for _, want := range []string{"graph LR", `n2["wget"]`, "n2 --> n0", "n2 -.->|build| n1"} {
+		if !strings.Contains(mermaid.String(), want) {
+			t.Errorf("Expected Mermaid output to contain %q:\n%s", want, mermaid.String())
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package homebrew
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// indexCache persists the formula and cask indexes on disk so they survive
+// across runs and can be revalidated with conditional requests.
+type indexCache struct {
+	dir string
+}
+
+// indexMeta records the HTTP validators and fetch time for a cached index.
+// Sum is the SHA-256 of the body the validators belong to, so a meta file
+// written by another process for a different body is never trusted, and
+// Base is the API base URL the body was downloaded from.
+type indexMeta struct {
+	Base         string    `json:"base,omitempty"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	FetchedAt    time.Time `json:"fetched_at"`
+	Sum          string    `json:"sha256"`
+}
+
+// DefaultCacheDir returns the directory goobrew uses for on-disk caches.
+// It honours HOMEBREW_CACHE when set and otherwise falls back to the
+// platform user cache directory (XDG_CACHE_HOME on Linux).
+func DefaultCacheDir() (string, error) {
+	if dir := os.Getenv("HOMEBREW_CACHE"); dir != "" {
+		return filepath.Join(dir, "goobrew"), nil
+	}
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "goobrew"), nil
+}
+
+// newIndexCache creates an index cache rooted at dir/api.
+func newIndexCache(dir string) *indexCache {
+	return &indexCache{dir: filepath.Join(dir, "api")}
+}
+
+// load returns the cached body and its metadata for the named index.
+// If the metadata does not describe the body on disk, the validators are
+// dropped and the file modification time is used as the fetch time.
+func (ic *indexCache) load(name string) ([]byte, indexMeta, error) {
+	var meta indexMeta
+
+	path := filepath.Join(ic.dir, name)
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, meta, err
+	}
+
+	data, err := os.ReadFile(path + ".meta")
+	if err == nil && json.Unmarshal(data, &meta) == nil && meta.Sum == checksum(body) {
+		return body, meta, nil
+	}
+
+	meta = indexMeta{}
+	if info, err := os.Stat(path); err == nil {
+		meta.FetchedAt = info.ModTime()
+	}
+	return body, meta, nil
+}
+
+// store atomically writes body and its metadata for the named index.
+func (ic *indexCache) store(name string, body []byte, meta indexMeta) error {
+	if err := os.MkdirAll(ic.dir, 0o755); err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(filepath.Join(ic.dir, name), body); err != nil {
+		return err
+	}
+
+	meta.Sum = checksum(body)
+	return ic.storeMeta(name, meta)
+}
+
+// storeMeta atomically writes the metadata for the named index.
+func (ic *indexCache) storeMeta(name string, meta indexMeta) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(ic.dir, name+".meta"), data)
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory and
+// renames it over path, so concurrent readers never observe a partial file.
+func writeFileAtomic(path string, data []byte) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
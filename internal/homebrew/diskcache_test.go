@@ -0,0 +1,219 @@
+package homebrew
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIndexCacheStoreAndLoad(t *testing.T) {
+	cache := newIndexCache(t.TempDir())
+
+	body := []byte(`[{"name":"git","desc":"Distributed version control"}]`)
+	meta := indexMeta{ETag: `"abc"`, FetchedAt: time.Now()}
+	if err := cache.store("formula.json", body, meta); err != nil {
+		t.Fatalf("store failed: %v", err)
+	}
+
+	got, gotMeta, err := cache.load("formula.json")
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if string(got) != string(body) {
+		t.Errorf("Expected body %s, got %s", body, got)
+	}
+	if gotMeta.ETag != `"abc"` {
+		t.Errorf("Expected ETag %q, got %q", `"abc"`, gotMeta.ETag)
+	}
+
+	entries, _ := os.ReadDir(cache.dir)
+	if len(entries) != 2 {
+		t.Errorf("Expected only the index and meta files, got %d entries", len(entries))
+	}
+}
+
+func TestIndexCacheLoad_MismatchedMeta(t *testing.T) {
+	cache := newIndexCache(t.TempDir())
+
+	if err := cache.store("formula.json", []byte(`[]`), indexMeta{ETag: `"old"`}); err != nil {
+		t.Fatalf("store failed: %v", err)
+	}
+	// Simulate another process replacing the body but not yet the meta file
+	if err := writeFileAtomic(filepath.Join(cache.dir, "formula.json"), []byte(`[{}]`)); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	_, meta, err := cache.load("formula.json")
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if meta.ETag != "" {
+		t.Errorf("Expected validators to be dropped, got ETag %q", meta.ETag)
+	}
+	if meta.FetchedAt.IsZero() {
+		t.Error("Expected FetchedAt to fall back to the file modification time")
+	}
+}
+
+func TestFetchIndex_Revalidation(t *testing.T) {
+	var full, notModified atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		_ = json.NewEncoder(w).Encode([]FormulaListItem{{Name: "git"}})
+	}))
+	defer server.Close()
+
+	client := &Client{
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		indexCache: newIndexCache(t.TempDir()),
+		apiBases:   []string{server.URL},
+	}
+	ctx := context.Background()
+
+	var formulae []FormulaListItem
+	if _, err := client.fetchIndex(ctx, "formula.json", &formulae); err != nil {
+		t.Fatalf("first fetch failed: %v", err)
+	}
+	if len(formulae) != 1 || full.Load() != 1 {
+		t.Fatalf("Expected one full download, got %d (items %d)", full.Load(), len(formulae))
+	}
+
+	// A fresh snapshot is used without contacting the server
+	formulae = nil
+	if _, err := client.fetchIndex(ctx, "formula.json", &formulae); err != nil {
+		t.Fatalf("cached fetch failed: %v", err)
+	}
+	if len(formulae) != 1 || full.Load() != 1 || notModified.Load() != 0 {
+		t.Errorf("Expected fresh snapshot to skip the network")
+	}
+
+	// An expired snapshot is revalidated and the 304 honoured
+	_, meta, _ := client.indexCache.load("formula.json")
+	meta.FetchedAt = time.Now().Add(-2 * cacheExpiry)
+	if err := client.indexCache.storeMeta("formula.json", meta); err != nil {
+		t.Fatalf("storeMeta failed: %v", err)
+	}
+
+	formulae = nil
+	state, err := client.fetchIndex(ctx, "formula.json", &formulae)
+	if err != nil {
+		t.Fatalf("revalidation failed: %v", err)
+	}
+	if notModified.Load() != 1 || full.Load() != 1 {
+		t.Errorf("Expected a single 304 revalidation, got %d full and %d not-modified", full.Load(), notModified.Load())
+	}
+	if len(formulae) != 1 || state.stale || time.Since(state.fetchedAt) > time.Minute {
+		t.Errorf("Expected revalidated snapshot to be current, got %+v", state)
+	}
+}
+
+func TestFetchIndex_OfflineFallback(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close() // Unreachable
+
+	cache := newIndexCache(t.TempDir())
+	fetchedAt := time.Now().Add(-3 * cacheExpiry)
+	body, _ := json.Marshal([]CaskListItem{{Token: "firefox"}})
+	if err := cache.store("cask.json", body, indexMeta{Base: server.URL, FetchedAt: fetchedAt}); err != nil {
+		t.Fatalf("store failed: %v", err)
+	}
+
+	client := &Client{
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		indexCache: cache,
+		apiBases:   []string{server.URL},
+	}
+
+	var casks []CaskListItem
+	state, err := client.fetchIndex(context.Background(), "cask.json", &casks)
+	if err != nil {
+		t.Fatalf("Expected offline fallback, got error: %v", err)
+	}
+	if !state.stale {
+		t.Error("Expected snapshot to be marked stale")
+	}
+	if !state.fetchedAt.Equal(fetchedAt) {
+		t.Errorf("Expected fetchedAt %v, got %v", fetchedAt, state.fetchedAt)
+	}
+	if len(casks) != 1 || casks[0].Token != "firefox" {
+		t.Errorf("Expected cached casks, got %+v", casks)
+	}
+}
+
+func TestFetchIndex_OtherAPI(t *testing.T) {
+	var conditional atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" || r.Header.Get("If-Modified-Since") != "" {
+			conditional.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_ = json.NewEncoder(w).Encode([]FormulaListItem{{Name: "git"}, {Name: "wget"}})
+	}))
+	defer server.Close()
+
+	cache := newIndexCache(t.TempDir())
+	body, _ := json.Marshal([]FormulaListItem{{Name: "old"}})
+	meta := indexMeta{Base: "https://old.example.com/api", ETag: `"v1"`, FetchedAt: time.Now()}
+	if err := cache.store("formula.json", body, meta); err != nil {
+		t.Fatalf("store failed: %v", err)
+	}
+
+	client := &Client{
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		indexCache: cache,
+		apiBases:   []string{server.URL},
+	}
+	var formulae []FormulaListItem
+	if _, err := client.fetchIndex(context.Background(), "formula.json", &formulae); err != nil {
+		t.Fatalf("fetchIndex failed: %v", err)
+	}
+	if len(formulae) != 2 || conditional.Load() != 0 {
+		t.Errorf("Expected a full download from the new API, got %+v after %d conditional requests", formulae, conditional.Load())
+	}
+	if _, meta, _ := cache.load("formula.json"); meta.Base != server.URL {
+		t.Errorf("Expected the snapshot to be recorded for %s, got %q", server.URL, meta.Base)
+	}
+}
+
+func TestFetchIndex_NoSnapshot(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := &Client{
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		indexCache: newIndexCache(t.TempDir()),
+		apiBases:   []string{server.URL},
+	}
+
+	var formulae []FormulaListItem
+	if _, err := client.fetchIndex(context.Background(), "formula.json", &formulae); err == nil {
+		t.Error("Expected error when API fails and no snapshot exists")
+	}
+}
+
+func TestDefaultCacheDir_HomebrewCache(t *testing.T) {
+	t.Setenv("HOMEBREW_CACHE", "/tmp/brew-cache")
+
+	dir, err := DefaultCacheDir()
+	if err != nil {
+		t.Fatalf("DefaultCacheDir failed: %v", err)
+	}
+	if dir != filepath.Join("/tmp/brew-cache", "goobrew") {
+		t.Errorf("Expected dir under HOMEBREW_CACHE, got %s", dir)
+	}
+}
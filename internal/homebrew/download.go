@@ -0,0 +1,415 @@
+package homebrew
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ofkm/goobrew/internal/logger"
+	"github.com/ofkm/goobrew/internal/pkgversion"
+)
+
+// ErrNoBottle is returned when a formula has no bottle for the platform.
+var ErrNoBottle = errors.New("no bottle available")
+
+// ChecksumError is returned when a download does not match its expected
+// SHA-256 checksum.
+type ChecksumError struct {
+	Expected string // Expected is the checksum from the formula
+	Actual   string // Actual is the checksum of the downloaded file
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("checksum mismatch: expected %s, got %s", e.Expected, e.Actual)
+}
+
+// BottleDownload is a bottle in the download cache.
+type BottleDownload struct {
+	Formula string `json:"formula"` // Formula is the formula name
+	Version string `json:"version"` // Version is the formula version, with its revision
+	Tag     string `json:"tag"`     // Tag is the bottle tag, such as "arm64_sonoma" or "all"
+	SHA256  string `json:"sha256"`  // SHA256 is the verified checksum of the bottle
+	Path    string `json:"path"`    // Path is the bottle tarball in the download cache
+	Size    int64  `json:"size"`    // Size is the size of the bottle in bytes
+	Cached  bool   `json:"cached"`  // Cached is true if the bottle was already downloaded
+}
+
+// DownloadOption configures a single DownloadBottle call.
+type DownloadOption func(*downloadConfig)
+
+// downloadConfig holds the settings applied by DownloadOptions.
+type downloadConfig struct {
+	tag      string
+	progress func(done, total int64)
+}
+
+// WithBottleTag downloads the bottle for tag instead of the one for the
+// running system.
+func WithBottleTag(tag string) DownloadOption {
+	return func(cfg *downloadConfig) {
+		cfg.tag = tag
+	}
+}
+
+// WithDownloadProgress calls fn as the bottle is written, with the bytes
+// downloaded so far, including a resumed part, and the total size, or -1
+// if the server did not send it.
+func WithDownloadProgress(fn func(done, total int64)) DownloadOption {
+	return func(cfg *downloadConfig) {
+		cfg.progress = fn
+	}
+}
+
+// DownloadBottle fetches the bottle of f for the running system, or the
+// platform-independent "all" bottle, into the download cache, where it is
+// stored under its SHA-256 checksum. A bottle that is already cached is
+// verified and reused. Registries that answer with a bearer challenge, as
+// ghcr.io does, are sent an anonymous token, and an interrupted transfer is
+// resumed with a range request on the next call. It returns an error
+// wrapping ErrNoBottle if there is no bottle to download, and a
+// *ChecksumError if the download does not match the formula.
+func (c *Client) DownloadBottle(ctx context.Context, f *Formula, opts ...DownloadOption) (*BottleDownload, error) {
+	var cfg downloadConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if c.downloadDir == "" {
+		return nil, errors.New("download cache is not available")
+	}
+
+	tag := cfg.tag
+	if tag == "" {
+		var err error
+		if tag, err = BottleTag(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	file, found, ok := f.Bottle.File(tag)
+	if !ok {
+		return nil, fmt.Errorf("%s for %s: %w", f.Name, tag, ErrNoBottle)
+	}
+	sum := strings.ToLower(file.Sha256)
+	if len(sum) != sha256.Size*2 {
+		return nil, fmt.Errorf("%s has an invalid bottle checksum %q", f.Name, file.Sha256)
+	}
+	url := file.URL
+	if url == "" {
+		url = bottleURL(f.Bottle.RootURL, f.Name, sum)
+	}
+
+	download := &BottleDownload{
+		Formula: f.Name,
+		Version: pkgversion.NewPkgVersion(f.Versions.Stable, f.Revision, f.VersionScheme).String(),
+		Tag:     found,
+		SHA256:  sum,
+		Path:    filepath.Join(c.downloadDir, "sha256", sum),
+	}
+
+	if info, err := os.Stat(download.Path); err == nil {
+		if actual, err := fileChecksum(download.Path); err == nil && actual == sum {
+			logger.Log.Debug("using cached bottle", "formula", f.Name, "path", download.Path)
+			download.Size, download.Cached = info.Size(), true
+			return download, nil
+		}
+		logger.Log.Warn("removing corrupt cached bottle", "formula", f.Name, "path", download.Path)
+		_ = os.Remove(download.Path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(download.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create download cache: %w", err)
+	}
+
+	logger.Log.Info("downloading bottle", "formula", f.Name, "tag", found, "url", url)
+	size, err := c.downloadFile(ctx, url, download.Path, sum, cfg.progress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", f.Name, err)
+	}
+	download.Size = size
+	return download, nil
+}
+
+// bottleURL builds the registry URL of a bottle blob from the formula's
+// bottle root URL, the way Homebrew names its GitHub Packages images:
+// "@" becomes "/" and "+" becomes "x".
+func bottleURL(rootURL, name, sum string) string {
+	image := strings.NewReplacer("@", "/", "+", "x").Replace(name)
+	return fmt.Sprintf("%s/%s/blobs/sha256:%s", strings.TrimRight(rootURL, "/"), image, sum)
+}
+
+// downloadFile downloads url to path, verifying that its content has the
+// SHA-256 checksum sum, and returns its size. The transfer is written to
+// path + ".incomplete" and resumed from there if it exists. A resumed file
+// that fails verification is downloaded once more from the start.
+func (c *Client) downloadFile(ctx context.Context, url, path, sum string, progress func(done, total int64)) (int64, error) {
+	partial := path + ".incomplete"
+	for attempt := 0; ; attempt++ {
+		size, actual, resumed, err := c.downloadPartial(ctx, url, partial, progress)
+		if err != nil {
+			return 0, err
+		}
+		if actual == sum {
+			return size, os.Rename(partial, path)
+		}
+
+		_ = os.Remove(partial)
+		if !resumed || attempt > 0 {
+			return 0, &ChecksumError{Expected: sum, Actual: actual}
+		}
+		logger.Log.Warn("resumed download failed verification, starting over", "url", url)
+	}
+}
+
+// downloadPartial appends the rest of url to the partial file, restarting
+// it if the server ignores the range request, and returns the size and
+// checksum of the file and whether an earlier transfer was resumed. On a
+// transfer error the partial file is kept for the next attempt.
+func (c *Client) downloadPartial(ctx context.Context, url, partial string, progress func(done, total int64)) (int64, string, bool, error) {
+	out, err := os.OpenFile(partial, os.O_RDWR|os.O_CREATE, 0o644) //nolint:gosec // bottles in the cache are world-readable like Homebrew's
+	if err != nil {
+		return 0, "", false, err
+	}
+	defer out.Close()
+
+	hash := sha256.New()
+	offset, err := io.Copy(hash, out)
+	if err != nil {
+		return 0, "", false, err
+	}
+
+	resp, err := c.registryGet(ctx, url, offset)
+	if err != nil {
+		return 0, "", false, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0 && contentRangeStart(resp) == offset:
+		logger.Log.Debug("resuming download", "url", url, "offset", offset)
+	case resp.StatusCode == http.StatusOK:
+		if offset > 0 {
+			logger.Log.Debug("server ignored range request, restarting download", "url", url)
+		}
+		if err := out.Truncate(0); err != nil {
+			return 0, "", false, err
+		}
+		if _, err := out.Seek(0, io.SeekStart); err != nil {
+			return 0, "", false, err
+		}
+		hash.Reset()
+		offset = 0
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// The partial file is already complete, or longer than the blob
+		return offset, hex.EncodeToString(hash.Sum(nil)), offset > 0, nil
+	default:
+		return 0, "", false, fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+	}
+	var w io.Writer = io.MultiWriter(out, hash)
+	if progress != nil {
+		w = &progressWriter{w: w, done: offset, total: total, fn: progress}
+	}
+
+	n, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return 0, "", false, err
+	}
+	return offset + n, hex.EncodeToString(hash.Sum(nil)), offset > 0, nil
+}
+
+// contentRangeStart returns the first byte position of a 206 response's
+// Content-Range header, or -1 if it cannot be parsed.
+func contentRangeStart(resp *http.Response) int64 {
+	value, ok := strings.CutPrefix(resp.Header.Get("Content-Range"), "bytes ")
+	if !ok {
+		return -1
+	}
+	start, _, _ := strings.Cut(value, "-")
+	n, err := strconv.ParseInt(start, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// registryGet requests url starting at offset. If the server answers with a
+// bearer challenge, an anonymous token is requested from the challenge's
+// realm, cached for its scope, and the request is sent again with it.
+func (c *Client) registryGet(ctx context.Context, url string, offset int64) (*http.Response, error) {
+	return c.registryRequest(ctx, http.MethodGet, url, offset)
+}
+
+// registryRequest sends a request like registryGet with any method.
+func (c *Client) registryRequest(ctx context.Context, method, url string, offset int64) (*http.Response, error) {
+	do := func(token string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, method, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		if offset > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		return c.downloadClient().Do(req)
+	}
+
+	scope := registryScope(url)
+	token, _ := c.registryTokens.Load(scope)
+	resp, err := do(stringValue(token))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	header := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+	params, ok := parseBearerChallenge(header)
+	if !ok {
+		return nil, fmt.Errorf("registry requires authentication: %q", header)
+	}
+
+	fresh, err := c.registryToken(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	c.registryTokens.Store(scope, fresh)
+	return do(fresh)
+}
+
+// registryScope returns the key under which the token for url is cached:
+// its scheme, host and repository path up to "/blobs/".
+func registryScope(url string) string {
+	if i := strings.Index(url, "/blobs/"); i >= 0 {
+		return url[:i]
+	}
+	return url
+}
+
+// stringValue returns v as a string, or "" if it is not one.
+func stringValue(v any) string {
+	s, _ := v.(string)
+	return s
+}
+
+// registryToken requests an anonymous bearer token from the realm of a
+// challenge.
+func (c *Client) registryToken(ctx context.Context, params map[string]string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, params["realm"], nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid token realm %q: %w", params["realm"], err)
+	}
+	query := req.URL.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	req.URL.RawQuery = query.Encode()
+
+	logger.Log.Debug("requesting registry token", "url", req.URL.String())
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to parse registry token: %w", err)
+	}
+	if body.Token == "" {
+		body.Token = body.AccessToken
+	}
+	if body.Token == "" {
+		return "", errors.New("registry returned an empty token")
+	}
+	return body.Token, nil
+}
+
+// parseBearerChallenge parses a WWW-Authenticate header such as
+// `Bearer realm="https://ghcr.io/token",service="ghcr.io",scope="..."`.
+func parseBearerChallenge(header string) (map[string]string, bool) {
+	scheme, rest, _ := strings.Cut(header, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return nil, false
+	}
+
+	params := make(map[string]string)
+	for rest = strings.TrimSpace(rest); rest != ""; {
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		if strings.HasPrefix(value, `"`) {
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				return nil, false
+			}
+			params[key], rest = value[1:end+1], value[end+2:]
+		} else {
+			params[key], rest, _ = strings.Cut(value, ",")
+		}
+		rest = strings.TrimLeft(rest, ", ")
+	}
+	return params, params["realm"] != ""
+}
+
+// downloadClient returns an HTTP client for large transfers: it shares the
+// API client's transport but has no overall timeout, which would cut off
+// slow downloads, and relies on the context instead.
+func (c *Client) downloadClient() *http.Client {
+	client := *c.httpClient
+	client.Timeout = 0
+	return &client
+}
+
+// fileChecksum returns the hex SHA-256 checksum of the file at path.
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path) //nolint:gosec // path is inside the download cache
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// progressWriter reports the bytes written through it.
+type progressWriter struct {
+	w     io.Writer
+	done  int64
+	total int64
+	fn    func(done, total int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.done += int64(n)
+	p.fn(p.done, p.total)
+	return n, err
+}
@@ -0,0 +1,190 @@
+package homebrew
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeRegistry stands in for ghcr.io: blobs require a bearer token issued
+// anonymously by /token, and support range requests. If truncate is set,
+// the first full download is cut off halfway.
+type fakeRegistry struct {
+	*httptest.Server
+	blob     []byte
+	truncate bool
+	tokens   atomic.Int32
+	blobs    atomic.Int32
+	ranges   []string
+}
+
+func newFakeRegistry(t *testing.T, blob []byte) *fakeRegistry {
+	t.Helper()
+	reg := &fakeRegistry{blob: blob}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("scope") != "repository:homebrew/core/wget:pull" {
+			http.Error(w, "bad scope", http.StatusBadRequest)
+			return
+		}
+		reg.tokens.Add(1)
+		_, _ = w.Write([]byte(`{"token":"anonymous"}`))
+	})
+	mux.HandleFunc("/v2/homebrew/core/wget/blobs/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer anonymous" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+reg.URL+`/token",service="ghcr.io",scope="repository:homebrew/core/wget:pull"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		reg.blobs.Add(1)
+		if rng := r.Header.Get("Range"); rng != "" {
+			reg.ranges = append(reg.ranges, rng)
+		}
+		if reg.truncate {
+			reg.truncate = false
+			w.Header().Set("Content-Length", strconv.Itoa(len(reg.blob)))
+			_, _ = w.Write(reg.blob[:len(reg.blob)/2])
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(reg.blob))
+	})
+	reg.Server = httptest.NewServer(mux)
+	t.Cleanup(reg.Close)
+	return reg
+}
+
+// bottleFormula returns a wget formula whose bottle for tag is served by
+// reg with the checksum of blob.
+func bottleFormula(reg *fakeRegistry, tag string, blob []byte) *Formula {
+	sum := sha256.Sum256(blob)
+	return &Formula{
+		Name:     "wget",
+		Versions: Versions{Stable: "1.24.5"},
+		Revision: 1,
+		Bottle: Bottle{
+			RootURL: reg.URL + "/v2/homebrew/core",
+			Files:   map[string]BottleFile{tag: {Sha256: hex.EncodeToString(sum[:])}},
+		},
+	}
+}
+
+func TestDownloadBottle(t *testing.T) {
+	blob := bytes.Repeat([]byte("bottle"), 10000)
+	reg := newFakeRegistry(t, blob)
+	client := &Client{httpClient: &http.Client{Timeout: 5 * time.Second}, downloadDir: t.TempDir()}
+	formula := bottleFormula(reg, "x86_64_linux", blob)
+
+	var done, total int64
+	download, err := client.DownloadBottle(context.Background(), formula,
+		WithBottleTag("x86_64_linux"),
+		WithDownloadProgress(func(d, t int64) { done, total = d, t }))
+	if err != nil {
+		t.Fatalf("DownloadBottle failed: %v", err)
+	}
+	if download.Cached || download.Tag != "x86_64_linux" || download.Version != "1.24.5_1" || download.Size != int64(len(blob)) {
+		t.Errorf("Unexpected download: %+v", download)
+	}
+	if want := filepath.Join(client.downloadDir, "sha256", download.SHA256); download.Path != want {
+		t.Errorf("Path = %s, want %s", download.Path, want)
+	}
+	if data, err := os.ReadFile(download.Path); err != nil || !bytes.Equal(data, blob) {
+		t.Errorf("Cached bottle does not match the blob: %v", err)
+	}
+	if done != int64(len(blob)) || total != int64(len(blob)) {
+		t.Errorf("Progress = %d/%d, want %d", done, total, len(blob))
+	}
+
+	download, err = client.DownloadBottle(context.Background(), formula, WithBottleTag("x86_64_linux"))
+	if err != nil || !download.Cached {
+		t.Fatalf("Expected the cached bottle to be reused, got %+v, %v", download, err)
+	}
+	if n := reg.blobs.Load(); n != 1 {
+		t.Errorf("Expected 1 blob request, got %d", n)
+	}
+	if n := reg.tokens.Load(); n != 1 {
+		t.Errorf("Expected 1 token request, got %d", n)
+	}
+}
+
+func TestDownloadBottleResume(t *testing.T) {
+	blob := bytes.Repeat([]byte("0123456789"), 10000)
+	reg := newFakeRegistry(t, blob)
+	reg.truncate = true
+	client := &Client{httpClient: &http.Client{Timeout: 5 * time.Second}, downloadDir: t.TempDir()}
+	formula := bottleFormula(reg, "all", blob)
+
+	if _, err := client.DownloadBottle(context.Background(), formula, WithBottleTag("arm64_sonoma")); err == nil {
+		t.Fatal("Expected the truncated transfer to fail")
+	}
+	partial := filepath.Join(client.downloadDir, "sha256", formula.Bottle.Files["all"].Sha256+".incomplete")
+	if info, err := os.Stat(partial); err != nil || info.Size() != int64(len(blob)/2) {
+		t.Fatalf("Expected half the bottle to be kept, got %v, %v", info, err)
+	}
+
+	download, err := client.DownloadBottle(context.Background(), formula, WithBottleTag("arm64_sonoma"))
+	if err != nil {
+		t.Fatalf("Resumed download failed: %v", err)
+	}
+	if download.Tag != "all" || download.Size != int64(len(blob)) {
+		t.Errorf("Unexpected download: %+v", download)
+	}
+	if want := "bytes=" + strconv.Itoa(len(blob)/2) + "-"; len(reg.ranges) != 1 || reg.ranges[0] != want {
+		t.Errorf("Range requests = %v, want [%s]", reg.ranges, want)
+	}
+	if _, err := os.Stat(partial); !os.IsNotExist(err) {
+		t.Errorf("Expected the partial file to be renamed, got %v", err)
+	}
+}
+
+func TestDownloadBottleChecksumMismatch(t *testing.T) {
+	blob := []byte("tampered")
+	reg := newFakeRegistry(t, blob)
+	client := &Client{httpClient: &http.Client{Timeout: 5 * time.Second}, downloadDir: t.TempDir()}
+	formula := bottleFormula(reg, "all", []byte("expected"))
+
+	_, err := client.DownloadBottle(context.Background(), formula, WithBottleTag("all"))
+	var ce *ChecksumError
+	if !errors.As(err, &ce) {
+		t.Fatalf("Expected a ChecksumError, got %v", err)
+	}
+	entries, _ := os.ReadDir(filepath.Join(client.downloadDir, "sha256"))
+	if len(entries) != 0 {
+		t.Errorf("Expected nothing to be cached, found %d files", len(entries))
+	}
+
+	formula.Bottle.Files = nil
+	if _, err := client.DownloadBottle(context.Background(), formula, WithBottleTag("all")); !errors.Is(err, ErrNoBottle) {
+		t.Errorf("Expected ErrNoBottle, got %v", err)
+	}
+}
+
+func TestParseBearerChallenge(t *testing.T) {
+	params, ok := parseBearerChallenge(`Bearer realm="https://ghcr.io/token",service="ghcr.io",scope="repository:homebrew/core/wget:pull"`)
+	if !ok || params["realm"] != "https://ghcr.io/token" || params["service"] != "ghcr.io" || params["scope"] != "repository:homebrew/core/wget:pull" {
+		t.Errorf("Unexpected params: %v, %v", params, ok)
+	}
+	if _, ok := parseBearerChallenge(`Basic realm="registry"`); ok {
+		t.Error("Expected Basic challenges to be rejected")
+	}
+}
+
+func TestBottleURL(t *testing.T) {
+	got := bottleURL("https://ghcr.io/v2/homebrew/core/", "openssl@3", "abc")
+	if want := "https://ghcr.io/v2/homebrew/core/openssl/3/blobs/sha256:abc"; got != want {
+		t.Errorf("bottleURL = %s, want %s", got, want)
+	}
+	if got := bottleURL("https://ghcr.io/v2/homebrew/core", "libxml++", "abc"); !strings.Contains(got, "/libxmlxx/") {
+		t.Errorf("bottleURL = %s, want + replaced with x", got)
+	}
+}
@@ -0,0 +1,388 @@
+package homebrew
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Dependency install stages reported in DependencyStatus.Stage.
+const (
+	depPending     = "pending"
+	depDownloading = "downloading"
+	depInstalling  = "installing"
+	depCompleted   = "completed"
+)
+
+var (
+	// ansiPattern matches the color escape sequences brew emits on a terminal.
+	ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+	// barPattern matches curl's --progress-bar output, e.g. "#####  45.2%".
+	barPattern = regexp.MustCompile(`^#*\s*(\d{1,3}(?:\.\d+)?)%$`)
+	// meterPattern matches a row of curl's default progress meter:
+	// "% Total", "Total", "% Received", "Received", ...
+	meterPattern = regexp.MustCompile(`^(\d{1,3})\s+(\d+(?:\.\d+)?[kMGT]?)\s+(\d{1,3})\s+(\d+(?:\.\d+)?[kMGT]?)\s`)
+	// depsPattern matches "Installing dependencies for X: a, b" and its Fetching counterpart.
+	depsPattern = regexp.MustCompile(`^(?:Installing|Fetching) dependencies for (\S+): (.+)$`)
+	// depInstallPattern matches "Installing X dependency: Y".
+	depInstallPattern = regexp.MustCompile(`^Installing \S+ dependency: (\S+)`)
+)
+
+// installLog turns the stdout and stderr of one `brew install` into
+// InstallationStatus updates. It recognises brew's "==>" phase headers,
+// curl's progress output, bottle pours, caveats, summaries and dependency
+// installs, and keeps the full transcript of the run.
+//
+// Progress is derived from what brew actually reports: half of it tracks
+// downloads and half tracks pours, each split evenly across the package and
+// the dependencies brew announced, and a running download contributes its
+// real percentage.
+type installLog struct {
+	mu          sync.Mutex
+	readers     sync.WaitGroup // readers tracks running consume calls
+	status      InstallationStatus
+	transcript  []string
+	deps        []DependencyStatus
+	current     string  // current is the package brew is working on
+	downloads   int     // downloads counts finished bottle downloads
+	download    float64 // download is the fraction of the running download received
+	downloading bool    // downloading is true while a download is in progress
+	pours       int     // pours counts finished pours (one Summary line each)
+	pouring     bool    // pouring is true between a Pouring header and its summary
+}
+
+// newInstallLog creates a parser for the installation of pkg.
+func newInstallLog(pkg string, startTime time.Time, dependency bool) *installLog {
+	return &installLog{
+		status: InstallationStatus{
+			Formula:    pkg,
+			Stage:      "starting",
+			StartTime:  startTime,
+			Dependency: dependency,
+		},
+		current: pkg,
+	}
+}
+
+// scanInstallLines is a bufio.SplitFunc that splits on "\n" and on the bare
+// "\r" curl uses to redraw its progress, dropping empty tokens.
+func scanInstallLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	for start := 0; start < len(data); {
+		i := bytes.IndexAny(data[start:], "\r\n")
+		if i < 0 {
+			if atEOF {
+				return len(data), data[start:], nil
+			}
+			return start, nil, nil
+		}
+		if i > 0 {
+			return start + i + 1, data[start : start+i], nil
+		}
+		start++
+	}
+	return len(data), nil, nil
+}
+
+// consume reads r line by line, sending a status to statusChan whenever a
+// line changes the package's stage, detail or progress.
+// The caller must have added it to l.readers.
+func (l *installLog) consume(r io.Reader, statusChan chan<- InstallationStatus) {
+	defer l.readers.Done()
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	scanner.Split(scanInstallLines)
+	for scanner.Scan() {
+		if status, ok := l.parseLine(scanner.Text()); ok {
+			statusChan <- status
+		}
+	}
+}
+
+// parseLine feeds one line of output to the parser. It returns the updated
+// status and true if the line changed anything worth reporting.
+func (l *installLog) parseLine(raw string) (InstallationStatus, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	line := strings.TrimSpace(ansiPattern.ReplaceAllString(raw, ""))
+	if line == "" {
+		return InstallationStatus{}, false
+	}
+
+	before := l.snapshot()
+	if l.parseProgress(line) {
+		// Progress redraws are not worth keeping in the transcript.
+		return l.changed(before)
+	}
+	l.transcript = append(l.transcript, line)
+
+	if header, ok := strings.CutPrefix(line, "==>"); ok {
+		l.parseHeader(strings.TrimSpace(header))
+		return l.changed(before)
+	}
+
+	switch {
+	case strings.HasPrefix(line, "Already downloaded:"):
+		l.finishDownload()
+	case strings.HasPrefix(line, "🍺"):
+		l.finishPour()
+	case strings.HasPrefix(line, "Error:"):
+		l.status.Detail = line
+	}
+
+	return l.changed(before)
+}
+
+// parseHeader handles the text of a "==>" phase header.
+func (l *installLog) parseHeader(header string) {
+	if m := depsPattern.FindStringSubmatch(header); m != nil {
+		for _, name := range strings.Split(m[2], ",") {
+			l.dependency(strings.TrimSpace(name))
+		}
+		if strings.HasPrefix(header, "Installing") {
+			l.status.Stage = "installing"
+		} else {
+			l.status.Stage = "downloading"
+		}
+		l.status.Detail = "dependencies: " + m[2]
+		return
+	}
+
+	if m := depInstallPattern.FindStringSubmatch(header); m != nil {
+		l.current = m[1]
+		l.dependency(m[1]).Stage = depInstalling
+		l.status.Stage = "installing"
+		l.status.Detail = m[1]
+		return
+	}
+
+	word, rest, _ := strings.Cut(header, " ")
+	switch word {
+	case "Fetching":
+		if rest != "" && !strings.HasPrefix(rest, "dependencies") {
+			l.current = strings.Fields(rest)[0]
+			if dep := l.findDependency(l.current); dep != nil {
+				dep.Stage = depDownloading
+			}
+		}
+		l.status.Stage = "downloading"
+		l.status.Detail = rest
+	case "Downloading":
+		if l.downloading {
+			l.finishDownload()
+		}
+		l.downloading = true
+		l.status.Stage = "downloading"
+		if l.status.Detail == "" {
+			l.status.Detail = l.current
+		}
+	case "Installing", "Upgrading":
+		l.current = l.status.Formula
+		l.status.Stage = "installing"
+		l.status.Detail = ""
+	case "Pouring":
+		if l.downloads <= l.pours {
+			l.downloads = min(l.pours+1, l.units())
+		}
+		l.download = 0
+		l.downloading = false
+		l.pouring = true
+		l.status.Stage = "installing"
+		l.status.Detail = "pouring " + rest
+	case "Linking", "Symlinking":
+		l.status.Stage = "linking"
+		l.status.Detail = ""
+	case "Caveats":
+		l.status.Detail = "caveats"
+	case "Summary":
+		l.status.Detail = "summary"
+	}
+}
+
+// parseProgress recognises a curl progress line, updating the running
+// download and byte counts. It reports whether the line was progress output.
+func (l *installLog) parseProgress(line string) bool {
+	if m := barPattern.FindStringSubmatch(line); m != nil {
+		pct, _ := strconv.ParseFloat(m[1], 64)
+		l.setDownload(pct)
+		return true
+	}
+
+	if m := meterPattern.FindStringSubmatch(line); m != nil {
+		pct, _ := strconv.ParseFloat(m[3], 64)
+		l.status.BytesTotal = parseCurlSize(m[2])
+		l.status.BytesDone = parseCurlSize(m[4])
+		l.setDownload(pct)
+		return true
+	}
+
+	return false
+}
+
+// setDownload records the percentage of the running download.
+func (l *installLog) setDownload(pct float64) {
+	if pct >= 100 {
+		if l.downloading {
+			l.finishDownload()
+		}
+		return
+	}
+	l.status.Stage = "downloading"
+	l.downloading = true
+	l.download = pct / 100
+}
+
+// finishDownload counts the running download as complete.
+func (l *installLog) finishDownload() {
+	l.downloading = false
+	l.download = 0
+	l.downloads = min(l.downloads+1, l.units())
+	if dep := l.findDependency(l.current); dep != nil && dep.Stage == depDownloading {
+		dep.Stage = depPending
+	}
+}
+
+// finishPour counts a pour as complete, marking the dependency brew was
+// installing, if any, as completed.
+func (l *installLog) finishPour() {
+	l.pouring = false
+	l.pours = min(l.pours+1, l.units())
+	if dep := l.findDependency(l.current); dep != nil {
+		dep.Stage = depCompleted
+	}
+}
+
+// dependency returns the sub-status for name, adding it if needed.
+func (l *installLog) dependency(name string) *DependencyStatus {
+	if dep := l.findDependency(name); dep != nil {
+		return dep
+	}
+	l.deps = append(l.deps, DependencyStatus{Name: name, Stage: depPending})
+	return &l.deps[len(l.deps)-1]
+}
+
+// findDependency returns the sub-status for name, or nil if brew has not
+// announced it as a dependency.
+func (l *installLog) findDependency(name string) *DependencyStatus {
+	for i := range l.deps {
+		if l.deps[i].Name == name {
+			return &l.deps[i]
+		}
+	}
+	return nil
+}
+
+// units is the number of bottles brew is expected to download and pour.
+func (l *installLog) units() int {
+	return len(l.deps) + 1
+}
+
+// progress computes the overall percentage from the counters. It stays
+// below 100 until the package has been reported as completed.
+func (l *installLog) progress() int {
+	units := float64(l.units())
+	pour := float64(l.pours)
+	if l.pouring {
+		pour += 0.5
+	}
+	pct := int((float64(l.downloads)+l.download)/units*50 + pour/units*50)
+	return min(max(pct, l.status.Progress), 99)
+}
+
+// snapshot returns a copy of the current status with fresh progress and
+// dependency sub-statuses.
+func (l *installLog) snapshot() InstallationStatus {
+	status := l.status
+	status.Progress = l.progress()
+	status.Dependencies = append([]DependencyStatus(nil), l.deps...)
+	return status
+}
+
+// changed stores the latest snapshot and reports it if it differs from
+// before in anything but the byte counts of an unchanged percentage.
+func (l *installLog) changed(before InstallationStatus) (InstallationStatus, bool) {
+	after := l.snapshot()
+	l.status.Progress = after.Progress
+
+	same := after.Stage == before.Stage &&
+		after.Detail == before.Detail &&
+		after.Progress == before.Progress &&
+		after.BytesDone == before.BytesDone &&
+		depsEqual(after.Dependencies, before.Dependencies)
+	return after, !same
+}
+
+// Wait blocks until every stream passed to consume has been read.
+func (l *installLog) Wait() {
+	l.readers.Wait()
+}
+
+// Transcript returns every non-progress line brew printed, in order.
+func (l *installLog) Transcript() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.transcript...)
+}
+
+// Finish returns the final status for the run: completed with 100%
+// progress, or failed with err, carrying the full transcript.
+func (l *installLog) Finish(err error) InstallationStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	status := l.snapshot()
+	status.EndTime = time.Now()
+	status.Transcript = append([]string(nil), l.transcript...)
+	if err != nil {
+		status.Stage = "failed"
+		status.Error = err
+		return status
+	}
+
+	status.Stage = "completed"
+	status.Progress = 100
+	status.Detail = ""
+	for i := range status.Dependencies {
+		status.Dependencies[i].Stage = depCompleted
+	}
+	return status
+}
+
+// depsEqual reports whether two dependency sub-status lists are identical.
+func depsEqual(a, b []DependencyStatus) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// parseCurlSize converts a size from curl's progress meter, such as "512",
+// "1234k" or "2.5M", into bytes.
+func parseCurlSize(s string) int64 {
+	mult := 1.0
+	switch s[len(s)-1] {
+	case 'k':
+		mult = 1 << 10
+	case 'M':
+		mult = 1 << 20
+	case 'G':
+		mult = 1 << 30
+	case 'T':
+		mult = 1 << 40
+	}
+	n, err := strconv.ParseFloat(strings.TrimRight(s, "kMGT"), 64)
+	if err != nil {
+		return 0
+	}
+	return int64(n * mult)
+}
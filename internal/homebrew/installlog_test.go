@@ -0,0 +1,187 @@
+package homebrew
+
+import (
+	"bufio"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScanInstallLines(t *testing.T) {
+	input := "==> Downloading x\n####   10.0%\r#########  50.0%\r\n\nPouring\n"
+
+	scanner := bufio.NewScanner(strings.NewReader(input))
+	scanner.Split(scanInstallLines)
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	want := []string{"==> Downloading x", "####   10.0%", "#########  50.0%", "Pouring"}
+	if !slices.Equal(lines, want) {
+		t.Errorf("Expected %q, got %q", want, lines)
+	}
+}
+
+func TestInstallLog_Stages(t *testing.T) {
+	tests := []struct {
+		name          string
+		line          string
+		expectedStage string
+	}{
+		{"Fetching", "==> Fetching git", "downloading"},
+		{"Downloading", "==> Downloading https://ghcr.io/v2/homebrew/core/git/blobs/sha256:abc", "downloading"},
+		{"Installing", "==> Installing git", "installing"},
+		{"Upgrading", "==> Upgrading git", "installing"},
+		{"Pouring", "==> Pouring git--2.51.1.arm64_sonoma.bottle.tar.gz", "installing"},
+		{"Linking", "==> Linking git", "linking"},
+		{"Colored header", "\x1b[34m==>\x1b[0m \x1b[1mPouring git--2.51.1.bottle.tar.gz\x1b[0m", "installing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := newInstallLog("git", time.Now(), false)
+			status, ok := log.parseLine(tt.line)
+			if !ok {
+				t.Fatalf("Expected a status for line: %s", tt.line)
+			}
+			if status.Stage != tt.expectedStage {
+				t.Errorf("Expected stage '%s', got '%s'", tt.expectedStage, status.Stage)
+			}
+			if status.Formula != "git" {
+				t.Errorf("Expected formula 'git', got '%s'", status.Formula)
+			}
+			if status.StartTime.IsZero() {
+				t.Error("Expected StartTime to be set")
+			}
+		})
+	}
+
+	log := newInstallLog("git", time.Now(), false)
+	if _, ok := log.parseLine("Some other output"); ok {
+		t.Error("Unrecognised output should not produce a status")
+	}
+}
+
+func TestInstallLog_DownloadProgress(t *testing.T) {
+	log := newInstallLog("jq", time.Now(), false)
+	log.parseLine("==> Downloading https://example.com/jq.tar.gz")
+
+	status, ok := log.parseLine("##########                    50.0%")
+	if !ok || status.Progress != 25 {
+		t.Errorf("Expected 25%% overall at half the only download, got %d", status.Progress)
+	}
+
+	if _, ok := log.parseLine("##########                    50.0%"); ok {
+		t.Error("A repeated progress redraw should not produce a status")
+	}
+
+	log.parseLine("######################################## 100.0%")
+	status, _ = log.parseLine("######################################## 100.0%")
+	if status.Progress != 50 {
+		t.Errorf("Expected 50%% after the download finished, got %d", status.Progress)
+	}
+
+	if got := log.Transcript(); len(got) != 1 {
+		t.Errorf("Progress redraws should not be kept in the transcript, got %q", got)
+	}
+}
+
+func TestInstallLog_CurlMeterBytes(t *testing.T) {
+	log := newInstallLog("jq", time.Now(), false)
+	log.parseLine("==> Downloading https://example.com/jq.tar.gz")
+
+	status, ok := log.parseLine(" 40 2048k   40  819k    0     0  1200k      0  0:00:01 --:--:--  0:00:01 1200k")
+	if !ok {
+		t.Fatal("Expected a status for a curl meter line")
+	}
+	if status.BytesTotal != 2048*1024 || status.BytesDone != 819*1024 {
+		t.Errorf("Expected 819k of 2048k, got %d of %d", status.BytesDone, status.BytesTotal)
+	}
+	if status.Progress != 20 {
+		t.Errorf("Expected 20%% overall, got %d", status.Progress)
+	}
+}
+
+func TestInstallLog_Dependencies(t *testing.T) {
+	transcript := []string{
+		"==> Fetching dependencies for wget: libunistring, libidn2",
+		"==> Fetching libunistring",
+		"==> Downloading https://example.com/libunistring",
+		"######################################## 100.0%",
+		"==> Fetching libidn2",
+		"Already downloaded: /cache/libidn2.tar.gz",
+		"==> Fetching wget",
+		"==> Downloading https://example.com/wget",
+		"######################################## 100.0%",
+		"==> Installing dependencies for wget: libunistring, libidn2",
+		"==> Installing wget dependency: libunistring",
+		"==> Pouring libunistring--1.3.arm64_sonoma.bottle.tar.gz",
+		"🍺  /opt/homebrew/Cellar/libunistring/1.3: 59 files, 5.3MB",
+		"==> Installing wget dependency: libidn2",
+	}
+
+	log := newInstallLog("wget", time.Now(), false)
+	var status InstallationStatus
+	for _, line := range transcript {
+		if s, ok := log.parseLine(line); ok {
+			status = s
+		}
+	}
+
+	if status.Stage != "installing" || status.Detail != "libidn2" {
+		t.Errorf("Expected to be installing libidn2, got %s (%s)", status.Stage, status.Detail)
+	}
+	want := []DependencyStatus{
+		{Name: "libunistring", Stage: "completed"},
+		{Name: "libidn2", Stage: "installing"},
+	}
+	if !slices.Equal(status.Dependencies, want) {
+		t.Errorf("Expected dependencies %+v, got %+v", want, status.Dependencies)
+	}
+	// All three bottles downloaded (50%) and one of three poured (1/3 of 50%).
+	if status.Progress != 66 {
+		t.Errorf("Expected 66%% progress, got %d", status.Progress)
+	}
+
+	final := log.Finish(nil)
+	if final.Stage != "completed" || final.Progress != 100 {
+		t.Errorf("Expected completed at 100%%, got %s at %d", final.Stage, final.Progress)
+	}
+	if len(final.Transcript) != len(transcript)-2 {
+		t.Errorf("Expected %d transcript lines, got %d", len(transcript)-2, len(final.Transcript))
+	}
+}
+
+func TestInstallLog_FinishFailed(t *testing.T) {
+	log := newInstallLog("broken", time.Now(), true)
+	log.parseLine("==> Fetching broken")
+	log.parseLine("Error: No available formula with the name \"broken\".")
+
+	final := log.Finish(errors.New("exit status 1"))
+	if final.Stage != "failed" || final.Error == nil {
+		t.Errorf("Expected failed status with error, got %+v", final)
+	}
+	if !final.Dependency {
+		t.Error("Expected the dependency flag to be preserved")
+	}
+	if len(final.Transcript) != 2 || !strings.HasPrefix(final.Transcript[1], "Error:") {
+		t.Errorf("Expected the error line in the transcript, got %q", final.Transcript)
+	}
+}
+
+func TestParseCurlSize(t *testing.T) {
+	tests := map[string]int64{
+		"512":  512,
+		"12k":  12 * 1024,
+		"2.5M": 5 * 1024 * 1024 / 2,
+		"1G":   1 << 30,
+	}
+	for in, want := range tests {
+		if got := parseCurlSize(in); got != want {
+			t.Errorf("parseCurlSize(%q) = %d, want %d", in, got, want)
+		}
+	}
+}
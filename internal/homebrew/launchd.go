@@ -0,0 +1,197 @@
+package homebrew
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// launchdManager runs services as launchd agents, written to agentDir and
+// controlled with launchctl in the user's GUI domain.
+type launchdManager struct {
+	client    *Client
+	launchctl string // launchctl is the launchctl binary
+	agentDir  string // agentDir is the user's LaunchAgents directory
+}
+
+// platform implements serviceManager.
+func (m *launchdManager) platform() string {
+	return "darwin"
+}
+
+// files renders the property list of the agent.
+func (m *launchdManager) files(def *ServiceDefinition) (map[string][]byte, error) {
+	plist, err := renderLaunchdPlist(def)
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{m.path(def): plist}, nil
+}
+
+// reload implements serviceManager. launchd reads the property list when
+// the agent is bootstrapped, so there is nothing to reload.
+func (m *launchdManager) reload(context.Context) error {
+	return nil
+}
+
+// start bootstraps the agent into the user's GUI domain.
+func (m *launchdManager) start(ctx context.Context, def *ServiceDefinition) error {
+	_, err := m.client.serviceCommand(ctx, m.launchctl, "bootstrap", m.domain(), m.path(def))
+	return err
+}
+
+// stop removes the agent from the user's GUI domain.
+func (m *launchdManager) stop(ctx context.Context, def *ServiceDefinition) error {
+	_, err := m.client.serviceCommand(ctx, m.launchctl, "bootout", m.domain()+"/"+def.Label)
+	return err
+}
+
+// status reads the state of the agent from launchctl print, which fails
+// for agents that are not loaded.
+func (m *launchdManager) status(ctx context.Context, def *ServiceDefinition) (serviceRuntime, error) {
+	var rt serviceRuntime
+	out, err := m.client.serviceCommand(ctx, m.launchctl, "print", m.domain()+"/"+def.Label)
+	if err != nil {
+		return rt, nil //nolint:nilerr // launchctl print fails for agents that are not loaded
+	}
+	rt.loaded = true
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), " = ")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "state":
+			rt.running = value == "running"
+		case "pid":
+			rt.pid, _ = strconv.Atoi(value)
+		case "last exit code":
+			if code, err := strconv.Atoi(value); err == nil {
+				rt.exitCode = &code
+			}
+		}
+	}
+	return rt, scanner.Err()
+}
+
+// path returns where the property list of the agent is written.
+func (m *launchdManager) path(def *ServiceDefinition) string {
+	return filepath.Join(m.agentDir, def.Label+".plist")
+}
+
+// domain returns the launchd domain of the user's agents.
+func (m *launchdManager) domain() string {
+	return "gui/" + strconv.Itoa(os.Getuid())
+}
+
+// renderLaunchdPlist renders the property list of def, in the layout brew
+// services uses.
+func renderLaunchdPlist(def *ServiceDefinition) ([]byte, error) {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+`)
+	plistString(&b, "Label", def.Label)
+	b.WriteString("\t<key>ProgramArguments</key>\n\t<array>\n")
+	for _, arg := range def.Command {
+		fmt.Fprintf(&b, "\t\t<string>%s</string>\n", plistEscape(arg))
+	}
+	b.WriteString("\t</array>\n")
+	plistBool(&b, "RunAtLoad", def.RunAtLoad)
+
+	switch def.Restart {
+	case "always":
+		plistBool(&b, "KeepAlive", true)
+	case "on-failure", "on-success":
+		b.WriteString("\t<key>KeepAlive</key>\n\t<dict>\n")
+		fmt.Fprintf(&b, "\t\t<key>SuccessfulExit</key>\n\t\t<%t/>\n", def.Restart == "on-success")
+		b.WriteString("\t</dict>\n")
+	}
+	if def.RestartDelay > 0 {
+		fmt.Fprintf(&b, "\t<key>ThrottleInterval</key>\n\t<integer>%d</integer>\n", def.RestartDelay)
+	}
+
+	switch def.RunType {
+	case "interval":
+		if def.Interval <= 0 {
+			return nil, fmt.Errorf("service of %s has no interval", def.Formula)
+		}
+		fmt.Fprintf(&b, "\t<key>StartInterval</key>\n\t<integer>%d</integer>\n", def.Interval)
+	case "cron":
+		if err := plistCalendar(&b, def.Cron); err != nil {
+			return nil, fmt.Errorf("service of %s: %w", def.Formula, err)
+		}
+	}
+
+	plistString(&b, "WorkingDirectory", def.WorkingDir)
+	plistString(&b, "RootDirectory", def.RootDir)
+	plistString(&b, "StandardOutPath", def.LogPath)
+	plistString(&b, "StandardErrorPath", def.ErrorLogPath)
+	if len(def.Environment) > 0 {
+		keys := make([]string, 0, len(def.Environment))
+		for k := range def.Environment {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b.WriteString("\t<key>EnvironmentVariables</key>\n\t<dict>\n")
+		for _, k := range keys {
+			fmt.Fprintf(&b, "\t\t<key>%s</key>\n\t\t<string>%s</string>\n", plistEscape(k), plistEscape(def.Environment[k]))
+		}
+		b.WriteString("\t</dict>\n")
+	}
+	b.WriteString("</dict>\n</plist>\n")
+	return []byte(b.String()), nil
+}
+
+// plistCalendar writes the StartCalendarInterval of a cron schedule.
+// launchd takes a single value per field, so lists are rejected.
+func plistCalendar(b *strings.Builder, expr string) error {
+	cron, err := parseCron(expr)
+	if err != nil {
+		return err
+	}
+	b.WriteString("\t<key>StartCalendarInterval</key>\n\t<dict>\n")
+	for _, field := range []struct{ key, value string }{
+		{"Minute", cron.minute}, {"Hour", cron.hour}, {"Day", cron.day}, {"Month", cron.month}, {"Weekday", cron.weekday},
+	} {
+		if field.value == "*" {
+			continue
+		}
+		n, err := strconv.Atoi(field.value)
+		if err != nil {
+			return fmt.Errorf("launchd cannot run the cron schedule %q", expr)
+		}
+		fmt.Fprintf(b, "\t\t<key>%s</key>\n\t\t<integer>%d</integer>\n", field.key, n)
+	}
+	b.WriteString("\t</dict>\n")
+	return nil
+}
+
+// plistString writes a string entry, unless value is empty.
+func plistString(b *strings.Builder, key, value string) {
+	if value != "" {
+		fmt.Fprintf(b, "\t<key>%s</key>\n\t<string>%s</string>\n", key, plistEscape(value))
+	}
+}
+
+// plistBool writes a boolean entry.
+func plistBool(b *strings.Builder, key string, value bool) {
+	fmt.Fprintf(b, "\t<key>%s</key>\n\t<%t/>\n", key, value)
+}
+
+// plistEscape escapes text for a property list.
+func plistEscape(text string) string {
+	var b bytes.Buffer
+	_ = xml.EscapeText(&b, []byte(text))
+	return b.String()
+}
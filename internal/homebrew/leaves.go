@@ -0,0 +1,127 @@
+package homebrew
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// Leaves returns the installed formulae that no other installed formula
+// depends on, in the order brew lists them.
+func (c *Client) Leaves(ctx context.Context) ([]Formula, error) {
+	installed, err := c.InstalledFormulae(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dependents := installedReverseIndex(installed)
+	var leaves []Formula
+	for _, f := range installed {
+		if len(dependents[f.Name]) == 0 {
+			leaves = append(leaves, f)
+		}
+	}
+	return leaves, nil
+}
+
+// Orphans returns the installed formulae that were installed only as a
+// dependency and that no formula installed on request still needs,
+// directly or indirectly. These are the formulae `brew autoremove` removes.
+func (c *Client) Orphans(ctx context.Context) ([]Formula, error) {
+	installed, err := c.InstalledFormulae(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deps := make(map[string][]string, len(installed))
+	var queue []string
+	for _, f := range installed {
+		deps[f.Name] = installedDependencies(f)
+		if installedOnRequest(f) {
+			queue = append(queue, f.Name)
+		}
+	}
+
+	needed := make(map[string]bool)
+	for _, name := range queue {
+		needed[name] = true
+	}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		for _, dep := range deps[name] {
+			if !needed[dep] {
+				needed[dep] = true
+				queue = append(queue, dep)
+			}
+		}
+	}
+
+	var orphans []Formula
+	for _, f := range installed {
+		if !needed[f.Name] {
+			orphans = append(orphans, f)
+		}
+	}
+	return orphans, nil
+}
+
+// installedOnRequest reports whether any installed version of f was
+// installed explicitly rather than as a dependency. A version that records
+// neither, as installed by old versions of brew, counts as on request so
+// that it is never removed by mistake.
+func installedOnRequest(f Formula) bool {
+	for _, info := range f.Installed {
+		if info.InstalledOnRequest || !info.InstalledAsDependency {
+			return true
+		}
+	}
+	return false
+}
+
+// KegSizes returns the disk space used by every installed version of the
+// named formulae in the Cellar, in bytes. Formulae that are not in the
+// Cellar are reported as zero.
+func (c *Client) KegSizes(ctx context.Context, names []string) (map[string]int64, error) {
+	out, err := c.output(c.command(ctx, "--cellar"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate the Cellar: %w", err)
+	}
+	cellar := strings.TrimSpace(string(out))
+
+	sizes := make(map[string]int64, len(names))
+	for _, name := range names {
+		size, err := diskUsage(filepath.Join(cellar, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to measure %s: %w", name, err)
+		}
+		sizes[name] = size
+	}
+	return sizes, nil
+}
+
+// diskUsage returns the total size of the regular files below dir, without
+// following symlinks. A missing dir has a size of zero.
+func diskUsage(dir string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			total += info.Size()
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	return total, err
+}
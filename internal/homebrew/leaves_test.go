@@ -0,0 +1,86 @@
+package homebrew
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// orphansJSON is `brew info --json=v1 --installed` output where git was
+// installed on request, curl and openssl@3 are needed by it, and wget was
+// uninstalled, leaving libidn2 and its dependency libunistring behind.
+const orphansJSON = `[
+ {"name":"git","installed":[{"version":"2.47.0","installed_on_request":true,"runtime_dependencies":[
+   {"full_name":"curl","declared_directly":true}]}]},
+ {"name":"curl","installed":[{"version":"8.10.1","installed_as_dependency":true,"runtime_dependencies":[
+   {"full_name":"openssl@3","declared_directly":true}]}]},
+ {"name":"openssl@3","installed":[{"version":"3.4.0","installed_as_dependency":true}]},
+ {"name":"libidn2","installed":[{"version":"2.3.7","installed_as_dependency":true,"runtime_dependencies":[
+   {"full_name":"libunistring","declared_directly":true}]}]},
+ {"name":"libunistring","installed":[{"version":"1.3","installed_as_dependency":true}]},
+ {"name":"tree","installed":[{"version":"2.1.3"}]}
+]`
+
+func TestLeaves(t *testing.T) {
+	client := &Client{brewPath: writeFakeBrew(t, "echo '"+orphansJSON+"'")}
+
+	leaves, err := client.Leaves(context.Background())
+	if err != nil {
+		t.Fatalf("Leaves failed: %v", err)
+	}
+	if names := formulaNames(leaves); !slices.Equal(names, []string{"git", "libidn2", "tree"}) {
+		t.Errorf("Expected leaves [git libidn2 tree], got %v", names)
+	}
+}
+
+func TestOrphans(t *testing.T) {
+	client := &Client{brewPath: writeFakeBrew(t, "echo '"+orphansJSON+"'")}
+
+	orphans, err := client.Orphans(context.Background())
+	if err != nil {
+		t.Fatalf("Orphans failed: %v", err)
+	}
+	// tree records neither flag, as old brew versions did, and must be kept.
+	if names := formulaNames(orphans); !slices.Equal(names, []string{"libidn2", "libunistring"}) {
+		t.Errorf("Expected orphans [libidn2 libunistring], got %v", names)
+	}
+}
+
+func TestKegSizes(t *testing.T) {
+	cellar := t.TempDir()
+	keg := filepath.Join(cellar, "jq", "1.7.1", "bin")
+	if err := os.MkdirAll(keg, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(keg, "jq"), make([]byte, 1000), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cellar, "jq", "1.7.1", "README"), make([]byte, 24), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(keg, "jq"), filepath.Join(keg, "jq-link")); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &Client{brewPath: writeFakeBrew(t, "echo "+cellar)}
+	sizes, err := client.KegSizes(context.Background(), []string{"jq", "missing"})
+	if err != nil {
+		t.Fatalf("KegSizes failed: %v", err)
+	}
+	if sizes["jq"] != 1024 {
+		t.Errorf("Expected jq to use 1024 bytes, got %d", sizes["jq"])
+	}
+	if sizes["missing"] != 0 {
+		t.Errorf("Expected a formula outside the Cellar to use 0 bytes, got %d", sizes["missing"])
+	}
+}
+
+func formulaNames(formulae []Formula) []string {
+	names := make([]string, len(formulae))
+	for i, f := range formulae {
+		names[i] = f.Name
+	}
+	return names
+}
@@ -0,0 +1,680 @@
+package homebrew
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ofkm/goobrew/internal/logger"
+	"github.com/ofkm/goobrew/internal/pkgversion"
+)
+
+// linkDirs are the keg directories whose contents are linked into the
+// prefix.
+var linkDirs = []string{"bin", "sbin", "include", "lib", "share", "etc", "Frameworks"}
+
+// linkSkip are keg files brew never links, because they are shared by
+// every keg and maintained in the prefix itself.
+var linkSkip = map[string]bool{
+	"share/info/dir":            true,
+	"share/locale/locale.alias": true,
+	"lib/charset.alias":         true,
+}
+
+// LinkOp is the kind of a LinkChange.
+type LinkOp string
+
+// Link operations.
+const (
+	LinkOpMkdir  LinkOp = "mkdir"  // LinkOpMkdir creates a directory in the prefix
+	LinkOpLink   LinkOp = "link"   // LinkOpLink creates a symlink into the keg
+	LinkOpRemove LinkOp = "remove" // LinkOpRemove removes a symlink, file or empty directory
+)
+
+// LinkChange is a change Link or Unlink makes, or would make, to the prefix.
+type LinkChange struct {
+	Op     LinkOp `json:"op"`               // Op is the kind of change
+	Path   string `json:"path"`             // Path is relative to the prefix
+	Target string `json:"target,omitempty"` // Target is what a new symlink points to, relative to its directory
+}
+
+// LinkConflict is a file in the prefix that is in the way of a keg's
+// symlink.
+type LinkConflict struct {
+	Path         string `json:"path"`                    // Path is relative to the prefix
+	Owner        string `json:"owner,omitempty"`         // Owner is the formula whose keg the file belongs to, if any
+	OwnerVersion string `json:"owner_version,omitempty"` // OwnerVersion is the version of that keg
+}
+
+// String describes the conflict in one line.
+func (c LinkConflict) String() string {
+	if c.Owner != "" {
+		return fmt.Sprintf("%s belongs to %s %s", c.Path, c.Owner, c.OwnerVersion)
+	}
+	return c.Path + " is not a Homebrew symlink"
+}
+
+// LinkConflictError is returned by Link when files in the prefix are in
+// the way and may not be overwritten.
+type LinkConflictError struct {
+	Formula   string         // Formula is the formula that could not be linked
+	Conflicts []LinkConflict // Conflicts are the files in the way
+}
+
+func (e *LinkConflictError) Error() string {
+	return fmt.Sprintf("cannot link %s: %d conflicting files, first %s", e.Formula, len(e.Conflicts), e.Conflicts[0])
+}
+
+// LinkResult describes what Link or Unlink did, or would do with
+// WithLinkDryRun.
+type LinkResult struct {
+	Formula   string         `json:"formula"`             // Formula is the formula name
+	Version   string         `json:"version"`             // Version is the keg version
+	Keg       string         `json:"keg"`                 // Keg is the keg directory
+	KegOnly   bool           `json:"keg_only,omitempty"`  // KegOnly is true if the keg was not linked because the formula is keg-only
+	Changes   []LinkChange   `json:"changes"`             // Changes are the changes to the prefix, in order
+	Conflicts []LinkConflict `json:"conflicts,omitempty"` // Conflicts are the files that kept the keg from being linked
+}
+
+// LinkOption configures a single Link or Unlink call.
+type LinkOption func(*linkConfig)
+
+// linkConfig holds the settings applied by LinkOptions.
+type linkConfig struct {
+	dryRun    bool
+	force     bool
+	overwrite bool
+}
+
+// WithLinkDryRun plans the changes without making them.
+func WithLinkDryRun() LinkOption {
+	return func(cfg *linkConfig) {
+		cfg.dryRun = true
+	}
+}
+
+// WithLinkForce links keg-only formulae too.
+func WithLinkForce() LinkOption {
+	return func(cfg *linkConfig) {
+		cfg.force = true
+	}
+}
+
+// WithLinkOverwrite removes conflicting files instead of failing.
+func WithLinkOverwrite() LinkOption {
+	return func(cfg *linkConfig) {
+		cfg.overwrite = true
+	}
+}
+
+// Link symlinks the newest installed keg of the named formula into the
+// prefix without running brew. Files in the keg's bin, sbin, include, lib,
+// share, etc and Frameworks directories are linked individually, creating
+// the directories they are in, and the opt link and linked-keg record are
+// updated. Keg-only formulae only get their opt link unless forced.
+//
+// A file in the way is replaced if it is a symlink into another keg of the
+// same formula or a broken symlink, or if it matches one of the formula's
+// link_overwrite patterns and does not belong to another keg. Any other
+// file is a conflict: nothing is changed and a *LinkConflictError naming
+// the owner of each file is returned, unless WithLinkOverwrite is given.
+// With WithLinkDryRun the result lists the changes and conflicts without
+// touching the prefix.
+func (c *Client) Link(ctx context.Context, name string, opts ...LinkOption) (*LinkResult, error) {
+	var cfg linkConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	paths, err := c.Paths()
+	if err != nil {
+		return nil, err
+	}
+	name = shortFormulaName(name)
+	versions, err := kegVersions(paths.Cellar, name)
+	if err != nil {
+		return nil, err
+	}
+	version := versions[len(versions)-1]
+
+	keg := filepath.Join(paths.Cellar, name, version)
+	kegOnly, patterns, err := c.linkMetadata(ctx, name, keg)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &linker{paths: paths, name: name, keg: keg, cfg: cfg, patterns: patterns}
+	result := &LinkResult{Formula: name, Version: version, Keg: l.keg}
+
+	if linked := l.linkedVersion(); linked != "" && linked != version {
+		return nil, fmt.Errorf("%s %s is linked, unlink it first", name, linked)
+	}
+
+	l.planRecord(filepath.Join("opt", name))
+	if kegOnly && !cfg.force {
+		logger.Log.Info("not linking keg-only formula", "formula", name)
+		result.KegOnly = true
+	} else {
+		for _, dir := range linkDirs {
+			if err := l.planKeg(dir); err != nil {
+				return nil, err
+			}
+		}
+		l.planRecord(filepath.Join("var", "homebrew", "linked", name))
+	}
+
+	result.Changes, result.Conflicts = l.changes, l.conflicts
+	if len(l.conflicts) > 0 && !cfg.dryRun {
+		return result, &LinkConflictError{Formula: name, Conflicts: l.conflicts}
+	}
+	if cfg.dryRun {
+		return result, nil
+	}
+	logger.Log.Info("linking keg", "keg", l.keg, "changes", len(l.changes))
+	return result, l.apply()
+}
+
+// linkMetadata returns whether the formula is keg-only and its
+// link_overwrite patterns. They are read from the receipt of a keg goobrew
+// poured, or from the formula index in memory or on disk, and only fetched
+// from the API when neither has them.
+func (c *Client) linkMetadata(ctx context.Context, name, keg string) (bool, []string, error) {
+	if receipt, err := readReceipt(keg); err == nil {
+		if kegOnly, ok := receipt["keg_only"].(bool); ok {
+			var patterns []string
+			list, _ := receipt["link_overwrite"].([]any)
+			for _, v := range list {
+				if s, ok := v.(string); ok {
+					patterns = append(patterns, s)
+				}
+			}
+			return kegOnly, patterns, nil
+		}
+	}
+
+	for _, item := range c.cachedIndex() {
+		if item.Name == name {
+			return item.KegOnly, item.LinkOverwrite, nil
+		}
+	}
+
+	f, err := c.fetchFormula(ctx, fmt.Sprintf("formula/%s.json", name))
+	switch {
+	case errors.Is(err, ErrNotFound):
+		logger.Log.Debug("formula not in the API, linking without its metadata", "formula", name)
+		return false, nil, nil
+	case err != nil:
+		return false, nil, fmt.Errorf("failed to look up %s: %w", name, err)
+	}
+	return f.KegOnly, f.LinkOverwrite, nil
+}
+
+// Unlink removes the symlinks into every installed keg of the named
+// formula from the prefix, then the directories that are left empty and
+// the linked-keg record. The opt link is kept. With WithLinkDryRun the
+// result lists the changes without touching the prefix.
+func (c *Client) Unlink(_ context.Context, name string, opts ...LinkOption) (*LinkResult, error) {
+	var cfg linkConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	paths, err := c.Paths()
+	if err != nil {
+		return nil, err
+	}
+	name = shortFormulaName(name)
+	versions, err := kegVersions(paths.Cellar, name)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &linker{paths: paths, name: name, cfg: cfg, removed: make(map[string]bool)}
+	result := &LinkResult{Formula: name, Version: versions[len(versions)-1]}
+	if linked := l.linkedVersion(); linked != "" {
+		result.Version = linked
+	}
+	result.Keg = filepath.Join(paths.Cellar, name, result.Version)
+
+	var dirs []string
+	for _, version := range versions {
+		l.keg = filepath.Join(paths.Cellar, name, version)
+		for _, dir := range linkDirs {
+			visited, err := l.planUnlink(dir)
+			if err != nil {
+				return nil, err
+			}
+			dirs = append(dirs, visited...)
+		}
+	}
+	l.planRemoveEmpty(dirs)
+
+	record := filepath.Join("var", "homebrew", "linked", name)
+	if _, err := os.Lstat(filepath.Join(paths.Prefix, record)); err == nil {
+		l.change(LinkOpRemove, record, "")
+	}
+
+	result.Changes = l.changes
+	if cfg.dryRun {
+		return result, nil
+	}
+	logger.Log.Info("unlinking kegs", "formula", name, "changes", len(l.changes))
+	return result, l.apply()
+}
+
+// kegVersions returns the installed versions of a formula in the Cellar,
+// oldest first, or an error if there are none.
+func kegVersions(cellar, name string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(cellar, name))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	var versions []string
+	for _, e := range entries {
+		if e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+			versions = append(versions, e.Name())
+		}
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%s is not installed", name)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return pkgversion.ParsePkgVersion(versions[i]).Compare(pkgversion.ParsePkgVersion(versions[j])) < 0
+	})
+	return versions, nil
+}
+
+// linker plans the changes that link or unlink a keg.
+type linker struct {
+	paths     Paths
+	name      string
+	keg       string
+	cfg       linkConfig
+	patterns  []string
+	changes   []LinkChange
+	conflicts []LinkConflict
+	created   map[string]bool   // created are the prefix directories the plan creates
+	removed   map[string]bool   // removed are the prefix paths the plan removes
+	split     map[string]string // split are the prefix directories split from another keg, to its directory
+}
+
+// change appends a change to the plan.
+func (l *linker) change(op LinkOp, rel, target string) {
+	l.changes = append(l.changes, LinkChange{Op: op, Path: rel, Target: target})
+	switch op {
+	case LinkOpMkdir:
+		if l.created == nil {
+			l.created = make(map[string]bool)
+		}
+		l.created[rel] = true
+	case LinkOpRemove:
+		if l.removed != nil {
+			l.removed[rel] = true
+		}
+	}
+}
+
+// linkedVersion returns the version the linked-keg record points to, if
+// any.
+func (l *linker) linkedVersion() string {
+	target, err := os.Readlink(filepath.Join(l.paths.Prefix, "var", "homebrew", "linked", l.name))
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(target)
+}
+
+// target returns the relative symlink target that points from the prefix
+// path rel to the same path in the keg.
+func (l *linker) target(rel string) string {
+	target, _ := filepath.Rel(filepath.Dir(filepath.Join(l.paths.Prefix, rel)), filepath.Join(l.keg, rel))
+	return target
+}
+
+// resolve returns the absolute, cleaned path a symlink in the prefix
+// points to.
+func (l *linker) resolve(rel string) (string, error) {
+	link := filepath.Join(l.paths.Prefix, rel)
+	target, err := os.Readlink(link)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(link), target)
+	}
+	return filepath.Clean(target), nil
+}
+
+// owner returns the formula and version of the keg path is in, if any.
+func (l *linker) owner(path string) (string, string) {
+	rel, err := filepath.Rel(l.paths.Cellar, path)
+	if err != nil || !filepath.IsLocal(rel) {
+		return "", ""
+	}
+	parts := strings.SplitN(filepath.ToSlash(rel), "/", 3)
+	if len(parts) < 2 {
+		return "", ""
+	}
+	return parts[0], parts[1]
+}
+
+// planRecord plans a symlink from the prefix path rel to the keg itself,
+// as used for the opt link and the linked-keg record.
+func (l *linker) planRecord(rel string) {
+	target, _ := filepath.Rel(filepath.Dir(filepath.Join(l.paths.Prefix, rel)), l.keg)
+	if current, err := os.Readlink(filepath.Join(l.paths.Prefix, rel)); err == nil {
+		if current == target {
+			return
+		}
+		l.change(LinkOpRemove, rel, "")
+	}
+	if dir := filepath.Dir(rel); !l.created[dir] {
+		if _, err := os.Stat(filepath.Join(l.paths.Prefix, dir)); err != nil {
+			l.change(LinkOpMkdir, dir, "")
+		}
+	}
+	l.change(LinkOpLink, rel, target)
+}
+
+// planKeg plans the links for one of the keg's linked directories.
+func (l *linker) planKeg(dir string) error {
+	root := filepath.Join(l.keg, dir)
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return nil
+	}
+
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(l.keg, path)
+		if err != nil {
+			return err
+		}
+		if linkSkip[filepath.ToSlash(rel)] || d.Name() == ".DS_Store" {
+			return nil
+		}
+		if d.IsDir() {
+			if !l.planDir(rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		l.planLink(rel)
+		return nil
+	})
+}
+
+// planDir plans the prefix directory for the keg directory rel and reports
+// whether its contents should be linked. A symlink to the same directory
+// in the keg, as brew creates for directories no other keg uses, already
+// links them. A symlink to the directory in another keg is split into a
+// real directory holding links to that keg's files, so both can share it.
+func (l *linker) planDir(rel string) bool {
+	if _, ok := l.split[rel]; ok {
+		return true
+	}
+	if l.created[filepath.Dir(rel)] {
+		if conflict, ok := l.splitConflict(rel); ok {
+			l.conflicts = append(l.conflicts, conflict)
+			return false
+		}
+		l.change(LinkOpMkdir, rel, "")
+		return true
+	}
+
+	dst := filepath.Join(l.paths.Prefix, rel)
+	info, err := os.Lstat(dst)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		l.change(LinkOpMkdir, rel, "")
+		return true
+	case err != nil:
+		l.conflicts = append(l.conflicts, LinkConflict{Path: rel})
+		return false
+	case info.IsDir():
+		return true
+	case info.Mode()&fs.ModeSymlink == 0:
+		l.conflicts = append(l.conflicts, LinkConflict{Path: rel})
+		return false
+	}
+
+	target, err := l.resolve(rel)
+	if err == nil && target == filepath.Join(l.keg, rel) {
+		return false
+	}
+	stat, statErr := os.Stat(dst)
+	isDir := statErr == nil && stat.IsDir()
+	if owner, version := l.owner(target); owner != "" {
+		switch {
+		case !isDir:
+			l.conflicts = append(l.conflicts, LinkConflict{Path: rel, Owner: owner, OwnerVersion: version})
+			return false
+		case owner == l.name:
+			// A directory left behind by another keg of the formula
+			l.change(LinkOpRemove, rel, "")
+			l.change(LinkOpMkdir, rel, "")
+		default:
+			l.change(LinkOpRemove, rel, "")
+			l.planSplit(rel, target)
+		}
+		return true
+	}
+	if isDir {
+		// A symlink to a directory outside the Cellar, such as etc on
+		// some systems, is used like a directory
+		return true
+	}
+	l.conflicts = append(l.conflicts, LinkConflict{Path: rel})
+	return false
+}
+
+// planSplit plans the prefix directory rel in place of a symlink to the
+// directory dir in another keg, and links dir's entries into it. An entry
+// that is a directory in both kegs is split in turn, so that the keg being
+// linked can add to it.
+func (l *linker) planSplit(rel, dir string) {
+	l.change(LinkOpMkdir, rel, "")
+	if l.split == nil {
+		l.split = make(map[string]string)
+	}
+	l.split[rel] = dir
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		logger.Log.Warn("failed to read shared directory", "dir", dir, "error", err)
+		return
+	}
+	for _, e := range entries {
+		child := filepath.Join(rel, e.Name())
+		if e.IsDir() {
+			if info, err := os.Stat(filepath.Join(l.keg, child)); err == nil && info.IsDir() {
+				l.planSplit(child, filepath.Join(dir, e.Name()))
+				continue
+			}
+		}
+		target, _ := filepath.Rel(filepath.Join(l.paths.Prefix, rel), filepath.Join(dir, e.Name()))
+		l.change(LinkOpLink, child, target)
+	}
+}
+
+// splitConflict reports whether the prefix path rel, in a directory the
+// plan creates, is already taken by a link planSplit made to another keg.
+func (l *linker) splitConflict(rel string) (LinkConflict, bool) {
+	dir, ok := l.split[filepath.Dir(rel)]
+	if !ok {
+		return LinkConflict{}, false
+	}
+	path := filepath.Join(dir, filepath.Base(rel))
+	if _, err := os.Lstat(path); err != nil {
+		return LinkConflict{}, false
+	}
+	owner, version := l.owner(path)
+	return LinkConflict{Path: rel, Owner: owner, OwnerVersion: version}, true
+}
+
+// planLink plans the symlink for the keg file rel, replacing or reporting
+// whatever is in its place.
+func (l *linker) planLink(rel string) {
+	target := l.target(rel)
+	if l.created[filepath.Dir(rel)] {
+		if conflict, ok := l.splitConflict(rel); ok {
+			if !l.cfg.overwrite {
+				l.conflicts = append(l.conflicts, conflict)
+				return
+			}
+			l.change(LinkOpRemove, rel, "")
+		}
+		l.change(LinkOpLink, rel, target)
+		return
+	}
+
+	dst := filepath.Join(l.paths.Prefix, rel)
+	info, err := os.Lstat(dst)
+	if errors.Is(err, fs.ErrNotExist) {
+		l.change(LinkOpLink, rel, target)
+		return
+	}
+
+	conflict := LinkConflict{Path: rel}
+	if err != nil {
+		l.conflicts = append(l.conflicts, conflict)
+		return
+	}
+
+	replace := false
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		resolved, err := l.resolve(rel)
+		if err == nil && resolved == filepath.Join(l.keg, rel) {
+			return
+		}
+		conflict.Owner, conflict.OwnerVersion = l.owner(resolved)
+		_, statErr := os.Stat(dst)
+		replace = conflict.Owner == l.name || statErr != nil ||
+			conflict.Owner == "" && l.overwritable(rel)
+	case !info.IsDir():
+		replace = l.overwritable(rel)
+	}
+
+	if !replace && !(l.cfg.overwrite && !info.IsDir()) {
+		l.conflicts = append(l.conflicts, conflict)
+		return
+	}
+	l.change(LinkOpRemove, rel, "")
+	l.change(LinkOpLink, rel, target)
+}
+
+// overwritable reports whether the prefix path rel matches one of the
+// formula's link_overwrite patterns: the path itself, a directory above
+// it, or a pattern where * matches anything.
+func (l *linker) overwritable(rel string) bool {
+	rel = filepath.ToSlash(rel)
+	for _, p := range l.patterns {
+		if p == rel || strings.HasPrefix(rel, strings.TrimSuffix(p, "/")+"/") {
+			return true
+		}
+		pattern := "^" + strings.ReplaceAll(regexp.QuoteMeta(p), `\*`, ".*?") + "$"
+		if ok, _ := regexp.MatchString(pattern, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// planUnlink plans the removal of the symlinks into the keg for one of its
+// linked directories, and returns the real prefix directories it visited.
+func (l *linker) planUnlink(dir string) ([]string, error) {
+	root := filepath.Join(l.keg, dir)
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return nil, nil
+	}
+
+	var visited []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(l.keg, path)
+		if err != nil {
+			return err
+		}
+
+		info, err := os.Lstat(filepath.Join(l.paths.Prefix, rel))
+		if err != nil {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if target, err := l.resolve(rel); err == nil && target == path && !l.removed[rel] {
+				l.change(LinkOpRemove, rel, "")
+			}
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() && info.IsDir() {
+			visited = append(visited, rel)
+		}
+		return nil
+	})
+	return visited, err
+}
+
+// planRemoveEmpty plans the removal of the visited prefix directories
+// that the plan leaves empty, deepest first. The top-level directories
+// such as bin are kept.
+func (l *linker) planRemoveEmpty(dirs []string) {
+	sort.Slice(dirs, func(i, j int) bool { return len(dirs[i]) > len(dirs[j]) })
+	for _, rel := range dirs {
+		if !strings.ContainsRune(rel, filepath.Separator) || l.removed[rel] {
+			continue
+		}
+		entries, err := os.ReadDir(filepath.Join(l.paths.Prefix, rel))
+		if err != nil {
+			continue
+		}
+		empty := true
+		for _, e := range entries {
+			if !l.removed[filepath.Join(rel, e.Name())] {
+				empty = false
+				break
+			}
+		}
+		if empty {
+			l.change(LinkOpRemove, rel, "")
+		}
+	}
+}
+
+// apply makes the planned changes.
+func (l *linker) apply() error {
+	for _, ch := range l.changes {
+		path := filepath.Join(l.paths.Prefix, ch.Path)
+		var err error
+		switch ch.Op {
+		case LinkOpMkdir:
+			err = os.MkdirAll(path, 0o755)
+		case LinkOpLink:
+			err = os.Symlink(ch.Target, path)
+		case LinkOpRemove:
+			err = os.Remove(path)
+			if errors.Is(err, fs.ErrNotExist) {
+				err = nil
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to %s %s: %w", ch.Op, ch.Path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package homebrew
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newLinkClient returns a client for a temporary prefix and an API that
+// serves the given formula JSON by name.
+func newLinkClient(t *testing.T, formulae map[string]string) (*Client, string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := filepath.Base(r.URL.Path)
+		body, ok := formulae[name[:len(name)-len(".json")]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	prefix := t.TempDir()
+	client := &Client{httpClient: &http.Client{Timeout: 5 * time.Second}}
+	WithAPIEndpoints(server.URL)(client)
+	WithPaths(Paths{Prefix: prefix, Cellar: filepath.Join(prefix, "Cellar"), Repository: prefix})(client)
+	return client, prefix
+}
+
+// writeFiles creates the given files, relative to dir.
+func writeFiles(t *testing.T, dir string, files ...string) {
+	t.Helper()
+	for _, f := range files {
+		path := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(f), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// symlink creates a symlink at dir/rel pointing to target.
+func symlink(t *testing.T, dir, rel, target string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, rel)), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, filepath.Join(dir, rel)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// assertLink fails unless dir/rel is a symlink to target.
+func assertLink(t *testing.T, dir, rel, target string) {
+	t.Helper()
+	if got, err := os.Readlink(filepath.Join(dir, rel)); err != nil || got != target {
+		t.Errorf("%s -> %q (%v), want %s", rel, got, err, target)
+	}
+}
+
+func TestLinkAndUnlink(t *testing.T) {
+	client, prefix := newLinkClient(t, map[string]string{
+		"wget": `{"name":"wget","link_overwrite":["share/doc/*"]}`,
+	})
+	keg := filepath.Join(prefix, "Cellar", "wget", "1.24.5")
+	writeFiles(t, keg, "bin/wget", "share/man/man1/wget.1", "share/info/dir", "share/doc/wget/README", "etc/wgetrc", "INSTALL_RECEIPT.json")
+	writeFiles(t, filepath.Join(prefix, "Cellar", "wget", "1.21.0"), "bin/wget")
+	writeFiles(t, prefix, "share/doc/wget/README")
+	symlink(t, prefix, "bin/wget", "../Cellar/wget/1.0/bin/wget")
+
+	result, err := client.Link(context.Background(), "wget", WithLinkDryRun())
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if result.Version != "1.24.5" || len(result.Conflicts) != 0 {
+		t.Errorf("Unexpected dry run result: %+v", result)
+	}
+	if _, err := os.Lstat(filepath.Join(prefix, "opt")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("A dry run should not change the prefix")
+	}
+	want := LinkChange{Op: LinkOpLink, Path: filepath.Join("bin", "wget"), Target: filepath.Join("..", "Cellar", "wget", "1.24.5", "bin", "wget")}
+	found := false
+	for _, ch := range result.Changes {
+		found = found || ch == want
+	}
+	if !found {
+		t.Errorf("Expected %+v in %+v", want, result.Changes)
+	}
+
+	if _, err := client.Link(context.Background(), "wget"); err != nil {
+		t.Fatalf("Link failed: %v", err)
+	}
+	assertLink(t, prefix, "bin/wget", "../Cellar/wget/1.24.5/bin/wget")
+	assertLink(t, prefix, "share/man/man1/wget.1", "../../../Cellar/wget/1.24.5/share/man/man1/wget.1")
+	assertLink(t, prefix, "share/doc/wget/README", "../../../Cellar/wget/1.24.5/share/doc/wget/README")
+	assertLink(t, prefix, "etc/wgetrc", "../Cellar/wget/1.24.5/etc/wgetrc")
+	assertLink(t, prefix, "opt/wget", "../Cellar/wget/1.24.5")
+	assertLink(t, prefix, "var/homebrew/linked/wget", "../../../Cellar/wget/1.24.5")
+	if _, err := os.Lstat(filepath.Join(prefix, "share", "info", "dir")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("share/info/dir should never be linked")
+	}
+
+	if result, err := client.Link(context.Background(), "wget"); err != nil || len(result.Changes) != 0 {
+		t.Errorf("Linking again should change nothing, got %+v, %v", result, err)
+	}
+
+	if _, err := client.Unlink(context.Background(), "wget"); err != nil {
+		t.Fatalf("Unlink failed: %v", err)
+	}
+	for _, rel := range []string{"bin/wget", "share/man", "share/doc/wget", "etc/wgetrc", "var/homebrew/linked/wget"} {
+		if _, err := os.Lstat(filepath.Join(prefix, rel)); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Expected %s to be removed, got %v", rel, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(prefix, "bin")); err != nil {
+		t.Errorf("Top-level directories should be kept: %v", err)
+	}
+	assertLink(t, prefix, "opt/wget", "../Cellar/wget/1.24.5")
+}
+
+func TestLinkConflicts(t *testing.T) {
+	client, prefix := newLinkClient(t, map[string]string{"wget": `{"name":"wget"}`})
+	writeFiles(t, filepath.Join(prefix, "Cellar", "wget", "1.24.5"), "bin/wget", "bin/curl", "include/wget.h")
+	writeFiles(t, filepath.Join(prefix, "Cellar", "curl", "8.10.1"), "bin/curl")
+	symlink(t, prefix, "bin/curl", "../Cellar/curl/8.10.1/bin/curl")
+	writeFiles(t, prefix, "include/wget.h")
+
+	result, err := client.Link(context.Background(), "wget")
+	var conflict *LinkConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Expected a LinkConflictError, got %v", err)
+	}
+	if len(result.Conflicts) != 2 {
+		t.Fatalf("Expected 2 conflicts, got %+v", result.Conflicts)
+	}
+	if c := result.Conflicts[0]; c.Path != filepath.Join("bin", "curl") || c.Owner != "curl" || c.OwnerVersion != "8.10.1" {
+		t.Errorf("Unexpected conflict: %+v", c)
+	}
+	if c := result.Conflicts[1]; c.Path != filepath.Join("include", "wget.h") || c.Owner != "" {
+		t.Errorf("Unexpected conflict: %+v", c)
+	}
+	if _, err := os.Lstat(filepath.Join(prefix, "bin", "wget")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Nothing should be linked when there are conflicts")
+	}
+
+	if _, err := client.Link(context.Background(), "wget", WithLinkOverwrite()); err != nil {
+		t.Fatalf("Link with overwrite failed: %v", err)
+	}
+	assertLink(t, prefix, "bin/curl", "../Cellar/wget/1.24.5/bin/curl")
+	assertLink(t, prefix, "include/wget.h", "../Cellar/wget/1.24.5/include/wget.h")
+}
+
+func TestLinkKegOnly(t *testing.T) {
+	client, prefix := newLinkClient(t, map[string]string{"openssl@3": `{"name":"openssl@3","keg_only":true}`})
+	writeFiles(t, filepath.Join(prefix, "Cellar", "openssl@3", "3.3.2_1"), "bin/openssl")
+
+	result, err := client.Link(context.Background(), "openssl@3")
+	if err != nil {
+		t.Fatalf("Link failed: %v", err)
+	}
+	if !result.KegOnly {
+		t.Error("Expected the result to be keg-only")
+	}
+	if _, err := os.Lstat(filepath.Join(prefix, "bin", "openssl")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("A keg-only formula should not be linked without force")
+	}
+	assertLink(t, prefix, "opt/openssl@3", "../Cellar/openssl@3/3.3.2_1")
+
+	if _, err := client.Link(context.Background(), "openssl@3", WithLinkForce()); err != nil {
+		t.Fatalf("Forced link failed: %v", err)
+	}
+	assertLink(t, prefix, "bin/openssl", "../Cellar/openssl@3/3.3.2_1/bin/openssl")
+
+	if _, err := client.Link(context.Background(), "missing"); err == nil {
+		t.Error("Expected an error for a formula that is not installed")
+	}
+}
+
+func TestLinkSharedDirectory(t *testing.T) {
+	client, prefix := newLinkClient(t, map[string]string{"b": `{"name":"b"}`})
+	writeFiles(t, filepath.Join(prefix, "Cellar", "a", "1"), "share/foo/a.txt", "share/foo/common", "share/foo/plugins/a.so")
+	writeFiles(t, filepath.Join(prefix, "Cellar", "b", "2"), "share/foo/b.txt", "share/foo/plugins/b.so")
+	symlink(t, prefix, "share/foo", "../Cellar/a/1/share/foo")
+
+	result, err := client.Link(context.Background(), "b", WithLinkDryRun())
+	if err != nil || len(result.Conflicts) != 0 {
+		t.Fatalf("Expected no conflicts, got %+v, %v", result, err)
+	}
+	if _, err := os.Readlink(filepath.Join(prefix, "share", "foo")); err != nil {
+		t.Error("A dry run should not split the directory")
+	}
+
+	if _, err := client.Link(context.Background(), "b"); err != nil {
+		t.Fatalf("Link failed: %v", err)
+	}
+	if info, err := os.Lstat(filepath.Join(prefix, "share", "foo")); err != nil || !info.IsDir() {
+		t.Fatalf("Expected share/foo to be split into a directory, got %v", err)
+	}
+	assertLink(t, prefix, "share/foo/a.txt", "../../Cellar/a/1/share/foo/a.txt")
+	assertLink(t, prefix, "share/foo/common", "../../Cellar/a/1/share/foo/common")
+	assertLink(t, prefix, "share/foo/plugins/a.so", "../../../Cellar/a/1/share/foo/plugins/a.so")
+	assertLink(t, prefix, "share/foo/b.txt", "../../Cellar/b/2/share/foo/b.txt")
+	assertLink(t, prefix, "share/foo/plugins/b.so", "../../../Cellar/b/2/share/foo/plugins/b.so")
+
+	if _, err := client.Unlink(context.Background(), "b"); err != nil {
+		t.Fatalf("Unlink failed: %v", err)
+	}
+	assertLink(t, prefix, "share/foo/a.txt", "../../Cellar/a/1/share/foo/a.txt")
+	if _, err := os.Lstat(filepath.Join(prefix, "share", "foo", "b.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected b.txt to be unlinked, got %v", err)
+	}
+}
+
+func TestLinkSharedDirectoryConflict(t *testing.T) {
+	client, prefix := newLinkClient(t, map[string]string{"b": `{"name":"b"}`})
+	writeFiles(t, filepath.Join(prefix, "Cellar", "a", "1"), "share/foo/common")
+	writeFiles(t, filepath.Join(prefix, "Cellar", "b", "2"), "share/foo/common")
+	symlink(t, prefix, "share/foo", "../Cellar/a/1/share/foo")
+
+	result, err := client.Link(context.Background(), "b")
+	var conflict *LinkConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Expected a LinkConflictError, got %v", err)
+	}
+	if len(result.Conflicts) != 1 || result.Conflicts[0].Owner != "a" || result.Conflicts[0].Path != filepath.Join("share", "foo", "common") {
+		t.Errorf("Unexpected conflicts: %+v", result.Conflicts)
+	}
+	assertLink(t, prefix, "share/foo", "../Cellar/a/1/share/foo")
+}
+
+func TestLinkMetadataOffline(t *testing.T) {
+	client, prefix := newLinkClient(t, nil)
+	client.formulaeCache = []FormulaListItem{{Name: "openssl@3", KegOnly: true}}
+	writeFiles(t, filepath.Join(prefix, "Cellar", "openssl@3", "3.3.2_1"), "bin/openssl")
+	writeFiles(t, filepath.Join(prefix, "Cellar", "wget", "1.24.5"), "bin/wget", "share/doc/wget/README")
+	writeFiles(t, prefix, "share/doc/wget/README")
+	receipt := `{"keg_only":false,"link_overwrite":["share/doc/*"]}`
+	if err := os.WriteFile(filepath.Join(prefix, "Cellar", "wget", "1.24.5", receiptFile), []byte(receipt), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := client.Link(context.Background(), "openssl@3", WithLinkDryRun())
+	if err != nil || !result.KegOnly {
+		t.Errorf("Expected keg-only from the index, got %+v, %v", result, err)
+	}
+	result, err = client.Link(context.Background(), "wget", WithLinkDryRun())
+	if err != nil || len(result.Conflicts) != 0 {
+		t.Errorf("Expected link_overwrite from the receipt, got %+v, %v", result, err)
+	}
+}
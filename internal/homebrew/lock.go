@@ -0,0 +1,293 @@
+package homebrew
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ofkm/goobrew/internal/lockfile"
+	"github.com/ofkm/goobrew/internal/pkgversion"
+)
+
+// Lock records the installed versions of names, which may be formulae or
+// casks, and of every formula they depend on at runtime. Formulae are
+// locked from brew's local metadata: the bottle checksums are recorded for
+// every platform when the installed version is the current one, and
+// otherwise left out with a warning. Casks are locked from the API. It
+// returns the lockfile and the warnings, or an error naming the packages
+// that are not installed.
+func (c *Client) Lock(ctx context.Context, names []string) (*lockfile.Lockfile, []string, error) {
+	installed, err := c.InstalledFormulae(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	formulae := make(map[string]*Formula)
+	for i := range installed {
+		f := &installed[i]
+		if len(f.Installed) == 0 {
+			continue
+		}
+		for _, name := range append([]string{f.Name, f.FullName, f.OldName}, f.Aliases...) {
+			if name != "" {
+				formulae[name] = f
+			}
+		}
+	}
+
+	casks, err := c.installedCaskVersions(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		lock       = &lockfile.Lockfile{Version: lockfile.FormatVersion, Packages: []lockfile.Package{}}
+		warnings   []string
+		missing    []string
+		caskTokens []string
+		seen       = make(map[string]bool)
+		queue      = append([]string(nil), names...)
+	)
+	for i := 0; i < len(queue); i++ {
+		name := queue[i]
+		f, ok := formulae[name]
+		if !ok {
+			f, ok = formulae[shortFormulaName(name)]
+		}
+		switch {
+		case ok && seen[f.Name]:
+		case ok:
+			seen[f.Name] = true
+			pkg, warning := lockFormula(f)
+			pkg.Dependency = i >= len(names)
+			if warning != "" {
+				warnings = append(warnings, warning)
+			}
+			lock.Packages = append(lock.Packages, pkg)
+			queue = append(queue, pkg.Dependencies...)
+		case casks[shortFormulaName(name)] != "":
+			if token := shortFormulaName(name); !seen["cask:"+token] {
+				seen["cask:"+token] = true
+				caskTokens = append(caskTokens, token)
+			}
+		case i < len(names):
+			missing = append(missing, name)
+		default:
+			warnings = append(warnings, fmt.Sprintf("%s is a dependency but is not installed", name))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, nil, fmt.Errorf("not installed: %s", strings.Join(missing, ", "))
+	}
+
+	caskPackages, caskWarnings := c.lockCasks(ctx, caskTokens, casks)
+	lock.Packages = append(lock.Packages, caskPackages...)
+	warnings = append(warnings, caskWarnings...)
+
+	lock.Sort()
+	return lock, warnings, nil
+}
+
+// lockFormula builds the lock entry of an installed formula, and a warning
+// if its bottle checksums cannot be recorded.
+func lockFormula(f *Formula) (lockfile.Package, string) {
+	current, _ := newestInstalled(*f)
+	pkg := lockfile.Package{
+		Name:         f.Name,
+		FullName:     f.FullName,
+		Tap:          f.Tap,
+		Version:      current.Version.String(),
+		Revision:     current.Revision,
+		TapGitHead:   f.TapGitHead,
+		Dependencies: installedDependencies(*f),
+	}
+	if pkg.FullName == f.Name {
+		pkg.FullName = ""
+	}
+	if pkg.Version == "" && len(f.Installed) > 0 {
+		// Only HEAD builds are installed
+		pkg.Version = f.Installed[len(f.Installed)-1].Version
+		return pkg, fmt.Sprintf("%s is a HEAD build, its bottle is not recorded", f.Name)
+	}
+
+	available := pkgversion.NewPkgVersion(f.Versions.Stable, f.Revision, f.VersionScheme)
+	if available.Compare(current) != 0 {
+		return pkg, fmt.Sprintf("%s %s is installed but the formula is at %s, its bottle is not recorded",
+			f.Name, current, available)
+	}
+	if len(f.Bottle.Files) > 0 {
+		pkg.Bottles = make(map[string]string, len(f.Bottle.Files))
+		for tag, file := range f.Bottle.Files {
+			pkg.Bottles[tag] = file.Sha256
+		}
+	}
+	return pkg, ""
+}
+
+// lockCasks builds the lock entries of installed casks from the API, in
+// parallel. A cask whose installed version differs from the API, or that
+// the API does not know, is locked without a checksum and with a warning.
+func (c *Client) lockCasks(ctx context.Context, tokens []string, installed map[string]string) ([]lockfile.Package, []string) {
+	packages := make([]lockfile.Package, len(tokens))
+	warnings := make([]string, len(tokens))
+
+	var wg sync.WaitGroup
+	for i, token := range tokens {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fields := strings.Fields(installed[token])
+			pkg := lockfile.Package{Name: token, Cask: true, Version: fields[len(fields)-1]}
+			var cask Cask
+			err := c.fetchJSON(ctx, fmt.Sprintf("cask/%s.json", token), &cask)
+			switch {
+			case err != nil:
+				warnings[i] = fmt.Sprintf("%s could not be looked up, its checksum is not recorded: %v", token, err)
+			case cask.Version != pkg.Version:
+				pkg.Tap, pkg.TapGitHead = cask.Tap, cask.TapGitHead
+				warnings[i] = fmt.Sprintf("%s %s is installed but the cask is at %s, its checksum is not recorded",
+					token, pkg.Version, cask.Version)
+			default:
+				pkg.Tap, pkg.TapGitHead, pkg.SHA256 = cask.Tap, cask.TapGitHead, cask.Sha256
+			}
+			packages[i] = pkg
+		}()
+	}
+	wg.Wait()
+
+	var nonEmpty []string
+	for _, w := range warnings {
+		if w != "" {
+			nonEmpty = append(nonEmpty, w)
+		}
+	}
+	return packages, nonEmpty
+}
+
+// VerifyLock compares the lockfile against what is installed and returns
+// every locked package that is missing or installed at another version.
+func (c *Client) VerifyLock(ctx context.Context, lock *lockfile.Lockfile) ([]lockfile.Drift, error) {
+	var (
+		formulae = make(map[string]string)
+		casks    map[string]string
+		err      error
+	)
+	for _, p := range lock.Packages {
+		if !p.Cask {
+			continue
+		}
+		if casks, err = c.installedCaskVersions(ctx); err != nil {
+			return nil, err
+		}
+		break
+	}
+
+	installed, err := c.InstalledFormulae(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range installed {
+		if v, ok := newestInstalled(f); ok {
+			formulae[f.Name] = v.String()
+		} else if len(f.Installed) > 0 {
+			formulae[f.Name] = f.Installed[len(f.Installed)-1].Version
+		}
+	}
+
+	var drifts []lockfile.Drift
+	for _, p := range lock.Packages {
+		actual, ok := formulae[p.Name]
+		if p.Cask {
+			fields := strings.Fields(casks[p.Name])
+			if ok = len(fields) > 0; ok {
+				actual = fields[len(fields)-1]
+			}
+		}
+		switch {
+		case !ok:
+			drifts = append(drifts, lockfile.Drift{Package: p.Name, Cask: p.Cask, Kind: lockfile.DriftMissing, Locked: p.PkgVersion()})
+		case actual != p.PkgVersion():
+			drifts = append(drifts, lockfile.Drift{Package: p.Name, Cask: p.Cask, Kind: lockfile.DriftVersion, Locked: p.PkgVersion(), Actual: actual})
+		}
+	}
+	return drifts, nil
+}
+
+// CheckLocked compares what installing names would fetch today against the
+// lockfile. Every requested package must be locked, and it and its locked
+// dependencies must still have the locked version and, for the platform
+// identified by tag, the locked bottle checksum in the API. Casks are
+// compared by version and download checksum. It returns every difference
+// found; packages the API cannot return are reported as DriftUnknown.
+func (c *Client) CheckLocked(ctx context.Context, lock *lockfile.Lockfile, names []string, tag string) []lockfile.Drift {
+	packages, unlocked := lock.Closure(names)
+
+	var drifts []lockfile.Drift
+	for _, name := range unlocked {
+		drifts = append(drifts, lockfile.Drift{Package: name, Kind: lockfile.DriftUnlocked})
+	}
+
+	results := make([][]lockfile.Drift, len(packages))
+	var wg sync.WaitGroup
+	for i, p := range packages {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if p.Cask {
+				results[i] = c.checkLockedCask(ctx, p)
+			} else {
+				results[i] = c.checkLockedFormula(ctx, p, tag)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, r := range results {
+		drifts = append(drifts, r...)
+	}
+	sort.SliceStable(drifts, func(i, j int) bool { return drifts[i].Package < drifts[j].Package })
+	return drifts
+}
+
+// checkLockedFormula compares a locked formula with the API.
+func (c *Client) checkLockedFormula(ctx context.Context, p lockfile.Package, tag string) []lockfile.Drift {
+	name := p.Name
+	f, err := c.fetchFormula(ctx, fmt.Sprintf("formula/%s.json", name))
+	if err != nil {
+		return []lockfile.Drift{{Package: name, Kind: lockfile.DriftUnknown, Actual: err.Error()}}
+	}
+
+	latest := pkgversion.NewPkgVersion(f.Versions.Stable, f.Revision, f.VersionScheme).String()
+	if latest != p.PkgVersion() {
+		return []lockfile.Drift{{Package: name, Kind: lockfile.DriftVersion, Locked: p.PkgVersion(), Actual: latest}}
+	}
+
+	var actual string
+	if file, _, ok := f.Bottle.File(tag); ok {
+		actual = file.Sha256
+	}
+	locked := p.Bottles[tag]
+	if locked == "" {
+		locked = p.Bottles["all"]
+	}
+	if actual != locked {
+		return []lockfile.Drift{{Package: name, Kind: lockfile.DriftBottle, Locked: locked, Actual: actual}}
+	}
+	return nil
+}
+
+// checkLockedCask compares a locked cask with the API.
+func (c *Client) checkLockedCask(ctx context.Context, p lockfile.Package) []lockfile.Drift {
+	var cask Cask
+	err := c.fetchJSON(ctx, fmt.Sprintf("cask/%s.json", p.Name), &cask)
+	switch {
+	case err != nil:
+		return []lockfile.Drift{{Package: p.Name, Cask: true, Kind: lockfile.DriftUnknown, Actual: err.Error()}}
+	case cask.Version != p.Version:
+		return []lockfile.Drift{{Package: p.Name, Cask: true, Kind: lockfile.DriftVersion, Locked: p.Version, Actual: cask.Version}}
+	case p.SHA256 != "" && cask.Sha256 != p.SHA256:
+		return []lockfile.Drift{{Package: p.Name, Cask: true, Kind: lockfile.DriftBottle, Locked: p.SHA256, Actual: cask.Sha256}}
+	}
+	return nil
+}
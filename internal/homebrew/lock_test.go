@@ -0,0 +1,203 @@
+package homebrew
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ofkm/goobrew/internal/lockfile"
+)
+
+// lockBrew is a fake brew with wget and its dependency openssl@3 installed
+// at their current versions, jq installed at an old version, and the
+// firefox cask.
+const lockBrew = `case "$1" in
+info) echo '[
+ {"name":"wget","full_name":"wget","tap":"homebrew/core","tap_git_head":"abc123","versions":{"stable":"1.24.5"},"revision":0,
+  "bottle":{"stable":{"files":{"arm64_sonoma":{"sha256":"aaa"},"x86_64_linux":{"sha256":"bbb"}}}},
+  "installed":[{"version":"1.24.5","installed_on_request":true,"runtime_dependencies":[{"full_name":"openssl@3","declared_directly":true}]}]},
+ {"name":"openssl@3","full_name":"openssl@3","tap":"homebrew/core","versions":{"stable":"3.3.2"},"revision":1,
+  "bottle":{"stable":{"files":{"x86_64_linux":{"sha256":"ccc"}}}},
+  "installed":[{"version":"3.3.2_1","installed_as_dependency":true}]},
+ {"name":"jq","full_name":"jq","versions":{"stable":"1.7.1"},
+  "bottle":{"stable":{"files":{"all":{"sha256":"ddd"}}}},
+  "installed":[{"version":"1.6","installed_on_request":true}]}
+]' ;;
+list) echo "firefox 120.0" ;;
+esac`
+
+func TestLock(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(Cask{Token: "firefox", Tap: "homebrew/cask", Version: "120.0", Sha256: "fff"})
+	}))
+	defer server.Close()
+
+	client := &Client{brewPath: writeFakeBrew(t, lockBrew), httpClient: &http.Client{Timeout: 5 * time.Second}}
+	WithAPIEndpoints(server.URL)(client)
+
+	lock, warnings, err := client.Lock(context.Background(), []string{"wget", "jq", "firefox"})
+	if err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+
+	var names []string
+	for _, p := range lock.Packages {
+		names = append(names, p.Name)
+	}
+	if got := strings.Join(names, " "); got != "jq openssl@3 wget firefox" {
+		t.Fatalf("Locked packages = %q", got)
+	}
+
+	wget, _ := lock.Find("wget")
+	if wget.Tap != "homebrew/core" || wget.TapGitHead != "abc123" || wget.Bottles["x86_64_linux"] != "bbb" || wget.Dependency {
+		t.Errorf("Unexpected wget entry: %+v", wget)
+	}
+	openssl, _ := lock.Find("openssl@3")
+	if openssl.PkgVersion() != "3.3.2_1" || openssl.Bottles["x86_64_linux"] != "ccc" || !openssl.Dependency {
+		t.Errorf("Unexpected openssl@3 entry: %+v", openssl)
+	}
+	if jq, _ := lock.Find("jq"); jq.Version != "1.6" || jq.Bottles != nil {
+		t.Errorf("An outdated formula should be locked without bottles: %+v", jq)
+	}
+	if firefox, _ := lock.Find("firefox"); !firefox.Cask || firefox.SHA256 != "fff" {
+		t.Errorf("Unexpected firefox entry: %+v", firefox)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "jq 1.6") {
+		t.Errorf("Expected a warning about jq, got %v", warnings)
+	}
+
+	if _, _, err := client.Lock(context.Background(), []string{"wget", "nope"}); err == nil || !strings.Contains(err.Error(), "nope") {
+		t.Errorf("Expected an error naming the missing package, got %v", err)
+	}
+}
+
+func TestVerifyLock(t *testing.T) {
+	client := &Client{brewPath: writeFakeBrew(t, lockBrew)}
+	lock := &lockfile.Lockfile{Packages: []lockfile.Package{
+		{Name: "wget", Version: "1.24.5"},
+		{Name: "openssl@3", Version: "3.3.2", Revision: 1},
+		{Name: "jq", Version: "1.7.1"},
+		{Name: "curl", Version: "8.10.1"},
+		{Name: "firefox", Cask: true, Version: "120.0"},
+	}}
+
+	drifts, err := client.VerifyLock(context.Background(), lock)
+	if err != nil {
+		t.Fatalf("VerifyLock failed: %v", err)
+	}
+	if len(drifts) != 2 {
+		t.Fatalf("Expected 2 drifts, got %+v", drifts)
+	}
+	if d := drifts[0]; d.Package != "jq" || d.Kind != lockfile.DriftVersion || d.Actual != "1.6" {
+		t.Errorf("Unexpected jq drift: %+v", d)
+	}
+	if d := drifts[1]; d.Package != "curl" || d.Kind != lockfile.DriftMissing {
+		t.Errorf("Unexpected curl drift: %+v", d)
+	}
+}
+
+func TestCheckLocked(t *testing.T) {
+	formulae := map[string]string{
+		"/formula/wget.json":      `{"name":"wget","versions":{"stable":"1.24.5"},"bottle":{"stable":{"files":{"x86_64_linux":{"sha256":"bbb"}}}}}`,
+		"/formula/openssl@3.json": `{"name":"openssl@3","versions":{"stable":"3.3.2"},"revision":1,"bottle":{"stable":{"files":{"x86_64_linux":{"sha256":"rebuilt"}}}}}`,
+		"/formula/jq.json":        `{"name":"jq","versions":{"stable":"1.7.1"},"bottle":{"stable":{"files":{"all":{"sha256":"ddd"}}}}}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := formulae[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	client := &Client{httpClient: &http.Client{Timeout: 5 * time.Second}}
+	WithAPIEndpoints(server.URL)(client)
+
+	lock := &lockfile.Lockfile{Packages: []lockfile.Package{
+		{Name: "wget", Version: "1.24.5", Bottles: map[string]string{"x86_64_linux": "bbb"}, Dependencies: []string{"openssl@3"}},
+		{Name: "openssl@3", Version: "3.3.2", Revision: 1, Bottles: map[string]string{"x86_64_linux": "ccc"}},
+		{Name: "jq", Version: "1.7.1", Bottles: map[string]string{"all": "ddd"}},
+	}}
+
+	if drifts := client.CheckLocked(context.Background(), lock, []string{"jq"}, "x86_64_linux"); len(drifts) != 0 {
+		t.Errorf("Expected jq to match its all bottle, got %+v", drifts)
+	}
+
+	drifts := client.CheckLocked(context.Background(), lock, []string{"wget", "tree"}, "x86_64_linux")
+	if len(drifts) != 2 {
+		t.Fatalf("Expected 2 drifts, got %+v", drifts)
+	}
+	if d := drifts[0]; d.Package != "openssl@3" || d.Kind != lockfile.DriftBottle || d.Locked != "ccc" || d.Actual != "rebuilt" {
+		t.Errorf("Unexpected openssl@3 drift: %+v", d)
+	}
+	if d := drifts[1]; d.Package != "tree" || d.Kind != lockfile.DriftUnlocked {
+		t.Errorf("Unexpected tree drift: %+v", d)
+	}
+}
+
+func TestBottleTags(t *testing.T) {
+	if got := linuxBottleTag("amd64"); got != "x86_64_linux" {
+		t.Errorf("linuxBottleTag(amd64) = %q", got)
+	}
+	if got := linuxBottleTag("arm64"); got != "arm64_linux" {
+		t.Errorf("linuxBottleTag(arm64) = %q", got)
+	}
+	if got, _ := macOSBottleTag("arm64", "14.5"); got != "arm64_sonoma" {
+		t.Errorf("macOSBottleTag(arm64, 14.5) = %q", got)
+	}
+	if got, _ := macOSBottleTag("amd64", "13.6.1"); got != "ventura" {
+		t.Errorf("macOSBottleTag(amd64, 13.6.1) = %q", got)
+	}
+	if _, err := macOSBottleTag("arm64", "10.15"); err == nil {
+		t.Error("Expected an error for an unsupported macOS version")
+	}
+}
+
+func TestBottleFile(t *testing.T) {
+	b := Bottle{Files: map[string]BottleFile{
+		"arm64_sonoma":  {Sha256: "arm-sonoma"},
+		"arm64_ventura": {Sha256: "arm-ventura"},
+		"sonoma":        {Sha256: "intel-sonoma"},
+		"x86_64_linux":  {Sha256: "linux"},
+	}}
+	tests := map[string]string{
+		"arm64_sequoia": "arm64_sonoma",
+		"arm64_tahoe":   "arm64_sonoma",
+		"arm64_sonoma":  "arm64_sonoma",
+		"arm64_ventura": "arm64_ventura",
+		"sequoia":       "sonoma",
+		"x86_64_linux":  "x86_64_linux",
+	}
+	for tag, want := range tests {
+		if _, got, ok := b.File(tag); !ok || got != want {
+			t.Errorf("File(%s) = %q, %v; want %q", tag, got, ok, want)
+		}
+	}
+	for _, tag := range []string{"arm64_monterey", "ventura", "arm64_linux"} {
+		if _, got, ok := b.File(tag); ok {
+			t.Errorf("File(%s) = %q; want no bottle", tag, got)
+		}
+	}
+}
+
+func TestBottleUnmarshal(t *testing.T) {
+	for _, data := range []string{
+		`{"stable":{"rebuild":1,"files":{"all":{"sha256":"ddd"}}}}`,
+		`{"rebuild":1,"files":{"all":{"sha256":"ddd"}}}`,
+	} {
+		var b Bottle
+		if err := json.Unmarshal([]byte(data), &b); err != nil {
+			t.Fatalf("Unmarshal(%s) failed: %v", data, err)
+		}
+		file, tag, ok := b.File("arm64_sonoma")
+		if !ok || tag != "all" || file.Sha256 != "ddd" || b.Rebuild != 1 {
+			t.Errorf("Unmarshal(%s) = %+v", data, b)
+		}
+	}
+}
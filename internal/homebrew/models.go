@@ -2,6 +2,8 @@ package homebrew
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -98,6 +100,23 @@ type BottleFile struct {
 	Sha256 string `json:"sha256"` // Sha256 is the file checksum
 }
 
+// UnmarshalJSON decodes a bottle either as the API sends it, nested under
+// "stable", or in the flat form Bottle is encoded in.
+func (b *Bottle) UnmarshalJSON(data []byte) error {
+	type flat Bottle
+	var nested struct {
+		Stable *flat `json:"stable"`
+	}
+	if err := json.Unmarshal(data, &nested); err != nil {
+		return err
+	}
+	if nested.Stable != nil {
+		*b = Bottle(*nested.Stable)
+		return nil
+	}
+	return json.Unmarshal(data, (*flat)(b))
+}
+
 // KegOnlyReason explains why a formula is keg-only.
 type KegOnlyReason struct {
 	Reason      string `json:"reason"`      // Reason is a short reason code
@@ -129,7 +148,8 @@ type InstalledInfo struct {
 	Time                  int64        `json:"time"`
 	RuntimeDependencies   []Dependency `json:"runtime_dependencies"`
 	InstalledAsDependency bool         `json:"installed_as_dependency"`
-	InstalledOnRequest    bool         `json:"installed_on_request"` // InstalledOnRequest indicates if installed explicitly
+	InstalledOnRequest    bool         `json:"installed_on_request"`     // InstalledOnRequest indicates if installed explicitly
+	VersionScheme         *int         `json:"version_scheme,omitempty"` // VersionScheme is the formula's version scheme when the keg was installed, if its receipt records it
 }
 
 // Dependency represents a formula dependency.
@@ -142,12 +162,124 @@ type Dependency struct {
 }
 
 // Service defines a background service configuration for a formula.
+// Paths and commands may contain $HOMEBREW_PREFIX and $HOME placeholders.
 type Service struct {
-	Name       string          `json:"name"`                  // Name is the service name
-	RunType    string          `json:"run_type"`              // RunType defines when the service runs
-	RunAtLoad  bool            `json:"run_at_load,omitempty"` // RunAtLoad indicates if service starts at load
-	KeepAlive  json.RawMessage `json:"keep_alive,omitempty"`  // KeepAlive can be bool or object
-	WorkingDir string          `json:"working_dir,omitempty"` // WorkingDir is the service working directory
+	Name         string            `json:"name"`                            // Name is the service name
+	Names        map[string]string `json:"names,omitempty"`                 // Names are custom service names by platform ("macos", "linux")
+	Run          json.RawMessage   `json:"run,omitempty"`                   // Run is the command, a list or a list per platform
+	RunType      string            `json:"run_type"`                        // RunType defines when the service runs
+	RunAtLoad    *bool             `json:"run_at_load,omitempty"`           // RunAtLoad indicates if service starts at load, true if nil
+	KeepAlive    json.RawMessage   `json:"keep_alive,omitempty"`            // KeepAlive can be bool or object
+	Interval     int               `json:"interval,omitempty"`              // Interval is the seconds between runs of an interval service
+	Cron         string            `json:"cron,omitempty"`                  // Cron is the schedule of a cron service
+	WorkingDir   string            `json:"working_dir,omitempty"`           // WorkingDir is the service working directory
+	RootDir      string            `json:"root_dir,omitempty"`              // RootDir is the root directory of the service
+	LogPath      string            `json:"log_path,omitempty"`              // LogPath receives the standard output
+	ErrorLogPath string            `json:"error_log_path,omitempty"`        // ErrorLogPath receives the standard error
+	Environment  map[string]string `json:"environment_variables,omitempty"` // Environment are extra environment variables
+	RequireRoot  bool              `json:"require_root,omitempty"`          // RequireRoot indicates the service must run as root
+	RestartDelay int               `json:"restart_delay,omitempty"`         // RestartDelay is the seconds to wait before a restart
+}
+
+// UnmarshalJSON decodes a service whose name is either a plain string or,
+// as the API sends custom names, an object with a name per platform.
+func (s *Service) UnmarshalJSON(data []byte) error {
+	type plain Service
+	var raw struct {
+		plain
+		Name json.RawMessage `json:"name"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*s = Service(raw.plain)
+	if len(raw.Name) == 0 || string(raw.Name) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(raw.Name, &s.Name); err == nil {
+		return nil
+	}
+	return json.Unmarshal(raw.Name, &s.Names)
+}
+
+// Command returns the command line of the service on goos, picking the
+// platform's entry when Run is given per platform. It returns nil if the
+// service has no command for goos.
+func (s *Service) Command(goos string) []string {
+	var list []string
+	if err := json.Unmarshal(s.Run, &list); err == nil {
+		return list
+	}
+	var single string
+	if err := json.Unmarshal(s.Run, &single); err == nil && single != "" {
+		return []string{single}
+	}
+	var perPlatform map[string]json.RawMessage
+	if err := json.Unmarshal(s.Run, &perPlatform); err != nil {
+		return nil
+	}
+	platform := Service{Run: perPlatform[servicePlatform(goos)]}
+	if len(platform.Run) == 0 {
+		return nil
+	}
+	return platform.Command(goos)
+}
+
+// Label returns the name the service manager on goos knows the service of
+// formula by: the custom name if the formula sets one, otherwise
+// homebrew.mxcl.<formula> for launchd and homebrew.<formula> for systemd.
+func (s *Service) Label(formula, goos string) string {
+	if name := s.Names[servicePlatform(goos)]; name != "" {
+		return name
+	}
+	if s.Name != "" {
+		return s.Name
+	}
+	if goos == "darwin" {
+		return "homebrew.mxcl." + formula
+	}
+	return "homebrew." + formula
+}
+
+// Restart returns when the service is restarted after it exits, in
+// systemd's terms: "always", "on-failure", "on-success" or "no".
+func (s *Service) Restart() string {
+	if len(s.KeepAlive) == 0 {
+		return "no"
+	}
+	var always bool
+	if err := json.Unmarshal(s.KeepAlive, &always); err == nil {
+		if always {
+			return "always"
+		}
+		return "no"
+	}
+	var policy struct {
+		Always         bool  `json:"always"`
+		Crashed        bool  `json:"crashed"`
+		SuccessfulExit *bool `json:"successful_exit"`
+	}
+	if err := json.Unmarshal(s.KeepAlive, &policy); err != nil {
+		return "no"
+	}
+	switch {
+	case policy.Always:
+		return "always"
+	case policy.SuccessfulExit != nil && *policy.SuccessfulExit:
+		return "on-success"
+	case policy.Crashed || policy.SuccessfulExit != nil:
+		return "on-failure"
+	}
+	return "no"
+}
+
+// servicePlatform returns the key the API uses for goos in per-platform
+// service fields.
+func servicePlatform(goos string) string {
+	if goos == "darwin" {
+		return "macos"
+	}
+	return "linux"
 }
 
 // GetKeepAliveBool attempts to extract a boolean value from the KeepAlive field.
@@ -176,16 +308,195 @@ type RubyChecksum struct {
 
 // SearchResult contains the results of a package search operation.
 type SearchResult struct {
-	Formulae []string `json:"formulae"` // Formulae lists matching formula names
-	Casks    []string `json:"casks"`    // Casks lists matching cask names
+	Term     string      `json:"term"`     // Term is the search term as entered
+	Formulae []SearchHit `json:"formulae"` // Formulae lists matching formulae, best first
+	Casks    []SearchHit `json:"casks"`    // Casks lists matching casks, best first
 }
 
 // InstallationStatus represents the current state of a package installation.
 // It is used to communicate progress updates from the Install method to callers.
 type InstallationStatus struct {
-	Formula   string    // Formula is the package name being installed
-	Stage     string    // Stage is one of: "downloading", "installing", "linking", "completed", "failed"
-	Progress  int       // Progress is a percentage from 0-100
-	StartTime time.Time // StartTime is when the installation began
-	Error     error     // Error contains any error that occurred
+	Formula      string             // Formula is the package name being installed
+	Stage        string             // Stage is one of: "starting", "downloading", "installing", "linking", "completed", "failed", "skipped"
+	Progress     int                // Progress is a percentage from 0-100
+	StartTime    time.Time          // StartTime is when the installation began
+	EndTime      time.Time          // EndTime is when the package completed, failed or was skipped
+	Error        error              // Error contains any error that occurred
+	Dependency   bool               // Dependency is true for a shared dependency installed ahead of the requested packages
+	Detail       string             // Detail describes the current step, e.g. the dependency being poured
+	BytesDone    int64              // BytesDone is how much of the current download has been received, if known
+	BytesTotal   int64              // BytesTotal is the size of the current download, if known
+	Dependencies []DependencyStatus // Dependencies tracks the dependencies brew installs along with the package
+	Transcript   []string           // Transcript is brew's full output, set on the final status only
+}
+
+// PackageFailure describes one package that Install did not install.
+type PackageFailure struct {
+	Package string // Package is the package name
+	Err     error  // Err is why it failed, or why it was skipped
+	Skipped bool   // Skipped is true if brew was never run for the package
+}
+
+// InstallError is returned by Install and UpgradeEach when one or more
+// packages failed to install or upgrade, or were skipped.
+type InstallError struct {
+	Failures []PackageFailure // Failures lists each package in schedule order
+	action   string           // action is the brew command that failed, "install" if empty
+}
+
+// Error summarises every failed package on one line.
+func (e *InstallError) Error() string {
+	parts := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		if f.Skipped {
+			parts[i] = fmt.Sprintf("%s: skipped: %v", f.Package, f.Err)
+		} else {
+			parts[i] = fmt.Sprintf("%s: %v", f.Package, f.Err)
+		}
+	}
+	noun := "packages"
+	if len(e.Failures) == 1 {
+		noun = "package"
+	}
+	done := "installed"
+	if e.action == "upgrade" {
+		done = "upgraded"
+	}
+	return fmt.Sprintf("%d %s not %s: %s", len(e.Failures), noun, done, strings.Join(parts, "; "))
+}
+
+// Unwrap returns the underlying error of every failure.
+func (e *InstallError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, f := range e.Failures {
+		errs[i] = f.Err
+	}
+	return errs
+}
+
+// DependencyStatus is the state of one dependency that brew installs as
+// part of a package.
+type DependencyStatus struct {
+	Name  string // Name is the dependency name
+	Stage string // Stage is one of: "pending", "downloading", "installing", "completed"
+}
+
+// Cask represents a Homebrew cask with the metadata from Homebrew's JSON API.
+type Cask struct {
+	Token              string         `json:"token"`
+	FullToken          string         `json:"full_token"`
+	OldTokens          []string       `json:"old_tokens,omitempty"`
+	Tap                string         `json:"tap"`
+	Name               []string       `json:"name"`
+	Desc               string         `json:"desc"`
+	Homepage           string         `json:"homepage"`
+	URL                string         `json:"url"`
+	Version            string         `json:"version"`
+	Sha256             string         `json:"sha256"`
+	Installed          string         `json:"installed,omitempty"`
+	InstalledTime      int64          `json:"installed_time,omitempty"`
+	Outdated           bool           `json:"outdated"`
+	Artifacts          []CaskArtifact `json:"artifacts"`
+	Caveats            string         `json:"caveats,omitempty"`
+	DependsOn          CaskDependsOn  `json:"depends_on"`
+	ConflictsWith      *CaskConflicts `json:"conflicts_with,omitempty"`
+	AutoUpdates        bool           `json:"auto_updates"`
+	Deprecated         bool           `json:"deprecated"`
+	DeprecationDate    string         `json:"deprecation_date,omitempty"`
+	DeprecationReason  string         `json:"deprecation_reason,omitempty"`
+	Disabled           bool           `json:"disabled"`
+	DisableDate        string         `json:"disable_date,omitempty"`
+	DisableReason      string         `json:"disable_reason,omitempty"`
+	TapGitHead         string         `json:"tap_git_head,omitempty"`
+	RubySourcePath     string         `json:"ruby_source_path,omitempty"`
+	RubySourceChecksum RubyChecksum   `json:"ruby_source_checksum,omitempty"`
+}
+
+// ArtifactPaths returns the string arguments of every artifact stanza of the
+// given type, such as the bundle names of "app" artifacts.
+func (c *Cask) ArtifactPaths(artifactType string) []string {
+	var paths []string
+	for _, a := range c.Artifacts {
+		if a.Type == artifactType {
+			paths = append(paths, a.Paths()...)
+		}
+	}
+	return paths
+}
+
+// CaskArtifact is a single artifact stanza such as app, binary or pkg.
+// In the API each artifact is an object with one key naming the stanza,
+// whose value lists the stanza's arguments.
+type CaskArtifact struct {
+	Type   string            // Type is the stanza name, e.g. "app" or "binary"
+	Values []json.RawMessage // Values are the raw stanza arguments
+}
+
+// UnmarshalJSON decodes a single-key artifact object.
+func (a *CaskArtifact) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	for key, value := range raw {
+		a.Type = key
+		if err := json.Unmarshal(value, &a.Values); err != nil {
+			// Some stanzas take a single argument rather than a list
+			a.Values = []json.RawMessage{value}
+		}
+	}
+
+	return nil
+}
+
+// MarshalJSON encodes the artifact back into its single-key object form.
+func (a CaskArtifact) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string][]json.RawMessage{a.Type: a.Values})
+}
+
+// Paths returns the string arguments of the artifact, skipping option objects
+// such as {"target": "..."}.
+func (a CaskArtifact) Paths() []string {
+	var paths []string
+	for _, v := range a.Values {
+		var s string
+		if err := json.Unmarshal(v, &s); err == nil {
+			paths = append(paths, s)
+		}
+	}
+	return paths
+}
+
+// CaskDependsOn lists what a cask requires to be installed.
+type CaskDependsOn struct {
+	Formula []string            `json:"formula,omitempty"` // Formula lists required formulae
+	Cask    []string            `json:"cask,omitempty"`    // Cask lists required casks
+	MacOS   map[string][]string `json:"macos,omitempty"`   // MacOS maps a comparator to macOS versions
+	Arch    json.RawMessage     `json:"arch,omitempty"`    // Arch lists supported architectures
+}
+
+// CaskConflicts lists packages a cask cannot be installed alongside.
+type CaskConflicts struct {
+	Formula []string `json:"formula,omitempty"` // Formula lists conflicting formulae
+	Cask    []string `json:"cask,omitempty"`    // Cask lists conflicting casks
+}
+
+// Package is either a formula or a cask. Exactly one of Formula and Cask is
+// set, matching Kind.
+type Package struct {
+	Kind    PackageKind `json:"kind"`              // Kind is formula or cask
+	Formula *Formula    `json:"formula,omitempty"` // Formula is set when Kind is KindFormula
+	Cask    *Cask       `json:"cask,omitempty"`    // Cask is set when Kind is KindCask
+}
+
+// Name returns the formula name or cask token.
+func (p *Package) Name() string {
+	if p.Cask != nil {
+		return p.Cask.Token
+	}
+	if p.Formula != nil {
+		return p.Formula.Name
+	}
+	return ""
 }
@@ -2,6 +2,7 @@ package homebrew
 
 import (
 	"encoding/json"
+	"errors"
 	"testing"
 	"time"
 )
@@ -137,6 +138,22 @@ func TestInstallationStatus(t *testing.T) {
 	}
 }
 
+func TestInstallError(t *testing.T) {
+	cause := errors.New("exit status 1")
+	err := &InstallError{Failures: []PackageFailure{
+		{Package: "openssl", Err: cause},
+		{Package: "curl", Err: errors.New("dependency openssl failed"), Skipped: true},
+	}}
+
+	want := "2 packages not installed: openssl: exit status 1; curl: skipped: dependency openssl failed"
+	if err.Error() != want {
+		t.Errorf("Expected %q, got %q", want, err.Error())
+	}
+	if !errors.Is(err, cause) {
+		t.Error("InstallError should unwrap to each failure's error")
+	}
+}
+
 func TestDependency(t *testing.T) {
 	jsonData := `{
 		"full_name": "openssl@3",
@@ -273,3 +290,90 @@ func TestComplexUsesFromMacos(t *testing.T) {
 		t.Errorf("Expected 3 uses_from_macos_bounds items, got %d", len(formula.UsesFromMacosBounds))
 	}
 }
+
+func TestCaskUnmarshal(t *testing.T) {
+	jsonData := `{
+		"token": "firefox",
+		"full_token": "firefox",
+		"tap": "homebrew/cask",
+		"name": ["Mozilla Firefox"],
+		"desc": "Web browser",
+		"homepage": "https://www.mozilla.org/firefox/",
+		"url": "https://download-installer.cdn.mozilla.net/firefox.dmg",
+		"version": "131.0",
+		"sha256": "abc123",
+		"installed": null,
+		"outdated": false,
+		"artifacts": [
+			{"uninstall": [{"quit": "org.mozilla.firefox"}]},
+			{"app": ["Firefox.app"]},
+			{"binary": ["$APPDIR/Firefox.app/Contents/MacOS/firefox", {"target": "firefox"}]},
+			{"zap": [{"trash": ["~/Library/Caches/Firefox"]}]}
+		],
+		"caveats": null,
+		"depends_on": {"macos": {">=": ["10.15"]}},
+		"conflicts_with": {"cask": ["firefox@beta"]},
+		"auto_updates": true,
+		"deprecated": false,
+		"disabled": false
+	}`
+
+	var cask Cask
+	if err := json.Unmarshal([]byte(jsonData), &cask); err != nil {
+		t.Fatalf("Failed to unmarshal cask: %v", err)
+	}
+
+	if cask.Token != "firefox" || cask.Version != "131.0" {
+		t.Errorf("Unexpected token/version: %s %s", cask.Token, cask.Version)
+	}
+	if !cask.AutoUpdates {
+		t.Error("Expected AutoUpdates to be true")
+	}
+	if len(cask.Artifacts) != 4 {
+		t.Fatalf("Expected 4 artifacts, got %d", len(cask.Artifacts))
+	}
+	if apps := cask.ArtifactPaths("app"); len(apps) != 1 || apps[0] != "Firefox.app" {
+		t.Errorf("Expected app Firefox.app, got %v", apps)
+	}
+	if bins := cask.ArtifactPaths("binary"); len(bins) != 1 {
+		t.Errorf("Expected binary path without target options, got %v", bins)
+	}
+	if got := cask.DependsOn.MacOS[">="]; len(got) != 1 || got[0] != "10.15" {
+		t.Errorf("Expected macOS >= 10.15, got %v", cask.DependsOn.MacOS)
+	}
+	if cask.ConflictsWith == nil || cask.ConflictsWith.Cask[0] != "firefox@beta" {
+		t.Errorf("Expected conflict with firefox@beta, got %+v", cask.ConflictsWith)
+	}
+}
+
+func TestCaskArtifactRoundTrip(t *testing.T) {
+	in := `{"pkg":["Installer.pkg"]}`
+
+	var artifact CaskArtifact
+	if err := json.Unmarshal([]byte(in), &artifact); err != nil {
+		t.Fatalf("Failed to unmarshal artifact: %v", err)
+	}
+	if artifact.Type != "pkg" {
+		t.Errorf("Expected type 'pkg', got '%s'", artifact.Type)
+	}
+
+	out, err := json.Marshal(artifact)
+	if err != nil {
+		t.Fatalf("Failed to marshal artifact: %v", err)
+	}
+	if string(out) != in {
+		t.Errorf("Expected %s, got %s", in, out)
+	}
+}
+
+func TestPackageName(t *testing.T) {
+	formula := &Package{Kind: KindFormula, Formula: &Formula{Name: "git"}}
+	cask := &Package{Kind: KindCask, Cask: &Cask{Token: "firefox"}}
+
+	if formula.Name() != "git" {
+		t.Errorf("Expected 'git', got '%s'", formula.Name())
+	}
+	if cask.Name() != "firefox" {
+		t.Errorf("Expected 'firefox', got '%s'", cask.Name())
+	}
+}
@@ -0,0 +1,168 @@
+package homebrew
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/ofkm/goobrew/internal/logger"
+	"github.com/ofkm/goobrew/internal/pkgversion"
+)
+
+// OutdatedOptions configures Outdated.
+type OutdatedOptions struct {
+	Greedy bool // Greedy also reports casks that update themselves or are versioned "latest"
+}
+
+// OutdatedPackage is an installed formula or cask with a newer version available.
+type OutdatedPackage struct {
+	Name             string          `json:"name"`                    // Name is the formula name or cask token
+	Cask             bool            `json:"cask,omitempty"`          // Cask is true for casks
+	InstalledVersion string          `json:"installed_version"`       // InstalledVersion is the newest installed version
+	LatestVersion    string          `json:"latest_version"`          // LatestVersion is the newest available version
+	Bump             pkgversion.Bump `json:"bump"`                    // Bump classifies the change between the two
+	Pinned           bool            `json:"pinned,omitempty"`        // Pinned is true if brew will not upgrade the formula
+	Homepage         string          `json:"homepage,omitempty"`      // Homepage is the project's website
+	ReleaseNotes     string          `json:"release_notes,omitempty"` // ReleaseNotes links to the project's releases, if known
+	Held             string          `json:"held,omitempty"`          // Held describes the goobrew hold blocking the upgrade, if any
+}
+
+// Outdated compares the installed formulae and casks against the API index
+// and returns those with a newer version available, formulae first, each
+// sorted by name. Formula versions are compared as package versions, taking
+// the revision and version scheme into account; a formula missing from the
+// index, such as one from a third-party tap, is compared against the version
+// recorded in its install receipt. Casks are outdated when their version differs from
+// the index. Casks that update themselves or are versioned "latest" are
+// skipped unless opts.Greedy is set. HEAD installs are never reported.
+func (c *Client) Outdated(ctx context.Context, opts OutdatedOptions) ([]OutdatedPackage, error) {
+	installed, err := c.InstalledFormulae(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	formulaIndex := make(map[string]FormulaListItem)
+	caskIndex := make(map[string]CaskListItem)
+	if formulae, casks, err := c.indexes(ctx); err == nil {
+		for _, f := range formulae {
+			formulaIndex[f.Name] = f
+		}
+		for _, cask := range casks {
+			caskIndex[cask.Token] = cask
+		}
+	} else {
+		logger.Log.Warn("comparing against local formula versions", "error", err)
+	}
+
+	outdated := []OutdatedPackage{}
+	for _, f := range installed {
+		latest := pkgversion.NewPkgVersion(f.Versions.Stable, f.Revision, f.VersionScheme)
+		homepage, source := f.Homepage, f.Urls.Stable.URL
+		if item, ok := formulaIndex[f.Name]; ok && item.Versions.Stable != "" {
+			latest = pkgversion.NewPkgVersion(item.Versions.Stable, item.Revision, item.VersionScheme)
+			homepage, source = item.Homepage, item.Urls.Stable.URL
+		}
+		current, ok := newestInstalled(f)
+		if !ok || latest.Version.String() == "" || current.Compare(latest) >= 0 {
+			continue
+		}
+
+		outdated = append(outdated, OutdatedPackage{
+			Name:             f.Name,
+			InstalledVersion: current.String(),
+			LatestVersion:    latest.String(),
+			Bump:             pkgversion.Classify(current, latest),
+			Pinned:           f.Pinned,
+			Homepage:         homepage,
+			ReleaseNotes:     ReleaseNotesURL(source, homepage),
+		})
+	}
+
+	casks, err := c.installedCaskVersions(ctx)
+	if err != nil {
+		logger.Log.Debug("could not list installed casks", "error", err)
+	}
+	var outdatedCasks []OutdatedPackage
+	for token, versions := range casks {
+		item, ok := caskIndex[token]
+		if !ok || item.Version == "" {
+			continue
+		}
+		if !opts.Greedy && (item.AutoUpdates || item.Version == "latest") {
+			continue
+		}
+
+		fields := strings.Fields(versions)
+		if len(fields) == 0 || fields[len(fields)-1] == item.Version {
+			continue
+		}
+		current := fields[len(fields)-1]
+
+		outdatedCasks = append(outdatedCasks, OutdatedPackage{
+			Name:             token,
+			Cask:             true,
+			InstalledVersion: current,
+			LatestVersion:    item.Version,
+			Bump:             pkgversion.Classify(caskVersion(current), caskVersion(item.Version)),
+			Homepage:         item.Homepage,
+			ReleaseNotes:     ReleaseNotesURL(item.URL, item.Homepage),
+		})
+	}
+
+	sort.Slice(outdated, func(i, j int) bool { return outdated[i].Name < outdated[j].Name })
+	sort.Slice(outdatedCasks, func(i, j int) bool { return outdatedCasks[i].Name < outdatedCasks[j].Name })
+	return append(outdated, outdatedCasks...), nil
+}
+
+// newestInstalled returns the newest installed version of f, ignoring HEAD
+// installs. Each keg is compared under the version scheme its install
+// receipt records, so that a keg from before a scheme bump is older than
+// any version under the new scheme. Kegs without one, as reported by brew
+// info, are taken to use the formula's current scheme.
+func newestInstalled(f Formula) (pkgversion.PkgVersion, bool) {
+	var (
+		newest pkgversion.PkgVersion
+		found  bool
+	)
+	for _, info := range f.Installed {
+		if strings.HasPrefix(info.Version, "HEAD") {
+			continue
+		}
+		v := pkgversion.ParsePkgVersion(info.Version)
+		v.Scheme = f.VersionScheme
+		if info.VersionScheme != nil {
+			v.Scheme = *info.VersionScheme
+		}
+		if !found || v.Compare(newest) > 0 {
+			newest, found = v, true
+		}
+	}
+	return newest, found
+}
+
+// caskVersion parses the version part of a cask version such as
+// "119.0,20231024", dropping the build identifier after the comma.
+func caskVersion(s string) pkgversion.PkgVersion {
+	version, _, _ := strings.Cut(s, ",")
+	return pkgversion.NewPkgVersion(version, 0, 0)
+}
+
+// ReleaseNotesURL returns the releases page of the first GitHub repository
+// found among urls, such as a formula's source URL or homepage, or "" if
+// none of them points to GitHub.
+func ReleaseNotesURL(urls ...string) string {
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Host != "github.com" && u.Host != "www.github.com") {
+			continue
+		}
+		parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+		if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+			continue
+		}
+		repo := strings.TrimSuffix(parts[1], ".git")
+		return "https://github.com/" + parts[0] + "/" + repo + "/releases"
+	}
+	return ""
+}
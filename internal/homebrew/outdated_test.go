@@ -0,0 +1,131 @@
+package homebrew
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ofkm/goobrew/internal/pkgversion"
+)
+
+func TestOutdated(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/formula.json":
+			_, _ = w.Write([]byte(`[
+ {"name":"node","versions":{"stable":"22.1.0"}},
+ {"name":"jq","versions":{"stable":"1.7.1"}},
+ {"name":"openssl@3","versions":{"stable":"3.4.0"},"revision":1},
+ {"name":"git","versions":{"stable":"2.47.0"}},
+ {"name":"neovim","versions":{"stable":"0.10.2"}}
+]`))
+		case "/cask.json":
+			_, _ = w.Write([]byte(`[
+ {"token":"firefox","version":"120.0","auto_updates":true},
+ {"token":"iterm2","version":"3.5.4"},
+ {"token":"obsidian","version":"1.7.4,abc"}
+]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	brew := writeFakeBrew(t, `
+case "$1" in
+info) echo '[
+ {"name":"node","installed":[{"version":"20.11.0"}]},
+ {"name":"jq","installed":[{"version":"1.6"},{"version":"1.7"}]},
+ {"name":"openssl@3","pinned":true,"installed":[{"version":"3.4.0"}]},
+ {"name":"git","installed":[{"version":"2.47.0"}]},
+ {"name":"neovim","installed":[{"version":"HEAD-abc1234"}]},
+ {"name":"mytool","versions":{"stable":"2.0"},"installed":[{"version":"1.9"}]}
+]' ;;
+list) echo 'firefox 119.0' ; echo 'iterm2 3.5.4' ; echo 'obsidian 1.7.3,abc' ;;
+esac`)
+	client := &Client{httpClient: &http.Client{Timeout: 5 * time.Second}, brewPath: brew}
+	WithAPIEndpoints(server.URL)(client)
+
+	outdated, err := client.Outdated(context.Background(), OutdatedOptions{})
+	if err != nil {
+		t.Fatalf("Outdated failed: %v", err)
+	}
+
+	want := []OutdatedPackage{
+		{Name: "jq", InstalledVersion: "1.7", LatestVersion: "1.7.1", Bump: pkgversion.BumpPatch},
+		{Name: "mytool", InstalledVersion: "1.9", LatestVersion: "2.0", Bump: pkgversion.BumpMajor},
+		{Name: "node", InstalledVersion: "20.11.0", LatestVersion: "22.1.0", Bump: pkgversion.BumpMajor},
+		{Name: "openssl@3", InstalledVersion: "3.4.0", LatestVersion: "3.4.0_1", Bump: pkgversion.BumpRevision, Pinned: true},
+		{Name: "obsidian", Cask: true, InstalledVersion: "1.7.3,abc", LatestVersion: "1.7.4,abc", Bump: pkgversion.BumpPatch},
+	}
+	if len(outdated) != len(want) {
+		t.Fatalf("Expected %d outdated packages, got %+v", len(want), outdated)
+	}
+	for i := range want {
+		if outdated[i] != want[i] {
+			t.Errorf("Expected %+v, got %+v", want[i], outdated[i])
+		}
+	}
+
+	outdated, err = client.Outdated(context.Background(), OutdatedOptions{Greedy: true})
+	if err != nil {
+		t.Fatalf("Outdated failed: %v", err)
+	}
+	if last := outdated[len(outdated)-2]; last.Name != "firefox" || last.Bump != pkgversion.BumpMajor {
+		t.Errorf("Expected --greedy to include the self-updating firefox cask, got %+v", outdated)
+	}
+}
+
+func TestReleaseNotesURL(t *testing.T) {
+	tests := []struct {
+		urls []string
+		want string
+	}{
+		{[]string{"https://github.com/jqlang/jq/releases/download/jq-1.7.1/jq-1.7.1.tar.gz"}, "https://github.com/jqlang/jq/releases"},
+		{[]string{"https://nodejs.org/dist/v22.1.0/node-v22.1.0.tar.xz", "https://github.com/nodejs/node.git"}, "https://github.com/nodejs/node/releases"},
+		{[]string{"https://www.gnu.org/software/wget/", "https://github.com/"}, ""},
+		{nil, ""},
+	}
+
+	for _, tt := range tests {
+		if got := ReleaseNotesURL(tt.urls...); got != tt.want {
+			t.Errorf("ReleaseNotesURL(%q) = %q, want %q", tt.urls, got, tt.want)
+		}
+	}
+}
+
+func TestOutdated_VersionSchemeBump(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/formula.json":
+			_, _ = w.Write([]byte(`[
+ {"name":"fmt","versions":{"stable":"1.0"},"version_scheme":1},
+ {"name":"jq","versions":{"stable":"1.7.1"},"version_scheme":1}
+]`))
+		case "/cask.json":
+			_, _ = w.Write([]byte(`[]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	// fmt went from 2024.1 down to 1.0 when its versioning restarted
+	client := &Client{httpClient: &http.Client{Timeout: 5 * time.Second}}
+	WithAPIEndpoints(server.URL)(client)
+	WithPaths(writeCellar(t, map[string]string{
+		"Cellar/fmt/2024.1/INSTALL_RECEIPT.json": `{"source":{"versions":{"stable":"2024.1","version_scheme":0}}}`,
+		"Cellar/jq/1.7.1/INSTALL_RECEIPT.json":   `{"source":{"versions":{"stable":"1.7.1","version_scheme":1}}}`,
+	}))(client)
+
+	outdated, err := client.Outdated(context.Background(), OutdatedOptions{})
+	if err != nil {
+		t.Fatalf("Outdated failed: %v", err)
+	}
+	want := OutdatedPackage{Name: "fmt", InstalledVersion: "2024.1", LatestVersion: "1.0", Bump: pkgversion.BumpMajor}
+	if len(outdated) != 1 || outdated[0] != want {
+		t.Errorf("Expected %+v, got %+v", want, outdated)
+	}
+}
@@ -0,0 +1,564 @@
+package homebrew
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ofkm/goobrew/internal/logger"
+	"github.com/ofkm/goobrew/internal/pkgversion"
+)
+
+// PlanAction is what an install plan does with a package.
+type PlanAction string
+
+// Plan actions.
+const (
+	PlanInstall PlanAction = "install" // PlanInstall installs a package that is not installed
+	PlanUpgrade PlanAction = "upgrade" // PlanUpgrade upgrades an installed package that is outdated
+)
+
+// sizeLookups is how many bottle sizes Plan looks up at once.
+const sizeLookups = 8
+
+// PlanStep is one package an install plan installs or upgrades.
+type PlanStep struct {
+	Name             string      `json:"name"`                        // Name is the formula name or cask token
+	Kind             PackageKind `json:"kind"`                        // Kind is formula or cask
+	Action           PlanAction  `json:"action"`                      // Action is install or upgrade
+	Version          string      `json:"version,omitempty"`           // Version is the version that will be installed
+	InstalledVersion string      `json:"installed_version,omitempty"` // InstalledVersion is the version being upgraded
+	Requested        bool        `json:"requested"`                   // Requested is true for packages named by the user
+	FromSource       bool        `json:"from_source,omitempty"`       // FromSource is true if there is no bottle for the platform
+	DownloadBytes    int64       `json:"download_bytes"`              // DownloadBytes is the bottle size, or -1 if unknown
+	Deprecated       bool        `json:"deprecated,omitempty"`        // Deprecated is true if the package is deprecated
+	Disabled         bool        `json:"disabled,omitempty"`          // Disabled is true if the package can no longer be installed
+	Reason           string      `json:"reason,omitempty"`            // Reason explains the deprecation or disable
+
+	bottleURL string
+}
+
+// PlanConflict is a formula in an install plan that cannot be installed
+// alongside another installed or planned formula.
+type PlanConflict struct {
+	Formula       string `json:"formula"`          // Formula is the planned formula
+	ConflictsWith string `json:"conflicts_with"`   // ConflictsWith is the formula it clashes with
+	Reason        string `json:"reason,omitempty"` // Reason explains the conflict
+}
+
+// String describes the conflict, such as "a conflicts with b (both install bin/x)".
+func (c PlanConflict) String() string {
+	s := c.Formula + " conflicts with " + c.ConflictsWith
+	if c.Reason != "" {
+		s += " (" + c.Reason + ")"
+	}
+	return s
+}
+
+// UnmetRequirement is a system requirement of a planned formula that the
+// platform does not meet.
+type UnmetRequirement struct {
+	Formula     string `json:"formula"`           // Formula is the planned formula
+	Requirement string `json:"requirement"`       // Requirement is the requirement's name, such as "macos"
+	Version     string `json:"version,omitempty"` // Version is the required version or architecture
+}
+
+// String describes the requirement, such as "wget requires macos ventura".
+func (r UnmetRequirement) String() string {
+	s := r.Formula + " requires " + r.Requirement
+	if r.Version != "" {
+		s += " " + r.Version
+	}
+	return s
+}
+
+// InstallPlan is everything installing some packages would change, as
+// computed by Plan.
+type InstallPlan struct {
+	Tag           string             `json:"tag"`                     // Tag is the bottle tag the plan was made for
+	Steps         []PlanStep         `json:"steps"`                   // Steps are in install order, dependencies first
+	Installed     []string           `json:"installed,omitempty"`     // Installed are requested packages that are already up to date
+	Unknown       []string           `json:"unknown,omitempty"`       // Unknown are packages the API index does not know, left to brew
+	Conflicts     []PlanConflict     `json:"conflicts,omitempty"`     // Conflicts would make brew refuse the install
+	Unmet         []UnmetRequirement `json:"unmet,omitempty"`         // Unmet are requirements the platform does not meet
+	DownloadBytes int64              `json:"download_bytes"`          // DownloadBytes is the total size of the bottles with a known size
+	UnknownSizes  int                `json:"unknown_sizes,omitempty"` // UnknownSizes counts steps whose download size is unknown
+}
+
+// Blocked reports whether the plan has conflicts, unmet requirements or
+// disabled packages that would make the install fail.
+func (p *InstallPlan) Blocked() bool {
+	return len(p.Conflicts) > 0 || len(p.Unmet) > 0 || len(p.Disabled()) > 0
+}
+
+// Disabled returns the steps for packages that can no longer be installed.
+func (p *InstallPlan) Disabled() []PlanStep {
+	var steps []PlanStep
+	for _, step := range p.Steps {
+		if step.Disabled {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// Deprecated returns the steps for packages that are deprecated but can
+// still be installed.
+func (p *InstallPlan) Deprecated() []PlanStep {
+	var steps []PlanStep
+	for _, step := range p.Steps {
+		if step.Deprecated && !step.Disabled {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// PlanOption configures a single Plan call.
+type PlanOption func(*planConfig)
+
+// planConfig holds the settings applied by PlanOptions.
+type planConfig struct {
+	tag string
+}
+
+// WithPlanTag plans for the bottle tag of another platform, such as
+// "arm64_sonoma", instead of the running one.
+func WithPlanTag(tag string) PlanOption {
+	return func(cfg *planConfig) {
+		cfg.tag = tag
+	}
+}
+
+// Plan computes what installing packages would do, without changing
+// anything. It walks the runtime and recommended dependencies, the
+// dependencies macOS does not provide on the planned platform, and the
+// platform requirements of each formula in the API index; subtracts
+// formulae that are installed and up to date or pinned; and orders the
+// rest so that every dependency comes before the formulae that need it.
+// Deprecated and disabled packages are marked with the reason.
+// Outdated dependencies are planned as upgrades, since brew upgrades them
+// along with the packages that need them. Conflicts with installed or
+// planned formulae are reported, and the download size of each bottle is
+// asked of its registry. Packages in neither index, such as formulae from
+// third-party taps, are listed as unknown and left to brew. Returns an
+// error if the index cannot be loaded or the dependencies form a cycle.
+func (c *Client) Plan(ctx context.Context, packages []string, opts ...PlanOption) (*InstallPlan, error) {
+	var cfg planConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.tag == "" {
+		tag, err := BottleTag(ctx)
+		if err != nil {
+			return nil, err
+		}
+		cfg.tag = tag
+	}
+
+	formulae, casks, err := c.indexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p := &planner{
+		plan:     &InstallPlan{Tag: cfg.tag, Steps: []PlanStep{}},
+		platform: tagPlatform(cfg.tag),
+		formulae: make(map[string]*FormulaListItem, len(formulae)),
+		casks:    make(map[string]CaskListItem, len(casks)),
+		state:    make(map[string]int),
+	}
+	for i := range formulae {
+		f := &formulae[i]
+		for _, name := range append(append([]string{f.Name}, f.Aliases...), f.OldNames...) {
+			if _, ok := p.formulae[name]; !ok || name == f.Name {
+				p.formulae[name] = f
+			}
+		}
+	}
+	for _, cask := range casks {
+		p.casks[cask.Token] = cask
+	}
+
+	if p.installed, err = c.InstalledFormulae(ctx); err != nil {
+		logger.Log.Debug("planning as if no formulae were installed", "error", err)
+	}
+	if p.installedCasks, err = c.installedCaskVersions(ctx); err != nil {
+		logger.Log.Debug("planning as if no casks were installed", "error", err)
+	}
+
+	for _, name := range packages {
+		if err := p.visit(name, true); err != nil {
+			return nil, err
+		}
+	}
+	p.findConflicts()
+	c.measureDownloads(ctx, p.plan)
+	return p.plan, nil
+}
+
+// planner holds the state of a single Plan call.
+type planner struct {
+	plan           *InstallPlan
+	platform       platform
+	formulae       map[string]*FormulaListItem // formulae holds the index by name, alias and old name
+	casks          map[string]CaskListItem
+	installed      []Formula
+	installedCasks map[string]string
+	state          map[string]int // state is 1 while a formula's dependencies are visited, 2 after
+	stack          []string
+}
+
+// visit adds name and the dependencies it needs to the plan, after them.
+func (p *planner) visit(name string, requested bool) error {
+	item, ok := p.formulae[name]
+	if !ok {
+		return p.visitCask(name, requested)
+	}
+	name = item.Name
+
+	switch p.state[name] {
+	case 1:
+		start := slices.Index(p.stack, name)
+		return fmt.Errorf("dependency cycle: %s", strings.Join(append(slices.Clone(p.stack[start:]), name), " -> "))
+	case 2:
+		if requested {
+			p.markRequested(name)
+		}
+		return nil
+	}
+	p.state[name] = 1
+	p.stack = append(p.stack, name)
+	defer func() {
+		p.state[name] = 2
+		p.stack = p.stack[:len(p.stack)-1]
+	}()
+
+	step := PlanStep{
+		Name:          name,
+		Kind:          KindFormula,
+		Action:        PlanInstall,
+		Requested:     requested,
+		DownloadBytes: -1,
+		Deprecated:    item.Deprecated,
+		Disabled:      item.Disabled,
+		Reason:        lifecycleReason(item.Disabled, item.DisableReason, item.DeprecationReason),
+	}
+	latest := pkgversion.NewPkgVersion(item.Versions.Stable, item.Revision, item.VersionScheme)
+	step.Version = latest.String()
+	if f := p.installedFormula(name); f != nil {
+		current, ok := newestInstalled(*f)
+		if !ok || f.Pinned || current.Compare(latest) >= 0 {
+			if requested {
+				p.plan.Installed = append(p.plan.Installed, name)
+			}
+			return nil
+		}
+		step.Action = PlanUpgrade
+		step.InstalledVersion = current.String()
+	}
+
+	for _, req := range item.Requirements {
+		if !p.platform.meets(req) {
+			p.plan.Unmet = append(p.plan.Unmet, UnmetRequirement{Formula: name, Requirement: req.Name, Version: req.Version})
+		}
+	}
+	for _, dep := range p.platform.dependencies(item) {
+		if _, ok := p.formulae[dep]; !ok {
+			if !slices.Contains(p.plan.Unknown, dep) {
+				p.plan.Unknown = append(p.plan.Unknown, dep)
+			}
+			continue
+		}
+		if err := p.visit(dep, false); err != nil {
+			return err
+		}
+	}
+
+	if file, _, ok := item.Bottle.File(p.plan.Tag); ok {
+		step.bottleURL = file.URL
+		if step.bottleURL == "" && item.Bottle.RootURL != "" {
+			step.bottleURL = bottleURL(item.Bottle.RootURL, name, file.Sha256)
+		}
+	} else {
+		step.FromSource = true
+	}
+	p.plan.Steps = append(p.plan.Steps, step)
+	return nil
+}
+
+// visitCask adds a requested cask to the plan, or lists name as unknown if
+// there is no such cask either. Casks' dependencies are left to brew.
+func (p *planner) visitCask(name string, requested bool) error {
+	cask, ok := p.casks[name]
+	if !ok || !requested {
+		if !slices.Contains(p.plan.Unknown, name) {
+			p.plan.Unknown = append(p.plan.Unknown, name)
+		}
+		return nil
+	}
+	if _, ok := p.installedCasks[name]; ok {
+		if !slices.Contains(p.plan.Installed, name) {
+			p.plan.Installed = append(p.plan.Installed, name)
+		}
+		return nil
+	}
+	for _, step := range p.plan.Steps {
+		if step.Kind == KindCask && step.Name == name {
+			return nil
+		}
+	}
+	p.plan.Steps = append(p.plan.Steps, PlanStep{
+		Name:          name,
+		Kind:          KindCask,
+		Action:        PlanInstall,
+		Version:       cask.Version,
+		Requested:     true,
+		DownloadBytes: -1,
+		Deprecated:    cask.Deprecated,
+		Disabled:      cask.Disabled,
+		Reason:        lifecycleReason(cask.Disabled, cask.DisableReason, cask.DeprecationReason),
+	})
+	return nil
+}
+
+// lifecycleReason returns the text for why a package is disabled, or
+// deprecated if it is not.
+func lifecycleReason(disabled bool, disableReason, deprecationReason string) string {
+	if disabled && disableReason != "" {
+		return DeprecationMessage(disableReason)
+	}
+	return DeprecationMessage(deprecationReason)
+}
+
+// markRequested marks an already planned formula as requested, or lists
+// it as installed if the plan leaves it alone.
+func (p *planner) markRequested(name string) {
+	for i := range p.plan.Steps {
+		if p.plan.Steps[i].Kind == KindFormula && p.plan.Steps[i].Name == name {
+			p.plan.Steps[i].Requested = true
+			return
+		}
+	}
+	if !slices.Contains(p.plan.Installed, name) {
+		p.plan.Installed = append(p.plan.Installed, name)
+	}
+}
+
+// installedFormula returns the installed formula called name, or nil.
+func (p *planner) installedFormula(name string) *Formula {
+	for i := range p.installed {
+		if p.installed[i].Name == name && len(p.installed[i].Installed) > 0 {
+			return &p.installed[i]
+		}
+	}
+	return nil
+}
+
+// findConflicts records every planned formula that conflicts with an
+// installed formula or with another planned one. A conflict between two
+// planned formulae is reported once.
+func (p *planner) findConflicts() {
+	planned := make(map[string]bool, len(p.plan.Steps))
+	for _, step := range p.plan.Steps {
+		if step.Kind == KindFormula {
+			planned[step.Name] = true
+		}
+	}
+
+	reported := make(map[[2]string]bool)
+	for _, step := range p.plan.Steps {
+		if step.Kind != KindFormula {
+			continue
+		}
+		item := p.formulae[step.Name]
+		for i, other := range item.ConflictsWith {
+			if other == step.Name || !planned[other] && p.installedFormula(other) == nil {
+				continue
+			}
+			if reported[[2]string{other, step.Name}] {
+				continue
+			}
+			reported[[2]string{step.Name, other}] = true
+
+			conflict := PlanConflict{Formula: step.Name, ConflictsWith: other}
+			if i < len(item.ConflictsWithReasons) {
+				conflict.Reason = item.ConflictsWithReasons[i]
+			}
+			p.plan.Conflicts = append(p.plan.Conflicts, conflict)
+		}
+	}
+}
+
+// measureDownloads asks the registry for the size of every planned bottle,
+// a few at a time, and totals them. A size that cannot be determined is
+// left at -1 and counted in UnknownSizes.
+func (c *Client) measureDownloads(ctx context.Context, plan *InstallPlan) {
+	var (
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, sizeLookups)
+	)
+	for i := range plan.Steps {
+		step := &plan.Steps[i]
+		if step.bottleURL == "" {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			size, err := c.bottleSize(ctx, step.bottleURL)
+			if err != nil {
+				logger.Log.Debug("could not determine bottle size", "formula", step.Name, "error", err)
+				return
+			}
+			step.DownloadBytes = size
+		}()
+	}
+	wg.Wait()
+
+	for _, step := range plan.Steps {
+		if step.DownloadBytes < 0 {
+			plan.UnknownSizes++
+		} else {
+			plan.DownloadBytes += step.DownloadBytes
+		}
+	}
+}
+
+// bottleSize returns the size of the bottle at url without downloading it.
+func (c *Client) bottleSize(ctx context.Context, url string) (int64, error) {
+	resp, err := c.registryRequest(ctx, http.MethodHead, url, 0)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+	if resp.ContentLength < 0 {
+		return 0, fmt.Errorf("server did not report the size")
+	}
+	return resp.ContentLength, nil
+}
+
+// platform is the system an install is planned for, as described by its
+// bottle tag.
+type platform struct {
+	os    string // os is "darwin" or "linux"
+	arch  string // arch is "arm64" or "x86_64"
+	macOS int    // macOS is the major macOS version, or 0 on Linux
+}
+
+// tagPlatform returns the platform a bottle tag such as "arm64_sonoma" or
+// "x86_64_linux" is for.
+func tagPlatform(tag string) platform {
+	arch, name := "x86_64", tag
+	for _, prefix := range []string{"arm64", "x86_64"} {
+		if rest, ok := strings.CutPrefix(tag, prefix+"_"); ok {
+			arch, name = prefix, rest
+		}
+	}
+	if name == "linux" {
+		return platform{os: "linux", arch: arch}
+	}
+	return platform{os: "darwin", arch: arch, macOS: macOSMajor(name)}
+}
+
+// macOSMajor returns the major version of a macOS release given by version
+// number or named the way Homebrew names it, such as 14 for "sonoma", or 0
+// if it is unknown, which is taken to mean older than any release in
+// macOSCodenames.
+func macOSMajor(name string) int {
+	major, _, _ := strings.Cut(name, ".")
+	if n, err := strconv.Atoi(major); err == nil {
+		return n
+	}
+	for major, codename := range macOSCodenames {
+		if codename == name {
+			n, _ := strconv.Atoi(major)
+			return n
+		}
+	}
+	return 0
+}
+
+// dependencies returns the runtime and recommended dependencies of f on
+// the platform. On Linux that includes every dependency macOS provides; on
+// macOS only those the running release does not provide yet.
+func (pl platform) dependencies(f *FormulaListItem) []string {
+	deps := append(append([]string{}, f.Dependencies...), f.RecommendedDeps...)
+	for i, raw := range f.UsesFromMacos {
+		name, runtime := usesFromMacos(raw)
+		if name == "" || !runtime {
+			continue
+		}
+		if pl.os == "darwin" {
+			since := ""
+			if i < len(f.UsesFromMacosBounds) {
+				since = f.UsesFromMacosBounds[i]["since"]
+			}
+			if since == "" || pl.macOS >= macOSMajor(since) {
+				continue
+			}
+		}
+		if !slices.Contains(deps, name) {
+			deps = append(deps, name)
+		}
+	}
+	return deps
+}
+
+// usesFromMacos decodes a uses_from_macos entry, which is either a name or
+// an object mapping the name to the kinds of dependency it is, and reports
+// whether it is needed at runtime.
+func usesFromMacos(raw json.RawMessage) (string, bool) {
+	var name string
+	if err := json.Unmarshal(raw, &name); err == nil {
+		return name, true
+	}
+	var entry map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return "", false
+	}
+	for name, kinds := range entry {
+		var list []string
+		if err := json.Unmarshal(kinds, &list); err != nil {
+			var kind string
+			_ = json.Unmarshal(kinds, &kind)
+			list = []string{kind}
+		}
+		for _, kind := range list {
+			if kind != string(DepBuild) && kind != string(DepTest) {
+				return name, true
+			}
+		}
+		return name, false
+	}
+	return "", false
+}
+
+// meets reports whether the platform meets a requirement. Only operating
+// system and architecture requirements are checked, and requirements that
+// only apply when building or testing are ignored.
+func (pl platform) meets(req Requirement) bool {
+	if len(req.Contexts) > 0 && !slices.ContainsFunc(req.Contexts, func(c string) bool {
+		return c != string(DepBuild) && c != string(DepTest)
+	}) {
+		return true
+	}
+	switch req.Name {
+	case "macos":
+		return pl.os == "darwin" && (req.Version == "" || pl.macOS >= macOSMajor(req.Version))
+	case "linux":
+		return pl.os == "linux"
+	case "arch":
+		return req.Version == "" || req.Version == pl.arch
+	}
+	return true
+}
@@ -0,0 +1,172 @@
+package homebrew
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// planIndex is a formula index for the plan tests. Bottle URLs are
+// relative to the test server, which substitutes its address for @@URL@@.
+const planIndex = `[
+ {"name":"jq","versions":{"stable":"1.7.1"},"dependencies":["oniguruma"],"conflicts_with":["jaq"],"conflicts_with_reasons":["both install bin/jq"],
+  "bottle":{"stable":{"files":{"arm64_sonoma":{"url":"@@URL@@/v2/homebrew/core/jq/blobs/sha256:aa","sha256":"aa"},"x86_64_linux":{"url":"@@URL@@/v2/homebrew/core/jq/blobs/sha256:bb","sha256":"bb"}}}}},
+ {"name":"oniguruma","versions":{"stable":"6.9.9"},
+  "bottle":{"stable":{"files":{"all":{"url":"@@URL@@/v2/homebrew/core/oniguruma/blobs/sha256:cc","sha256":"cc"}}}}},
+ {"name":"curl","aliases":["curl-openssl"],"versions":{"stable":"8.10.1"},"dependencies":["openssl@3"],"uses_from_macos":["zlib",{"pkgconf":"build"}],"uses_from_macos_bounds":[{"since":"sonoma"},{}],
+  "bottle":{"stable":{"files":{"x86_64_linux":{"url":"@@URL@@/v2/homebrew/core/curl/blobs/sha256:dd","sha256":"dd"}}}}},
+ {"name":"openssl@3","versions":{"stable":"3.4.0"},"revision":1},
+ {"name":"zlib","versions":{"stable":"1.3.1"}},
+ {"name":"pkgconf","versions":{"stable":"2.3.0"}},
+ {"name":"jaq","versions":{"stable":"2.0.0"}},
+ {"name":"mas","versions":{"stable":"1.8.7"},"requirements":[{"name":"macos","version":"ventura","contexts":[]}]},
+ {"name":"chicken","versions":{"stable":"1"},"dependencies":["egg"]},
+ {"name":"egg","versions":{"stable":"1"},"dependencies":["chicken"]},
+ {"name":"tapdep","versions":{"stable":"1"},"dependencies":["me/tools/helper"]},
+ {"name":"node@18","versions":{"stable":"18.20.4"},"deprecated":true,"deprecation_reason":"unsupported"},
+ {"name":"python@3.8","versions":{"stable":"3.8.20"},"deprecated":true,"disabled":true,"disable_reason":"Use python@3.12"}
+]`
+
+func newPlanClient(t *testing.T, files map[string]string) *Client {
+	t.Helper()
+	var url string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/formula.json":
+			_, _ = w.Write([]byte(strings.ReplaceAll(planIndex, "@@URL@@", url)))
+		case r.URL.Path == "/cask.json":
+			_, _ = w.Write([]byte(`[{"token":"iterm2","version":"3.5.4"},{"token":"firefox","version":"120.0"}]`))
+		case r.Method == http.MethodHead && strings.HasSuffix(r.URL.Path, "sha256:aa"):
+			w.Header().Set("Content-Length", "2048")
+		case r.Method == http.MethodHead && strings.HasSuffix(r.URL.Path, "sha256:cc"):
+			w.Header().Set("Content-Length", "1024")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	url = server.URL
+
+	client := &Client{httpClient: &http.Client{Timeout: 5 * time.Second}}
+	WithAPIEndpoints(server.URL)(client)
+	WithPaths(writeCellar(t, files))(client)
+	return client
+}
+
+func TestPlan(t *testing.T) {
+	client := newPlanClient(t, map[string]string{
+		"Cellar/oniguruma/6.9.8/INSTALL_RECEIPT.json":      `{}`,
+		"Cellar/jaq/2.0.0/INSTALL_RECEIPT.json":            `{}`,
+		"Caskroom/firefox/.metadata/120.0/20240101/x.json": "",
+	})
+
+	plan, err := client.Plan(context.Background(), []string{"jq", "iterm2", "firefox", "me/tools/thing"}, WithPlanTag("arm64_sonoma"))
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	var got []string
+	for _, step := range plan.Steps {
+		got = append(got, string(step.Action)+" "+step.Name)
+	}
+	if want := "upgrade oniguruma, install jq, install iterm2"; strings.Join(got, ", ") != want {
+		t.Errorf("Expected steps %q, got %q", want, strings.Join(got, ", "))
+	}
+	if s := plan.Steps[0]; s.InstalledVersion != "6.9.8" || s.Version != "6.9.9" || s.Requested || s.DownloadBytes != 1024 {
+		t.Errorf("Unexpected oniguruma step: %+v", s)
+	}
+	if s := plan.Steps[1]; !s.Requested || s.DownloadBytes != 2048 || s.FromSource {
+		t.Errorf("Unexpected jq step: %+v", s)
+	}
+	if s := plan.Steps[2]; s.Kind != KindCask || s.DownloadBytes != -1 {
+		t.Errorf("Unexpected iterm2 step: %+v", s)
+	}
+	if plan.DownloadBytes != 3072 || plan.UnknownSizes != 1 {
+		t.Errorf("Expected 3072 bytes and one unknown size, got %d and %d", plan.DownloadBytes, plan.UnknownSizes)
+	}
+	if strings.Join(plan.Installed, ",") != "firefox" || strings.Join(plan.Unknown, ",") != "me/tools/thing" {
+		t.Errorf("Unexpected installed %v or unknown %v", plan.Installed, plan.Unknown)
+	}
+	if len(plan.Conflicts) != 1 || plan.Conflicts[0].String() != "jq conflicts with jaq (both install bin/jq)" || !plan.Blocked() {
+		t.Errorf("Expected the jaq conflict, got %v", plan.Conflicts)
+	}
+}
+
+func TestPlanPlatforms(t *testing.T) {
+	client := newPlanClient(t, nil)
+
+	tests := []struct {
+		tag   string
+		want  string
+		unmet string
+	}{
+		{tag: "x86_64_linux", want: "openssl@3 zlib curl mas", unmet: "mas requires macos ventura"},
+		{tag: "arm64_sonoma", want: "openssl@3 curl mas"},
+		{tag: "ventura", want: "openssl@3 zlib curl mas"},
+		{tag: "monterey", want: "openssl@3 zlib curl mas", unmet: "mas requires macos ventura"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			plan, err := client.Plan(context.Background(), []string{"curl-openssl", "mas"}, WithPlanTag(tt.tag))
+			if err != nil {
+				t.Fatalf("Plan failed: %v", err)
+			}
+			var got []string
+			for _, step := range plan.Steps {
+				got = append(got, step.Name)
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, strings.Join(got, " "))
+			}
+			var unmet []string
+			for _, r := range plan.Unmet {
+				unmet = append(unmet, r.String())
+			}
+			if strings.Join(unmet, ", ") != tt.unmet {
+				t.Errorf("Expected unmet %q, got %q", tt.unmet, unmet)
+			}
+			if curl := plan.Steps[len(plan.Steps)-2]; curl.FromSource != (tt.tag != "x86_64_linux") {
+				t.Errorf("Unexpected FromSource for curl on %s", tt.tag)
+			}
+		})
+	}
+}
+
+func TestPlanErrors(t *testing.T) {
+	client := newPlanClient(t, nil)
+
+	if _, err := client.Plan(context.Background(), []string{"chicken"}, WithPlanTag("x86_64_linux")); err == nil ||
+		!strings.Contains(err.Error(), "chicken -> egg -> chicken") {
+		t.Errorf("Expected a dependency cycle error, got %v", err)
+	}
+
+	plan, err := client.Plan(context.Background(), []string{"tapdep"}, WithPlanTag("x86_64_linux"))
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(plan.Steps) != 1 || strings.Join(plan.Unknown, ",") != "me/tools/helper" {
+		t.Errorf("Expected the tap dependency to be left to brew, got %+v", plan)
+	}
+}
+
+func TestPlanLifecycle(t *testing.T) {
+	client := newPlanClient(t, nil)
+
+	plan, err := client.Plan(context.Background(), []string{"node@18", "python@3.8", "jq"}, WithPlanTag("x86_64_linux"))
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	deprecated, disabled := plan.Deprecated(), plan.Disabled()
+	if len(deprecated) != 1 || deprecated[0].Name != "node@18" || deprecated[0].Reason != "is not supported upstream" {
+		t.Errorf("Expected node@18 to be deprecated, got %+v", deprecated)
+	}
+	if len(disabled) != 1 || disabled[0].Name != "python@3.8" || disabled[0].Reason != "Use python@3.12" {
+		t.Errorf("Expected python@3.8 to be disabled, got %+v", disabled)
+	}
+	if !plan.Blocked() {
+		t.Error("Expected a disabled package to block the plan")
+	}
+}
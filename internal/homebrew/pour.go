@@ -0,0 +1,502 @@
+package homebrew
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ofkm/goobrew/internal/logger"
+)
+
+// ErrKegExists is returned when a bottle would be poured over a keg that is
+// already installed.
+var ErrKegExists = errors.New("keg already exists")
+
+// receiptFile is the name of the install receipt Homebrew keeps in every keg.
+const receiptFile = "INSTALL_RECEIPT.json"
+
+// Keg is a formula version installed in the Cellar.
+type Keg struct {
+	Name        string   `json:"name"`                  // Name is the formula name
+	Version     string   `json:"version"`               // Version is the keg version, with its revision
+	Path        string   `json:"path"`                  // Path is the keg directory
+	Unrelocated []string `json:"unrelocated,omitempty"` // Unrelocated are keg files whose placeholders could not be rewritten
+}
+
+// PourOption configures a single Pour call.
+type PourOption func(*pourConfig)
+
+// pourConfig holds the settings applied by PourOptions.
+type pourConfig struct {
+	asDependency bool
+}
+
+// PourAsDependency records the keg as installed as a dependency rather
+// than on request.
+func PourAsDependency() PourOption {
+	return func(cfg *pourConfig) {
+		cfg.asDependency = true
+	}
+}
+
+// Pour installs a downloaded bottle of f into the Cellar without running
+// brew. The bottle is extracted next to its keg and moved into place only
+// once it is complete, so an interrupted pour leaves nothing behind. The
+// @@HOMEBREW_PREFIX@@ and related placeholders are rewritten in text files,
+// and in binaries where the new path fits in place of the placeholder;
+// files where it does not are reported in Keg.Unrelocated. The install
+// receipt is updated the way brew does when pouring. It returns an error
+// wrapping ErrKegExists if the version is already installed. The keg is
+// not linked.
+func (c *Client) Pour(ctx context.Context, f *Formula, bottle *BottleDownload, opts ...PourOption) (*Keg, error) {
+	var cfg pourConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	paths, err := c.Paths()
+	if err != nil {
+		return nil, err
+	}
+
+	keg := &Keg{Name: f.Name, Version: bottle.Version, Path: filepath.Join(paths.Cellar, f.Name, bottle.Version)}
+	if _, err := os.Lstat(keg.Path); err == nil {
+		return nil, fmt.Errorf("%s %s: %w", f.Name, bottle.Version, ErrKegExists)
+	}
+
+	rack := filepath.Dir(keg.Path)
+	if err := os.MkdirAll(rack, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", rack, err)
+	}
+	staging, err := os.MkdirTemp(rack, "."+bottle.Version+".pouring-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(staging)
+
+	logger.Log.Info("pouring bottle", "formula", f.Name, "keg", keg.Path)
+	if err := extractBottle(ctx, bottle.Path, staging, f.Name+"/"+bottle.Version); err != nil {
+		return nil, fmt.Errorf("failed to extract %s: %w", bottle.Path, err)
+	}
+
+	receipt, err := readReceipt(staging)
+	if err != nil {
+		return nil, err
+	}
+	keg.Unrelocated, err = relocateKeg(staging, receiptChangedFiles(receipt), relocations(paths, f))
+	if err != nil {
+		return nil, fmt.Errorf("failed to relocate %s: %w", f.Name, err)
+	}
+	for _, file := range keg.Unrelocated {
+		logger.Log.Warn("could not relocate file", "formula", f.Name, "file", file)
+	}
+
+	updateReceipt(receipt, f, cfg, time.Now())
+	if err := writeReceipt(staging, receipt); err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(staging, 0o755); err != nil { //nolint:gosec // kegs are world-readable like Homebrew's
+		return nil, err
+	}
+	if err := os.Rename(staging, keg.Path); err != nil {
+		if _, statErr := os.Lstat(keg.Path); statErr == nil {
+			return nil, fmt.Errorf("%s %s: %w", f.Name, bottle.Version, ErrKegExists)
+		}
+		return nil, err
+	}
+	return keg, nil
+}
+
+// extractBottle extracts the entries below root in the gzipped tarball at
+// archive into dest. Entries outside root, paths that escape dest, and
+// writes through symlinks are rejected.
+func extractBottle(ctx context.Context, archive, dest, root string) error {
+	file, err := os.Open(archive) //nolint:gosec // archive is a bottle in the download cache
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		rel, err := bottleEntryPath(hdr.Name, root)
+		if err != nil {
+			return err
+		}
+		if rel == "" {
+			continue
+		}
+		target := filepath.Join(dest, rel)
+		if err := checkNoSymlinks(dest, filepath.Dir(rel)); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeEntry(target, tr, hdr); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			src, err := bottleEntryPath(hdr.Linkname, root)
+			if err != nil || src == "" {
+				return fmt.Errorf("invalid hard link %s to %s", hdr.Name, hdr.Linkname)
+			}
+			if err := os.Link(filepath.Join(dest, src), target); err != nil {
+				return err
+			}
+		default:
+			logger.Log.Debug("skipping bottle entry", "entry", hdr.Name, "type", hdr.Typeflag)
+		}
+	}
+}
+
+// bottleEntryPath returns the path of a tarball entry relative to root, or
+// "" for root itself. It fails for entries outside root.
+func bottleEntryPath(name, root string) (string, error) {
+	name = path.Clean(strings.TrimPrefix(name, "./"))
+	if name == root {
+		return "", nil
+	}
+	rel, ok := strings.CutPrefix(name, root+"/")
+	if !ok || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("unexpected bottle entry %s outside %s", name, root)
+	}
+	return filepath.FromSlash(rel), nil
+}
+
+// checkNoSymlinks fails if any directory on the way from dest to dest/rel
+// is a symlink, so a crafted tarball cannot write outside dest.
+func checkNoSymlinks(dest, rel string) error {
+	dir := dest
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		if part == "." || part == "" {
+			continue
+		}
+		dir = filepath.Join(dir, part)
+		info, err := os.Lstat(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("bottle entry below symlink %s", dir)
+		}
+	}
+	return nil
+}
+
+// writeEntry writes a regular tarball entry to target with its permissions
+// and modification time.
+func writeEntry(target string, r io.Reader, hdr *tar.Header) error {
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, hdr.FileInfo().Mode().Perm()|0o200)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil { //nolint:gosec // bottles are verified against their checksum before pouring
+		_ = out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(target, hdr.FileInfo().Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(target, hdr.ModTime, hdr.ModTime)
+}
+
+// relocation is a placeholder in bottles and the path that replaces it.
+type relocation struct {
+	placeholder string
+	value       string
+}
+
+// relocations returns the placeholders brew rewrites when pouring a bottle
+// of f into paths.
+func relocations(paths Paths, f *Formula) []relocation {
+	perl := "/usr/bin/perl"
+	if runtime.GOOS != "darwin" || slices.Contains(f.Dependencies, "perl") {
+		perl = filepath.Join(paths.Prefix, "opt", "perl", "bin", "perl")
+	}
+	return []relocation{
+		{"@@HOMEBREW_PREFIX@@", paths.Prefix},
+		{"@@HOMEBREW_CELLAR@@", paths.Cellar},
+		{"@@HOMEBREW_REPOSITORY@@", paths.Repository},
+		{"@@HOMEBREW_LIBRARY@@", filepath.Join(paths.Repository, "Library")},
+		{"@@HOMEBREW_PERL@@", perl},
+		{"@@HOMEBREW_JAVA@@", filepath.Join(paths.Prefix, "opt", "openjdk", "libexec")},
+	}
+}
+
+// relocateKeg rewrites the placeholders in the files of keg, relative
+// paths taken from the receipt's changed_files or, if there are none,
+// every regular file. It returns the files that could only be partly
+// rewritten or not at all.
+func relocateKeg(keg string, changed []string, reps []relocation) ([]string, error) {
+	if len(changed) == 0 {
+		err := filepath.WalkDir(keg, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return err
+			}
+			rel, err := filepath.Rel(keg, p)
+			changed = append(changed, rel)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var unrelocated []string
+	for _, rel := range changed {
+		if !filepath.IsLocal(rel) {
+			continue
+		}
+		ok, err := relocateFile(filepath.Join(keg, rel), reps)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			unrelocated = append(unrelocated, rel)
+		}
+	}
+	return unrelocated, nil
+}
+
+// relocateFile rewrites the placeholders in one file and reports whether
+// all of them could be rewritten. Text files are rewritten freely; in
+// binaries only NUL-terminated strings are rewritten, and only if the new
+// string fits in the old one, with the rest padded with NUL bytes. Mach-O
+// files changed on macOS are signed again ad hoc.
+func relocateFile(name string, reps []relocation) (bool, error) {
+	info, err := os.Lstat(name)
+	if errors.Is(err, fs.ErrNotExist) || err == nil && !info.Mode().IsRegular() {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	data, err := os.ReadFile(name) //nolint:gosec // name is a file in the keg being poured
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Contains(data, []byte("@@HOMEBREW_")) {
+		return true, nil
+	}
+
+	binary := isBinary(data)
+	complete := true
+	if binary {
+		data, complete = relocateBinary(data, reps)
+	} else {
+		for _, r := range reps {
+			data = bytes.ReplaceAll(data, []byte(r.placeholder), []byte(r.value))
+		}
+	}
+
+	perm := info.Mode().Perm()
+	if perm&0o200 == 0 {
+		if err := os.Chmod(name, perm|0o200); err != nil {
+			return false, err
+		}
+	}
+	if err := os.WriteFile(name, data, perm|0o200); err != nil {
+		return false, err
+	}
+	if err := os.Chmod(name, perm); err != nil {
+		return false, err
+	}
+
+	if binary && isMachO(data) && runtime.GOOS == "darwin" {
+		if out, err := exec.Command("codesign", "--force", "--sign", "-", name).CombinedOutput(); err != nil { //nolint:gosec // name is a file in the keg being poured
+			logger.Log.Warn("failed to re-sign relocated file", "file", name, "error", err, "output", string(out))
+		}
+	}
+	return complete, nil
+}
+
+// relocateBinary rewrites the placeholders in the NUL-terminated strings of
+// a binary, in place, and reports whether all of them fit.
+func relocateBinary(data []byte, reps []relocation) ([]byte, bool) {
+	complete := true
+	for _, r := range reps {
+		placeholder := []byte(r.placeholder)
+		for from := 0; ; {
+			i := bytes.Index(data[from:], placeholder)
+			if i < 0 {
+				break
+			}
+			i += from
+			start := bytes.LastIndexByte(data[:i], 0) + 1
+			end := bytes.IndexByte(data[i:], 0)
+			if end < 0 {
+				complete = false
+				break
+			}
+			end += i
+
+			s := data[start:end]
+			for _, r := range reps {
+				s = bytes.ReplaceAll(s, []byte(r.placeholder), []byte(r.value))
+			}
+			if len(s) > end-start {
+				complete = false
+				from = end
+				continue
+			}
+			n := copy(data[start:end], s)
+			clear(data[start+n : end])
+			from = start + n
+		}
+	}
+	return data, complete
+}
+
+// isBinary reports whether data is an ELF or Mach-O file or looks binary.
+func isBinary(data []byte) bool {
+	if bytes.HasPrefix(data, []byte("\x7fELF")) || isMachO(data) {
+		return true
+	}
+	return bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0
+}
+
+// isMachO reports whether data starts with a Mach-O or universal binary
+// magic number.
+func isMachO(data []byte) bool {
+	if len(data) < 4 {
+		return false
+	}
+	switch string(data[:4]) {
+	case "\xfe\xed\xfa\xce", "\xce\xfa\xed\xfe", "\xfe\xed\xfa\xcf", "\xcf\xfa\xed\xfe", "\xca\xfe\xba\xbe":
+		return true
+	}
+	return false
+}
+
+// readReceipt reads the install receipt of a bottle, keeping every field so
+// that it can be written back unchanged apart from what pouring updates. A
+// bottle without a receipt gets an empty one.
+func readReceipt(keg string) (map[string]any, error) {
+	receipt := make(map[string]any)
+	data, err := os.ReadFile(filepath.Join(keg, receiptFile)) //nolint:gosec // keg is being poured
+	if errors.Is(err, fs.ErrNotExist) {
+		return receipt, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &receipt); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", receiptFile, err)
+	}
+	return receipt, nil
+}
+
+// receiptChangedFiles returns the files a bottle's receipt lists as
+// containing placeholders.
+func receiptChangedFiles(receipt map[string]any) []string {
+	list, _ := receipt["changed_files"].([]any)
+	var files []string
+	for _, v := range list {
+		if s, ok := v.(string); ok {
+			files = append(files, filepath.FromSlash(s))
+		}
+	}
+	return files
+}
+
+// updateReceipt records a pour of f in receipt, the way brew does.
+func updateReceipt(receipt map[string]any, f *Formula, cfg pourConfig, now time.Time) {
+	receipt["poured_from_bottle"] = true
+	receipt["built_as_bottle"] = true
+	receipt["loaded_from_api"] = true
+	receipt["installed_on_request"] = !cfg.asDependency
+	receipt["installed_as_dependency"] = cfg.asDependency
+	receipt["time"] = now.Unix()
+	receipt["keg_only"] = f.KegOnly
+	if len(f.LinkOverwrite) > 0 {
+		receipt["link_overwrite"] = f.LinkOverwrite
+	}
+	for _, key := range []string{"used_options", "unused_options"} {
+		if receipt[key] == nil {
+			receipt[key] = []string{}
+		}
+	}
+	if receipt["runtime_dependencies"] == nil {
+		deps := make([]Dependency, 0, len(f.Dependencies))
+		for _, dep := range f.Dependencies {
+			deps = append(deps, Dependency{FullName: dep, DeclaredDirectly: true})
+		}
+		receipt["runtime_dependencies"] = deps
+	}
+
+	source, _ := receipt["source"].(map[string]any)
+	if source == nil {
+		source = make(map[string]any)
+	}
+	if f.Tap != "" {
+		source["tap"] = f.Tap
+	}
+	source["spec"] = "stable"
+	source["versions"] = map[string]any{
+		"stable":         f.Versions.Stable,
+		"head":           nil,
+		"version_scheme": f.VersionScheme,
+	}
+	receipt["source"] = source
+}
+
+// writeReceipt writes receipt into keg.
+func writeReceipt(keg string, receipt map[string]any) error {
+	data, err := json.MarshalIndent(receipt, "", "  ")
+	if err != nil {
+		return err
+	}
+	name := filepath.Join(keg, receiptFile)
+	_ = os.Remove(name)
+	return os.WriteFile(name, append(data, '\n'), 0o644) //nolint:gosec // receipts are world-readable like Homebrew's
+}
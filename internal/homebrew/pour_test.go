@@ -0,0 +1,197 @@
+package homebrew
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tarEntry is a file in a test bottle.
+type tarEntry struct {
+	name     string
+	body     string
+	mode     int64
+	typeflag byte
+	linkname string
+}
+
+// writeBottle writes a gzipped tarball with the given entries and returns
+// its path.
+func writeBottle(t *testing.T, entries []tarEntry) string {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: e.mode, Typeflag: e.typeflag, Linkname: e.linkname, Size: int64(len(e.body)), ModTime: time.Unix(1700000000, 0)}
+		if hdr.Typeflag == 0 {
+			hdr.Typeflag = tar.TypeReg
+		}
+		if hdr.Mode == 0 {
+			hdr.Mode = 0o644
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			hdr.Size = 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.body[:hdr.Size])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "bottle.tar.gz")
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPour(t *testing.T) {
+	cellar := filepath.Join(t.TempDir(), "Cellar")
+	client := &Client{}
+	WithPaths(Paths{Prefix: "/opt/hb", Cellar: cellar, Repository: "/opt/hb"})(client)
+
+	rpath := "@@HOMEBREW_PREFIX@@/lib:@@HOMEBREW_PREFIX@@/opt/openssl@3/lib"
+	elf := "\x7fELF\x02\x01\x01\x00" + rpath + "\x00libc.so.6\x00"
+	long := "\x7fELF\x02\x01\x01\x00@@HOMEBREW_CELLAR@@\x00"
+	receipt := `{"homebrew_version":"4.4.0","changed_files":["bin/wget","bin/wget-cellar","etc/wgetrc"],"runtime_dependencies":[{"full_name":"openssl@3","version":"3.3.2","revision":1,"pkg_version":"3.3.2_1","declared_directly":true}],"source":{"path":"x","tap":"homebrew/core"}}`
+	bottle := writeBottle(t, []tarEntry{
+		{name: "wget/1.24.5_1/", typeflag: tar.TypeDir, mode: 0o755},
+		{name: "wget/1.24.5_1/bin/wget", body: elf, mode: 0o555},
+		{name: "wget/1.24.5_1/bin/wget-cellar", body: long, mode: 0o755},
+		{name: "wget/1.24.5_1/etc/wgetrc", body: "ca_directory = @@HOMEBREW_PREFIX@@/etc/ca-certificates\n"},
+		{name: "wget/1.24.5_1/lib/libwget.so.2", body: "lib", mode: 0o444},
+		{name: "wget/1.24.5_1/lib/libwget.so", typeflag: tar.TypeSymlink, linkname: "libwget.so.2"},
+		{name: "wget/1.24.5_1/lib/libwget-hard.so", typeflag: tar.TypeLink, linkname: "wget/1.24.5_1/lib/libwget.so.2"},
+		{name: "wget/1.24.5_1/INSTALL_RECEIPT.json", body: receipt},
+	})
+
+	formula := &Formula{Name: "wget", Tap: "homebrew/core", Versions: Versions{Stable: "1.24.5"}, Revision: 1}
+	download := &BottleDownload{Formula: "wget", Version: "1.24.5_1", Path: bottle}
+
+	keg, err := client.Pour(context.Background(), formula, download, PourAsDependency())
+	if err != nil {
+		t.Fatalf("Pour failed: %v", err)
+	}
+	if want := filepath.Join(cellar, "wget", "1.24.5_1"); keg.Path != want {
+		t.Errorf("Keg path = %s, want %s", keg.Path, want)
+	}
+	if !slices.Equal(keg.Unrelocated, []string{filepath.Join("bin", "wget-cellar")}) {
+		t.Errorf("Unrelocated = %v", keg.Unrelocated)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(keg.Path, "bin", "wget"))
+	if len(data) != len(elf) {
+		t.Errorf("Relocating a binary changed its size from %d to %d", len(elf), len(data))
+	}
+	if want := "/opt/hb/lib:/opt/hb/opt/openssl@3/lib\x00"; !bytes.Contains(data, []byte(want)) || !bytes.Contains(data, []byte("\x00libc.so.6\x00")) {
+		t.Errorf("Binary not relocated in place: %q", data)
+	}
+	if info, _ := os.Stat(filepath.Join(keg.Path, "bin", "wget")); info.Mode().Perm() != 0o555 {
+		t.Errorf("Expected mode 0555 to be kept, got %v", info.Mode())
+	}
+	if data, _ := os.ReadFile(filepath.Join(keg.Path, "bin", "wget-cellar")); string(data) != long {
+		t.Errorf("A binary that cannot be relocated should be left alone: %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(keg.Path, "etc", "wgetrc")); string(data) != "ca_directory = /opt/hb/etc/ca-certificates\n" {
+		t.Errorf("Text file not relocated: %q", data)
+	}
+	if target, err := os.Readlink(filepath.Join(keg.Path, "lib", "libwget.so")); err != nil || target != "libwget.so.2" {
+		t.Errorf("Symlink = %q, %v", target, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(keg.Path, "lib", "libwget-hard.so")); string(data) != "lib" {
+		t.Errorf("Hard link content = %q", data)
+	}
+
+	data, err = os.ReadFile(filepath.Join(keg.Path, receiptFile))
+	if err != nil {
+		t.Fatalf("Failed to read receipt: %v", err)
+	}
+	var info InstalledInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		t.Fatalf("Receipt does not parse as InstalledInfo: %v", err)
+	}
+	if !info.PouredFromBottle || !info.InstalledAsDependency || info.InstalledOnRequest || info.Time == 0 {
+		t.Errorf("Unexpected receipt: %+v", info)
+	}
+	if len(info.RuntimeDependencies) != 1 || info.RuntimeDependencies[0].PkgVersion != "3.3.2_1" {
+		t.Errorf("Expected the bottle's runtime dependencies to be kept: %+v", info.RuntimeDependencies)
+	}
+	if !strings.Contains(string(data), `"homebrew_version": "4.4.0"`) || !strings.Contains(string(data), `"stable": "1.24.5"`) {
+		t.Errorf("Expected the receipt to keep the bottle's fields and record the source version:\n%s", data)
+	}
+
+	if _, err := client.Pour(context.Background(), formula, download); !errors.Is(err, ErrKegExists) {
+		t.Errorf("Expected ErrKegExists, got %v", err)
+	}
+}
+
+func TestPourRejectsUnsafeBottles(t *testing.T) {
+	tests := map[string][]tarEntry{
+		"traversal": {{name: "wget/1.24.5/../../../evil", body: "x"}},
+		"outside":   {{name: "curl/8.10.1/bin/curl", body: "x"}},
+		"symlink": {
+			{name: "wget/1.24.5/lib", typeflag: tar.TypeSymlink, linkname: "/tmp"},
+			{name: "wget/1.24.5/lib/evil", body: "x"},
+		},
+	}
+	for name, entries := range tests {
+		t.Run(name, func(t *testing.T) {
+			cellar := filepath.Join(t.TempDir(), "Cellar")
+			client := &Client{paths: &Paths{Prefix: "/opt/hb", Cellar: cellar, Repository: "/opt/hb"}}
+			formula := &Formula{Name: "wget", Versions: Versions{Stable: "1.24.5"}}
+
+			_, err := client.Pour(context.Background(), formula, &BottleDownload{Version: "1.24.5", Path: writeBottle(t, entries)})
+			if err == nil {
+				t.Fatal("Expected the bottle to be rejected")
+			}
+			if entries, _ := os.ReadDir(filepath.Join(cellar, "wget")); len(entries) != 0 {
+				t.Errorf("Expected nothing to be left in the rack, found %v", entries)
+			}
+		})
+	}
+}
+
+func TestPaths(t *testing.T) {
+	prefix := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(prefix, "bin"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(prefix, "Cellar"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOMEBREW_PREFIX", "")
+	t.Setenv("HOMEBREW_CELLAR", "")
+	t.Setenv("HOMEBREW_REPOSITORY", "")
+
+	client := &Client{brewPath: filepath.Join(prefix, "bin", "brew")}
+	paths, err := client.Paths()
+	if err != nil {
+		t.Fatalf("Paths failed: %v", err)
+	}
+	if paths.Prefix != prefix || paths.Cellar != filepath.Join(prefix, "Cellar") || paths.Repository != prefix {
+		t.Errorf("Unexpected paths: %+v", paths)
+	}
+
+	t.Setenv("HOMEBREW_CELLAR", "/custom/Cellar")
+	if paths, _ := client.Paths(); paths.Cellar != "/custom/Cellar" {
+		t.Errorf("Expected HOMEBREW_CELLAR to be honoured, got %s", paths.Cellar)
+	}
+}
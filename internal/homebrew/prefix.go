@@ -0,0 +1,62 @@
+package homebrew
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// Paths locates a Homebrew installation.
+type Paths struct {
+	Prefix     string `json:"prefix"`     // Prefix is where kegs are linked, such as /opt/homebrew
+	Cellar     string `json:"cellar"`     // Cellar is where kegs are installed, usually Prefix/Cellar
+	Repository string `json:"repository"` // Repository is the Homebrew/brew checkout
+}
+
+// WithPaths makes the client install into paths instead of the Homebrew
+// installation that brew belongs to.
+func WithPaths(paths Paths) ClientOption {
+	return func(c *Client) {
+		c.paths = &paths
+	}
+}
+
+// Paths returns the locations of the Homebrew installation, the way brew
+// determines them but without running it: HOMEBREW_PREFIX, HOMEBREW_CELLAR
+// and HOMEBREW_REPOSITORY if they are set, and otherwise the directory
+// brew is in, the directory its symlink points into, and the Cellar in
+// the prefix if there is one or in the repository if not.
+func (c *Client) Paths() (Paths, error) {
+	if c.paths != nil {
+		return *c.paths, nil
+	}
+	if c.brewPath == "" {
+		return Paths{}, errors.New("cannot locate Homebrew without brew")
+	}
+
+	brew, err := filepath.Abs(c.brewPath)
+	if err != nil {
+		return Paths{}, err
+	}
+	paths := Paths{
+		Prefix:     os.Getenv("HOMEBREW_PREFIX"),
+		Cellar:     os.Getenv("HOMEBREW_CELLAR"),
+		Repository: os.Getenv("HOMEBREW_REPOSITORY"),
+	}
+	if paths.Prefix == "" {
+		paths.Prefix = filepath.Dir(filepath.Dir(brew))
+	}
+	if paths.Repository == "" {
+		paths.Repository = paths.Prefix
+		if resolved, err := filepath.EvalSymlinks(brew); err == nil {
+			paths.Repository = filepath.Dir(filepath.Dir(resolved))
+		}
+	}
+	if paths.Cellar == "" {
+		paths.Cellar = filepath.Join(paths.Prefix, "Cellar")
+		if info, err := os.Stat(paths.Cellar); err != nil || !info.IsDir() {
+			paths.Cellar = filepath.Join(paths.Repository, "Cellar")
+		}
+	}
+	return paths, nil
+}
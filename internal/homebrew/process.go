@@ -0,0 +1,141 @@
+package homebrew
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sync"
+)
+
+// ErrInterrupted is wrapped by errors for work that was stopped because the
+// context was cancelled, for example when the user pressed Ctrl-C.
+var ErrInterrupted = errors.New("interrupted")
+
+// processes tracks the brew processes a Client is running so that they can
+// be force-killed. The zero value is ready to use.
+type processes struct {
+	mu      sync.Mutex
+	running map[*exec.Cmd]struct{}
+}
+
+// ErrTerminalInterrupt is the cause to cancel a context with when the user
+// pressed Ctrl-C in the terminal. Commands that share goobrew's process
+// group received the same SIGINT from the terminal, so cancelling such a
+// context does not signal them a second time.
+var ErrTerminalInterrupt = errors.New("interrupted from the terminal")
+
+// command builds a brew command that only reports, such as info or list,
+// and runs in its own process group, so a Ctrl-C in the terminal reaches
+// goobrew only. Cancelling ctx sends SIGINT to the whole group, letting brew
+// stop at the end of its current step; Kill ends it immediately. Commands
+// that change anything use interactiveCommand instead.
+func (c *Client) command(ctx context.Context, args ...string) *exec.Cmd {
+	//nolint:gosec // brewPath is validated at client creation, args are package names or commands
+	cmd := exec.CommandContext(ctx, c.brewPath, args...)
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return interruptProcess(cmd)
+	}
+	return cmd
+}
+
+// interactiveCommand builds a brew command that may read from the terminal,
+// such as an install whose cask runs sudo. It stays in goobrew's foreground
+// process group, since a background group that opens /dev/tty is stopped
+// with SIGTTIN. A Ctrl-C in the terminal reaches it directly; any other
+// cancellation of ctx forwards SIGINT to it. Kill ends it immediately, but
+// not the processes it started.
+func (c *Client) interactiveCommand(ctx context.Context, args ...string) *exec.Cmd {
+	return c.processCommand(ctx, c.brewPath, args...)
+}
+
+// toolCommand builds a command for another tool brew bundle relies on,
+// such as mas or code, found in PATH. It behaves like interactiveCommand.
+func (c *Client) toolCommand(ctx context.Context, tool string, args ...string) (*exec.Cmd, error) {
+	path, err := exec.LookPath(tool)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not installed: %w", tool, err)
+	}
+	return c.processCommand(ctx, path, args...), nil
+}
+
+// processCommand builds the command behind interactiveCommand and
+// toolCommand.
+func (c *Client) processCommand(ctx context.Context, path string, args ...string) *exec.Cmd {
+	//nolint:gosec // path is brew or a tool found in PATH, args are package names or commands
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Cancel = func() error {
+		if errors.Is(context.Cause(ctx), ErrTerminalInterrupt) {
+			return nil
+		}
+		return interruptProcess(cmd)
+	}
+	return cmd
+}
+
+// foregroundCommand builds a brew command that stays in goobrew's process
+// group so that it can read from the terminal. It receives Ctrl-C from the
+// terminal directly, so cancelling ctx does not signal it a second time.
+func (c *Client) foregroundCommand(ctx context.Context, args ...string) *exec.Cmd {
+	//nolint:gosec // brewPath is validated at client creation, args are user commands
+	cmd := exec.CommandContext(ctx, c.brewPath, args...)
+	cmd.Cancel = func() error {
+		return nil
+	}
+	return cmd
+}
+
+// start starts cmd and tracks it until wait is called.
+func (c *Client) start(cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	c.procs.mu.Lock()
+	if c.procs.running == nil {
+		c.procs.running = make(map[*exec.Cmd]struct{})
+	}
+	c.procs.running[cmd] = struct{}{}
+	c.procs.mu.Unlock()
+	return nil
+}
+
+// wait waits for a command started with start and stops tracking it.
+func (c *Client) wait(cmd *exec.Cmd) error {
+	err := cmd.Wait()
+
+	c.procs.mu.Lock()
+	delete(c.procs.running, cmd)
+	c.procs.mu.Unlock()
+	return err
+}
+
+// run starts cmd and waits for it to finish.
+func (c *Client) run(cmd *exec.Cmd) error {
+	if err := c.start(cmd); err != nil {
+		return err
+	}
+	return c.wait(cmd)
+}
+
+// output runs cmd and returns its standard output.
+func (c *Client) output(cmd *exec.Cmd) ([]byte, error) {
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	err := c.run(cmd)
+	return stdout.Bytes(), err
+}
+
+// Kill immediately kills every brew process the client is running, along
+// with their children where they share a process group. It is intended for
+// a second Ctrl-C, after cancelling the context has asked brew to stop.
+func (c *Client) Kill() {
+	c.procs.mu.Lock()
+	defer c.procs.mu.Unlock()
+
+	for cmd := range c.procs.running {
+		_ = killProcess(cmd)
+	}
+}
@@ -0,0 +1,99 @@
+package homebrew
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// openPty opens a pseudo-terminal and returns its master and slave ends.
+func openPty(t *testing.T) (*os.File, *os.File) {
+	t.Helper()
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+	if err != nil {
+		t.Skipf("Skipping: no pseudo-terminals: %v", err)
+	}
+	t.Cleanup(func() { _ = master.Close() })
+
+	var unlock int32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); errno != 0 {
+		t.Skipf("Skipping: cannot unlock pseudo-terminal: %v", errno)
+	}
+	var n uint32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), syscall.TIOCGPTN, uintptr(unsafe.Pointer(&n))); errno != 0 {
+		t.Skipf("Skipping: cannot name pseudo-terminal: %v", errno)
+	}
+	slave, err := os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("Skipping: cannot open pseudo-terminal: %v", err)
+	}
+	return master, slave
+}
+
+// TestInteractiveCommand_ReadsTerminal runs an install whose brew reads
+// from /dev/tty, as sudo does, from a process that owns a terminal. A brew
+// in a background process group would be stopped with SIGTTIN instead.
+func TestInteractiveCommand_ReadsTerminal(t *testing.T) {
+	if brew := os.Getenv("GOOBREW_TEST_TTY_BREW"); brew != "" {
+		client := &Client{brewPath: brew}
+		cmd := client.interactiveCommand(context.Background(), "install", "--cask", "driver")
+		cmd.Stdout = os.Stdout
+		if err := client.run(cmd); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	brew := writeFakeBrew(t, `read -r password < /dev/tty && echo "got $password"`)
+	master, slave := openPty(t)
+
+	// Run this test again as the session leader of the terminal, acting as
+	// goobrew in the terminal's foreground process group.
+	helper := exec.Command(os.Args[0], "-test.run=^TestInteractiveCommand_ReadsTerminal$") //nolint:gosec // the test binary
+	helper.Env = append(os.Environ(), "GOOBREW_TEST_TTY_BREW="+brew)
+	helper.Stdin, helper.Stdout, helper.Stderr = slave, slave, slave
+	helper.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true, Ctty: 0}
+	if err := helper.Start(); err != nil {
+		t.Fatalf("Failed to start helper: %v", err)
+	}
+	_ = slave.Close()
+	defer func() {
+		_ = helper.Process.Kill()
+		_ = helper.Wait()
+	}()
+
+	if _, err := master.Write([]byte("secret\n")); err != nil {
+		t.Fatalf("Failed to type into the terminal: %v", err)
+	}
+
+	output := make(chan string, 1)
+	go func() {
+		var buf bytes.Buffer
+		chunk := make([]byte, 256)
+		for {
+			n, err := master.Read(chunk)
+			buf.Write(chunk[:n])
+			if strings.Contains(buf.String(), "got secret") || err != nil {
+				output <- buf.String()
+				return
+			}
+		}
+	}()
+
+	select {
+	case out := <-output:
+		if !strings.Contains(out, "got secret") {
+			t.Errorf("Expected brew to read the terminal, got %q", out)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("brew did not read from the terminal; it was probably stopped with SIGTTIN")
+	}
+}
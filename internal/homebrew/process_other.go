@@ -0,0 +1,31 @@
+//go:build !unix
+
+package homebrew
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup is a no-op on platforms without process groups.
+func setProcessGroup(*exec.Cmd) {}
+
+// interruptProcess asks cmd to stop, falling back to killing it where
+// interrupts cannot be delivered.
+func interruptProcess(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return os.ErrProcessDone
+	}
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		return cmd.Process.Kill()
+	}
+	return nil
+}
+
+// killProcess kills cmd.
+func killProcess(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return os.ErrProcessDone
+	}
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,159 @@
+//go:build unix
+
+package homebrew
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommand_CancelInterruptsProcessGroup(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "interrupted")
+	brew := writeFakeBrew(t, `
+trap 'echo done > `+marker+`; exit 130' INT
+sleep 5 &
+wait`)
+	client := &Client{brewPath: brew}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := client.command(ctx, "install", "jq")
+	if err := client.start(cmd); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- client.wait(cmd) }()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Expected an error from an interrupted command")
+		}
+	case <-time.After(3 * time.Second):
+		client.Kill()
+		t.Fatal("Cancelled command did not exit")
+	}
+
+	if data, err := os.ReadFile(marker); err != nil || strings.TrimSpace(string(data)) != "done" {
+		t.Errorf("Expected brew to handle SIGINT, marker: %q, %v", data, err)
+	}
+	if len(client.procs.running) != 0 {
+		t.Errorf("Expected no tracked processes after wait, got %d", len(client.procs.running))
+	}
+}
+
+func TestKill(t *testing.T) {
+	brew := writeFakeBrew(t, `
+trap '' INT
+sleep 5`)
+	client := &Client{brewPath: brew}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cmd := client.command(ctx, "install", "jq")
+	if err := client.start(cmd); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	cancel() // ignored by the script
+
+	done := make(chan error, 1)
+	go func() { done <- client.wait(cmd) }()
+	select {
+	case <-done:
+		t.Fatal("Script ignoring SIGINT should still be running")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	client.Kill()
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Kill did not stop the command")
+	}
+}
+
+func TestInstall_Interrupted(t *testing.T) {
+	brew := writeFakeBrew(t, `
+case "$1" in
+install) echo "==> Fetching $2"; sleep 5 & trap 'kill $!; exit 130' INT; wait ;;
+esac`)
+	client := &Client{brewPath: brew}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	statusChan := make(chan InstallationStatus, 100)
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		cancel()
+	}()
+
+	err := client.Install(ctx, []string{"jq"}, statusChan, WithJobs(1))
+	close(statusChan)
+
+	if !errors.Is(err, ErrInterrupted) {
+		t.Errorf("Expected an error wrapping ErrInterrupted, got %v", err)
+	}
+
+	var final InstallationStatus
+	for status := range statusChan {
+		final = status
+	}
+	if final.Stage != "failed" || !errors.Is(final.Error, ErrInterrupted) {
+		t.Errorf("Expected the final status to be interrupted, got %+v", final)
+	}
+}
+
+func TestInteractiveCommand_Cancel(t *testing.T) {
+	tests := []struct {
+		name        string
+		cause       error
+		interrupted bool
+	}{
+		{name: "forwards SIGINT", interrupted: true},
+		{name: "leaves terminal interrupts to the terminal", cause: ErrTerminalInterrupt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			marker := filepath.Join(t.TempDir(), "interrupted")
+			brew := writeFakeBrew(t, `
+trap 'echo done > `+marker+`; exit 130' INT TERM
+sleep 5 &
+wait`)
+			client := &Client{brewPath: brew}
+
+			ctx, cancel := context.WithCancelCause(context.Background())
+			cmd := client.interactiveCommand(ctx, "install", "jq")
+			if err := client.start(cmd); err != nil {
+				t.Fatalf("start failed: %v", err)
+			}
+			if cmd.SysProcAttr != nil && cmd.SysProcAttr.Setpgid {
+				t.Error("Expected the command to stay in goobrew's process group")
+			}
+
+			time.Sleep(100 * time.Millisecond)
+			cancel(tt.cause)
+
+			done := make(chan error, 1)
+			go func() { done <- client.wait(cmd) }()
+			select {
+			case <-done:
+			case <-time.After(500 * time.Millisecond):
+				client.Kill()
+				<-done
+			}
+
+			_, err := os.Stat(marker)
+			if interrupted := err == nil; interrupted != tt.interrupted {
+				t.Errorf("Expected interrupted to be %v, got %v", tt.interrupted, interrupted)
+			}
+		})
+	}
+}
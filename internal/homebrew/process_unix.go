@@ -0,0 +1,45 @@
+//go:build unix
+
+package homebrew
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup makes cmd the leader of a new process group.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// interruptProcess sends SIGINT to cmd, or to its process group if it
+// leads one.
+func interruptProcess(cmd *exec.Cmd) error {
+	return signalProcess(cmd, syscall.SIGINT)
+}
+
+// killProcess sends SIGKILL to cmd, or to its process group if it leads one.
+func killProcess(cmd *exec.Cmd) error {
+	return signalProcess(cmd, syscall.SIGKILL)
+}
+
+// signalProcess delivers sig to a started command. A process that has
+// already exited is reported as os.ErrProcessDone.
+func signalProcess(cmd *exec.Cmd, sig syscall.Signal) error {
+	if cmd.Process == nil {
+		return os.ErrProcessDone
+	}
+
+	var err error
+	if cmd.SysProcAttr != nil && cmd.SysProcAttr.Setpgid {
+		err = syscall.Kill(-cmd.Process.Pid, sig)
+	} else {
+		err = cmd.Process.Signal(sig)
+	}
+	if errors.Is(err, syscall.ESRCH) {
+		return os.ErrProcessDone
+	}
+	return err
+}
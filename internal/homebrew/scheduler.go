@@ -0,0 +1,252 @@
+package homebrew
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ofkm/goobrew/internal/logger"
+)
+
+// DefaultInstallJobs is how many brew processes Install runs at once unless
+// WithJobs says otherwise.
+const DefaultInstallJobs = 3
+
+// InstallOption configures a single Install call.
+type InstallOption func(*installConfig)
+
+// installConfig holds the settings applied by InstallOptions.
+type installConfig struct {
+	jobs int
+	args map[string][]string // args are extra brew flags per package
+}
+
+// WithJobs limits how many packages Install works on concurrently.
+// Values below one are treated as one, which installs strictly in order.
+func WithJobs(n int) InstallOption {
+	return func(cfg *installConfig) {
+		cfg.jobs = max(n, 1)
+	}
+}
+
+// WithPackageArgs passes extra brew flags for one package, such as "--cask"
+// or "--HEAD". They are placed before the package name.
+func WithPackageArgs(pkg string, args ...string) InstallOption {
+	return func(cfg *installConfig) {
+		if cfg.args == nil {
+			cfg.args = make(map[string][]string)
+		}
+		cfg.args[pkg] = append(cfg.args[pkg], args...)
+	}
+}
+
+// installNode is one brew install invocation in an install schedule.
+type installNode struct {
+	name       string   // name is the formula, cask or dependency to install
+	dependency bool     // dependency is true for shared dependencies scheduled on their own
+	needs      []string // needs names the nodes that must finish before this one starts
+	args       []string // args are extra brew flags placed before the name
+}
+
+// planInstall builds an install schedule for packages. Every requested
+// package becomes a node; so does every dependency that is not installed and
+// is shared by two or more requested packages, so that it is fetched once
+// instead of by several brew processes racing for its lock. A node needs
+// every other node in its transitive dependency closure. Dependencies are
+// read from the JSON API; packages the API does not know about, such as
+// casks, are scheduled without dependencies. It fails if the installed
+// formulae cannot be listed.
+func (c *Client) planInstall(ctx context.Context, packages []string) ([]*installNode, error) {
+	deps := c.resolveDependencies(ctx, packages)
+
+	formulae, err := c.InstalledFormulae(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing installed formulae: %w", err)
+	}
+	installed := make(map[string]bool)
+	for _, f := range formulae {
+		if len(f.Installed) > 0 {
+			installed[f.Name] = true
+		}
+	}
+
+	requested := make(map[string]bool, len(packages))
+	for _, pkg := range packages {
+		requested[pkg] = true
+	}
+
+	closures := make(map[string][]string, len(packages))
+	users := make(map[string]int)
+	var order []string
+	for _, pkg := range packages {
+		if _, ok := closures[pkg]; ok {
+			continue
+		}
+		closure := dependencyClosure(pkg, deps)
+		closures[pkg] = closure
+		for _, dep := range closure {
+			if users[dep] == 0 {
+				order = append(order, dep)
+			}
+			users[dep]++
+		}
+	}
+
+	scheduled := make(map[string]bool)
+	var nodes []*installNode
+	for _, dep := range order {
+		if users[dep] < 2 || installed[dep] || requested[dep] {
+			continue
+		}
+		scheduled[dep] = true
+		closures[dep] = dependencyClosure(dep, deps)
+		nodes = append(nodes, &installNode{name: dep, dependency: true})
+	}
+	for _, pkg := range packages {
+		if !scheduled[pkg] {
+			scheduled[pkg] = true
+			nodes = append(nodes, &installNode{name: pkg})
+		}
+	}
+
+	for _, n := range nodes {
+		for _, dep := range closures[n.name] {
+			if scheduled[dep] && dep != n.name {
+				n.needs = append(n.needs, dep)
+			}
+		}
+	}
+
+	return nodes, nil
+}
+
+// resolveDependencies fetches the runtime dependencies of packages and of
+// everything they depend on, one breadth-first level at a time with the
+// requests in each level made in parallel. The result maps each formula
+// name to its direct dependencies.
+func (c *Client) resolveDependencies(ctx context.Context, packages []string) map[string][]string {
+	deps := make(map[string][]string)
+	frontier := append([]string{}, packages...)
+
+	for len(frontier) > 0 {
+		var names []string
+		for _, name := range frontier {
+			if _, seen := deps[name]; !seen {
+				deps[name] = nil
+				names = append(names, name)
+			}
+		}
+
+		formulae, errs := c.fetchFormulae(ctx, names)
+		frontier = nil
+		for i, name := range names {
+			if errs[i] != nil {
+				logger.Log.Debug("no dependency data, scheduling without dependencies", "package", name, "error", errs[i])
+				continue
+			}
+			deps[name] = formulae[i].Dependencies
+			frontier = append(frontier, formulae[i].Dependencies...)
+		}
+	}
+
+	return deps
+}
+
+// dependencyClosure returns every package name reachable from name through
+// deps, excluding name itself, in depth-first order. Cycles are tolerated.
+func dependencyClosure(name string, deps map[string][]string) []string {
+	var (
+		closure []string
+		visit   func(string)
+	)
+	seen := map[string]bool{name: true}
+	visit = func(n string) {
+		for _, dep := range deps[n] {
+			if seen[dep] {
+				continue
+			}
+			seen[dep] = true
+			closure = append(closure, dep)
+			visit(dep)
+		}
+	}
+	visit(name)
+	return closure
+}
+
+// nodeResult reports the outcome of running one node.
+type nodeResult struct {
+	name string
+	err  error
+}
+
+// runSchedule runs each node with run once every node it needs has
+// succeeded, keeping at most jobs nodes in flight. A node whose
+// prerequisite failed, or that cannot start because of a dependency cycle
+// or a cancelled context, is passed to skip with the reason instead.
+func runSchedule(ctx context.Context, nodes []*installNode, jobs int, run func(*installNode) error, skip func(*installNode, error)) {
+	var (
+		done    = make(map[string]bool)
+		failed  = make(map[string]bool)
+		results = make(chan nodeResult)
+		pending = append([]*installNode(nil), nodes...)
+		running int
+	)
+
+	for len(pending) > 0 || running > 0 {
+		progressed := false
+		remaining := pending[:0]
+		for _, n := range pending {
+			if err := ctx.Err(); err != nil {
+				failed[n.name] = true
+				skip(n, err)
+				progressed = true
+				continue
+			}
+
+			ready, blockedBy := true, ""
+			for _, need := range n.needs {
+				if failed[need] {
+					blockedBy = need
+					break
+				}
+				if !done[need] {
+					ready = false
+				}
+			}
+
+			switch {
+			case blockedBy != "":
+				failed[n.name] = true
+				skip(n, fmt.Errorf("dependency %s failed", blockedBy))
+				progressed = true
+			case ready && running < jobs:
+				running++
+				progressed = true
+				go func(n *installNode) {
+					results <- nodeResult{name: n.name, err: run(n)}
+				}(n)
+			default:
+				remaining = append(remaining, n)
+			}
+		}
+		pending = remaining
+
+		if running == 0 {
+			if progressed {
+				continue
+			}
+			for _, n := range pending {
+				skip(n, fmt.Errorf("dependency cycle involving %s", n.name))
+			}
+			return
+		}
+
+		r := <-results
+		running--
+		if r.err != nil {
+			failed[r.name] = true
+		} else {
+			done[r.name] = true
+		}
+	}
+}
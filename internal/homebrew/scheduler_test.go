@@ -0,0 +1,326 @@
+package homebrew
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDependencyClosure(t *testing.T) {
+	deps := map[string][]string{
+		"a": {"b", "c"},
+		"b": {"c", "d"},
+		"d": {"a"}, // cycle back to a
+	}
+
+	got := dependencyClosure("a", deps)
+	want := []string{"b", "c", "d"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected closure %v, got %v", want, got)
+	}
+}
+
+func TestRunSchedule_Order(t *testing.T) {
+	nodes := []*installNode{
+		{name: "openssl", dependency: true},
+		{name: "curl", needs: []string{"openssl"}},
+		{name: "wget", needs: []string{"openssl"}},
+		{name: "jq"},
+	}
+
+	var (
+		mu      sync.Mutex
+		started []string
+		running int32
+		peak    int32
+	)
+	run := func(n *installNode) error {
+		mu.Lock()
+		started = append(started, n.name)
+		mu.Unlock()
+
+		cur := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if cur <= p || atomic.CompareAndSwapInt32(&peak, p, cur) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return nil
+	}
+	skip := func(n *installNode, err error) {
+		t.Errorf("Unexpected skip of %s: %v", n.name, err)
+	}
+
+	runSchedule(context.Background(), nodes, 2, run, skip)
+
+	if len(started) != 4 {
+		t.Fatalf("Expected 4 nodes to run, got %v", started)
+	}
+	openssl := slices.Index(started, "openssl")
+	if openssl > slices.Index(started, "curl") || openssl > slices.Index(started, "wget") {
+		t.Errorf("openssl must start before its dependents, got %v", started)
+	}
+	if peak > 2 {
+		t.Errorf("Expected at most 2 concurrent jobs, got %d", peak)
+	}
+}
+
+func TestRunSchedule_FailureSkipsDependents(t *testing.T) {
+	nodes := []*installNode{
+		{name: "openssl", dependency: true},
+		{name: "curl", needs: []string{"openssl"}},
+		{name: "jq"},
+	}
+
+	var ran []string
+	skipped := make(map[string]error)
+	run := func(n *installNode) error {
+		ran = append(ran, n.name)
+		if n.name == "openssl" {
+			return errors.New("boom")
+		}
+		return nil
+	}
+	skip := func(n *installNode, err error) {
+		skipped[n.name] = err
+	}
+
+	runSchedule(context.Background(), nodes, 1, run, skip)
+
+	if slices.Contains(ran, "curl") {
+		t.Error("curl should not run after openssl failed")
+	}
+	if !slices.Contains(ran, "jq") {
+		t.Error("jq does not depend on openssl and should still run")
+	}
+	if err := skipped["curl"]; err == nil || !strings.Contains(err.Error(), "openssl") {
+		t.Errorf("Expected curl to be skipped because of openssl, got %v", err)
+	}
+}
+
+func TestRunSchedule_Cycle(t *testing.T) {
+	nodes := []*installNode{
+		{name: "a", needs: []string{"b"}},
+		{name: "b", needs: []string{"a"}},
+	}
+
+	var skipped []string
+	runSchedule(context.Background(), nodes, 2,
+		func(n *installNode) error {
+			t.Errorf("Unexpected run of %s", n.name)
+			return nil
+		},
+		func(n *installNode, err error) {
+			skipped = append(skipped, n.name)
+		})
+
+	if len(skipped) != 2 {
+		t.Errorf("Expected both nodes of the cycle to be skipped, got %v", skipped)
+	}
+}
+
+func TestPlanInstall_SharedDependencies(t *testing.T) {
+	formulae := map[string][]string{
+		"curl":    {"openssl", "libssh2"},
+		"wget":    {"openssl", "libidn2"},
+		"openssl": {"ca-certificates"},
+		"libidn2": {"libunistring"},
+		"libssh2": {"openssl"},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/formula/"), ".json")
+		deps, ok := formulae[name]
+		if !ok && name != "ca-certificates" && name != "libunistring" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(Formula{Name: name, Dependencies: deps})
+	}))
+	defer server.Close()
+
+	brew := writeFakeBrew(t, `echo '[{"name":"ca-certificates","installed":[{"version":"2024"}]}]'`)
+	client := &Client{httpClient: &http.Client{Timeout: 5 * time.Second}, brewPath: brew}
+	WithAPIEndpoints(server.URL)(client)
+
+	nodes, err := client.planInstall(context.Background(), []string{"curl", "wget", "firefox"})
+	if err != nil {
+		t.Fatalf("planInstall failed: %v", err)
+	}
+
+	byName := make(map[string]*installNode)
+	var names []string
+	for _, n := range nodes {
+		byName[n.name] = n
+		names = append(names, n.name)
+	}
+
+	want := []string{"openssl", "curl", "wget", "firefox"}
+	if !slices.Equal(names, want) {
+		t.Fatalf("Expected schedule %v, got %v", want, names)
+	}
+	if !byName["openssl"].dependency {
+		t.Error("openssl should be scheduled as a shared dependency")
+	}
+	if len(byName["openssl"].needs) != 0 {
+		t.Errorf("ca-certificates is installed and should not be needed, got %v", byName["openssl"].needs)
+	}
+	if !slices.Equal(byName["curl"].needs, []string{"openssl"}) {
+		t.Errorf("Expected curl to need openssl, got %v", byName["curl"].needs)
+	}
+	if len(byName["firefox"].needs) != 0 {
+		t.Errorf("Expected firefox to have no dependencies, got %v", byName["firefox"].needs)
+	}
+}
+
+func TestPlanInstall_InstalledListFails(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	brew := writeFakeBrew(t, `echo "Error: broken" >&2; exit 1`)
+	client := &Client{httpClient: &http.Client{Timeout: 5 * time.Second}, brewPath: brew}
+	WithAPIEndpoints(server.URL)(client)
+
+	if _, err := client.planInstall(context.Background(), []string{"curl", "wget"}); err == nil {
+		t.Error("Expected planInstall to fail when the installed formulae cannot be listed")
+	}
+}
+
+func TestInstall_Concurrent(t *testing.T) {
+	brew := writeFakeBrew(t, `
+case "$1" in
+info) echo '[]' ;;
+install)
+  echo "==> Downloading $2"
+  sleep 0.2
+  [ "$2" = "broken" ] && exit 1
+  echo "==> Pouring $2.bottle.tar.gz" ;;
+esac`)
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	client := &Client{httpClient: &http.Client{Timeout: 5 * time.Second}, brewPath: brew}
+	WithAPIEndpoints(server.URL)(client)
+
+	statusChan := make(chan InstallationStatus, 100)
+	start := time.Now()
+	err := client.Install(context.Background(), []string{"jq", "broken", "tree"}, statusChan, WithJobs(3))
+	elapsed := time.Since(start)
+	close(statusChan) // Install must not send after returning
+
+	final := make(map[string]string)
+	for status := range statusChan {
+		if status.Stage == "completed" || status.Stage == "failed" {
+			if prev, ok := final[status.Formula]; ok {
+				t.Errorf("%s reported a second final status %s after %s", status.Formula, status.Stage, prev)
+			}
+			final[status.Formula] = status.Stage
+		}
+	}
+
+	var installErr *InstallError
+	if !errors.As(err, &installErr) {
+		t.Fatalf("Expected an *InstallError, got %v", err)
+	}
+	if len(installErr.Failures) != 1 || installErr.Failures[0].Package != "broken" {
+		t.Errorf("Expected only broken to be reported, got %+v", installErr.Failures)
+	}
+
+	if final["jq"] != "completed" || final["tree"] != "completed" {
+		t.Errorf("Expected jq and tree to complete, got %v", final)
+	}
+	if final["broken"] != "failed" {
+		t.Errorf("Expected broken to fail, got %v", final)
+	}
+	if elapsed > 550*time.Millisecond {
+		t.Errorf("Expected packages to install in parallel, took %s", elapsed)
+	}
+}
+
+func TestInstall_SkipsDependentsOfFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/formula/curl.json", "/formula/wget.json":
+			_ = json.NewEncoder(w).Encode(Formula{Dependencies: []string{"openssl"}})
+		case "/formula/openssl.json":
+			_ = json.NewEncoder(w).Encode(Formula{Name: "openssl"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	brew := writeFakeBrew(t, `
+case "$1" in
+info) echo '[]' ;;
+install) [ "$2" = "openssl" ] && { echo "Error: download failed" >&2; exit 1; } ; echo "==> Pouring $2" ;;
+esac`)
+	client := &Client{httpClient: &http.Client{Timeout: 5 * time.Second}, brewPath: brew}
+	WithAPIEndpoints(server.URL)(client)
+
+	statusChan := make(chan InstallationStatus, 100)
+	err := client.Install(context.Background(), []string{"curl", "wget"}, statusChan)
+	close(statusChan)
+
+	final := make(map[string]InstallationStatus)
+	for status := range statusChan {
+		final[status.Formula] = status
+	}
+
+	if final["openssl"].Stage != "failed" || len(final["openssl"].Transcript) == 0 {
+		t.Errorf("Expected openssl to fail with a transcript, got %+v", final["openssl"])
+	}
+	for _, pkg := range []string{"curl", "wget"} {
+		if final[pkg].Stage != "skipped" {
+			t.Errorf("Expected %s to be skipped, got %s", pkg, final[pkg].Stage)
+		}
+	}
+
+	var installErr *InstallError
+	if !errors.As(err, &installErr) || len(installErr.Failures) != 3 {
+		t.Fatalf("Expected three failures, got %v", err)
+	}
+	if !installErr.Failures[1].Skipped || installErr.Failures[0].Skipped {
+		t.Errorf("Expected openssl failed and its dependents skipped, got %+v", installErr.Failures)
+	}
+}
+
+func TestUpgradeEach(t *testing.T) {
+	brew := writeFakeBrew(t, `
+[ "$1" = "upgrade" ] || exit 2
+echo "==> Upgrading $2"
+[ "$2" = "broken" ] && exit 1
+echo "==> Pouring $2.bottle.tar.gz"`)
+	client := &Client{brewPath: brew}
+
+	statusChan := make(chan InstallationStatus, 100)
+	err := client.UpgradeEach(context.Background(), []string{"jq", "broken"}, statusChan)
+	close(statusChan)
+
+	final := make(map[string]string)
+	for status := range statusChan {
+		final[status.Formula] = status.Stage
+	}
+	if final["jq"] != "completed" || final["broken"] != "failed" {
+		t.Errorf("Expected jq completed and broken failed, got %v", final)
+	}
+
+	var installErr *InstallError
+	if !errors.As(err, &installErr) || len(installErr.Failures) != 1 {
+		t.Fatalf("Expected one failure, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "1 package not upgraded") {
+		t.Errorf("Expected the error to mention the upgrade, got %q", err)
+	}
+}
@@ -0,0 +1,285 @@
+package homebrew
+
+import (
+	"sort"
+	"strings"
+)
+
+// MatchKind identifies how a search term matched a package.
+type MatchKind int
+
+// Match kinds, from strongest to weakest.
+const (
+	MatchExact   MatchKind = iota // MatchExact is an exact name match
+	MatchPrefix                   // MatchPrefix is a name prefix match
+	MatchAlias                    // MatchAlias is an alias or cask display name match
+	MatchOldName                  // MatchOldName is a match on a former name
+	MatchToken                    // MatchToken is a substring match within the name
+	MatchFuzzy                    // MatchFuzzy is a typo-tolerant name match
+	MatchDesc                     // MatchDesc is a description match
+)
+
+// String returns a short label for the match kind.
+func (k MatchKind) String() string {
+	switch k {
+	case MatchExact:
+		return "exact"
+	case MatchPrefix:
+		return "prefix"
+	case MatchAlias:
+		return "alias"
+	case MatchOldName:
+		return "oldname"
+	case MatchToken:
+		return "token"
+	case MatchFuzzy:
+		return "fuzzy"
+	case MatchDesc:
+		return "description"
+	}
+	return "unknown"
+}
+
+// MarshalText encodes the match kind as its label.
+func (k MatchKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// Span marks a matched byte range [Start, End) within a string.
+type Span struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// PackageKind distinguishes formulae from casks.
+type PackageKind string
+
+// Package kinds.
+const (
+	KindFormula PackageKind = "formula" // KindFormula is a Homebrew formula
+	KindCask    PackageKind = "cask"    // KindCask is a Homebrew cask
+)
+
+// SearchHit is a single scored search result.
+type SearchHit struct {
+	Name             string      `json:"name"`                        // Name is the formula name or cask token
+	Kind             PackageKind `json:"kind"`                        // Kind is formula or cask
+	Desc             string      `json:"desc,omitempty"`              // Desc is the package description
+	Version          string      `json:"version,omitempty"`           // Version is the latest available version
+	Installed        bool        `json:"installed"`                   // Installed indicates the package is installed
+	InstalledVersion string      `json:"installed_version,omitempty"` // InstalledVersion is the installed version
+	Outdated         bool        `json:"outdated"`                    // Outdated indicates a newer version is available
+	Deprecated       bool        `json:"deprecated"`                  // Deprecated indicates the package is deprecated
+	Disabled         bool        `json:"disabled"`                    // Disabled indicates the package is disabled
+	Score            int         `json:"score"`                       // Score ranks the hit; higher is better
+	Match            MatchKind   `json:"match"`                       // Match is how the term matched
+	MatchedAs        string      `json:"matched_as,omitempty"`        // MatchedAs is the alias or old name that matched
+	NameSpans        []Span      `json:"name_spans,omitempty"`        // NameSpans are matched ranges within Name
+	DescSpans        []Span      `json:"desc_spans,omitempty"`        // DescSpans are matched ranges within Desc
+}
+
+// Scores assigned to each match kind. Within a kind, shorter names and
+// earlier match positions rank higher.
+const (
+	scoreExact   = 1000
+	scorePrefix  = 800
+	scoreAlias   = 700
+	scoreOldName = 650
+	scoreToken   = 500
+	scoreFuzzy   = 300
+	scoreDesc    = 100
+)
+
+// searchCandidate is the normalised form of an index entry used for scoring.
+type searchCandidate struct {
+	name     string
+	desc     string
+	aliases  []string
+	oldNames []string
+}
+
+// scoreCandidate scores a candidate against the lowercased search term.
+// It returns false if the candidate does not match at all.
+func scoreCandidate(c searchCandidate, term string) (SearchHit, bool) {
+	hit := SearchHit{Name: c.name, Desc: c.desc}
+	lowerName := strings.ToLower(c.name)
+
+	switch {
+	case lowerName == term:
+		hit.Match, hit.Score = MatchExact, scoreExact
+		hit.NameSpans = spanAt(c.name, lowerName, 0, len(term))
+	case strings.HasPrefix(lowerName, term):
+		hit.Match, hit.Score = MatchPrefix, scorePrefix-lengthPenalty(lowerName, term)
+		hit.NameSpans = spanAt(c.name, lowerName, 0, len(term))
+	default:
+		if alias, ok := matchExactly(c.aliases, term); ok {
+			hit.Match, hit.Score, hit.MatchedAs = MatchAlias, scoreAlias, alias
+		} else if old, ok := matchExactly(c.oldNames, term); ok {
+			hit.Match, hit.Score, hit.MatchedAs = MatchOldName, scoreOldName, old
+		} else if idx := strings.Index(lowerName, term); idx >= 0 {
+			hit.Match, hit.Score = MatchToken, scoreToken-idx-lengthPenalty(lowerName, term)
+			if isTokenBoundary(lowerName, idx) {
+				hit.Score += 50
+			}
+			hit.NameSpans = spanAt(c.name, lowerName, idx, idx+len(term))
+		} else if alias, ok := matchContains(c.aliases, term); ok {
+			hit.Match, hit.Score, hit.MatchedAs = MatchAlias, scoreToken-100, alias
+		} else if dist, ok := fuzzyMatch(lowerName, term); ok {
+			hit.Match, hit.Score = MatchFuzzy, scoreFuzzy-50*dist-lengthPenalty(lowerName, term)
+		}
+	}
+
+	if idx := strings.Index(strings.ToLower(c.desc), term); idx >= 0 {
+		hit.DescSpans = spanAt(c.desc, strings.ToLower(c.desc), idx, idx+len(term))
+		if hit.Score == 0 {
+			hit.Match, hit.Score = MatchDesc, scoreDesc
+			if isTokenBoundary(strings.ToLower(c.desc), idx) {
+				hit.Score += 20
+			}
+		}
+	}
+
+	return hit, hit.Score > 0
+}
+
+// rankHits sorts hits by descending score, breaking ties by name.
+func rankHits(hits []SearchHit) {
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Name < hits[j].Name
+	})
+}
+
+// lengthPenalty favours names close in length to the search term.
+func lengthPenalty(name, term string) int {
+	return min(len(name)-len(term), 50)
+}
+
+// matchExactly returns the first value equal to term, ignoring case.
+func matchExactly(values []string, term string) (string, bool) {
+	for _, v := range values {
+		if strings.ToLower(v) == term {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// matchContains returns the first value containing term, ignoring case.
+func matchContains(values []string, term string) (string, bool) {
+	for _, v := range values {
+		if strings.Contains(strings.ToLower(v), term) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// isTokenBoundary reports whether idx starts a new token within s.
+func isTokenBoundary(s string, idx int) bool {
+	if idx == 0 {
+		return true
+	}
+	switch s[idx-1] {
+	case '-', '_', '@', '.', ' ', '/', '+':
+		return true
+	}
+	return false
+}
+
+// spanAt returns the span [start, end) in original, or nil if lowercasing
+// changed byte offsets and the span cannot be mapped back safely.
+func spanAt(original, lower string, start, end int) []Span {
+	if len(original) != len(lower) {
+		return nil
+	}
+	return []Span{{Start: start, End: end}}
+}
+
+// maxEdits returns the edit distance tolerated for a term of the given length.
+func maxEdits(n int) int {
+	switch {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// fuzzyMatch reports whether term is within the tolerated edit distance of
+// name or of any of its '-'/'_'/'@' separated tokens.
+func fuzzyMatch(name, term string) (int, bool) {
+	limit := maxEdits(len(term))
+	if limit == 0 {
+		return 0, false
+	}
+
+	best := limit + 1
+	check := func(s string) {
+		if abs(len(s)-len(term)) <= limit {
+			if d := editDistance(s, term, limit); d < best {
+				best = d
+			}
+		}
+	}
+
+	check(name)
+	for _, tok := range strings.FieldsFunc(name, func(r rune) bool {
+		return r == '-' || r == '_' || r == '@' || r == '.'
+	}) {
+		if tok != name {
+			check(tok)
+		}
+	}
+
+	return best, best <= limit
+}
+
+// editDistance computes the optimal string alignment distance between a and
+// b (Levenshtein plus adjacent transpositions). It stops early and returns
+// limit+1 once the distance is known to exceed limit.
+func editDistance(a, b string, limit int) int {
+	if a == b {
+		return 0
+	}
+
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+
+	return prev[len(b)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,189 @@
+package homebrew
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSearchRanking(t *testing.T) {
+	client := &Client{
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		formulaeCache: []FormulaListItem{
+			{Name: "bfg", Desc: "Remove large files or passwords from Git history"},
+			{Name: "git-lfs", Desc: "Git extension for versioning large files"},
+			{Name: "lazygit", Desc: "Simple terminal UI for git commands"},
+			{Name: "git", Desc: "Distributed revision control system"},
+			{Name: "gh", Desc: "GitHub command-line tool", Aliases: []string{"github-cli"}},
+		},
+		casksCache: []CaskListItem{
+			{Token: "firefox", Name: []string{"Mozilla Firefox"}, Desc: "Web browser"},
+		},
+		cacheTimestamp: time.Now(),
+	}
+
+	result, err := client.Search(context.Background(), "git")
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	formulae := result.Formulae
+
+	expected := []string{"git", "git-lfs", "lazygit", "gh", "bfg"}
+	if len(formulae) != len(expected) {
+		t.Fatalf("Expected %d results, got %d: %+v", len(expected), len(formulae), formulae)
+	}
+	for i, name := range expected {
+		if formulae[i].Name != name {
+			t.Errorf("Result %d: expected %s, got %s", i, name, formulae[i].Name)
+		}
+	}
+
+	if formulae[0].Match != MatchExact {
+		t.Errorf("Expected exact match first, got %s", formulae[0].Match)
+	}
+	if formulae[2].Match != MatchToken || formulae[2].NameSpans[0] != (Span{Start: 4, End: 7}) {
+		t.Errorf("Expected token match with span 4-7 for lazygit, got %+v", formulae[2])
+	}
+	if formulae[3].Match != MatchAlias || formulae[3].MatchedAs != "github-cli" {
+		t.Errorf("Expected alias match for gh, got %+v", formulae[3])
+	}
+	if formulae[4].Match != MatchDesc || len(formulae[4].DescSpans) != 1 {
+		t.Errorf("Expected description match for bfg, got %+v", formulae[4])
+	}
+}
+
+func TestSearchAliasAndOldName(t *testing.T) {
+	client := &Client{
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		formulaeCache: []FormulaListItem{
+			{Name: "python@3.13", Aliases: []string{"python3", "python"}},
+			{Name: "ffmpeg", OldName: "libav"},
+		},
+		casksCache: []CaskListItem{
+			{Token: "visual-studio-code", Name: []string{"Microsoft Visual Studio Code", "VS Code"}},
+		},
+		cacheTimestamp: time.Now(),
+	}
+
+	formulae := search(t, client, "python3").Formulae
+	if len(formulae) != 1 || formulae[0].Match != MatchAlias || formulae[0].MatchedAs != "python3" {
+		t.Errorf("Expected alias match for python3, got %+v", formulae)
+	}
+
+	formulae = search(t, client, "libav").Formulae
+	if len(formulae) != 1 || formulae[0].Match != MatchOldName {
+		t.Errorf("Expected old name match for libav, got %+v", formulae)
+	}
+
+	casks := search(t, client, "vs code").Casks
+	if len(casks) != 1 || casks[0].Name != "visual-studio-code" {
+		t.Errorf("Expected cask display name match, got %+v", casks)
+	}
+}
+
+func TestSearchFuzzy(t *testing.T) {
+	client := &Client{
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		formulaeCache: []FormulaListItem{
+			{Name: "postgresql@16"},
+			{Name: "kubectl"},
+			{Name: "node"},
+		},
+		casksCache:     []CaskListItem{{Token: "docker"}},
+		cacheTimestamp: time.Now(),
+	}
+
+	tests := []struct {
+		term     string
+		expected string
+	}{
+		{"kubetcl", "kubectl"},         // transposition
+		{"postgrsql", "postgresql@16"}, // deletion within a token
+		{"nodd", "node"},               // substitution
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.term, func(t *testing.T) {
+			formulae := search(t, client, tt.term).Formulae
+			if len(formulae) == 0 || formulae[0].Name != tt.expected || formulae[0].Match != MatchFuzzy {
+				t.Errorf("Expected fuzzy match %s for %q, got %+v", tt.expected, tt.term, formulae)
+			}
+		})
+	}
+
+	// Short terms are not fuzzy matched to avoid noise
+	if formulae := search(t, client, "nod").Formulae; len(formulae) != 1 || formulae[0].Match != MatchPrefix {
+		t.Errorf("Expected only a prefix match for 'nod', got %+v", formulae)
+	}
+}
+
+func TestSearchResultMetadata(t *testing.T) {
+	client := &Client{
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		formulaeCache: []FormulaListItem{
+			{Name: "python@3.9", Desc: "Interpreted language", Versions: Versions{Stable: "3.9.20"}, Deprecated: true},
+		},
+		casksCache: []CaskListItem{
+			{Token: "python-launcher", Desc: "Launch Python scripts", Version: "1.0", Disabled: true},
+		},
+		cacheTimestamp: time.Now(),
+	}
+
+	result := search(t, client, "python")
+	if result.Term != "python" {
+		t.Errorf("Expected term to be recorded, got '%s'", result.Term)
+	}
+
+	f := result.Formulae[0]
+	if f.Kind != KindFormula || f.Version != "3.9.20" || !f.Deprecated || f.Desc == "" {
+		t.Errorf("Unexpected formula hit: %+v", f)
+	}
+
+	c := result.Casks[0]
+	if c.Kind != KindCask || c.Version != "1.0" || !c.Disabled {
+		t.Errorf("Unexpected cask hit: %+v", c)
+	}
+}
+
+func TestSearchEmptyTerm(t *testing.T) {
+	client := &Client{
+		formulaeCache:  []FormulaListItem{{Name: "git"}},
+		casksCache:     []CaskListItem{{Token: "firefox"}},
+		cacheTimestamp: time.Now(),
+	}
+
+	result := search(t, client, "  ")
+	if len(result.Formulae) != 0 || len(result.Casks) != 0 {
+		t.Errorf("Expected no results for an empty term, got %+v", result)
+	}
+}
+
+func search(t *testing.T, client *Client, term string) *SearchResult {
+	t.Helper()
+	result, err := client.Search(context.Background(), term)
+	if err != nil {
+		t.Fatalf("Search(%q) failed: %v", term, err)
+	}
+	return result
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		limit    int
+		expected int
+	}{
+		{"git", "git", 2, 0},
+		{"git", "gti", 2, 1},
+		{"kitten", "sitting", 3, 3},
+		{"kitten", "sitting", 1, 2}, // Stops early at limit+1
+		{"", "abc", 3, 3},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b, tt.limit); got != tt.expected {
+			t.Errorf("editDistance(%q, %q, %d) = %d, expected %d", tt.a, tt.b, tt.limit, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,536 @@
+package homebrew
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ofkm/goobrew/internal/logger"
+)
+
+// ErrNoService is returned for formulae that do not define a service.
+var ErrNoService = errors.New("formula does not define a service")
+
+// ErrServicesUnsupported is returned on platforms without a supported
+// service manager, and on Linux when systemctl is missing or no systemd
+// user manager is running, as in most containers.
+var ErrServicesUnsupported = errors.New("services are only supported with systemd and launchd")
+
+// ServiceStatus is the state of a formula's service.
+type ServiceStatus string
+
+// Service statuses, named like brew services names them.
+const (
+	ServiceStarted   ServiceStatus = "started"   // ServiceStarted services are running
+	ServiceScheduled ServiceStatus = "scheduled" // ServiceScheduled services are loaded and run on a timer
+	ServiceError     ServiceStatus = "error"     // ServiceError services are loaded but exited with a non-zero code
+	ServiceStopped   ServiceStatus = "stopped"   // ServiceStopped services are started but not running
+	ServiceNone      ServiceStatus = "none"      // ServiceNone services have not been started
+)
+
+// ServiceDefinition is a formula's service resolved for one platform and
+// prefix, as it is rendered into a systemd unit or a launchd property list.
+type ServiceDefinition struct {
+	Formula      string            `json:"formula"`                  // Formula is the formula that defines the service
+	Label        string            `json:"label"`                    // Label is the unit or launchd job name
+	Command      []string          `json:"command"`                  // Command is the program and its arguments
+	RunType      string            `json:"run_type"`                 // RunType is immediate, interval or cron
+	Interval     int               `json:"interval,omitempty"`       // Interval is the seconds between runs of an interval service
+	Cron         string            `json:"cron,omitempty"`           // Cron is the schedule of a cron service
+	RunAtLoad    bool              `json:"run_at_load"`              // RunAtLoad starts the service as soon as it is loaded
+	Restart      string            `json:"restart"`                  // Restart is when the service is restarted, see Service.Restart
+	RestartDelay int               `json:"restart_delay,omitempty"`  // RestartDelay is the seconds to wait before a restart
+	WorkingDir   string            `json:"working_dir,omitempty"`    // WorkingDir is the working directory
+	RootDir      string            `json:"root_dir,omitempty"`       // RootDir is the root directory
+	LogPath      string            `json:"log_path,omitempty"`       // LogPath receives the standard output
+	ErrorLogPath string            `json:"error_log_path,omitempty"` // ErrorLogPath receives the standard error
+	Environment  map[string]string `json:"environment,omitempty"`    // Environment are extra environment variables
+	RequireRoot  bool              `json:"require_root,omitempty"`   // RequireRoot indicates the service must run as root
+}
+
+// scheduled reports whether the service runs on a timer rather than
+// staying up.
+func (d *ServiceDefinition) scheduled() bool {
+	return d.RunType == "interval" || d.RunType == "cron"
+}
+
+// ServiceInfo is the state of a formula's service.
+type ServiceInfo struct {
+	Name       string             `json:"name"`                 // Name is the formula name
+	Label      string             `json:"label"`                // Label is the unit or launchd job name
+	Status     ServiceStatus      `json:"status"`               // Status is the state of the service
+	PID        int                `json:"pid,omitempty"`        // PID is the process ID while it is running
+	ExitCode   *int               `json:"exit_code,omitempty"`  // ExitCode is the last exit code, nil if it never exited
+	File       string             `json:"file,omitempty"`       // File is the unit or property list goobrew wrote
+	StartedAt  *time.Time         `json:"started_at,omitempty"` // StartedAt is when goobrew started the service
+	Definition *ServiceDefinition `json:"definition,omitempty"` // Definition is the rendered service, set by ServiceInfo
+}
+
+// cronSchedule is a five-field cron schedule. Each field is "*" or a
+// comma-separated list of numbers.
+type cronSchedule struct {
+	minute, hour, day, month, weekday string
+}
+
+// parseCron parses the cron schedule of a service. Ranges and steps are
+// not supported, as neither launchd nor the formulae need them.
+func parseCron(expr string) (cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return cronSchedule{}, fmt.Errorf("invalid cron schedule %q", expr)
+	}
+	for _, field := range fields {
+		if field == "*" {
+			continue
+		}
+		for _, part := range strings.Split(field, ",") {
+			if _, err := strconv.Atoi(part); err != nil {
+				return cronSchedule{}, fmt.Errorf("unsupported cron field %q in %q", field, expr)
+			}
+		}
+	}
+	return cronSchedule{minute: fields[0], hour: fields[1], day: fields[2], month: fields[3], weekday: fields[4]}, nil
+}
+
+// serviceRuntime is what a service manager reports about a service.
+type serviceRuntime struct {
+	loaded   bool // loaded is true while the manager knows the service
+	running  bool // running is true while the service's process is up
+	pid      int  // pid is the process ID while running
+	exitCode *int // exitCode is the last exit code, nil if unknown
+}
+
+// serviceManager is the init system that runs services: systemd on Linux
+// and launchd on macOS.
+type serviceManager interface {
+	// platform returns the GOOS whose service fields the manager uses.
+	platform() string
+	// files renders the files that define the service, by path.
+	files(def *ServiceDefinition) (map[string][]byte, error)
+	// reload makes the manager pick up written or removed files.
+	reload(ctx context.Context) error
+	// start loads and starts a service whose files were written.
+	start(ctx context.Context, def *ServiceDefinition) error
+	// stop stops and unloads a service before its files are removed.
+	stop(ctx context.Context, def *ServiceDefinition) error
+	// status reports whether the service is loaded and running.
+	status(ctx context.Context, def *ServiceDefinition) (serviceRuntime, error)
+}
+
+// serviceState is what goobrew records about a service it started.
+type serviceState struct {
+	Label     string    `json:"label"`      // Label is the unit or launchd job name
+	Files     []string  `json:"files"`      // Files are the files written for the service
+	StartedAt time.Time `json:"started_at"` // StartedAt is when the service was started
+}
+
+// DefaultServiceDir returns the directory goobrew records the services it
+// starts in, goobrew/services under the user config directory.
+func DefaultServiceDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "goobrew", "services"), nil
+}
+
+// serviceManager returns the service manager for this platform. On Linux
+// that needs systemctl and a running systemd user manager, which it asks
+// for with systemctl --user show-environment.
+func (c *Client) serviceManager(ctx context.Context) (serviceManager, error) {
+	if c.services != nil {
+		return c.services, nil
+	}
+	switch runtime.GOOS {
+	case "linux":
+		systemctl, err := exec.LookPath("systemctl")
+		if err != nil {
+			return nil, fmt.Errorf("%w: systemctl is not installed", ErrServicesUnsupported)
+		}
+		if _, err := c.serviceCommand(ctx, systemctl, "--user", "show-environment"); err != nil {
+			return nil, fmt.Errorf("%w: the systemd user manager is not running: %w", ErrServicesUnsupported, err)
+		}
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, err
+		}
+		c.services = &systemdManager{client: c, systemctl: systemctl, unitDir: filepath.Join(dir, "systemd", "user")}
+	case "darwin":
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		c.services = &launchdManager{client: c, launchctl: "launchctl", agentDir: filepath.Join(home, "Library", "LaunchAgents")}
+	default:
+		return nil, ErrServicesUnsupported
+	}
+	return c.services, nil
+}
+
+// ServiceDefinition resolves the service of an installed formula for this
+// platform, filling in the Homebrew prefix and home directory. It returns
+// an error wrapping ErrNotFound for formulae missing from the API index and
+// ErrNoService for formulae without a service.
+func (c *Client) ServiceDefinition(ctx context.Context, name string) (*ServiceDefinition, error) {
+	mgr, err := c.serviceManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+	formulae, _, err := c.indexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range formulae {
+		if item.Name != name {
+			continue
+		}
+		if item.Service == nil {
+			return nil, fmt.Errorf("%s: %w", name, ErrNoService)
+		}
+		installed, err := c.localInstallInfo(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if len(installed) == 0 {
+			return nil, fmt.Errorf("%s is not installed", name)
+		}
+		return c.resolveService(name, item.Service, mgr.platform())
+	}
+	return nil, fmt.Errorf("formula %s: %w", name, ErrNotFound)
+}
+
+// resolveService builds the definition of the service of formula on goos.
+func (c *Client) resolveService(formula string, svc *Service, goos string) (*ServiceDefinition, error) {
+	paths, err := c.Paths()
+	if err != nil {
+		return nil, err
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	// $HOMEBREW_PREFIX comes first so that $HOME does not match its start.
+	expand := strings.NewReplacer("$HOMEBREW_PREFIX", paths.Prefix, "$HOME", home).Replace
+
+	command := svc.Command(goos)
+	if len(command) == 0 {
+		return nil, fmt.Errorf("service of %s has no command for this platform", formula)
+	}
+	def := &ServiceDefinition{
+		Formula:      formula,
+		Label:        svc.Label(formula, goos),
+		RunType:      svc.RunType,
+		Interval:     svc.Interval,
+		Cron:         svc.Cron,
+		RunAtLoad:    svc.RunAtLoad == nil || *svc.RunAtLoad,
+		Restart:      svc.Restart(),
+		RestartDelay: svc.RestartDelay,
+		WorkingDir:   expand(svc.WorkingDir),
+		RootDir:      expand(svc.RootDir),
+		LogPath:      expand(svc.LogPath),
+		ErrorLogPath: expand(svc.ErrorLogPath),
+		RequireRoot:  svc.RequireRoot,
+	}
+	if def.RunType == "" {
+		def.RunType = "immediate"
+	}
+	for _, arg := range command {
+		def.Command = append(def.Command, expand(arg))
+	}
+	if len(svc.Environment) > 0 {
+		def.Environment = make(map[string]string, len(svc.Environment))
+		for k, v := range svc.Environment {
+			def.Environment[k] = expand(v)
+		}
+	}
+	return def, nil
+}
+
+// StartService renders the service of an installed formula for systemd or
+// launchd, writes it, starts it and records it in the service directory.
+// Starting a service that is already running leaves it alone.
+func (c *Client) StartService(ctx context.Context, name string) (*ServiceInfo, error) {
+	mgr, def, err := c.serviceFor(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if def.RequireRoot && os.Geteuid() != 0 {
+		return nil, fmt.Errorf("the service of %s must run as root", name)
+	}
+	rt, err := mgr.status(ctx, def)
+	if err != nil {
+		return nil, err
+	}
+	if rt.running {
+		logger.Log.Debug("service already running", "formula", name)
+		return c.serviceInfo(ctx, mgr, def)
+	}
+
+	files, err := mgr.files(def)
+	if err != nil {
+		return nil, err
+	}
+	state := serviceState{Label: def.Label, StartedAt: time.Now().UTC()}
+	for path, data := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { //nolint:gosec // unit directories are world-readable
+			return nil, err
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil { //nolint:gosec // units are read by the service manager
+			return nil, err
+		}
+		state.Files = append(state.Files, path)
+	}
+	sort.Strings(state.Files)
+	if err := mgr.reload(ctx); err != nil {
+		return nil, err
+	}
+	if err := mgr.start(ctx, def); err != nil {
+		return nil, err
+	}
+	if err := c.writeServiceState(name, state); err != nil {
+		return nil, err
+	}
+	return c.serviceInfo(ctx, mgr, def)
+}
+
+// StopService stops the service of a formula, removes the files written
+// for it and forgets it. Stopping a service that is not loaded only
+// removes what is left of it.
+func (c *Client) StopService(ctx context.Context, name string) (*ServiceInfo, error) {
+	mgr, def, err := c.serviceFor(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	rt, err := mgr.status(ctx, def)
+	if err != nil {
+		return nil, err
+	}
+	if rt.loaded {
+		if err := mgr.stop(ctx, def); err != nil {
+			return nil, err
+		}
+	}
+
+	state, _ := c.readServiceState(name)
+	paths := state.Files
+	if len(paths) == 0 {
+		files, err := mgr.files(def)
+		if err != nil {
+			return nil, err
+		}
+		for path := range files {
+			paths = append(paths, path)
+		}
+	}
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	if err := mgr.reload(ctx); err != nil {
+		return nil, err
+	}
+	if err := c.removeServiceState(name); err != nil {
+		return nil, err
+	}
+	return c.serviceInfo(ctx, mgr, def)
+}
+
+// RestartService stops the service of a formula if it is loaded, then
+// renders and starts it again, picking up any change to its definition.
+func (c *Client) RestartService(ctx context.Context, name string) (*ServiceInfo, error) {
+	if _, err := c.StopService(ctx, name); err != nil {
+		return nil, err
+	}
+	return c.StartService(ctx, name)
+}
+
+// ServiceInfo returns the state of the service of an installed formula
+// together with its definition.
+func (c *Client) ServiceInfo(ctx context.Context, name string) (*ServiceInfo, error) {
+	mgr, def, err := c.serviceFor(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := c.serviceInfo(ctx, mgr, def)
+	if err != nil {
+		return nil, err
+	}
+	info.Definition = def
+	return info, nil
+}
+
+// ListServices returns the state of the service of every installed formula
+// that defines one for this platform, sorted by name.
+func (c *Client) ListServices(ctx context.Context) ([]ServiceInfo, error) {
+	mgr, err := c.serviceManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+	formulae, _, err := c.indexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	installed, err := c.InstalledFormulae(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	services := make(map[string]*Service)
+	for _, item := range formulae {
+		if item.Service != nil {
+			services[item.Name] = item.Service
+		}
+	}
+	list := []ServiceInfo{}
+	for _, f := range installed {
+		svc, ok := services[f.Name]
+		if !ok {
+			continue
+		}
+		def, err := c.resolveService(f.Name, svc, mgr.platform())
+		if err != nil {
+			logger.Log.Debug("skipping service", "formula", f.Name, "error", err)
+			continue
+		}
+		info, err := c.serviceInfo(ctx, mgr, def)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *info)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// serviceFor returns the service manager and the definition of the
+// service of a formula.
+func (c *Client) serviceFor(ctx context.Context, name string) (serviceManager, *ServiceDefinition, error) {
+	mgr, err := c.serviceManager(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	def, err := c.ServiceDefinition(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return mgr, def, nil
+}
+
+// serviceInfo combines what the service manager reports about a service
+// with what goobrew recorded when it started it.
+func (c *Client) serviceInfo(ctx context.Context, mgr serviceManager, def *ServiceDefinition) (*ServiceInfo, error) {
+	rt, err := mgr.status(ctx, def)
+	if err != nil {
+		return nil, err
+	}
+	info := &ServiceInfo{Name: def.Formula, Label: def.Label, PID: rt.pid, ExitCode: rt.exitCode}
+	state, recorded := c.readServiceState(def.Formula)
+	if recorded {
+		startedAt := state.StartedAt
+		info.StartedAt = &startedAt
+		if len(state.Files) > 0 {
+			info.File = state.Files[0]
+		}
+	}
+
+	switch {
+	case rt.running:
+		info.Status = ServiceStarted
+	case rt.loaded && def.scheduled():
+		info.Status = ServiceScheduled
+	case rt.loaded && rt.exitCode != nil && *rt.exitCode != 0:
+		info.Status = ServiceError
+	case rt.loaded || recorded:
+		info.Status = ServiceStopped
+	default:
+		info.Status = ServiceNone
+	}
+	return info, nil
+}
+
+// serviceStatePath returns where the state of the service of formula is
+// recorded.
+func (c *Client) serviceStatePath(formula string) (string, error) {
+	dir := c.serviceDir
+	if dir == "" {
+		var err error
+		if dir, err = DefaultServiceDir(); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(dir, formula+".json"), nil
+}
+
+// readServiceState returns the recorded state of the service of formula,
+// and false if goobrew has not started it.
+func (c *Client) readServiceState(formula string) (serviceState, bool) {
+	var state serviceState
+	path, err := c.serviceStatePath(formula)
+	if err != nil {
+		return state, false
+	}
+	data, err := os.ReadFile(path) //nolint:gosec // path is in goobrew's service directory
+	if err != nil {
+		return state, false
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		logger.Log.Debug("ignoring unreadable service state", "path", path, "error", err)
+		return serviceState{}, false
+	}
+	return state, true
+}
+
+// writeServiceState records the state of the service of formula.
+func (c *Client) writeServiceState(formula string, state serviceState) error {
+	path, err := c.serviceStatePath(formula)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { //nolint:gosec // the service directory is not secret
+		return err
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644) //nolint:gosec // the state is not secret
+}
+
+// removeServiceState forgets the service of formula.
+func (c *Client) removeServiceState(formula string) error {
+	path, err := c.serviceStatePath(formula)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// serviceCommand runs a service manager tool and returns its standard
+// output, including its standard error in the error if it fails.
+func (c *Client) serviceCommand(ctx context.Context, tool string, args ...string) ([]byte, error) {
+	cmd := c.processCommand(ctx, tool, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := c.output(cmd)
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return out, fmt.Errorf("%s %s: %w: %s", filepath.Base(tool), strings.Join(args, " "), err, msg)
+		}
+		return out, fmt.Errorf("%s %s: %w", filepath.Base(tool), strings.Join(args, " "), err)
+	}
+	return out, nil
+}
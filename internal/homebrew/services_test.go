@@ -0,0 +1,295 @@
+package homebrew
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serviceIndex is a formula index for the services tests.
+const serviceIndex = `[
+ {"name":"redis","versions":{"stable":"7.2.4"},"service":{"run":["$HOMEBREW_PREFIX/opt/redis/bin/redis-server","$HOMEBREW_PREFIX/etc/redis.conf"],
+  "keep_alive":{"always":true},"working_dir":"$HOMEBREW_PREFIX/var","log_path":"$HOMEBREW_PREFIX/var/log/redis.log","environment_variables":{"LANG":"en_US.UTF-8"}}},
+ {"name":"backup","versions":{"stable":"1.0"},"service":{"run":{"macos":["$HOME/bin/backup","--mac"],"linux":["$HOME/bin/backup"]},"run_type":"cron","cron":"30 2 * * 1"}},
+ {"name":"dbus","versions":{"stable":"1.14"},"service":{"name":{"macos":"org.freedesktop.dbus-session"},"run":["$HOMEBREW_PREFIX/bin/dbus-daemon"]}},
+ {"name":"jq","versions":{"stable":"1.7.1"}}
+]`
+
+// fakeSystemctl is a systemctl that logs its arguments to log and reports
+// a unit as running with PID 4242 once it is enabled, or as failed with
+// exit code 3 when dir/<unit>.failed exists.
+const fakeSystemctl = `
+echo "$@" >> %[1]s/log
+shift
+case "$1" in
+  enable) touch "%[1]s/$3" ;;
+  disable) rm -f "%[1]s/$3" ;;
+  show)
+    if [ -f "%[1]s/$2" ]; then
+      printf 'LoadState=loaded\nActiveState=active\nMainPID=4242\nExecMainStatus=0\nExecMainExitTimestamp=\n'
+    elif [ -f "%[1]s/$2.failed" ]; then
+      printf 'LoadState=loaded\nActiveState=failed\nMainPID=0\nExecMainStatus=3\nExecMainExitTimestamp=Mon 2026-10-12 10:00:00 UTC\n'
+    else
+      printf 'LoadState=not-found\nActiveState=inactive\nMainPID=0\nExecMainStatus=0\nExecMainExitTimestamp=\n'
+    fi ;;
+esac`
+
+func newServiceClient(t *testing.T) (*Client, string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/formula.json":
+			_, _ = w.Write([]byte(serviceIndex))
+		case "/cask.json":
+			_, _ = w.Write([]byte(`[{"token":"firefox","version":"120.0"}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	state := t.TempDir()
+	t.Setenv("HOME", "/home/test")
+	client := &Client{httpClient: &http.Client{Timeout: 5 * time.Second}, serviceDir: filepath.Join(state, "services")}
+	WithAPIEndpoints(server.URL)(client)
+	WithPaths(writeCellar(t, map[string]string{
+		"Cellar/redis/7.2.4/INSTALL_RECEIPT.json": `{}`,
+		"Cellar/backup/1.0/INSTALL_RECEIPT.json":  `{}`,
+		"Cellar/jq/1.7.1/INSTALL_RECEIPT.json":    `{}`,
+		"Cellar/dbus/1.14/INSTALL_RECEIPT.json":   `{}`,
+	}))(client)
+	client.services = &systemdManager{
+		client:    client,
+		systemctl: writeFakeBrew(t, fmt.Sprintf(fakeSystemctl, state)),
+		unitDir:   filepath.Join(state, "units"),
+	}
+	return client, state
+}
+
+func TestServiceModel(t *testing.T) {
+	svc, err := parseService(`{"name":{"macos":"org.freedesktop.dbus-session"},"run":{"linux":["dbus-daemon","--session"]},"keep_alive":{"successful_exit":false}}`)
+	if err != nil {
+		t.Fatalf("Failed to parse service: %v", err)
+	}
+	if got := svc.Label("dbus", "darwin"); got != "org.freedesktop.dbus-session" {
+		t.Errorf("Expected the custom launchd label, got %q", got)
+	}
+	if got := svc.Label("dbus", "linux"); got != "homebrew.dbus" {
+		t.Errorf("Expected the default systemd label, got %q", got)
+	}
+	if got := strings.Join(svc.Command("linux"), " "); got != "dbus-daemon --session" {
+		t.Errorf("Expected the linux command, got %q", got)
+	}
+	if got := svc.Command("darwin"); got != nil {
+		t.Errorf("Expected no macOS command, got %v", got)
+	}
+
+	tests := map[string]string{
+		`true`:                     "always",
+		`false`:                    "no",
+		`{"always":true}`:          "always",
+		`{"crashed":true}`:         "on-failure",
+		`{"successful_exit":true}`: "on-success",
+		`{"path":"/tmp/flag"}`:     "no",
+	}
+	for keepAlive, want := range tests {
+		svc, err := parseService(`{"run":"x","keep_alive":` + keepAlive + `}`)
+		if err != nil {
+			t.Fatalf("Failed to parse service: %v", err)
+		}
+		if got := svc.Restart(); got != want {
+			t.Errorf("keep_alive %s: expected %q, got %q", keepAlive, want, got)
+		}
+	}
+}
+
+func parseService(data string) (*Service, error) {
+	var svc Service
+	err := json.Unmarshal([]byte(data), &svc)
+	return &svc, err
+}
+
+func TestStartStopService(t *testing.T) {
+	client, state := newServiceClient(t)
+	ctx := context.Background()
+
+	info, err := client.StartService(ctx, "redis")
+	if err != nil {
+		t.Fatalf("StartService failed: %v", err)
+	}
+	if info.Status != ServiceStarted || info.PID != 4242 || info.Label != "homebrew.redis" || info.StartedAt == nil {
+		t.Errorf("Unexpected service info: %+v", info)
+	}
+	unit := filepath.Join(state, "units", "homebrew.redis.service")
+	if info.File != unit {
+		t.Errorf("Expected file %s, got %s", unit, info.File)
+	}
+	data, err := os.ReadFile(unit)
+	if err != nil {
+		t.Fatalf("Unit was not written: %v", err)
+	}
+	prefix := client.paths.Prefix
+	for _, want := range []string{
+		"ExecStart=" + prefix + "/opt/redis/bin/redis-server " + prefix + "/etc/redis.conf\n",
+		"Restart=always\n",
+		"WorkingDirectory=" + prefix + "/var\n",
+		"StandardOutput=append:" + prefix + "/var/log/redis.log\n",
+		"Environment=LANG=en_US.UTF-8\n",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected unit to contain %q, got:\n%s", want, data)
+		}
+	}
+
+	list, err := client.ListServices(ctx)
+	if err != nil {
+		t.Fatalf("ListServices failed: %v", err)
+	}
+	var got []string
+	for _, s := range list {
+		got = append(got, s.Name+"="+string(s.Status))
+	}
+	if want := "backup=none dbus=none redis=started"; strings.Join(got, " ") != want {
+		t.Errorf("Expected %q, got %q", want, strings.Join(got, " "))
+	}
+
+	info, err = client.StopService(ctx, "redis")
+	if err != nil {
+		t.Fatalf("StopService failed: %v", err)
+	}
+	if info.Status != ServiceNone || info.File != "" {
+		t.Errorf("Expected the service to be gone, got %+v", info)
+	}
+	if _, err := os.Stat(unit); !os.IsNotExist(err) {
+		t.Errorf("Expected the unit to be removed, got %v", err)
+	}
+
+	log, _ := os.ReadFile(filepath.Join(state, "log"))
+	for _, want := range []string{
+		"--user daemon-reload",
+		"--user enable --now homebrew.redis.service",
+		"--user disable --now homebrew.redis.service",
+	} {
+		if !strings.Contains(string(log), want+"\n") {
+			t.Errorf("Expected systemctl %q, got:\n%s", want, log)
+		}
+	}
+}
+
+func TestServiceInfo(t *testing.T) {
+	client, state := newServiceClient(t)
+	ctx := context.Background()
+
+	if err := os.WriteFile(filepath.Join(state, "homebrew.backup.service.failed"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := client.ServiceInfo(ctx, "backup")
+	if err != nil {
+		t.Fatalf("ServiceInfo failed: %v", err)
+	}
+	if info.ExitCode == nil || *info.ExitCode != 3 {
+		t.Errorf("Expected exit code 3, got %+v", info)
+	}
+	def := info.Definition
+	if def == nil || strings.Join(def.Command, " ") != "/home/test/bin/backup" || def.RunType != "cron" {
+		t.Fatalf("Unexpected definition: %+v", def)
+	}
+	timer, err := renderSystemdTimer(def)
+	if err != nil {
+		t.Fatalf("renderSystemdTimer failed: %v", err)
+	}
+	if !strings.Contains(string(timer), "OnCalendar=Mon *-*-* 02:30:00\n") {
+		t.Errorf("Unexpected timer:\n%s", timer)
+	}
+	plist, err := renderLaunchdPlist(def)
+	if err != nil {
+		t.Fatalf("renderLaunchdPlist failed: %v", err)
+	}
+	want := "<key>StartCalendarInterval</key>\n\t<dict>\n\t\t<key>Minute</key>\n\t\t<integer>30</integer>\n" +
+		"\t\t<key>Hour</key>\n\t\t<integer>2</integer>\n\t\t<key>Weekday</key>\n\t\t<integer>1</integer>\n\t</dict>\n"
+	if !strings.Contains(string(plist), want) {
+		t.Errorf("Unexpected plist:\n%s", plist)
+	}
+
+	if _, err := client.StartService(ctx, "jq"); !errors.Is(err, ErrNoService) {
+		t.Errorf("Expected ErrNoService, got %v", err)
+	}
+	if _, err := client.StartService(ctx, "nope"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestRenderLaunchdPlist(t *testing.T) {
+	def := &ServiceDefinition{
+		Label:       "homebrew.mxcl.redis",
+		Command:     []string{"/opt/homebrew/bin/redis-server", "a&b"},
+		RunType:     "immediate",
+		RunAtLoad:   true,
+		Restart:     "on-failure",
+		LogPath:     "/opt/homebrew/var/log/redis.log",
+		Environment: map[string]string{"B": "2", "A": "1"},
+	}
+	plist, err := renderLaunchdPlist(def)
+	if err != nil {
+		t.Fatalf("renderLaunchdPlist failed: %v", err)
+	}
+	for _, want := range []string{
+		"<key>Label</key>\n\t<string>homebrew.mxcl.redis</string>\n",
+		"\t\t<string>a&amp;b</string>\n",
+		"<key>RunAtLoad</key>\n\t<true/>\n",
+		"<key>KeepAlive</key>\n\t<dict>\n\t\t<key>SuccessfulExit</key>\n\t\t<false/>\n",
+		"<key>StandardOutPath</key>\n\t<string>/opt/homebrew/var/log/redis.log</string>\n",
+		"\t\t<key>A</key>\n\t\t<string>1</string>\n\t\t<key>B</key>",
+	} {
+		if !strings.Contains(string(plist), want) {
+			t.Errorf("Expected plist to contain %q, got:\n%s", want, plist)
+		}
+	}
+}
+
+func TestServiceManagerRequiresSystemd(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("systemd is only used on Linux")
+	}
+	ctx := context.Background()
+
+	t.Setenv("PATH", t.TempDir())
+	if _, err := (&Client{}).serviceManager(ctx); !errors.Is(err, ErrServicesUnsupported) {
+		t.Errorf("Expected ErrServicesUnsupported without systemctl, got %v", err)
+	}
+
+	dir := t.TempDir()
+	systemctl := `#!/bin/sh
+echo "Failed to connect to bus: No medium found" >&2
+exit 1
+`
+	if err := os.WriteFile(filepath.Join(dir, "systemctl"), []byte(systemctl), 0o755); err != nil { //nolint:gosec // test script must be executable
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+	_, err := (&Client{}).serviceManager(ctx)
+	if !errors.Is(err, ErrServicesUnsupported) || !strings.Contains(err.Error(), "Failed to connect to bus") {
+		t.Errorf("Expected ErrServicesUnsupported without a user manager, got %v", err)
+	}
+
+	systemctl = "#!/bin/sh\necho PATH=/usr/bin\n"
+	if err := os.WriteFile(filepath.Join(dir, "systemctl"), []byte(systemctl), 0o755); err != nil { //nolint:gosec // test script must be executable
+		t.Fatal(err)
+	}
+	mgr, err := (&Client{}).serviceManager(ctx)
+	if err != nil {
+		t.Fatalf("Expected systemd with a running user manager, got %v", err)
+	}
+	if m, ok := mgr.(*systemdManager); !ok || m.systemctl != filepath.Join(dir, "systemctl") {
+		t.Errorf("Unexpected service manager: %+v", mgr)
+	}
+}
@@ -0,0 +1,242 @@
+package homebrew
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// systemdManager runs services as systemd user units, written to unitDir
+// and controlled with systemctl --user.
+type systemdManager struct {
+	client    *Client
+	systemctl string // systemctl is the systemctl binary
+	unitDir   string // unitDir is the systemd user unit directory
+}
+
+// platform implements serviceManager.
+func (m *systemdManager) platform() string {
+	return "linux"
+}
+
+// files renders a service unit, plus a timer unit for services that run on
+// an interval or cron schedule.
+func (m *systemdManager) files(def *ServiceDefinition) (map[string][]byte, error) {
+	files := map[string][]byte{filepath.Join(m.unitDir, def.Label+".service"): renderSystemdService(def)}
+	if def.scheduled() {
+		timer, err := renderSystemdTimer(def)
+		if err != nil {
+			return nil, err
+		}
+		files[filepath.Join(m.unitDir, def.Label+".timer")] = timer
+	}
+	return files, nil
+}
+
+// reload implements serviceManager.
+func (m *systemdManager) reload(ctx context.Context) error {
+	_, err := m.systemctlRun(ctx, "daemon-reload")
+	return err
+}
+
+// start enables the timer of a scheduled service, or enables the service
+// and starts it unless it does not run at load.
+func (m *systemdManager) start(ctx context.Context, def *ServiceDefinition) error {
+	args := []string{"enable"}
+	if def.RunAtLoad || def.scheduled() {
+		args = append(args, "--now")
+	}
+	args = append(args, m.unit(def))
+	_, err := m.systemctlRun(ctx, args...)
+	return err
+}
+
+// stop disables and stops the service and its timer.
+func (m *systemdManager) stop(ctx context.Context, def *ServiceDefinition) error {
+	args := []string{"disable", "--now", def.Label + ".service"}
+	if def.scheduled() {
+		args = append(args, def.Label+".timer")
+	}
+	_, err := m.systemctlRun(ctx, args...)
+	return err
+}
+
+// status reads the state of the service unit, and of the timer for a
+// scheduled service, from systemctl show.
+func (m *systemdManager) status(ctx context.Context, def *ServiceDefinition) (serviceRuntime, error) {
+	var rt serviceRuntime
+	props, err := m.show(ctx, def.Label+".service")
+	if err != nil {
+		return rt, err
+	}
+	rt.loaded = props["LoadState"] == "loaded"
+	if pid, _ := strconv.Atoi(props["MainPID"]); pid > 0 {
+		rt.pid = pid
+		rt.running = true
+	}
+	if props["ExecMainExitTimestamp"] != "" && !rt.running {
+		if code, err := strconv.Atoi(props["ExecMainStatus"]); err == nil {
+			rt.exitCode = &code
+		}
+	}
+	if def.scheduled() {
+		timer, err := m.show(ctx, def.Label+".timer")
+		if err != nil {
+			return rt, err
+		}
+		rt.loaded = timer["ActiveState"] == "active"
+	}
+	return rt, nil
+}
+
+// unit returns the unit start enables for a service.
+func (m *systemdManager) unit(def *ServiceDefinition) string {
+	if def.scheduled() {
+		return def.Label + ".timer"
+	}
+	return def.Label + ".service"
+}
+
+// show returns the properties systemctl shows for unit.
+func (m *systemdManager) show(ctx context.Context, unit string) (map[string]string, error) {
+	out, err := m.systemctlRun(ctx, "show", unit,
+		"--property=LoadState,ActiveState,MainPID,ExecMainStatus,ExecMainExitTimestamp")
+	if err != nil {
+		return nil, err
+	}
+	props := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if key, value, ok := strings.Cut(scanner.Text(), "="); ok {
+			props[key] = value
+		}
+	}
+	return props, scanner.Err()
+}
+
+// systemctlRun runs systemctl --user with args.
+func (m *systemdManager) systemctlRun(ctx context.Context, args ...string) ([]byte, error) {
+	return m.client.serviceCommand(ctx, m.systemctl, append([]string{"--user"}, args...)...)
+}
+
+// renderSystemdService renders the service unit of def, in the layout
+// brew services uses.
+func renderSystemdService(def *ServiceDefinition) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[Unit]\nDescription=Homebrew generated unit for %s\n\n", def.Formula)
+	b.WriteString("[Install]\nWantedBy=default.target\n\n")
+	b.WriteString("[Service]\n")
+	if def.scheduled() {
+		b.WriteString("Type=oneshot\n")
+	} else {
+		b.WriteString("Type=simple\n")
+	}
+
+	args := make([]string, len(def.Command))
+	for i, arg := range def.Command {
+		args[i] = systemdQuote(strings.ReplaceAll(arg, "$", "$$"))
+	}
+	fmt.Fprintf(&b, "ExecStart=%s\n", strings.Join(args, " "))
+	if !def.scheduled() && def.Restart != "no" {
+		fmt.Fprintf(&b, "Restart=%s\n", def.Restart)
+		if def.RestartDelay > 0 {
+			fmt.Fprintf(&b, "RestartSec=%d\n", def.RestartDelay)
+		}
+	}
+	if def.WorkingDir != "" {
+		fmt.Fprintf(&b, "WorkingDirectory=%s\n", def.WorkingDir)
+	}
+	if def.RootDir != "" {
+		fmt.Fprintf(&b, "RootDirectory=%s\n", def.RootDir)
+	}
+	if def.LogPath != "" {
+		fmt.Fprintf(&b, "StandardOutput=append:%s\n", def.LogPath)
+	}
+	if def.ErrorLogPath != "" {
+		fmt.Fprintf(&b, "StandardError=append:%s\n", def.ErrorLogPath)
+	}
+	keys := make([]string, 0, len(def.Environment))
+	for k := range def.Environment {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "Environment=%s\n", systemdQuote(k+"="+def.Environment[k]))
+	}
+	return []byte(b.String())
+}
+
+// renderSystemdTimer renders the timer unit that runs a scheduled service.
+func renderSystemdTimer(def *ServiceDefinition) ([]byte, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[Unit]\nDescription=Homebrew generated timer for %s\n\n", def.Formula)
+	b.WriteString("[Install]\nWantedBy=timers.target\n\n")
+	fmt.Fprintf(&b, "[Timer]\nUnit=%s.service\n", def.Label)
+	switch def.RunType {
+	case "interval":
+		if def.Interval <= 0 {
+			return nil, fmt.Errorf("service of %s has no interval", def.Formula)
+		}
+		if def.RunAtLoad {
+			b.WriteString("OnActiveSec=0\n")
+		} else {
+			fmt.Fprintf(&b, "OnActiveSec=%d\n", def.Interval)
+		}
+		fmt.Fprintf(&b, "OnUnitActiveSec=%d\n", def.Interval)
+	case "cron":
+		calendar, err := systemdCalendar(def.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("service of %s: %w", def.Formula, err)
+		}
+		b.WriteString("Persistent=true\n")
+		fmt.Fprintf(&b, "OnCalendar=%s\n", calendar)
+	}
+	return []byte(b.String()), nil
+}
+
+// systemdWeekdays are the systemd names of cron's weekdays, Sunday first.
+var systemdWeekdays = []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+
+// systemdCalendar converts a cron schedule to a systemd OnCalendar
+// expression.
+func systemdCalendar(expr string) (string, error) {
+	cron, err := parseCron(expr)
+	if err != nil {
+		return "", err
+	}
+	pad := func(field string) string {
+		if n, err := strconv.Atoi(field); err == nil {
+			return fmt.Sprintf("%02d", n)
+		}
+		return field
+	}
+	calendar := fmt.Sprintf("*-%s-%s %s:%s:00", pad(cron.month), pad(cron.day), pad(cron.hour), pad(cron.minute))
+	if cron.weekday == "*" {
+		return calendar, nil
+	}
+	var days []string
+	for _, d := range strings.Split(cron.weekday, ",") {
+		n, err := strconv.Atoi(d)
+		if err != nil || n < 0 || n >= len(systemdWeekdays) {
+			return "", fmt.Errorf("invalid cron weekday %q", d)
+		}
+		days = append(days, systemdWeekdays[n])
+	}
+	return strings.Join(days, ",") + " " + calendar, nil
+}
+
+// systemdQuote quotes a word for a unit file, escaping the specifiers
+// systemd would otherwise expand. ExecStart also expands variables, so its
+// words have $ escaped first.
+func systemdQuote(word string) string {
+	word = strings.ReplaceAll(word, "%", "%%")
+	if word != "" && !strings.ContainsAny(word, " \t\"'\\") {
+		return word
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(word) + `"`
+}
@@ -0,0 +1,143 @@
+package homebrew
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// UsesOptions configures Uses.
+type UsesOptions struct {
+	Recursive bool // Recursive also reports formulae that depend on the named ones indirectly
+	All       bool // All searches every formula in the API index, not only installed ones
+}
+
+// Dependent is a formula that depends on one or more queried formulae.
+type Dependent struct {
+	Name      string   `json:"name"`          // Name is the dependent formula
+	Installed bool     `json:"installed"`     // Installed is true if the dependent is installed
+	Uses      []string `json:"uses"`          // Uses lists the queried formulae it depends on
+	Via       string   `json:"via,omitempty"` // Via is the dependent it depends on them through, empty if direct
+}
+
+// Uses returns the formulae that depend on any of the named formulae,
+// sorted by name. By default only installed formulae are considered,
+// using the runtime dependencies recorded when they were installed; with
+// opts.All every formula in the API index is searched instead. With
+// opts.Recursive, formulae that depend on the named ones through other
+// formulae are included too. The named formulae themselves are never
+// reported.
+func (c *Client) Uses(ctx context.Context, names []string, opts UsesOptions) ([]Dependent, error) {
+	installed, err := c.InstalledFormulae(ctx)
+	if err != nil && !opts.All {
+		return nil, err
+	}
+	isInstalled := make(map[string]bool, len(installed))
+	for _, f := range installed {
+		isInstalled[f.Name] = true
+	}
+
+	var index map[string][]string
+	if opts.All {
+		formulae, _, err := c.indexes(ctx)
+		if err != nil {
+			return nil, err
+		}
+		index = reverseIndex(formulae)
+	} else {
+		index = installedReverseIndex(installed)
+	}
+
+	queried := make(map[string]bool, len(names))
+	for _, name := range names {
+		queried[name] = true
+	}
+
+	found := make(map[string]*Dependent)
+	for _, name := range names {
+		seen := map[string]bool{name: true}
+		queue := []string{name}
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+
+			for _, dependent := range index[current] {
+				if seen[dependent] || queried[dependent] {
+					continue
+				}
+				seen[dependent] = true
+
+				d, ok := found[dependent]
+				if !ok {
+					d = &Dependent{Name: dependent, Installed: isInstalled[dependent], Via: current}
+					found[dependent] = d
+				}
+				if current == name {
+					d.Via = ""
+				}
+				d.Uses = append(d.Uses, name)
+
+				if opts.Recursive {
+					queue = append(queue, dependent)
+				}
+			}
+		}
+	}
+
+	dependents := make([]Dependent, 0, len(found))
+	for _, d := range found {
+		dependents = append(dependents, *d)
+	}
+	sort.Slice(dependents, func(i, j int) bool { return dependents[i].Name < dependents[j].Name })
+	return dependents, nil
+}
+
+// reverseIndex maps each formula in the API index to the formulae that
+// declare it as a dependency.
+func reverseIndex(formulae []FormulaListItem) map[string][]string {
+	index := make(map[string][]string)
+	for _, f := range formulae {
+		for _, dep := range f.Dependencies {
+			index[dep] = append(index[dep], f.Name)
+		}
+	}
+	return index
+}
+
+// installedReverseIndex maps each formula to the installed formulae that
+// depend on it directly.
+func installedReverseIndex(installed []Formula) map[string][]string {
+	index := make(map[string][]string)
+	for _, f := range installed {
+		for _, dep := range installedDependencies(f) {
+			index[dep] = append(index[dep], f.Name)
+		}
+	}
+	return index
+}
+
+// installedDependencies returns the direct dependencies of an installed
+// formula. It uses the runtime dependencies recorded for the newest
+// installed version, which brew marks as declared directly or not;
+// formulae installed by older versions of brew, which do not record this,
+// fall back to the formula's declared dependencies.
+func installedDependencies(f Formula) []string {
+	var deps []string
+	if n := len(f.Installed); n > 0 {
+		for _, dep := range f.Installed[n-1].RuntimeDependencies {
+			if dep.DeclaredDirectly {
+				deps = append(deps, shortFormulaName(dep.FullName))
+			}
+		}
+	}
+	if len(deps) == 0 {
+		deps = f.Dependencies
+	}
+	return deps
+}
+
+// shortFormulaName strips the tap from a full formula name such as
+// "homebrew/core/openssl@3".
+func shortFormulaName(fullName string) string {
+	return fullName[strings.LastIndex(fullName, "/")+1:]
+}
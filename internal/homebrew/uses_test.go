@@ -0,0 +1,116 @@
+package homebrew
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// installedJSON is `brew info --json=v1 --installed` output for curl and
+// wget depending on openssl@3, and git depending on curl. wget was
+// installed by an older brew that did not record declared_directly.
+const installedJSON = `[
+ {"name":"openssl@3","installed":[{"version":"3.4.0"}]},
+ {"name":"curl","installed":[{"version":"8.10.1","runtime_dependencies":[
+   {"full_name":"homebrew/core/openssl@3","declared_directly":true},
+   {"full_name":"ca-certificates","declared_directly":false}]}]},
+ {"name":"wget","dependencies":["openssl@3"],"installed":[{"version":"1.24.5"}]},
+ {"name":"git","installed":[{"version":"2.47.0","runtime_dependencies":[
+   {"full_name":"curl","declared_directly":true},
+   {"full_name":"openssl@3","declared_directly":false}]}]}
+]`
+
+func TestUses_Installed(t *testing.T) {
+	brew := writeFakeBrew(t, "echo '"+installedJSON+"'")
+	client := &Client{httpClient: &http.Client{Timeout: 5 * time.Second}, brewPath: brew}
+
+	dependents, err := client.Uses(context.Background(), []string{"openssl@3"}, UsesOptions{})
+	if err != nil {
+		t.Fatalf("Uses failed: %v", err)
+	}
+	if names := dependentNames(dependents); !slices.Equal(names, []string{"curl", "wget"}) {
+		t.Errorf("Expected direct dependents [curl wget], got %v", names)
+	}
+
+	dependents, err = client.Uses(context.Background(), []string{"openssl@3"}, UsesOptions{Recursive: true})
+	if err != nil {
+		t.Fatalf("Uses failed: %v", err)
+	}
+	if names := dependentNames(dependents); !slices.Equal(names, []string{"curl", "git", "wget"}) {
+		t.Fatalf("Expected recursive dependents [curl git wget], got %v", names)
+	}
+	if git := dependents[1]; git.Via != "curl" || !git.Installed || !slices.Equal(git.Uses, []string{"openssl@3"}) {
+		t.Errorf("Expected git to use openssl@3 via curl, got %+v", git)
+	}
+
+	dependents, err = client.Uses(context.Background(), []string{"openssl@3", "curl"}, UsesOptions{})
+	if err != nil {
+		t.Fatalf("Uses failed: %v", err)
+	}
+	if names := dependentNames(dependents); !slices.Equal(names, []string{"git", "wget"}) {
+		t.Errorf("Queried formulae should not be reported, got %v", names)
+	}
+}
+
+func TestUses_All(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/formula.json":
+			_, _ = w.Write([]byte(`[
+ {"name":"curl","dependencies":["openssl@3"]},
+ {"name":"httpie","dependencies":["python@3.13"]},
+ {"name":"python@3.13","dependencies":["openssl@3"]}
+]`))
+		default:
+			_, _ = w.Write([]byte(`[]`))
+		}
+	}))
+	defer server.Close()
+
+	brew := writeFakeBrew(t, `echo '[{"name":"curl","installed":[{"version":"8.10.1"}]}]'`)
+	client := &Client{httpClient: &http.Client{Timeout: 5 * time.Second}, brewPath: brew}
+	WithAPIEndpoints(server.URL)(client)
+
+	dependents, err := client.Uses(context.Background(), []string{"openssl@3"}, UsesOptions{All: true, Recursive: true})
+	if err != nil {
+		t.Fatalf("Uses failed: %v", err)
+	}
+	if names := dependentNames(dependents); !slices.Equal(names, []string{"curl", "httpie", "python@3.13"}) {
+		t.Fatalf("Expected [curl httpie python@3.13], got %v", names)
+	}
+	if !dependents[0].Installed || dependents[1].Installed {
+		t.Errorf("Expected only curl to be installed, got %+v", dependents)
+	}
+}
+
+func TestUninstall_IgnoreDependencies(t *testing.T) {
+	argsFile := filepath.Join(t.TempDir(), "args")
+	brew := writeFakeBrew(t, `echo "$@" > `+argsFile)
+	client := &Client{brewPath: brew}
+
+	if err := client.Uninstall(context.Background(), []string{"openssl@3"}, WithIgnoreDependencies()); err != nil {
+		t.Fatalf("Uninstall failed: %v", err)
+	}
+
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatalf("Failed to read brew arguments: %v", err)
+	}
+	if got := strings.TrimSpace(string(args)); got != "uninstall --ignore-dependencies openssl@3" {
+		t.Errorf("Unexpected brew arguments: %q", got)
+	}
+}
+
+func dependentNames(dependents []Dependent) []string {
+	names := make([]string, len(dependents))
+	for i, d := range dependents {
+		names[i] = d.Name
+	}
+	return names
+}
@@ -0,0 +1,177 @@
+// Package lockfile reads and writes goobrew.lock, which records the exact
+// formula and cask versions of an environment so that it can be verified
+// and reproduced. Formulae record the sha256 of their bottle for every
+// platform and the commit of the tap they came from.
+package lockfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// Name is the default file name of a lockfile.
+const Name = "goobrew.lock"
+
+// FormatVersion is the version of the lockfile format written by Write.
+const FormatVersion = 1
+
+// Lockfile is the content of goobrew.lock.
+type Lockfile struct {
+	Version  int       `json:"version"`  // Version is the lockfile format version
+	Packages []Package `json:"packages"` // Packages are the locked formulae, then casks, each sorted by name
+}
+
+// Package is a locked formula or cask.
+type Package struct {
+	Name         string            `json:"name"`                   // Name is the formula name or cask token
+	FullName     string            `json:"full_name,omitempty"`    // FullName includes the tap for third-party formulae
+	Tap          string            `json:"tap,omitempty"`          // Tap is the repository the package came from
+	Cask         bool              `json:"cask,omitempty"`         // Cask is true for casks
+	Version      string            `json:"version"`                // Version is the installed version
+	Revision     int               `json:"revision,omitempty"`     // Revision is the formula revision
+	Bottles      map[string]string `json:"bottles,omitempty"`      // Bottles maps bottle tags to the sha256 of the bottle
+	SHA256       string            `json:"sha256,omitempty"`       // SHA256 is the checksum of a cask's download
+	TapGitHead   string            `json:"tap_git_head,omitempty"` // TapGitHead is the tap commit the package was locked at
+	Dependencies []string          `json:"dependencies,omitempty"` // Dependencies are the locked runtime dependencies of a formula
+	Dependency   bool              `json:"dependency,omitempty"`   // Dependency is true if the package is only locked as a dependency
+}
+
+// PkgVersion returns the version with the revision suffix Homebrew uses
+// for kegs, such as "1.2.3_1".
+func (p Package) PkgVersion() string {
+	if p.Revision > 0 {
+		return p.Version + "_" + strconv.Itoa(p.Revision)
+	}
+	return p.Version
+}
+
+// Find returns the locked package called name, matching formulae by name
+// or full name.
+func (l *Lockfile) Find(name string) (*Package, bool) {
+	for i := range l.Packages {
+		if p := &l.Packages[i]; p.Name == name || p.FullName == name {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// Closure returns the locked packages called names together with every
+// locked dependency they need, each once, and the names that are not
+// locked.
+func (l *Lockfile) Closure(names []string) ([]Package, []string) {
+	var (
+		packages []Package
+		unlocked []string
+		seen     = make(map[string]bool)
+	)
+	queue := append([]string(nil), names...)
+	requested := len(queue)
+	for i := 0; i < len(queue); i++ {
+		p, ok := l.Find(queue[i])
+		switch {
+		case !ok && i < requested:
+			unlocked = append(unlocked, queue[i])
+			continue
+		case !ok || seen[p.Name]:
+			continue
+		}
+		seen[p.Name] = true
+		packages = append(packages, *p)
+		queue = append(queue, p.Dependencies...)
+	}
+	return packages, unlocked
+}
+
+// Sort orders formulae before casks, each by name.
+func (l *Lockfile) Sort() {
+	sort.Slice(l.Packages, func(i, j int) bool {
+		a, b := l.Packages[i], l.Packages[j]
+		if a.Cask != b.Cask {
+			return !a.Cask
+		}
+		return a.Name < b.Name
+	})
+}
+
+// Read loads the lockfile at path.
+func Read(path string) (*Lockfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var l Lockfile
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, fmt.Errorf("failed to parse lockfile %s: %w", path, err)
+	}
+	if l.Version > FormatVersion {
+		return nil, fmt.Errorf("lockfile %s has format version %d, this goobrew supports up to %d", path, l.Version, FormatVersion)
+	}
+	return &l, nil
+}
+
+// Write saves l to path as indented JSON, with the packages sorted so that
+// the file diffs cleanly.
+func Write(path string, l *Lockfile) error {
+	l.Version = FormatVersion
+	l.Sort()
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode lockfile: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil { //nolint:gosec // the lockfile is meant to be committed and shared
+		return fmt.Errorf("failed to write lockfile: %w", err)
+	}
+	return nil
+}
+
+// DriftKind classifies a difference between a lockfile and the system.
+type DriftKind string
+
+// Drift kinds.
+const (
+	DriftMissing  DriftKind = "missing"  // DriftMissing means a locked package is not installed
+	DriftVersion  DriftKind = "version"  // DriftVersion means the version differs from the locked one
+	DriftBottle   DriftKind = "bottle"   // DriftBottle means the bottle or download checksum differs from the locked one
+	DriftUnlocked DriftKind = "unlocked" // DriftUnlocked means a package is not in the lockfile
+	DriftUnknown  DriftKind = "unknown"  // DriftUnknown means the package could not be checked
+)
+
+// Drift is one difference between a lockfile and the system or the API.
+type Drift struct {
+	Package string    `json:"package"`          // Package is the formula name or cask token
+	Cask    bool      `json:"cask,omitempty"`   // Cask is true for casks
+	Kind    DriftKind `json:"kind"`             // Kind classifies the difference
+	Locked  string    `json:"locked,omitempty"` // Locked is the locked value
+	Actual  string    `json:"actual,omitempty"` // Actual is the value found instead
+}
+
+// String describes the drift in one line.
+func (d Drift) String() string {
+	switch d.Kind {
+	case DriftMissing:
+		return fmt.Sprintf("%s %s is locked but not installed", d.Package, d.Locked)
+	case DriftVersion:
+		return fmt.Sprintf("%s is %s, locked at %s", d.Package, d.Actual, d.Locked)
+	case DriftBottle:
+		return fmt.Sprintf("%s checksum is %s, locked at %s", d.Package, short(d.Actual), short(d.Locked))
+	case DriftUnlocked:
+		return d.Package + " is not in the lockfile"
+	}
+	return fmt.Sprintf("%s could not be checked: %s", d.Package, d.Actual)
+}
+
+// short abbreviates a checksum for display.
+func short(sum string) string {
+	switch {
+	case sum == "":
+		return "none"
+	case len(sum) > 12:
+		return sum[:12]
+	}
+	return sum
+}
@@ -0,0 +1,84 @@
+package lockfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClosure(t *testing.T) {
+	l := &Lockfile{Packages: []Package{
+		{Name: "wget", Dependencies: []string{"openssl@3", "libidn2"}},
+		{Name: "openssl@3", Dependencies: []string{"ca-certificates"}},
+		{Name: "ca-certificates"},
+		{Name: "nginx-full", FullName: "denji/nginx/nginx-full", Dependencies: []string{"openssl@3"}},
+		{Name: "jq"},
+	}}
+
+	packages, unlocked := l.Closure([]string{"wget", "denji/nginx/nginx-full", "tree"})
+	var names []string
+	for _, p := range packages {
+		names = append(names, p.Name)
+	}
+	if got := strings.Join(names, " "); got != "wget nginx-full openssl@3 ca-certificates" {
+		t.Errorf("Closure = %q", got)
+	}
+	if len(unlocked) != 1 || unlocked[0] != "tree" {
+		t.Errorf("Unlocked = %v, unlocked dependencies such as libidn2 should not be reported", unlocked)
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), Name)
+	l := &Lockfile{Packages: []Package{
+		{Name: "firefox", Cask: true, Version: "120.0", SHA256: "fff"},
+		{Name: "wget", Tap: "homebrew/core", Version: "1.24.5", Bottles: map[string]string{"all": "aaa"}, TapGitHead: "abc123"},
+		{Name: "openssl@3", Version: "3.3.2", Revision: 1, Dependency: true},
+	}}
+	if err := Write(path, l); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	read, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if read.Version != FormatVersion || len(read.Packages) != 3 {
+		t.Fatalf("Unexpected lockfile: %+v", read)
+	}
+	if read.Packages[0].Name != "openssl@3" || read.Packages[1].Name != "wget" || read.Packages[2].Name != "firefox" {
+		t.Errorf("Expected formulae before casks, each sorted by name: %+v", read.Packages)
+	}
+	if p := read.Packages[0]; p.PkgVersion() != "3.3.2_1" || !p.Dependency {
+		t.Errorf("Unexpected openssl@3 entry: %+v", p)
+	}
+	if p := read.Packages[1]; p.Bottles["all"] != "aaa" || p.TapGitHead != "abc123" {
+		t.Errorf("Unexpected wget entry: %+v", p)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"version": 99, "packages": []}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(path); err == nil {
+		t.Error("Expected an error for a newer format version")
+	}
+}
+
+func TestDriftString(t *testing.T) {
+	tests := []struct {
+		drift Drift
+		want  string
+	}{
+		{Drift{Package: "curl", Kind: DriftMissing, Locked: "8.10.1"}, "curl 8.10.1 is locked but not installed"},
+		{Drift{Package: "jq", Kind: DriftVersion, Locked: "1.7.1", Actual: "1.6"}, "jq is 1.6, locked at 1.7.1"},
+		{Drift{Package: "wget", Kind: DriftBottle, Locked: "0123456789abcdef", Actual: ""}, "wget checksum is none, locked at 0123456789ab"},
+		{Drift{Package: "tree", Kind: DriftUnlocked}, "tree is not in the lockfile"},
+		{Drift{Package: "git", Kind: DriftUnknown, Actual: "timeout"}, "git could not be checked: timeout"},
+	}
+	for _, tt := range tests {
+		if got := tt.drift.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Package output renders machine-readable command results.
+// Every document shares a versioned envelope so scripts can rely on its
+// shape, and is written without ANSI colors or icons.
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaVersion is the version of the document envelope. It is incremented
+// whenever a field is removed or changes meaning.
+const SchemaVersion = 1
+
+// Format selects how command results are rendered.
+type Format string
+
+// Supported output formats.
+const (
+	FormatText Format = "text" // FormatText is the default human-friendly output
+	FormatJSON Format = "json" // FormatJSON emits a JSON document
+	FormatYAML Format = "yaml" // FormatYAML emits a YAML document
+)
+
+// ParseFormat validates an --output flag value.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatText, FormatJSON, FormatYAML:
+		return f, nil
+	case "":
+		return FormatText, nil
+	}
+	return "", fmt.Errorf("invalid output format %q (must be text, json or yaml)", s)
+}
+
+// Machine reports whether the format is machine-readable.
+func (f Format) Machine() bool {
+	return f == FormatJSON || f == FormatYAML
+}
+
+// Document is the envelope for every machine-readable result.
+type Document struct {
+	SchemaVersion int      `json:"schema_version"`     // SchemaVersion is the envelope version
+	Command       string   `json:"command"`            // Command is the goobrew command that ran
+	Data          any      `json:"data,omitempty"`     // Data is the command-specific result
+	Warnings      []string `json:"warnings,omitempty"` // Warnings are non-fatal problems
+	Error         *Error   `json:"error,omitempty"`    // Error is set when the command failed
+}
+
+// Error describes a command failure.
+type Error struct {
+	Message string `json:"message"`          // Message is a human-readable summary
+	Detail  string `json:"detail,omitempty"` // Detail is the underlying error, if any
+}
+
+// NewError builds an Error from a summary message and an optional cause.
+func NewError(message string, err error) *Error {
+	e := &Error{Message: message}
+	if err != nil {
+		e.Detail = err.Error()
+	}
+	return e
+}
+
+// Write encodes doc to w in the given format, filling in SchemaVersion.
+func Write(w io.Writer, format Format, doc Document) error {
+	doc.SchemaVersion = SchemaVersion
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case FormatJSON:
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	case FormatYAML:
+		return writeYAML(w, data)
+	case FormatText:
+	}
+	return fmt.Errorf("format %q is not machine-readable", format)
+}
+
+// writeYAML converts JSON to block-style YAML. Going through JSON keeps the
+// field names and order identical between the two formats.
+func writeYAML(w io.Writer, data []byte) error {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	resetStyle(&node)
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// resetStyle clears the flow and quoting styles inherited from JSON so the
+// encoder chooses idiomatic block YAML.
+func resetStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		resetStyle(c)
+	}
+}
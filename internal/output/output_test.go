@@ -0,0 +1,92 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Format
+		wantErr bool
+	}{
+		{"", FormatText, false},
+		{"text", FormatText, false},
+		{"json", FormatJSON, false},
+		{"yaml", FormatYAML, false},
+		{"xml", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseFormat(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFormat(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseFormat(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	doc := Document{
+		Command: "search",
+		Data:    map[string]any{"formulae": []string{"git"}},
+	}
+
+	if err := Write(&buf, FormatJSON, doc); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if decoded["schema_version"] != float64(SchemaVersion) {
+		t.Errorf("Expected schema_version %d, got %v", SchemaVersion, decoded["schema_version"])
+	}
+	if decoded["command"] != "search" {
+		t.Errorf("Expected command 'search', got %v", decoded["command"])
+	}
+	if _, ok := decoded["error"]; ok {
+		t.Error("Successful document should not contain an error")
+	}
+	if strings.Contains(buf.String(), "\033[") {
+		t.Error("Output should not contain ANSI escape codes")
+	}
+}
+
+func TestWriteYAML(t *testing.T) {
+	var buf bytes.Buffer
+	doc := Document{
+		Command: "info",
+		Error:   NewError("package not found", errors.New("API returned status 404")),
+	}
+
+	if err := Write(&buf, FormatYAML, doc); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	expected := `schema_version: 1
+command: info
+error:
+  message: package not found
+  detail: API returned status 404
+`
+	if buf.String() != expected {
+		t.Errorf("Unexpected YAML output:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, FormatText, Document{}); err == nil {
+		t.Error("Expected error when writing a document as text")
+	}
+}
@@ -0,0 +1,255 @@
+// Package pkgversion parses and compares Homebrew version strings.
+// Versions are compared the way Homebrew compares them: split into numeric
+// and alphabetic tokens, with pre-release tokens such as "rc" or "beta"
+// ordering before the release they precede. A package version adds the
+// formula revision ("_N" suffix) and version scheme on top.
+package pkgversion
+
+import (
+	"cmp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Bump classifies the difference between two versions.
+type Bump string
+
+// Bump kinds, from the largest change to the smallest.
+const (
+	BumpMajor    Bump = "major"    // BumpMajor changes the first version component or the version scheme
+	BumpMinor    Bump = "minor"    // BumpMinor changes the second version component
+	BumpPatch    Bump = "patch"    // BumpPatch changes anything after the second component
+	BumpRevision Bump = "revision" // BumpRevision only changes the formula revision
+	BumpOther    Bump = "other"    // BumpOther is a change between versions that are not numeric
+	BumpNone     Bump = ""         // BumpNone means the versions are equal
+)
+
+// tokenKind orders token types when tokens of different kinds meet.
+type tokenKind int
+
+const (
+	kindPre     tokenKind = iota // kindPre is a pre-release marker such as "rc"
+	kindNull                     // kindNull pads the shorter version
+	kindString                   // kindString is any other word
+	kindNumeric                  // kindNumeric is a run of digits
+	kindPatch                    // kindPatch is a post-release marker such as "p"
+)
+
+// preRelease ranks the pre-release markers Homebrew recognises.
+var preRelease = map[string]int{"alpha": 1, "a": 1, "beta": 2, "b": 2, "pre": 3, "rc": 4}
+
+// token is one component of a version string.
+type token struct {
+	kind tokenKind
+	num  uint64 // num is the value of a numeric token or the rank of a pre-release token
+	text string
+}
+
+// Version is a parsed Homebrew version such as "1.2.3" or "2.0rc1".
+type Version struct {
+	raw    string
+	tokens []token
+}
+
+// Parse splits a version string into tokens. Parse never fails; a version
+// without any digits simply compares as text.
+func Parse(s string) Version {
+	v := Version{raw: s}
+
+	var current []rune
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		text := string(current)
+		current = current[:0]
+
+		if unicode.IsDigit([]rune(text)[0]) {
+			n, err := strconv.ParseUint(text, 10, 64)
+			if err != nil {
+				v.tokens = append(v.tokens, token{kind: kindString, text: text})
+				return
+			}
+			v.tokens = append(v.tokens, token{kind: kindNumeric, num: n, text: text})
+			return
+		}
+
+		lower := strings.ToLower(text)
+		switch {
+		case preRelease[lower] > 0:
+			v.tokens = append(v.tokens, token{kind: kindPre, num: uint64(preRelease[lower]), text: lower})
+		case lower == "p" || lower == "patch" || lower == "post":
+			v.tokens = append(v.tokens, token{kind: kindPatch, text: lower})
+		default:
+			v.tokens = append(v.tokens, token{kind: kindString, text: lower})
+		}
+	}
+
+	for _, r := range s {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case len(current) > 0 && unicode.IsDigit(r) != unicode.IsDigit(current[len(current)-1]):
+			flush()
+			current = append(current, r)
+		default:
+			current = append(current, r)
+		}
+	}
+	flush()
+	return v
+}
+
+// String returns the version as it was parsed.
+func (v Version) String() string {
+	return v.raw
+}
+
+// Compare returns -1, 0 or 1 if v is older than, equal to or newer than o.
+func (v Version) Compare(o Version) int {
+	for i := 0; i < max(len(v.tokens), len(o.tokens)); i++ {
+		if c := compareTokens(v.token(i), o.token(i)); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// HasPrefix reports whether v starts with every component of p, so that
+// "20.11.1" has the prefix "20" and "20.11" but not "20.1".
+func (v Version) HasPrefix(p Version) bool {
+	if len(p.tokens) > len(v.tokens) {
+		return false
+	}
+	for i, t := range p.tokens {
+		if compareTokens(v.tokens[i], t) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// token returns the i-th token, or a null token past the end.
+func (v Version) token(i int) token {
+	if i < len(v.tokens) {
+		return v.tokens[i]
+	}
+	return token{kind: kindNull}
+}
+
+// numbers returns the leading numeric components of v, stopping at the
+// first token that is not numeric.
+func (v Version) numbers() []uint64 {
+	var nums []uint64
+	for _, t := range v.tokens {
+		if t.kind != kindNumeric {
+			break
+		}
+		nums = append(nums, t.num)
+	}
+	return nums
+}
+
+// compareTokens orders two tokens. A missing numeric component equals
+// zero, so "1.0" and "1.0.0" are the same version.
+func compareTokens(a, b token) int {
+	if a.kind == kindNull && b.kind == kindNumeric && b.num == 0 ||
+		b.kind == kindNull && a.kind == kindNumeric && a.num == 0 {
+		return 0
+	}
+	if a.kind != b.kind {
+		return cmp.Compare(int(a.kind), int(b.kind))
+	}
+
+	switch a.kind {
+	case kindNumeric, kindPre:
+		return cmp.Compare(a.num, b.num)
+	case kindString:
+		return strings.Compare(a.text, b.text)
+	}
+	return 0
+}
+
+// Compare parses and compares two version strings. It returns -1, 0 or 1
+// if a is older than, equal to or newer than b.
+func Compare(a, b string) int {
+	return Parse(a).Compare(Parse(b))
+}
+
+// PkgVersion is a formula version together with its revision and version
+// scheme, as in "1.2.3_1".
+type PkgVersion struct {
+	Version  Version // Version is the upstream version
+	Revision int     // Revision counts formula changes without a version change
+	Scheme   int     // Scheme is bumped when a formula's versioning restarts
+}
+
+// ParsePkgVersion parses a package version string such as "1.2.3_1". A
+// trailing "_N" is taken as the revision. The scheme is left at zero.
+func ParsePkgVersion(s string) PkgVersion {
+	if i := strings.LastIndexByte(s, '_'); i > 0 {
+		if rev, err := strconv.Atoi(s[i+1:]); err == nil && rev >= 0 {
+			return PkgVersion{Version: Parse(s[:i]), Revision: rev}
+		}
+	}
+	return PkgVersion{Version: Parse(s)}
+}
+
+// NewPkgVersion builds a package version from the fields of the formula API.
+func NewPkgVersion(version string, revision, scheme int) PkgVersion {
+	return PkgVersion{Version: Parse(version), Revision: revision, Scheme: scheme}
+}
+
+// String formats the package version the way Homebrew names kegs.
+func (p PkgVersion) String() string {
+	if p.Revision > 0 {
+		return p.Version.String() + "_" + strconv.Itoa(p.Revision)
+	}
+	return p.Version.String()
+}
+
+// Compare returns -1, 0 or 1 if p is older than, equal to or newer than o.
+// The version scheme is compared first, then the version, then the revision.
+func (p PkgVersion) Compare(o PkgVersion) int {
+	if p.Scheme != o.Scheme {
+		return cmp.Compare(p.Scheme, o.Scheme)
+	}
+	if c := p.Version.Compare(o.Version); c != 0 {
+		return c
+	}
+	return cmp.Compare(p.Revision, o.Revision)
+}
+
+// Classify describes how large the change from one package version to
+// another is, in semver terms. Versions whose leading components are not
+// numeric are classified as BumpOther.
+func Classify(from, to PkgVersion) Bump {
+	switch {
+	case from.Compare(to) == 0:
+		return BumpNone
+	case from.Scheme != to.Scheme:
+		return BumpMajor
+	case from.Version.Compare(to.Version) == 0:
+		return BumpRevision
+	}
+
+	a, b := from.Version.numbers(), to.Version.numbers()
+	if len(a) == 0 || len(b) == 0 {
+		return BumpOther
+	}
+	for i, bump := range []Bump{BumpMajor, BumpMinor} {
+		if component(a, i) != component(b, i) {
+			return bump
+		}
+	}
+	return BumpPatch
+}
+
+// component returns the i-th number, or zero past the end.
+func component(nums []uint64, i int) uint64 {
+	if i < len(nums) {
+		return nums[i]
+	}
+	return 0
+}
@@ -0,0 +1,115 @@
+package pkgversion
+
+import "testing"
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.0", "1.0.0", 0},
+		{"1.2.3", "1.2.4", -1},
+		{"1.10", "1.9", 1},
+		{"2.0", "10.0", -1},
+		{"1.0rc1", "1.0", -1},
+		{"1.0beta2", "1.0rc1", -1},
+		{"1.0-alpha", "1.0-beta", -1},
+		{"1.0rc2", "1.0rc10", -1},
+		{"1.0.1", "1.0rc1", 1},
+		{"1.1.1w", "1.1.1v", 1},
+		{"9.4p1", "9.4", 1},
+		{"2024-01-15", "2023-12-31", 1},
+		{"v1.2", "v1.10", -1},
+	}
+
+	for _, tt := range tests {
+		if got := Compare(tt.a, tt.b); got != tt.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := Compare(tt.b, tt.a); got != -tt.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
+
+func TestParsePkgVersion(t *testing.T) {
+	tests := []struct {
+		in       string
+		version  string
+		revision int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.2.3_1", "1.2.3", 1},
+		{"3.4.0_12", "3.4.0", 12},
+		{"2024_beta", "2024_beta", 0},
+	}
+
+	for _, tt := range tests {
+		p := ParsePkgVersion(tt.in)
+		if p.Version.String() != tt.version || p.Revision != tt.revision {
+			t.Errorf("ParsePkgVersion(%q) = %q rev %d, want %q rev %d", tt.in, p.Version, p.Revision, tt.version, tt.revision)
+		}
+		if p.String() != tt.in {
+			t.Errorf("Expected %q to round-trip, got %q", tt.in, p.String())
+		}
+	}
+}
+
+func TestPkgVersion_Compare(t *testing.T) {
+	if ParsePkgVersion("1.2.3_1").Compare(ParsePkgVersion("1.2.3")) != 1 {
+		t.Error("A higher revision should be newer")
+	}
+	if ParsePkgVersion("1.2.3_5").Compare(ParsePkgVersion("1.2.4")) != -1 {
+		t.Error("The version should outrank the revision")
+	}
+	if NewPkgVersion("1.0", 0, 1).Compare(NewPkgVersion("2024.1", 0, 0)) != 1 {
+		t.Error("A higher version scheme should outrank the version")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     Bump
+	}{
+		{"1.2.3", "2.0.0", BumpMajor},
+		{"1.2.3", "1.3.0", BumpMinor},
+		{"1.2.3", "1.2.4", BumpPatch},
+		{"1.2", "1.2.0.1", BumpPatch},
+		{"1.2.3", "1.2.3_1", BumpRevision},
+		{"1.2.3_1", "1.2.3_1", BumpNone},
+		{"1.0rc1", "1.0", BumpPatch},
+		{"latest", "nightly", BumpOther},
+	}
+
+	for _, tt := range tests {
+		if got := Classify(ParsePkgVersion(tt.from), ParsePkgVersion(tt.to)); got != tt.want {
+			t.Errorf("Classify(%q, %q) = %q, want %q", tt.from, tt.to, got, tt.want)
+		}
+	}
+
+	if got := Classify(NewPkgVersion("2.0", 0, 0), NewPkgVersion("2.0", 0, 1)); got != BumpMajor {
+		t.Errorf("Expected a scheme change to be major, got %q", got)
+	}
+}
+
+func TestHasPrefix(t *testing.T) {
+	tests := []struct {
+		v, prefix string
+		want      bool
+	}{
+		{"20.11.1", "20", true},
+		{"20.11.1", "20.11", true},
+		{"20.11.1", "20.1", false},
+		{"22.1.0", "20", false},
+		{"20", "20.0.1", false},
+		{"16.4", "16", true},
+	}
+
+	for _, tt := range tests {
+		if got := Parse(tt.v).HasPrefix(Parse(tt.prefix)); got != tt.want {
+			t.Errorf("Parse(%q).HasPrefix(%q) = %v, want %v", tt.v, tt.prefix, got, tt.want)
+		}
+	}
+}
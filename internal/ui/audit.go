@@ -0,0 +1,54 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/ofkm/goobrew/internal/homebrew"
+)
+
+// PrintAudit displays the installed packages that are disabled, scheduled
+// to be disabled within days, deprecated or renamed, each with its reason,
+// dates and suggested replacement, followed by a count per status.
+func PrintAudit(findings []homebrew.AuditFinding, days int) {
+	if len(findings) == 0 {
+		fmt.Printf("\n%s %sNo installed package is deprecated or disabled%s\n\n", IconSuccess, Green, Reset)
+		return
+	}
+
+	counts := make(map[homebrew.AuditStatus]int)
+	fmt.Printf("\n%s %sPackage audit%s (%d total)\n\n", IconWarning, Bold, Reset, len(findings))
+	for _, f := range findings {
+		counts[f.Status]++
+
+		name := f.Name
+		if f.Cask {
+			name += " (cask)"
+		}
+		var status string
+		switch f.Status {
+		case homebrew.AuditDisabled:
+			status = Red + "disabled" + Reset
+			if f.DisableDate != "" {
+				status += Gray + " since " + f.DisableDate + Reset
+			}
+		case homebrew.AuditScheduled:
+			status = fmt.Sprintf("%sdisabled on %s%s %s(in %d days)%s", Yellow, f.DisableDate, Reset, Gray, f.DaysLeft, Reset)
+		case homebrew.AuditDeprecated:
+			status = Yellow + "deprecated" + Reset
+			if f.DeprecationDate != "" {
+				status += Gray + " since " + f.DeprecationDate + Reset
+			}
+		case homebrew.AuditRenamed:
+			status = Blue + "renamed" + Reset
+		}
+
+		fmt.Printf("  %s●%s %s%-30s%s %s%-12s%s %s%s\n", Yellow, Reset, Cyan, name, Reset, Gray, f.Version, Reset, status, because(f.Reason))
+		if f.Replacement != "" {
+			fmt.Printf("    %s→ use %s instead%s\n", Gray, f.Replacement, Reset)
+		}
+	}
+
+	fmt.Printf("\n  %s%d disabled, %d to be disabled within %d days, %d deprecated, %d renamed%s\n\n",
+		Bold, counts[homebrew.AuditDisabled], counts[homebrew.AuditScheduled], days,
+		counts[homebrew.AuditDeprecated], counts[homebrew.AuditRenamed], Reset)
+}
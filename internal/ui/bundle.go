@@ -0,0 +1,55 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/ofkm/goobrew/internal/homebrew"
+	"github.com/ofkm/goobrew/internal/lockfile"
+)
+
+// PrintBundleCheck displays the Brewfile entries that are not installed,
+// grouped in file order, or a success message if every entry is.
+func PrintBundleCheck(items []homebrew.BundleItem) {
+	var missing []homebrew.BundleItem
+	for _, item := range items {
+		if !item.Installed {
+			missing = append(missing, item)
+		}
+	}
+
+	if len(missing) == 0 {
+		fmt.Printf("\n%s The Brewfile's dependencies are satisfied %s(%d entries)%s\n\n", IconSuccess, Gray, len(items), Reset)
+		return
+	}
+
+	fmt.Printf("\n%s %s%sMissing from the Brewfile%s (%d of %d entries)\n\n",
+		IconWarning, Bold, Yellow, Reset, len(missing), len(items))
+	for _, item := range missing {
+		fmt.Printf("  %s●%s %s%-8s%s %s%s%s", Red, Reset, Gray, item.Kind, Reset, Cyan, item.Name, Reset)
+		if item.Line > 0 {
+			fmt.Printf(" %s(line %d)%s", Gray, item.Line, Reset)
+		}
+		fmt.Println()
+	}
+	fmt.Printf("\n%sRun goobrew bundle install to install them.%s\n\n", Gray, Reset)
+}
+
+// PrintLockDrift displays the differences between the lockfile at path,
+// which locks total packages, and the system, or a success message if
+// there are none.
+func PrintLockDrift(path string, total int, drifts []lockfile.Drift) {
+	if len(drifts) == 0 {
+		fmt.Printf("\n%s All %d packages match %s\n\n", IconSuccess, total, path)
+		return
+	}
+
+	fmt.Printf("\n%s %s%s%d packages differ from %s%s\n\n", IconWarning, Bold, Yellow, len(drifts), path, Reset)
+	for _, d := range drifts {
+		color := Yellow
+		if d.Kind == lockfile.DriftMissing || d.Kind == lockfile.DriftBottle {
+			color = Red
+		}
+		fmt.Printf("  %s●%s %s%-9s%s %s\n", color, Reset, Gray, d.Kind, Reset, d)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,217 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ofkm/goobrew/internal/homebrew"
+)
+
+// dashboardRefresh is how often a live dashboard redraws to advance the
+// elapsed time of running packages.
+const dashboardRefresh = 250 * time.Millisecond
+
+// IsTerminal reports whether f is an interactive terminal that can render
+// cursor movement, i.e. a character device and TERM is not "dumb".
+func IsTerminal(f *os.File) bool {
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Dashboard renders installation progress for several packages at once.
+// In live mode it keeps one row per package, showing the stage, a progress
+// bar and the elapsed time, and redraws the rows in place. Otherwise it
+// writes a plain log line whenever a package changes stage or step, which keeps
+// output readable when stdout is a file or a pipe.
+type Dashboard struct {
+	mu    sync.Mutex
+	w     io.Writer
+	live  bool
+	rows  []*dashboardRow
+	index map[string]*dashboardRow
+	drawn int // drawn is the number of rows on screen from the last redraw
+}
+
+// dashboardRow is the latest status of one package on a Dashboard.
+type dashboardRow struct {
+	status homebrew.InstallationStatus
+	end    time.Time // end is when the package completed or failed
+}
+
+// NewDashboard creates a dashboard writing to w. If live is false, rows
+// are never redrawn and each stage change is logged on its own line.
+func NewDashboard(w io.Writer, live bool) *Dashboard {
+	return &Dashboard{
+		w:     w,
+		live:  live,
+		index: make(map[string]*dashboardRow),
+	}
+}
+
+// Update records a status and redraws or logs the affected package.
+func (d *Dashboard) Update(status homebrew.InstallationStatus) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	row, ok := d.index[status.Formula]
+	if !ok {
+		row = &dashboardRow{}
+		d.index[status.Formula] = row
+		d.rows = append(d.rows, row)
+	}
+
+	changed := !ok || row.status.Stage != status.Stage || row.status.Detail != status.Detail
+	if ok && row.status.Dependency {
+		status.Dependency = true
+	}
+	if status.StartTime.IsZero() {
+		status.StartTime = row.status.StartTime
+	}
+	row.status = status
+	if isFinalStage(status.Stage) {
+		row.end = time.Now()
+	} else {
+		row.end = time.Time{}
+	}
+
+	if d.live {
+		d.redraw()
+	} else if changed {
+		fmt.Fprintln(d.w, formatRow(row, false))
+	}
+}
+
+// Run consumes statuses until the channel is closed, redrawing a live
+// dashboard periodically so elapsed times keep moving.
+func (d *Dashboard) Run(statusChan <-chan homebrew.InstallationStatus) {
+	ticker := time.NewTicker(dashboardRefresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case status, ok := <-statusChan:
+			if !ok {
+				d.Refresh()
+				return
+			}
+			d.Update(status)
+		case <-ticker.C:
+			d.Refresh()
+		}
+	}
+}
+
+// Refresh redraws a live dashboard. It does nothing in plain mode.
+func (d *Dashboard) Refresh() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.live {
+		d.redraw()
+	}
+}
+
+// Statuses returns the latest status of every package, in the order the
+// packages first appeared.
+func (d *Dashboard) Statuses() []homebrew.InstallationStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	statuses := make([]homebrew.InstallationStatus, len(d.rows))
+	for i, row := range d.rows {
+		statuses[i] = row.status
+	}
+	return statuses
+}
+
+// redraw moves the cursor back over the previously drawn rows and writes
+// every row again. The caller holds d.mu.
+func (d *Dashboard) redraw() {
+	// Clear the line below the rows first, where the terminal echoes ^C.
+	var b strings.Builder
+	b.WriteString("\r\033[2K")
+	if d.drawn > 0 {
+		fmt.Fprintf(&b, "\033[%dA", d.drawn)
+	}
+	for _, row := range d.rows {
+		b.WriteString("\r\033[2K")
+		b.WriteString(formatRow(row, true))
+		b.WriteString("\n")
+	}
+	d.drawn = len(d.rows)
+	_, _ = io.WriteString(d.w, b.String())
+}
+
+// formatRow renders a dashboard row. The progress bar is only included
+// when bar is true.
+func formatRow(row *dashboardRow, bar bool) string {
+	status := row.status
+	icon, color := stageStyle(status.Stage)
+
+	elapsed := time.Since(status.StartTime)
+	switch {
+	case !status.EndTime.IsZero():
+		elapsed = status.EndTime.Sub(status.StartTime)
+	case !row.end.IsZero():
+		elapsed = row.end.Sub(status.StartTime)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s%-24s%s %s%-12s%s", icon, color, status.Formula, Reset, color, status.Stage, Reset)
+	if bar {
+		b.WriteString(" " + ProgressBar(status.Progress, 100, 20) + Reset)
+	}
+	fmt.Fprintf(&b, " %s[%s]%s", Gray, FormatDuration(elapsed), Reset)
+	if n := len(status.Dependencies); n > 0 {
+		done := 0
+		for _, dep := range status.Dependencies {
+			if dep.Stage == "completed" {
+				done++
+			}
+		}
+		fmt.Fprintf(&b, " %sdeps %d/%d%s", Gray, done, n, Reset)
+	}
+	if status.Stage == "downloading" && status.BytesTotal > 0 {
+		fmt.Fprintf(&b, " %s%s/%s%s", Gray, FormatSize(status.BytesDone), FormatSize(status.BytesTotal), Reset)
+	}
+	if status.Detail != "" && !isFinalStage(status.Stage) {
+		fmt.Fprintf(&b, " %s%s%s", Gray, status.Detail, Reset)
+	}
+	if status.Dependency {
+		fmt.Fprintf(&b, " %s(dependency)%s", Gray, Reset)
+	}
+	if status.Error != nil {
+		fmt.Fprintf(&b, " - %s%s%s", Red, status.Error, Reset)
+	}
+	return b.String()
+}
+
+// isFinalStage reports whether stage ends a package's installation.
+func isFinalStage(stage string) bool {
+	return stage == "completed" || stage == "failed" || stage == "skipped"
+}
+
+// stageStyle returns the icon and color used for an installation stage.
+func stageStyle(stage string) (string, string) {
+	switch stage {
+	case "downloading":
+		return IconDownload, Blue
+	case "installing":
+		return IconInstall, Yellow
+	case "linking":
+		return IconLink, Magenta
+	case "completed":
+		return IconSuccess, Green
+	case "failed":
+		return IconError, Red
+	case "skipped":
+		return IconWarning, Gray
+	}
+	return IconInstall, Cyan
+}
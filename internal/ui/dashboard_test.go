@@ -0,0 +1,103 @@
+package ui
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ofkm/goobrew/internal/homebrew"
+)
+
+func TestDashboard_Plain(t *testing.T) {
+	var buf bytes.Buffer
+	d := NewDashboard(&buf, false)
+	start := time.Now()
+
+	d.Update(homebrew.InstallationStatus{Formula: "openssl", Stage: "starting", StartTime: start, Dependency: true})
+	d.Update(homebrew.InstallationStatus{Formula: "openssl", Stage: "downloading", Progress: 25, StartTime: start})
+	d.Update(homebrew.InstallationStatus{Formula: "openssl", Stage: "downloading", Progress: 40, StartTime: start})
+	d.Update(homebrew.InstallationStatus{Formula: "wget", Stage: "failed", StartTime: start, Error: errors.New("exit status 1")})
+
+	out := buf.String()
+	if strings.Contains(out, "\033[2K") {
+		t.Error("Plain mode must not move the cursor")
+	}
+	if lines := strings.Count(out, "\n"); lines != 3 {
+		t.Errorf("Expected one line per stage change (3), got %d:\n%s", lines, out)
+	}
+	if !strings.Contains(out, "(dependency)") {
+		t.Error("Dependency rows should be labelled")
+	}
+	if !strings.Contains(out, "exit status 1") {
+		t.Error("Failed rows should include the error")
+	}
+
+	statuses := d.Statuses()
+	if len(statuses) != 2 || statuses[0].Progress != 40 || !statuses[0].Dependency {
+		t.Errorf("Unexpected final statuses: %+v", statuses)
+	}
+}
+
+func TestDashboard_Live(t *testing.T) {
+	var buf bytes.Buffer
+	d := NewDashboard(&buf, true)
+	start := time.Now()
+
+	d.Update(homebrew.InstallationStatus{Formula: "jq", Stage: "downloading", Progress: 50, StartTime: start})
+	d.Update(homebrew.InstallationStatus{Formula: "tree", Stage: "starting", StartTime: start})
+	buf.Reset()
+	d.Refresh()
+
+	out := buf.String()
+	if !strings.HasPrefix(out, "\r\033[2K\033[2A") {
+		t.Errorf("Expected redraw to move up over 2 rows, got %q", out)
+	}
+	if strings.Count(out, "\r\033[2K") != 3 {
+		t.Errorf("Expected both rows to be redrawn, got %q", out)
+	}
+	if !strings.Contains(out, "50%") {
+		t.Error("Live rows should include a progress bar")
+	}
+}
+
+func TestDashboard_Run(t *testing.T) {
+	var buf bytes.Buffer
+	d := NewDashboard(&buf, false)
+
+	statusChan := make(chan homebrew.InstallationStatus, 2)
+	statusChan <- homebrew.InstallationStatus{Formula: "jq", Stage: "starting", StartTime: time.Now()}
+	statusChan <- homebrew.InstallationStatus{Formula: "jq", Stage: "completed", Progress: 100, StartTime: time.Now()}
+	close(statusChan)
+
+	d.Run(statusChan)
+
+	statuses := d.Statuses()
+	if len(statuses) != 1 || statuses[0].Stage != "completed" {
+		t.Errorf("Expected jq to be completed, got %+v", statuses)
+	}
+}
+
+func TestPrintInstallSummary(t *testing.T) {
+	start := time.Now().Add(-3 * time.Second)
+	statuses := []homebrew.InstallationStatus{
+		{Formula: "openssl", Stage: "failed", StartTime: start, EndTime: start.Add(2 * time.Second)},
+		{Formula: "curl", Stage: "skipped", StartTime: start, EndTime: start},
+		{Formula: "jq", Stage: "completed", StartTime: start, EndTime: start.Add(3 * time.Second)},
+		{Formula: "tree", Stage: "installing", StartTime: start},
+	}
+
+	output := captureOutput(func() {
+		PrintInstallSummary(statuses)
+	})
+
+	for _, want := range []string{"openssl", "failed", "skipped", "succeeded", "3s", "1 succeeded", "1 failed", "1 skipped"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Summary should contain %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "tree") {
+		t.Error("Unfinished packages should not be listed")
+	}
+}
@@ -0,0 +1,212 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ofkm/goobrew/internal/homebrew"
+)
+
+// depTree describes how to walk a dependency graph for printing: children
+// returns the edges below a formula, and keep decides whether a formula is
+// shown. A formula that is not kept is still shown when something below it
+// is, so that the path to it stays visible.
+type depTree struct {
+	graph    *homebrew.DependencyGraph
+	children func(name string) []homebrew.DepEdge
+	keep     func(*homebrew.DepNode) bool
+	visible  map[string]bool
+}
+
+// PrintDependencyTree displays the dependency graph as an indented tree
+// below each root, marking each formula as installed or missing and
+// labelling build, test and recommended dependencies. Cycles are marked
+// where they close instead of being followed. If keep is non-nil, only
+// formulae it accepts, and the paths leading to them, are shown.
+func PrintDependencyTree(graph *homebrew.DependencyGraph, keep func(*homebrew.DepNode) bool) {
+	t := &depTree{
+		graph: graph,
+		children: func(name string) []homebrew.DepEdge {
+			if node := graph.Nodes[name]; node != nil {
+				return node.Deps
+			}
+			return nil
+		},
+		keep: keep,
+	}
+
+	for _, root := range graph.Roots {
+		fmt.Println()
+		t.print(root, "", map[string]bool{})
+	}
+	printCycles(graph)
+	fmt.Println()
+}
+
+// PrintReverseDependencyTree displays, for every dependency in the graph,
+// the chain of formulae that pull it in, up to the roots. If keep is
+// non-nil, only dependencies it accepts are listed.
+func PrintReverseDependencyTree(graph *homebrew.DependencyGraph, keep func(*homebrew.DepNode) bool) {
+	dependents := graph.Dependents()
+	t := &depTree{
+		graph: graph,
+		children: func(name string) []homebrew.DepEdge {
+			return dependents[name]
+		},
+	}
+
+	nodes := graph.Closure()
+	if len(nodes) == 0 {
+		fmt.Printf("\n%s No dependencies\n\n", IconInfo)
+		return
+	}
+	for _, node := range nodes {
+		if keep != nil && !keep(node) {
+			continue
+		}
+		fmt.Println()
+		t.print(node.Name, "", map[string]bool{})
+	}
+	printCycles(graph)
+	fmt.Println()
+}
+
+// print writes name and, indented below it, every visible child.
+// ancestors holds the formulae on the current path, to detect cycles.
+func (t *depTree) print(name, prefix string, ancestors map[string]bool) {
+	if prefix == "" {
+		fmt.Printf("%s%s%s\n", Bold, formatDepNode(t.graph.Nodes[name], ""), Reset)
+	}
+
+	ancestors[name] = true
+	defer delete(ancestors, name)
+
+	var edges []homebrew.DepEdge
+	for _, edge := range t.children(name) {
+		if ancestors[edge.Name] || t.isVisible(edge.Name, map[string]bool{}) {
+			edges = append(edges, edge)
+		}
+	}
+
+	for i, edge := range edges {
+		branch, indent := "├── ", "│   "
+		if i == len(edges)-1 {
+			branch, indent = "└── ", "    "
+		}
+
+		label := ""
+		if edge.Kind != homebrew.DepRuntime && edge.Kind != "" {
+			label = fmt.Sprintf(" %s[%s]%s", Magenta, edge.Kind, Reset)
+		}
+
+		if ancestors[edge.Name] {
+			fmt.Printf("%s%s%s%s %s↻ cycle%s\n", prefix, Gray, branch, edge.Name, Yellow, Reset)
+			continue
+		}
+
+		fmt.Printf("%s%s%s%s%s\n", prefix, Gray, branch, Reset, formatDepNode(t.graph.Nodes[edge.Name], label))
+		t.print(edge.Name, prefix+Gray+indent+Reset, ancestors)
+	}
+}
+
+// isVisible reports whether name is kept or leads to a kept formula.
+func (t *depTree) isVisible(name string, path map[string]bool) bool {
+	if t.keep == nil {
+		return true
+	}
+	if v, ok := t.visible[name]; ok {
+		return v
+	}
+	if path[name] {
+		return false
+	}
+	path[name] = true
+
+	visible := t.keep(t.graph.Nodes[name])
+	for _, edge := range t.children(name) {
+		if visible {
+			break
+		}
+		visible = t.isVisible(edge.Name, path)
+	}
+
+	if t.visible == nil {
+		t.visible = make(map[string]bool)
+	}
+	t.visible[name] = visible
+	return visible
+}
+
+// formatDepNode renders a formula with its installed state and version.
+func formatDepNode(node *homebrew.DepNode, label string) string {
+	if node == nil {
+		return label
+	}
+
+	var b strings.Builder
+	switch {
+	case node.Unknown:
+		fmt.Fprintf(&b, "%s?%s %s", Red, Reset, node.Name)
+	case node.Installed:
+		fmt.Fprintf(&b, "%s●%s %s", Green, Reset, node.Name)
+	default:
+		fmt.Fprintf(&b, "%s○%s %s", Gray, Reset, node.Name)
+	}
+
+	switch {
+	case node.Installed && node.InstalledVersion != "":
+		fmt.Fprintf(&b, " %s%s%s", Gray, node.InstalledVersion, Reset)
+	case node.Version != "":
+		fmt.Fprintf(&b, " %s%s (missing)%s", Gray, node.Version, Reset)
+	}
+	b.WriteString(label)
+	return b.String()
+}
+
+// printCycles lists any dependency cycles found while resolving the graph.
+func printCycles(graph *homebrew.DependencyGraph) {
+	if len(graph.Cycles) == 0 {
+		return
+	}
+
+	cycles := make([]string, len(graph.Cycles))
+	for i, c := range graph.Cycles {
+		cycles[i] = strings.Join(c, " → ")
+	}
+	sort.Strings(cycles)
+
+	fmt.Println()
+	for _, c := range cycles {
+		PrintWarning("Dependency cycle: " + c)
+	}
+}
+
+// PrintDependents displays the formulae that use the named formulae, with
+// their installed state and, for indirect dependents, the formula they
+// depend on them through.
+func PrintDependents(names []string, dependents []homebrew.Dependent) {
+	target := strings.Join(names, ", ")
+	if len(dependents) == 0 {
+		fmt.Printf("\n%s No formulae use %s\n\n", IconInfo, target)
+		return
+	}
+
+	fmt.Printf("\n%s %sFormulae that use %s%s (%d total)\n\n", IconPackage, Bold, target, Reset, len(dependents))
+	for _, d := range dependents {
+		statusIcon := Gray + "○" + Reset
+		if d.Installed {
+			statusIcon = Green + "●" + Reset
+		}
+
+		fmt.Printf("  %s %s%-30s%s", statusIcon, Cyan, d.Name, Reset)
+		if len(names) > 1 {
+			fmt.Printf(" %suses %s%s", Gray, strings.Join(d.Uses, ", "), Reset)
+		}
+		if d.Via != "" {
+			fmt.Printf(" %svia %s%s", Gray, d.Via, Reset)
+		}
+		fmt.Println()
+	}
+	fmt.Println()
+}
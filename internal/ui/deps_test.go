@@ -0,0 +1,112 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ofkm/goobrew/internal/homebrew"
+)
+
+func testDependencyGraph() *homebrew.DependencyGraph {
+	return &homebrew.DependencyGraph{
+		Roots: []string{"wget"},
+		Nodes: map[string]*homebrew.DepNode{
+			"wget": {Name: "wget", Version: "1.24.5", Deps: []homebrew.DepEdge{
+				{Name: "libidn2", Kind: homebrew.DepRuntime},
+				{Name: "pkgconf", Kind: homebrew.DepBuild},
+			}},
+			"libidn2": {Name: "libidn2", Version: "2.3.7", Installed: true, InstalledVersion: "2.3.7", Deps: []homebrew.DepEdge{
+				{Name: "libunistring", Kind: homebrew.DepRuntime},
+			}},
+			"libunistring": {Name: "libunistring", Version: "1.3"},
+			"pkgconf":      {Name: "pkgconf", Version: "2.3.0", Installed: true, InstalledVersion: "2.3.0"},
+		},
+	}
+}
+
+func TestPrintDependencyTree(t *testing.T) {
+	output := captureOutput(func() {
+		PrintDependencyTree(testDependencyGraph(), nil)
+	})
+
+	for _, want := range []string{"wget", "├── ", "└── ", "libunistring", "[build]", "(missing)"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected tree to contain %q:\n%s", want, output)
+		}
+	}
+	if strings.Index(output, "libidn2") > strings.Index(output, "libunistring") {
+		t.Error("libunistring should be printed below libidn2")
+	}
+}
+
+func TestPrintDependencyTree_Filter(t *testing.T) {
+	output := captureOutput(func() {
+		PrintDependencyTree(testDependencyGraph(), func(n *homebrew.DepNode) bool { return !n.Installed })
+	})
+
+	if strings.Contains(output, "pkgconf") {
+		t.Error("Installed leaves should be pruned when showing missing dependencies")
+	}
+	if !strings.Contains(output, "libidn2") || !strings.Contains(output, "libunistring") {
+		t.Errorf("The path to a missing dependency should stay visible:\n%s", output)
+	}
+}
+
+func TestPrintDependencyTree_Cycle(t *testing.T) {
+	graph := &homebrew.DependencyGraph{
+		Roots: []string{"a"},
+		Nodes: map[string]*homebrew.DepNode{
+			"a": {Name: "a", Deps: []homebrew.DepEdge{{Name: "b", Kind: homebrew.DepRuntime}}},
+			"b": {Name: "b", Deps: []homebrew.DepEdge{{Name: "a", Kind: homebrew.DepRuntime}}},
+		},
+		Cycles: [][]string{{"a", "b", "a"}},
+	}
+
+	output := captureOutput(func() {
+		PrintDependencyTree(graph, nil)
+	})
+
+	if !strings.Contains(output, "cycle") || !strings.Contains(output, "a → b → a") {
+		t.Errorf("Expected the cycle to be marked:\n%s", output)
+	}
+}
+
+func TestPrintReverseDependencyTree(t *testing.T) {
+	output := captureOutput(func() {
+		PrintReverseDependencyTree(testDependencyGraph(), nil)
+	})
+
+	start := strings.Index(output, "libunistring")
+	if start < 0 {
+		t.Fatalf("Expected libunistring in the reverse tree:\n%s", output)
+	}
+	rest := output[start:]
+	if !strings.Contains(rest, "libidn2") || !strings.Contains(rest, "wget") {
+		t.Errorf("Expected libunistring to lead back to wget through libidn2:\n%s", output)
+	}
+}
+
+func TestPrintDependents(t *testing.T) {
+	dependents := []homebrew.Dependent{
+		{Name: "curl", Installed: true, Uses: []string{"openssl@3"}},
+		{Name: "git", Installed: true, Uses: []string{"openssl@3"}, Via: "curl"},
+	}
+
+	output := captureOutput(func() {
+		PrintDependents([]string{"openssl@3"}, dependents)
+	})
+
+	if !strings.Contains(output, "openssl@3") || !strings.Contains(output, "2 total") {
+		t.Errorf("Expected a header for openssl@3 with the count:\n%s", output)
+	}
+	if !strings.Contains(output, "via curl") {
+		t.Errorf("Expected indirect dependents to show the path:\n%s", output)
+	}
+
+	output = captureOutput(func() {
+		PrintDependents([]string{"jq"}, nil)
+	})
+	if !strings.Contains(output, "No formulae use jq") {
+		t.Errorf("Expected an empty message, got:\n%s", output)
+	}
+}
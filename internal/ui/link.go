@@ -0,0 +1,59 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ofkm/goobrew/internal/homebrew"
+)
+
+// PrintLinkResult displays the outcome of linking or unlinking a keg, as
+// given by action, listing every change. With dryRun the changes are
+// described as the ones that would be made.
+func PrintLinkResult(action string, result *homebrew.LinkResult, dryRun bool) {
+	links, removals := 0, 0
+	for _, ch := range result.Changes {
+		switch ch.Op {
+		case homebrew.LinkOpLink:
+			links++
+		case homebrew.LinkOpRemove:
+			removals++
+		}
+	}
+
+	keg := fmt.Sprintf("%s%s%s %s", Cyan, result.Formula, Reset, result.Version)
+	switch {
+	case len(result.Conflicts) > 0:
+		fmt.Printf("\n%s %s%s%d files are in the way of %s%s\n\n", IconWarning, Bold, Yellow, len(result.Conflicts), result.Formula, Reset)
+		for _, c := range result.Conflicts {
+			fmt.Printf("  %s●%s %s\n", Red, Reset, c)
+		}
+		if !dryRun {
+			fmt.Println()
+			return
+		}
+	case result.KegOnly:
+		fmt.Printf("\n%s %s is keg-only and was not linked %s(use --force to link it anyway)%s\n", IconInfo, keg, Gray, Reset)
+	case len(result.Changes) == 0:
+		fmt.Printf("\n%s %s is already %sed\n", IconInfo, keg, action)
+	case dryRun:
+		fmt.Printf("\n%s Would %s %s: %d symlinks created, %d removed\n", IconInfo, action, keg, links, removals)
+	default:
+		fmt.Printf("\n%s %s%sed %s: %d symlinks created, %d removed\n", IconSuccess, strings.ToUpper(action[:1]), action[1:], keg, links, removals)
+	}
+
+	if dryRun {
+		fmt.Println()
+		for _, ch := range result.Changes {
+			switch ch.Op {
+			case homebrew.LinkOpLink:
+				fmt.Printf("  %s+%s %s %s-> %s%s\n", Green, Reset, ch.Path, Gray, ch.Target, Reset)
+			case homebrew.LinkOpRemove:
+				fmt.Printf("  %s-%s %s\n", Red, Reset, ch.Path)
+			case homebrew.LinkOpMkdir:
+				fmt.Printf("  %s+%s %s/\n", Gray, Reset, ch.Path)
+			}
+		}
+	}
+	fmt.Println()
+}
@@ -0,0 +1,145 @@
+package ui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ofkm/goobrew/internal/homebrew"
+)
+
+// PickerItem is one entry of a checklist shown by Pick.
+type PickerItem struct {
+	Label    string   // Label is the first column, such as a package name
+	Detail   string   // Detail is shown after the label
+	Links    []string // Links are shown on a line below the entry
+	Selected bool     // Selected is the initial state of the entry
+}
+
+// pickerHelp explains the commands Pick accepts.
+const pickerHelp = "Toggle with numbers (1 3-5), a = all, n = none, Enter = confirm, q = cancel"
+
+// Pick shows items as a numbered checklist on w and reads commands from r,
+// one per line, until the user confirms with an empty line. Numbers and
+// ranges toggle entries, "a" selects and "n" deselects everything. It
+// returns the indexes of the selected items in order, and false if the user
+// cancelled with "q" or the input ended.
+func Pick(r io.Reader, w io.Writer, title string, items []PickerItem) ([]int, bool) {
+	selected := make([]bool, len(items))
+	for i, item := range items {
+		selected[i] = item.Selected
+	}
+
+	in := bufio.NewScanner(r)
+	for {
+		drawPicker(w, title, items, selected)
+		fmt.Fprintf(w, "%s> %s", Bold, Reset)
+		if !in.Scan() {
+			fmt.Fprintln(w)
+			return nil, false
+		}
+
+		switch answer := strings.ToLower(strings.TrimSpace(in.Text())); answer {
+		case "":
+			var picked []int
+			for i, ok := range selected {
+				if ok {
+					picked = append(picked, i)
+				}
+			}
+			return picked, true
+		case "q", "quit":
+			return nil, false
+		case "a", "all":
+			for i := range selected {
+				selected[i] = true
+			}
+		case "n", "none":
+			for i := range selected {
+				selected[i] = false
+			}
+		default:
+			indexes, err := parseSelection(answer, len(items))
+			if err != nil {
+				fmt.Fprintf(w, "%s %s\n", IconWarning, err)
+				continue
+			}
+			for _, i := range indexes {
+				selected[i] = !selected[i]
+			}
+		}
+	}
+}
+
+// drawPicker writes the checklist with the current selection.
+func drawPicker(w io.Writer, title string, items []PickerItem, selected []bool) {
+	fmt.Fprintf(w, "\n%s%s%s\n\n", Bold, title, Reset)
+	for i, item := range items {
+		box := Gray + "[ ]" + Reset
+		if selected[i] {
+			box = Green + "[x]" + Reset
+		}
+		fmt.Fprintf(w, "  %s%3d%s %s %s%-30s%s %s\n", Gray, i+1, Reset, box, Cyan, item.Label, Reset, item.Detail)
+		if len(item.Links) > 0 {
+			fmt.Fprintf(w, "          %s%s%s\n", Gray, strings.Join(item.Links, "  ·  "), Reset)
+		}
+	}
+	fmt.Fprintf(w, "\n%s%s%s\n", Gray, pickerHelp, Reset)
+}
+
+// parseSelection parses space or comma separated entry numbers and ranges
+// such as "1 3-5" into zero-based indexes below n.
+func parseSelection(s string, n int) ([]int, error) {
+	var indexes []int
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' }) {
+		from, to, isRange := strings.Cut(field, "-")
+		first, err := strconv.Atoi(from)
+		last := first
+		if err == nil && isRange {
+			last, err = strconv.Atoi(to)
+		}
+		if err != nil || first < 1 || last > n || first > last {
+			return nil, fmt.Errorf("invalid selection %q, expected numbers between 1 and %d", field, n)
+		}
+		for i := first; i <= last; i++ {
+			indexes = append(indexes, i-1)
+		}
+	}
+	return indexes, nil
+}
+
+// PickUpgrades lets the user choose which outdated packages to upgrade with
+// Pick, showing the version change, its size and the homepage and release
+// notes of each package. Every package starts selected. It returns the
+// chosen packages, and false if the user cancelled.
+func PickUpgrades(r io.Reader, w io.Writer, packages []homebrew.OutdatedPackage) ([]homebrew.OutdatedPackage, bool) {
+	items := make([]PickerItem, len(packages))
+	for i, pkg := range packages {
+		color := bumpColor(pkg.Bump)
+		detail := fmt.Sprintf("%s%s%s → %s%s%s %s%s%s",
+			Gray, pkg.InstalledVersion, Reset, color, pkg.LatestVersion, Reset, color, pkg.Bump, Reset)
+		if pkg.Cask {
+			detail += fmt.Sprintf(" %s(cask)%s", Gray, Reset)
+		}
+
+		var links []string
+		for _, link := range []string{pkg.Homepage, pkg.ReleaseNotes} {
+			if link != "" {
+				links = append(links, link)
+			}
+		}
+		items[i] = PickerItem{Label: pkg.Name, Detail: detail, Links: links, Selected: true}
+	}
+
+	picked, ok := Pick(r, w, fmt.Sprintf("%s Select packages to upgrade", IconRocket), items)
+	if !ok {
+		return nil, false
+	}
+	chosen := make([]homebrew.OutdatedPackage, len(picked))
+	for i, index := range picked {
+		chosen[i] = packages[index]
+	}
+	return chosen, true
+}
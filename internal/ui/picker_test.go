@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ofkm/goobrew/internal/homebrew"
+	"github.com/ofkm/goobrew/internal/pkgversion"
+)
+
+func TestPick(t *testing.T) {
+	items := []PickerItem{{Label: "a", Selected: true}, {Label: "b"}, {Label: "c"}, {Label: "d"}}
+
+	tests := []struct {
+		name   string
+		input  string
+		want   []int
+		wantOK bool
+	}{
+		{"Confirm defaults", "\n", []int{0}, true},
+		{"Toggle", "1 2\n\n", []int{1}, true},
+		{"Range", "2-4\n\n", []int{0, 1, 2, 3}, true},
+		{"All then toggle", "a\n3,4\n\n", []int{0, 1}, true},
+		{"None", "n\n\n", nil, true},
+		{"Invalid is ignored", "9\nx\n\n", []int{0}, true},
+		{"Cancel", "1\nq\n", nil, false},
+		{"End of input", "2\n", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			got, ok := Pick(strings.NewReader(tt.input), &out, "Pick", items)
+			if ok != tt.wantOK || !slices.Equal(got, tt.want) {
+				t.Errorf("Pick(%q) = %v, %v; want %v, %v", tt.input, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestPick_InvalidSelection(t *testing.T) {
+	var out bytes.Buffer
+	Pick(strings.NewReader("0\n\n"), &out, "Pick", []PickerItem{{Label: "a"}})
+
+	if !strings.Contains(out.String(), "invalid selection") {
+		t.Errorf("Expected an invalid selection warning:\n%s", out.String())
+	}
+}
+
+func TestPickUpgrades(t *testing.T) {
+	packages := []homebrew.OutdatedPackage{
+		{Name: "node", InstalledVersion: "20.11.0", LatestVersion: "22.1.0", Bump: pkgversion.BumpMajor,
+			Homepage: "https://nodejs.org/", ReleaseNotes: "https://github.com/nodejs/node/releases"},
+		{Name: "jq", InstalledVersion: "1.7", LatestVersion: "1.7.1", Bump: pkgversion.BumpPatch},
+	}
+
+	var out bytes.Buffer
+	chosen, ok := PickUpgrades(strings.NewReader("1\n\n"), &out, packages)
+	if !ok || len(chosen) != 1 || chosen[0].Name != "jq" {
+		t.Errorf("Expected only jq to be chosen, got %+v", chosen)
+	}
+
+	for _, want := range []string{"[x]", "20.11.0", "22.1.0", "major", "https://nodejs.org/", "https://github.com/nodejs/node/releases"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected picker to show %q:\n%s", want, out.String())
+		}
+	}
+}
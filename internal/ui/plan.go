@@ -0,0 +1,93 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ofkm/goobrew/internal/homebrew"
+)
+
+// PrintInstallPlan displays what an install is about to do: the packages
+// to install and upgrade in order, marking the ones that were requested,
+// those already installed or left to brew, deprecated packages, any
+// conflicts, disabled packages or unmet requirements, and the total
+// download size.
+func PrintInstallPlan(plan *homebrew.InstallPlan) {
+	var installs, upgrades int
+	for _, step := range plan.Steps {
+		if step.Action == homebrew.PlanUpgrade {
+			upgrades++
+		} else {
+			installs++
+		}
+	}
+
+	fmt.Printf("\n%s %sInstall plan%s (%d new, %d upgraded)\n\n", IconPackage, Bold, Reset, installs, upgrades)
+	for _, step := range plan.Steps {
+		icon := Green + "+" + Reset
+		version := step.Version
+		if step.Action == homebrew.PlanUpgrade {
+			icon = Yellow + "↑" + Reset
+			version = step.InstalledVersion + " → " + step.Version
+		}
+
+		var notes []string
+		if step.Kind == homebrew.KindCask {
+			notes = append(notes, "cask")
+		}
+		if !step.Requested {
+			notes = append(notes, "dependency")
+		}
+		if step.FromSource {
+			notes = append(notes, "built from source")
+		}
+		switch {
+		case step.Disabled:
+			notes = append(notes, Red+"disabled"+Gray)
+		case step.Deprecated:
+			notes = append(notes, Yellow+"deprecated"+Gray)
+		}
+		size := ""
+		if step.DownloadBytes >= 0 {
+			size = FormatSize(step.DownloadBytes)
+		}
+		note := ""
+		if len(notes) > 0 {
+			note = " (" + strings.Join(notes, ", ") + ")"
+		}
+		fmt.Printf("  %s %s%-30s%s %s%-20s %10s%s%s\n", icon, Cyan, step.Name, Reset, Gray, version, size, note, Reset)
+	}
+
+	if len(plan.Installed) > 0 {
+		fmt.Printf("\n  %sAlready installed: %s%s\n", Gray, strings.Join(plan.Installed, ", "), Reset)
+	}
+	if len(plan.Unknown) > 0 {
+		fmt.Printf("  %sNot in the API index, left to brew: %s%s\n", Gray, strings.Join(plan.Unknown, ", "), Reset)
+	}
+
+	if deprecated := plan.Deprecated(); len(deprecated) > 0 {
+		fmt.Println()
+		for _, step := range deprecated {
+			fmt.Printf("  %s%s %s is deprecated%s%s\n", Yellow, IconWarning, step.Name, because(step.Reason), Reset)
+		}
+	}
+
+	if plan.Blocked() {
+		fmt.Println()
+		for _, step := range plan.Disabled() {
+			fmt.Printf("  %s●%s %s is disabled%s\n", Red, Reset, step.Name, because(step.Reason))
+		}
+		for _, c := range plan.Conflicts {
+			fmt.Printf("  %s●%s %s\n", Red, Reset, c)
+		}
+		for _, r := range plan.Unmet {
+			fmt.Printf("  %s●%s %s\n", Red, Reset, r)
+		}
+	}
+
+	total := FormatSize(plan.DownloadBytes)
+	if plan.UnknownSizes > 0 {
+		total = fmt.Sprintf("%s plus %d packages of unknown size", total, plan.UnknownSizes)
+	}
+	fmt.Printf("\n  %sDownloads %s%s\n\n", Bold, total, Reset)
+}
@@ -0,0 +1,103 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ofkm/goobrew/internal/homebrew"
+)
+
+// PrintServices displays the services of the installed formulae in a table
+// with their status, PID, last exit code and the file goobrew wrote.
+func PrintServices(services []homebrew.ServiceInfo) {
+	if len(services) == 0 {
+		fmt.Printf("\n%s No installed formula defines a service\n\n", IconInfo)
+		return
+	}
+
+	fmt.Printf("\n%s %sServices%s (%d total)\n\n", IconPackage, Bold, Reset, len(services))
+	fmt.Printf("  %s%-24s %-10s %-8s %-5s %s%s\n", Bold, "Name", "Status", "PID", "Exit", "File", Reset)
+	for _, s := range services {
+		pid, exit := "", ""
+		if s.PID > 0 {
+			pid = strconv.Itoa(s.PID)
+		}
+		if s.ExitCode != nil {
+			exit = strconv.Itoa(*s.ExitCode)
+		}
+		fmt.Printf("  %s%-24s%s %s%-10s%s %-8s %-5s %s%s%s\n",
+			Cyan, s.Name, Reset, serviceColor(s.Status), s.Status, Reset, pid, exit, Gray, s.File, Reset)
+	}
+	fmt.Println()
+}
+
+// PrintServiceInfo displays the state of a service and the definition it
+// is rendered from.
+func PrintServiceInfo(info *homebrew.ServiceInfo) {
+	fmt.Printf("\n%s %s%s%s (%s)\n\n", IconPackage, Bold, info.Name, Reset, info.Label)
+	fmt.Printf("  %sStatus:%s   %s%s%s\n", Bold, Reset, serviceColor(info.Status), info.Status, Reset)
+	if info.PID > 0 {
+		fmt.Printf("  %sPID:%s      %d\n", Bold, Reset, info.PID)
+	}
+	if info.ExitCode != nil {
+		fmt.Printf("  %sExit code:%s %d\n", Bold, Reset, *info.ExitCode)
+	}
+	if info.StartedAt != nil {
+		fmt.Printf("  %sStarted:%s  %s\n", Bold, Reset, info.StartedAt.Local().Format(time.DateTime))
+	}
+	if info.File != "" {
+		fmt.Printf("  %sFile:%s     %s\n", Bold, Reset, info.File)
+	}
+
+	def := info.Definition
+	if def == nil {
+		fmt.Println()
+		return
+	}
+	fmt.Printf("  %sCommand:%s  %s\n", Bold, Reset, strings.Join(def.Command, " "))
+	switch def.RunType {
+	case "interval":
+		fmt.Printf("  %sRuns:%s     every %d seconds\n", Bold, Reset, def.Interval)
+	case "cron":
+		fmt.Printf("  %sRuns:%s     on the schedule %s\n", Bold, Reset, def.Cron)
+	default:
+		fmt.Printf("  %sRuns:%s     %s, restart %s\n", Bold, Reset, def.RunType, def.Restart)
+	}
+	for _, field := range []struct{ label, value string }{
+		{"Directory:", def.WorkingDir},
+		{"Log:", def.LogPath},
+		{"Error log:", def.ErrorLogPath},
+	} {
+		if field.value != "" {
+			fmt.Printf("  %s%-10s%s %s\n", Bold, field.label, Reset, field.value)
+		}
+	}
+	if len(def.Environment) > 0 {
+		keys := make([]string, 0, len(def.Environment))
+		for k := range def.Environment {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		fmt.Printf("  %sEnvironment:%s\n", Bold, Reset)
+		for _, k := range keys {
+			fmt.Printf("    %s%s=%s%s\n", Gray, k, def.Environment[k], Reset)
+		}
+	}
+	fmt.Println()
+}
+
+// serviceColor returns the color a service status is shown in.
+func serviceColor(status homebrew.ServiceStatus) string {
+	switch status {
+	case homebrew.ServiceStarted, homebrew.ServiceScheduled:
+		return Green
+	case homebrew.ServiceError:
+		return Red
+	case homebrew.ServiceStopped:
+		return Yellow
+	}
+	return Gray
+}
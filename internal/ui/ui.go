@@ -4,11 +4,18 @@
 package ui
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"io"
+	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/ofkm/goobrew/internal/config"
 	"github.com/ofkm/goobrew/internal/homebrew"
+	"github.com/ofkm/goobrew/internal/pkgversion"
 )
 
 // Colors are ANSI escape codes for terminal text formatting.
@@ -78,7 +85,7 @@ func FormatSize(bytes int64) string {
 
 // PrintFormulaInfo displays detailed information about a Homebrew formula.
 // It prints the formula name, description, homepage, version, license,
-// installation status, dependencies, build dependencies, and any caveats.
+// deprecation or disable reason and date, installation status, dependencies, build dependencies, and any caveats.
 // All output is formatted with colors and icons for readability.
 func PrintFormulaInfo(formula *homebrew.Formula) {
 	fmt.Printf("\n%s %s%s%s\n", IconInfo, Bold, formula.Name, Reset)
@@ -97,6 +104,9 @@ func PrintFormulaInfo(formula *homebrew.Formula) {
 		fmt.Printf("  %sLicense:%s  %s\n", Cyan, Reset, formula.License)
 	}
 
+	printLifecycle(formula.Deprecated, formula.DeprecationDate, formula.DeprecationReason,
+		formula.Disabled, formula.DisableDate, formula.DisableReason)
+
 	// Installation status
 	if len(formula.Installed) > 0 {
 		latest := formula.Installed[len(formula.Installed)-1]
@@ -141,22 +151,172 @@ func PrintFormulaInfo(formula *homebrew.Formula) {
 	fmt.Println()
 }
 
-// PrintSearchResults displays search results for formulae and casks.
+// printLifecycle shows whether a formula or cask is deprecated or disabled,
+// since when and why, and when a deprecated one is going to be disabled.
+func printLifecycle(deprecated bool, deprecationDate, deprecationReason string, disabled bool, disableDate, disableReason string) {
+	since := func(date string) string {
+		if date == "" {
+			return ""
+		}
+		return " " + Gray + "(since " + date + ")" + Reset
+	}
+
+	switch {
+	case disabled:
+		fmt.Printf("\n  %s%sDisabled%s%s%s\n", Red, Bold, Reset, because(homebrew.DeprecationMessage(disableReason)), since(disableDate))
+	case deprecated:
+		fmt.Printf("\n  %s%sDeprecated%s%s%s\n", Yellow, Bold, Reset, because(homebrew.DeprecationMessage(deprecationReason)), since(deprecationDate))
+		if disableDate != "" {
+			fmt.Printf("  %sWill be disabled on %s%s%s\n", Yellow, disableDate, because(homebrew.DeprecationMessage(disableReason)), Reset)
+		}
+	}
+}
+
+// because returns ": reason", or "" if there is no reason.
+func because(reason string) string {
+	if reason == "" {
+		return ""
+	}
+	return ": " + reason
+}
+
+// PrintPackageInfo displays detailed information about a formula or cask,
+// dispatching to PrintFormulaInfo or PrintCaskInfo.
+func PrintPackageInfo(pkg *homebrew.Package) {
+	if pkg.Cask != nil {
+		PrintCaskInfo(pkg.Cask)
+		return
+	}
+	PrintFormulaInfo(pkg.Formula)
+}
+
+// PrintCaskInfo displays detailed information about a Homebrew cask.
+// It prints the token and display names, description, homepage, version,
+// auto-update behaviour, deprecation or disable, installation status, the artifacts the cask
+// installs (apps, binaries, pkgs and others), its requirements and conflicts,
+// and any caveats. All output is formatted with colors and icons for readability.
+func PrintCaskInfo(cask *homebrew.Cask) {
+	fmt.Printf("\n%s %s%s%s", IconInfo, Bold, cask.Token, Reset)
+	if len(cask.Name) > 0 {
+		fmt.Printf(" %s(%s)%s", Gray, strings.Join(cask.Name, ", "), Reset)
+	}
+	fmt.Printf(" %s[cask]%s\n", Magenta, Reset)
+
+	if cask.Desc != "" {
+		fmt.Printf("  %s\n", cask.Desc)
+	}
+
+	fmt.Printf("\n  %sHomepage:%s %s\n", Cyan, Reset, cask.Homepage)
+
+	if cask.Version != "" {
+		fmt.Printf("  %sVersion:%s  %s\n", Cyan, Reset, cask.Version)
+	}
+
+	if cask.AutoUpdates {
+		fmt.Printf("  %sUpdates:%s  auto-updates itself\n", Cyan, Reset)
+	}
+
+	printLifecycle(cask.Deprecated, cask.DeprecationDate, cask.DeprecationReason,
+		cask.Disabled, cask.DisableDate, cask.DisableReason)
+
+	// Installation status
+	if cask.Installed != "" {
+		fmt.Printf("\n  %s%sInstalled:%s %s", Green, Bold, Reset, cask.Installed)
+		if cask.InstalledTime > 0 {
+			fmt.Printf(" %s(on %s)%s", Gray, time.Unix(cask.InstalledTime, 0).Format("Jan 02, 2006"), Reset)
+		}
+		fmt.Println()
+		if cask.Outdated || (cask.Version != "" && cask.Installed != cask.Version) {
+			fmt.Printf("  %sUpdate available:%s %s → %s\n", Yellow, Reset, cask.Installed, cask.Version)
+		}
+	} else {
+		fmt.Printf("\n  %sNot installed%s\n", Yellow, Reset)
+	}
+
+	// Artifacts
+	artifactLabels := []struct{ kind, label string }{
+		{"app", "Apps"},
+		{"binary", "Binaries"},
+		{"pkg", "Packages"},
+		{"suite", "Suites"},
+		{"font", "Fonts"},
+		{"qlplugin", "QuickLook Plugins"},
+		{"prefpane", "Preference Panes"},
+		{"installer", "Installers"},
+	}
+	for _, a := range artifactLabels {
+		if paths := cask.ArtifactPaths(a.kind); len(paths) > 0 {
+			fmt.Printf("\n  %s%s:%s\n", Cyan, a.label, Reset)
+			for _, p := range paths {
+				fmt.Printf("    • %s\n", p)
+			}
+		}
+	}
+
+	// Requirements
+	if deps := cask.DependsOn; len(deps.Formula)+len(deps.Cask)+len(deps.MacOS) > 0 {
+		fmt.Printf("\n  %sRequires:%s\n", Cyan, Reset)
+		comparators := make([]string, 0, len(deps.MacOS))
+		for cmp := range deps.MacOS {
+			comparators = append(comparators, cmp)
+		}
+		sort.Strings(comparators)
+		for _, cmp := range comparators {
+			fmt.Printf("    • macOS %s %s\n", cmp, strings.Join(deps.MacOS[cmp], ", "))
+		}
+		for _, f := range deps.Formula {
+			fmt.Printf("    • %s\n", f)
+		}
+		for _, c := range deps.Cask {
+			fmt.Printf("    • %s %s(cask)%s\n", c, Gray, Reset)
+		}
+	}
+
+	if c := cask.ConflictsWith; c != nil && len(c.Formula)+len(c.Cask) > 0 {
+		fmt.Printf("\n  %sConflicts with:%s\n", Cyan, Reset)
+		for _, name := range append(append([]string{}, c.Formula...), c.Cask...) {
+			fmt.Printf("    • %s\n", name)
+		}
+	}
+
+	if cask.URL != "" {
+		fmt.Printf("\n  %sURL:%s    %s\n", Cyan, Reset, cask.URL)
+	}
+	if cask.Sha256 != "" {
+		fmt.Printf("  %sSHA-256:%s %s\n", Cyan, Reset, cask.Sha256)
+	}
+
+	// Caveats
+	if cask.Caveats != "" {
+		fmt.Printf("\n  %s%sℹ️  Caveats:%s\n", Yellow, Bold, Reset)
+		for _, line := range strings.Split(strings.TrimSpace(cask.Caveats), "\n") {
+			fmt.Printf("  %s\n", line)
+		}
+	}
+
+	fmt.Println()
+}
+
+// PrintSearchResults displays ranked search results for formulae and casks.
 // It separates formulae and casks into distinct sections with appropriate
-// icons and colors. If no results are found, it displays a warning message.
-// The function also shows the total count of matching packages.
-func PrintSearchResults(formulae, casks []string) {
+// icons and colors, highlights the matched part of each name and description,
+// and shows each hit's latest version, installed/outdated state and any
+// deprecation. If no results are found, it displays a warning message.
+// The total count is shown at the end.
+func PrintSearchResults(result *homebrew.SearchResult) {
+	formulae, casks := result.Formulae, result.Casks
+
 	if len(formulae) > 0 {
 		fmt.Printf("\n%s %s%sFormulae%s\n", IconPackage, Bold, Green, Reset)
 		for _, f := range formulae {
-			fmt.Printf("  • %s\n", f)
+			printSearchHit(f)
 		}
 	}
 
 	if len(casks) > 0 {
 		fmt.Printf("\n%s %s%sCasks%s\n", IconPackage, Bold, Cyan, Reset)
 		for _, c := range casks {
-			fmt.Printf("  • %s\n", c)
+			printSearchHit(c)
 		}
 	}
 
@@ -169,17 +329,112 @@ func PrintSearchResults(formulae, casks []string) {
 	fmt.Println()
 }
 
+// printSearchHit prints a single search result line.
+func printSearchHit(hit homebrew.SearchHit) {
+	const nameWidth = 30
+
+	statusIcon := "•"
+	switch {
+	case hit.Installed && hit.Outdated:
+		statusIcon = Yellow + "●" + Reset
+	case hit.Installed:
+		statusIcon = Green + "●" + Reset
+	}
+
+	name := Highlight(hit.Name, hit.NameSpans, "")
+	if pad := nameWidth - len(hit.Name); pad > 0 {
+		name += strings.Repeat(" ", pad)
+	}
+	fmt.Printf("  %s %s", statusIcon, name)
+
+	if hit.Version != "" {
+		fmt.Printf(" %s%s%s", Gray, hit.Version, Reset)
+	}
+
+	switch {
+	case hit.Installed && hit.Outdated:
+		fmt.Printf(" %s(installed %s, outdated)%s", Yellow, hit.InstalledVersion, Reset)
+	case hit.Installed:
+		fmt.Printf(" %s(installed)%s", Green, Reset)
+	}
+
+	switch {
+	case hit.Disabled:
+		fmt.Printf(" %s[disabled]%s", Red, Reset)
+	case hit.Deprecated:
+		fmt.Printf(" %s[deprecated]%s", Yellow, Reset)
+	}
+
+	if hit.MatchedAs != "" {
+		fmt.Printf(" %s(%s: %s)%s", Gray, hit.Match, hit.MatchedAs, Reset)
+	}
+
+	if hit.Desc != "" {
+		desc, spans := truncate(hit.Desc, hit.DescSpans, 60)
+		fmt.Printf(" %s%s%s", Gray, Highlight(desc, spans, Gray), Reset)
+	}
+	fmt.Println()
+}
+
+// Highlight wraps each span of text in bold yellow, restoring base afterwards.
+// Spans must be sorted, non-overlapping byte ranges within text; invalid
+// spans are ignored.
+func Highlight(text string, spans []homebrew.Span, base string) string {
+	var b strings.Builder
+	pos := 0
+	for _, sp := range spans {
+		if sp.Start < pos || sp.End > len(text) || sp.Start >= sp.End {
+			continue
+		}
+		b.WriteString(text[pos:sp.Start])
+		b.WriteString(Bold + Yellow + text[sp.Start:sp.End] + Reset + base)
+		pos = sp.End
+	}
+	b.WriteString(text[pos:])
+	return b.String()
+}
+
+// truncate shortens text to at most width runes with a trailing ellipsis,
+// clipping any spans that extend past the cut. The spans are byte offsets,
+// and the cut is always on a rune boundary.
+func truncate(text string, spans []homebrew.Span, width int) (string, []homebrew.Span) {
+	if utf8.RuneCountInString(text) <= width {
+		return text, spans
+	}
+
+	cut := 0
+	for range width - 3 {
+		_, size := utf8.DecodeRuneInString(text[cut:])
+		cut += size
+	}
+	var clipped []homebrew.Span
+	for _, sp := range spans {
+		if sp.Start >= cut {
+			continue
+		}
+		clipped = append(clipped, homebrew.Span{Start: sp.Start, End: min(sp.End, cut)})
+	}
+	return text[:cut] + "...", clipped
+}
+
 // PrintInstalledList displays a list of all installed packages.
 // Each package is shown with its name, version, and a status indicator
-// (green for up-to-date, yellow for outdated, blue for pinned).
+// (green for up-to-date, yellow for outdated, blue for pinned), and
+// deprecated and disabled packages are flagged.
 // If no packages are installed, it displays a warning message.
 func PrintInstalledList(formulae []homebrew.Formula) {
+	PrintFormulaList("Installed Packages", "No packages installed", formulae)
+}
+
+// PrintFormulaList displays installed formulae under the given title in the
+// same format as PrintInstalledList, or the empty message if there are none.
+func PrintFormulaList(title, empty string, formulae []homebrew.Formula) {
 	if len(formulae) == 0 {
-		fmt.Printf("\n%s No packages installed\n\n", IconWarning)
+		fmt.Printf("\n%s %s\n\n", IconWarning, empty)
 		return
 	}
 
-	fmt.Printf("\n%s %s%sInstalled Packages%s (%d total)\n\n", IconPackage, Bold, Green, Reset, len(formulae))
+	fmt.Printf("\n%s %s%s%s%s (%d total)\n\n", IconPackage, Bold, Green, title, Reset, len(formulae))
 
 	for _, f := range formulae {
 		version := ""
@@ -197,6 +452,13 @@ func PrintInstalledList(formulae []homebrew.Formula) {
 
 		fmt.Printf("  %s %s%-30s%s %s%s%s", statusIcon, Cyan, f.Name, Reset, Gray, version, Reset)
 
+		switch {
+		case f.Disabled:
+			fmt.Printf(" %s[disabled]%s", Red, Reset)
+		case f.Deprecated:
+			fmt.Printf(" %s[deprecated]%s", Yellow, Reset)
+		}
+
 		if f.Desc != "" {
 			desc := f.Desc
 			if len(desc) > 50 {
@@ -210,6 +472,80 @@ func PrintInstalledList(formulae []homebrew.Formula) {
 	fmt.Println()
 }
 
+// PrintOutdated displays packages with a newer version available, showing
+// the installed and latest versions and how large the update is. Major
+// updates are red, minor ones yellow and everything smaller green.
+func PrintOutdated(packages []homebrew.OutdatedPackage) {
+	if len(packages) == 0 {
+		fmt.Printf("\n%s Everything is up to date\n\n", IconSuccess)
+		return
+	}
+
+	fmt.Printf("\n%s %s%sOutdated Packages%s (%d total)\n\n", IconUpdate, Bold, Yellow, Reset, len(packages))
+
+	for _, pkg := range packages {
+		color := bumpColor(pkg.Bump)
+		fmt.Printf("  %s●%s %s%-30s%s %s%s%s → %s%s%s %s%s%s",
+			color, Reset, Cyan, pkg.Name, Reset,
+			Gray, pkg.InstalledVersion, Reset, color, pkg.LatestVersion, Reset,
+			color, pkg.Bump, Reset)
+		if pkg.Cask {
+			fmt.Printf(" %s(cask)%s", Gray, Reset)
+		}
+		if pkg.Pinned {
+			fmt.Printf(" %s📌 pinned%s", Blue, Reset)
+		}
+		if pkg.Held != "" {
+			fmt.Printf(" %s🔒 %s%s", Blue, pkg.Held, Reset)
+		}
+		fmt.Println()
+	}
+
+	fmt.Println()
+}
+
+// PrintPins displays the formulae pinned with brew and the goobrew holds.
+// Holds that have expired at now are marked as such.
+func PrintPins(pinned []string, holds []config.Hold, now time.Time) {
+	if len(pinned) == 0 && len(holds) == 0 {
+		fmt.Printf("\n%s No pinned or held packages\n\n", IconInfo)
+		return
+	}
+
+	if len(pinned) > 0 {
+		fmt.Printf("\n%s %sPinned Formulae%s (%d total)\n\n", IconPackage, Bold, Reset, len(pinned))
+		for _, name := range pinned {
+			fmt.Printf("  %s📌%s %s%s%s\n", Blue, Reset, Cyan, name, Reset)
+		}
+	}
+
+	if len(holds) > 0 {
+		fmt.Printf("\n%s %sHeld Packages%s (%d total)\n\n", IconPackage, Bold, Reset, len(holds))
+		for _, hold := range holds {
+			fmt.Printf("  %s🔒%s %s%-30s%s %s", Blue, Reset, Cyan, hold.Package, Reset, hold)
+			if !hold.Active(now) {
+				fmt.Printf(" %s(expired)%s", Gray, Reset)
+			}
+			fmt.Println()
+		}
+	}
+
+	fmt.Println()
+}
+
+// bumpColor returns the color used for an update of the given size.
+func bumpColor(bump pkgversion.Bump) string {
+	switch bump {
+	case pkgversion.BumpMajor:
+		return Red
+	case pkgversion.BumpMinor:
+		return Yellow
+	case pkgversion.BumpOther:
+		return Gray
+	}
+	return Green
+}
+
 // PrintInstallProgress displays real-time installation progress.
 // It shows the package name, current installation stage (downloading,
 // installing, linking, completed, or failed), elapsed time, and any
@@ -218,26 +554,7 @@ func PrintInstalledList(formulae []homebrew.Formula) {
 func PrintInstallProgress(status homebrew.InstallationStatus) {
 	elapsed := time.Since(status.StartTime)
 
-	icon := IconInstall
-	color := Cyan
-
-	switch status.Stage {
-	case "downloading":
-		icon = IconDownload
-		color = Blue
-	case "installing":
-		icon = IconInstall
-		color = Yellow
-	case "linking":
-		icon = IconLink
-		color = Magenta
-	case "completed":
-		icon = IconSuccess
-		color = Green
-	case "failed":
-		icon = IconError
-		color = Red
-	}
+	icon, color := stageStyle(status.Stage)
 
 	fmt.Printf("\r%s %s%s%s %s%-20s%s [%s]",
 		icon, color, status.Formula, Reset, Gray, status.Stage, Reset, FormatDuration(elapsed))
@@ -247,6 +564,48 @@ func PrintInstallProgress(status homebrew.InstallationStatus) {
 	}
 }
 
+// PrintInstallSummary displays a table with one row per package showing
+// whether it succeeded, failed, was skipped or was interrupted and how long
+// it took, followed by the totals. Packages that have not finished are not
+// listed.
+func PrintInstallSummary(statuses []homebrew.InstallationStatus) {
+	var succeeded, failed, skipped, interrupted int
+
+	fmt.Printf("  %s%-30s %-11s %s%s\n", Bold, "Package", "Result", "Duration", Reset)
+	for _, s := range statuses {
+		var result, color string
+		switch {
+		case !isFinalStage(s.Stage):
+			continue
+		case errors.Is(s.Error, homebrew.ErrInterrupted):
+			result, color = "interrupted", Yellow
+			interrupted++
+		case s.Stage == "completed":
+			result, color = "succeeded", Green
+			succeeded++
+		case s.Stage == "failed":
+			result, color = "failed", Red
+			failed++
+		default:
+			result, color = "skipped", Gray
+			skipped++
+		}
+
+		duration := "-"
+		if s.Stage != "skipped" && !s.EndTime.IsZero() {
+			duration = FormatDuration(s.EndTime.Sub(s.StartTime))
+		}
+		fmt.Printf("  %-30s %s%-11s%s %s\n", s.Formula, color, result, Reset, duration)
+	}
+
+	fmt.Printf("\n  %s%d succeeded%s, %s%d failed%s, %s%d skipped%s",
+		Green, succeeded, Reset, Red, failed, Reset, Gray, skipped, Reset)
+	if interrupted > 0 {
+		fmt.Printf(", %s%d interrupted%s", Yellow, interrupted, Reset)
+	}
+	fmt.Println()
+}
+
 // PrintSuccess displays a success message with a checkmark icon and green color.
 func PrintSuccess(message string) {
 	fmt.Printf("%s %s%s%s\n", IconSuccess, Green, message, Reset)
@@ -267,6 +626,37 @@ func PrintInfo(message string) {
 	fmt.Printf("%s %s\n", IconInfo, message)
 }
 
+// Confirm prints a yes/no question and reads the answer from r. Only an
+// answer starting with "y" or "Y" counts as yes; anything else, including
+// end of input, is no.
+func Confirm(r io.Reader, question string) bool {
+	fmt.Printf("%s %s %s[y/N]%s ", IconWarning, question, Gray, Reset)
+	answer, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Println()
+		return false
+	}
+	answer = strings.TrimSpace(answer)
+	return strings.HasPrefix(answer, "y") || strings.HasPrefix(answer, "Y")
+}
+
+// PrintRemovalPlan displays the formulae that are about to be removed with
+// their installed version and the disk space each occupies, followed by the
+// total space that removing them frees.
+func PrintRemovalPlan(formulae []homebrew.Formula, sizes map[string]int64) {
+	var total int64
+	fmt.Printf("\n%s %sFormulae to remove%s (%d total)\n\n", IconTrash, Bold, Reset, len(formulae))
+	for _, f := range formulae {
+		version := ""
+		if len(f.Installed) > 0 {
+			version = f.Installed[len(f.Installed)-1].Version
+		}
+		total += sizes[f.Name]
+		fmt.Printf("  %s●%s %s%-30s%s %s%-12s %10s%s\n", Yellow, Reset, Cyan, f.Name, Reset, Gray, version, FormatSize(sizes[f.Name]), Reset)
+	}
+	fmt.Printf("\n  %sFrees %s%s\n\n", Bold, FormatSize(total), Reset)
+}
+
 // ProgressBar creates a visual progress bar string.
 // It takes the current progress value, total value, and desired width in characters.
 // Returns a colored progress bar with percentage. If total is 0, returns a full bar.
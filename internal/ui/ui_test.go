@@ -2,13 +2,19 @@ package ui
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
 	"os"
 	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
+	"github.com/ofkm/goobrew/internal/brewfile"
+	"github.com/ofkm/goobrew/internal/config"
 	"github.com/ofkm/goobrew/internal/homebrew"
+	"github.com/ofkm/goobrew/internal/lockfile"
+	"github.com/ofkm/goobrew/internal/pkgversion"
 )
 
 func TestFormatDuration(t *testing.T) {
@@ -113,11 +119,18 @@ func TestPrintFormulaInfo(t *testing.T) {
 }
 
 func TestPrintSearchResults(t *testing.T) {
-	formulae := []string{"git", "github-cli", "gitlab-runner"}
-	casks := []string{"github", "gitkraken"}
+	formulae := []homebrew.SearchHit{
+		{Name: "git", Desc: "Distributed revision control system", NameSpans: []homebrew.Span{{Start: 0, End: 3}}},
+		{Name: "github-cli", NameSpans: []homebrew.Span{{Start: 0, End: 3}}},
+		{Name: "gitlab-runner", NameSpans: []homebrew.Span{{Start: 0, End: 3}}},
+	}
+	casks := []homebrew.SearchHit{
+		{Name: "github", Desc: "Desktop client for GitHub"},
+		{Name: "gitkraken", Match: homebrew.MatchAlias, MatchedAs: "GitKraken"},
+	}
 
 	output := captureOutput(func() {
-		PrintSearchResults(formulae, casks)
+		PrintSearchResults(&homebrew.SearchResult{Term: "git", Formulae: formulae, Casks: casks})
 	})
 
 	if !strings.Contains(output, "git") {
@@ -129,11 +142,98 @@ func TestPrintSearchResults(t *testing.T) {
 	if !strings.Contains(output, "5 results") {
 		t.Error("Output should show total count")
 	}
+	if !strings.Contains(output, "Distributed revision control system") {
+		t.Error("Output should contain descriptions")
+	}
+	if !strings.Contains(output, Bold+Yellow+"git"+Reset) {
+		t.Error("Output should highlight the matched part of the name")
+	}
+	if !strings.Contains(output, "alias: GitKraken") {
+		t.Error("Output should show the alias a hit matched through")
+	}
+}
+
+func TestPrintSearchResults_Status(t *testing.T) {
+	result := &homebrew.SearchResult{
+		Term: "node",
+		Formulae: []homebrew.SearchHit{
+			{Name: "node", Kind: homebrew.KindFormula, Version: "22.1.0", Installed: true, InstalledVersion: "20.0.0", Outdated: true},
+			{Name: "node@18", Kind: homebrew.KindFormula, Version: "18.20.0", Deprecated: true},
+			{Name: "nodenv", Kind: homebrew.KindFormula, Version: "1.5.0", Installed: true, InstalledVersion: "1.5.0"},
+		},
+	}
+
+	output := captureOutput(func() {
+		PrintSearchResults(result)
+	})
+
+	if !strings.Contains(output, "22.1.0") {
+		t.Error("Output should show the latest version")
+	}
+	if !strings.Contains(output, "installed 20.0.0, outdated") {
+		t.Error("Output should mark outdated installed packages")
+	}
+	if !strings.Contains(output, "(installed)") {
+		t.Error("Output should mark installed packages")
+	}
+	if !strings.Contains(output, "[deprecated]") {
+		t.Error("Output should flag deprecated packages")
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		spans    []homebrew.Span
+		expected string
+	}{
+		{"No spans", "git", nil, "git"},
+		{"Prefix", "github", []homebrew.Span{{Start: 0, End: 3}}, Bold + Yellow + "git" + Reset + "hub"},
+		{"Middle", "lazygit", []homebrew.Span{{Start: 4, End: 7}}, "lazy" + Bold + Yellow + "git" + Reset},
+		{"Out of range", "git", []homebrew.Span{{Start: 2, End: 10}}, "git"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Highlight(tt.text, tt.spans, ""); got != tt.expected {
+				t.Errorf("Highlight(%q) = %q, expected %q", tt.text, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		spans    []homebrew.Span
+		width    int
+		expected string
+		clipped  []homebrew.Span
+	}{
+		{"Short", "Get a file", nil, 10, "Get a file", nil},
+		{"ASCII", "Internet file retriever", []homebrew.Span{{Start: 9, End: 13}}, 10, "Interne...", nil},
+		{"Multibyte", "Générateur de données", []homebrew.Span{{Start: 0, End: 11}}, 8, "Génér...", []homebrew.Span{{Start: 0, End: 7}}},
+		{"CJK", "日本語の説明文です", nil, 6, "日本語...", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, clipped := truncate(tt.text, tt.spans, tt.width)
+			if got != tt.expected || !utf8.ValidString(got) {
+				t.Errorf("truncate(%q) = %q, expected %q", tt.text, got, tt.expected)
+			}
+			if len(clipped) != len(tt.clipped) || len(clipped) > 0 && clipped[0] != tt.clipped[0] {
+				t.Errorf("truncate(%q) spans = %v, expected %v", tt.text, clipped, tt.clipped)
+			}
+		})
+	}
 }
 
 func TestPrintSearchResults_Empty(t *testing.T) {
 	output := captureOutput(func() {
-		PrintSearchResults([]string{}, []string{})
+		PrintSearchResults(&homebrew.SearchResult{})
 	})
 
 	if !strings.Contains(output, "No results") {
@@ -364,3 +464,338 @@ func TestPrintFormulaInfo_WithAllFields(t *testing.T) {
 		t.Error("Output should indicate installed from source")
 	}
 }
+
+func TestPrintCaskInfo(t *testing.T) {
+	var cask homebrew.Cask
+	data := `{
+		"token": "firefox",
+		"name": ["Mozilla Firefox"],
+		"desc": "Web browser",
+		"homepage": "https://www.mozilla.org/firefox/",
+		"version": "131.0",
+		"installed": "130.0",
+		"auto_updates": true,
+		"artifacts": [{"app": ["Firefox.app"]}, {"binary": ["firefox-bin", {"target": "firefox"}]}],
+		"depends_on": {"macos": {">=": ["10.15"]}},
+		"conflicts_with": {"cask": ["firefox@beta"]}
+	}`
+	if err := json.Unmarshal([]byte(data), &cask); err != nil {
+		t.Fatalf("Failed to unmarshal cask: %v", err)
+	}
+
+	output := captureOutput(func() {
+		PrintPackageInfo(&homebrew.Package{Kind: homebrew.KindCask, Cask: &cask})
+	})
+
+	for _, want := range []string{"firefox", "Mozilla Firefox", "Firefox.app", "firefox-bin", "auto-updates", "130.0 → 131.0", "macOS >= 10.15", "firefox@beta"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Output should contain %q", want)
+		}
+	}
+}
+
+func TestPrintFormulaList(t *testing.T) {
+	formulae := []homebrew.Formula{{Name: "tree", Installed: []homebrew.InstalledInfo{{Version: "2.1.3"}}}}
+
+	output := captureOutput(func() {
+		PrintFormulaList("Leaves", "No leaves", formulae)
+	})
+	if !strings.Contains(output, "Leaves") || !strings.Contains(output, "tree") || !strings.Contains(output, "2.1.3") {
+		t.Errorf("Expected the title and formula, got:\n%s", output)
+	}
+
+	output = captureOutput(func() {
+		PrintFormulaList("Leaves", "No leaves", nil)
+	})
+	if !strings.Contains(output, "No leaves") {
+		t.Errorf("Expected the empty message, got:\n%s", output)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	tests := map[string]bool{
+		"y\n":   true,
+		"Yes\n": true,
+		"n\n":   false,
+		"\n":    false,
+		"":      false,
+		"y":     true,
+	}
+	for input, want := range tests {
+		var got bool
+		captureOutput(func() {
+			got = Confirm(strings.NewReader(input), "Remove?")
+		})
+		if got != want {
+			t.Errorf("Confirm(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
+
+func TestPrintRemovalPlan(t *testing.T) {
+	formulae := []homebrew.Formula{
+		{Name: "libidn2", Installed: []homebrew.InstalledInfo{{Version: "2.3.7"}}},
+		{Name: "libunistring", Installed: []homebrew.InstalledInfo{{Version: "1.3"}}},
+	}
+	sizes := map[string]int64{"libidn2": 1024, "libunistring": 2048}
+
+	output := captureOutput(func() {
+		PrintRemovalPlan(formulae, sizes)
+	})
+
+	for _, want := range []string{"libidn2", "2.3.7", "libunistring", "Frees 3.0 KB"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected removal plan to contain %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestPrintOutdated(t *testing.T) {
+	packages := []homebrew.OutdatedPackage{
+		{Name: "node", InstalledVersion: "20.11.0", LatestVersion: "22.1.0", Bump: pkgversion.BumpMajor},
+		{Name: "firefox", Cask: true, InstalledVersion: "119.0", LatestVersion: "120.0", Bump: pkgversion.BumpMajor},
+		{Name: "openssl@3", InstalledVersion: "3.4.0", LatestVersion: "3.4.0_1", Bump: pkgversion.BumpRevision, Pinned: true},
+		{Name: "postgresql@16", InstalledVersion: "16.4", LatestVersion: "16.5", Bump: pkgversion.BumpMinor, Held: "held until 2026-03-01"},
+	}
+
+	output := captureOutput(func() {
+		PrintOutdated(packages)
+	})
+
+	for _, want := range []string{"4 total", "20.11.0", "→", "22.1.0", "major", "(cask)", "revision", "pinned", "held until 2026-03-01"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected outdated list to contain %q:\n%s", want, output)
+		}
+	}
+
+	output = captureOutput(func() {
+		PrintOutdated(nil)
+	})
+	if !strings.Contains(output, "up to date") {
+		t.Errorf("Expected an up to date message, got:\n%s", output)
+	}
+}
+
+func TestPrintPins(t *testing.T) {
+	now := time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
+	holds := []config.Hold{
+		{Package: "node", Version: "20", Reason: "LTS"},
+		{Package: "postgresql@16", Until: "2026-01-01"},
+	}
+
+	output := captureOutput(func() {
+		PrintPins([]string{"go"}, holds, now)
+	})
+
+	for _, want := range []string{"Pinned Formulae", "go", "Held Packages", "held at 20 (LTS)", "held until 2026-01-01", "(expired)"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected pin list to contain %q:\n%s", want, output)
+		}
+	}
+
+	output = captureOutput(func() {
+		PrintPins(nil, nil, now)
+	})
+	if !strings.Contains(output, "No pinned or held packages") {
+		t.Errorf("Expected an empty message, got:\n%s", output)
+	}
+}
+
+func TestPrintBundleCheck(t *testing.T) {
+	items := []homebrew.BundleItem{
+		{Entry: brewfile.Entry{Kind: brewfile.KindBrew, Name: "git", Line: 1}, Installed: true},
+		{Entry: brewfile.Entry{Kind: brewfile.KindCask, Name: "slack", Line: 2}},
+	}
+
+	output := captureOutput(func() {
+		PrintBundleCheck(items)
+	})
+	for _, want := range []string{"1 of 2 entries", "slack", "line 2", "bundle install"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected bundle check to contain %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "git") {
+		t.Errorf("Installed entries should not be listed:\n%s", output)
+	}
+
+	output = captureOutput(func() {
+		PrintBundleCheck(items[:1])
+	})
+	if !strings.Contains(output, "satisfied") {
+		t.Errorf("Expected a satisfied message, got:\n%s", output)
+	}
+}
+
+func TestPrintLockDrift(t *testing.T) {
+	drifts := []lockfile.Drift{
+		{Package: "jq", Kind: lockfile.DriftVersion, Locked: "1.7.1", Actual: "1.6"},
+		{Package: "curl", Kind: lockfile.DriftMissing, Locked: "8.10.1"},
+	}
+
+	output := captureOutput(func() {
+		PrintLockDrift("goobrew.lock", 5, drifts)
+	})
+	for _, want := range []string{"2 packages differ from goobrew.lock", "jq is 1.6, locked at 1.7.1", "curl 8.10.1 is locked but not installed"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected lock drift to contain %q:\n%s", want, output)
+		}
+	}
+
+	output = captureOutput(func() {
+		PrintLockDrift("goobrew.lock", 5, nil)
+	})
+	if !strings.Contains(output, "All 5 packages match") {
+		t.Errorf("Expected a success message, got:\n%s", output)
+	}
+}
+
+func TestPrintLinkResult(t *testing.T) {
+	result := &homebrew.LinkResult{Formula: "wget", Version: "1.24.5", Changes: []homebrew.LinkChange{
+		{Op: homebrew.LinkOpMkdir, Path: "share/man"},
+		{Op: homebrew.LinkOpRemove, Path: "bin/wget"},
+		{Op: homebrew.LinkOpLink, Path: "bin/wget", Target: "../Cellar/wget/1.24.5/bin/wget"},
+	}}
+
+	output := captureOutput(func() {
+		PrintLinkResult("link", result, true)
+	})
+	for _, want := range []string{"Would link", "1 symlinks created, 1 removed", "bin/wget", "-> ../Cellar/wget/1.24.5/bin/wget", "share/man/"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected dry run to contain %q:\n%s", want, output)
+		}
+	}
+
+	output = captureOutput(func() {
+		PrintLinkResult("unlink", result, false)
+	})
+	if !strings.Contains(output, "Unlinked") || strings.Contains(output, "->") {
+		t.Errorf("Expected a summary without the changes:\n%s", output)
+	}
+
+	result.Conflicts = []homebrew.LinkConflict{{Path: "bin/curl", Owner: "curl", OwnerVersion: "8.10.1"}}
+	output = captureOutput(func() {
+		PrintLinkResult("link", result, false)
+	})
+	if !strings.Contains(output, "bin/curl belongs to curl 8.10.1") {
+		t.Errorf("Expected the conflict owner to be shown:\n%s", output)
+	}
+}
+
+func TestPrintInstallPlan(t *testing.T) {
+	plan := &homebrew.InstallPlan{
+		Tag: "arm64_sonoma",
+		Steps: []homebrew.PlanStep{
+			{Name: "oniguruma", Kind: homebrew.KindFormula, Action: homebrew.PlanUpgrade, InstalledVersion: "6.9.8", Version: "6.9.9", DownloadBytes: 1024},
+			{Name: "jq", Kind: homebrew.KindFormula, Action: homebrew.PlanInstall, Version: "1.7.1", Requested: true, DownloadBytes: 2048},
+			{Name: "iterm2", Kind: homebrew.KindCask, Action: homebrew.PlanInstall, Version: "3.5.4", Requested: true, DownloadBytes: -1},
+		},
+		Installed:     []string{"git"},
+		Conflicts:     []homebrew.PlanConflict{{Formula: "jq", ConflictsWith: "jaq", Reason: "both install bin/jq"}},
+		DownloadBytes: 3072,
+		UnknownSizes:  1,
+	}
+
+	output := captureOutput(func() {
+		PrintInstallPlan(plan)
+	})
+	for _, want := range []string{"2 new, 1 upgraded", "6.9.8 → 6.9.9", "(dependency)", "(cask)", "Already installed: git",
+		"jq conflicts with jaq (both install bin/jq)", "Downloads 3.0 KB plus 1 packages of unknown size"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected install plan to contain %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestPrintLifecycle(t *testing.T) {
+	output := captureOutput(func() {
+		PrintFormulaInfo(&homebrew.Formula{
+			Name:              "node@18",
+			Deprecated:        true,
+			DeprecationDate:   "2024-10-29",
+			DeprecationReason: "unsupported",
+			DisableDate:       "2025-10-29",
+			DisableReason:     "unsupported",
+		})
+	})
+	for _, want := range []string{"Deprecated", "is not supported upstream", "(since 2024-10-29)", "Will be disabled on 2025-10-29"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected formula info to contain %q:\n%s", want, output)
+		}
+	}
+
+	output = captureOutput(func() {
+		PrintCaskInfo(&homebrew.Cask{Token: "old-app", Disabled: true, DisableDate: "2025-01-01", DisableReason: "Upstream is gone"})
+	})
+	if !strings.Contains(output, "Disabled") || !strings.Contains(output, "Upstream is gone") || !strings.Contains(output, "(since 2025-01-01)") {
+		t.Errorf("Expected cask info to show the disable:\n%s", output)
+	}
+
+	output = captureOutput(func() {
+		PrintInstalledList([]homebrew.Formula{{Name: "node@18", Deprecated: true}, {Name: "python@3.8", Disabled: true, Deprecated: true}})
+	})
+	if !strings.Contains(output, "[deprecated]") || !strings.Contains(output, "[disabled]") {
+		t.Errorf("Expected the list to flag deprecated and disabled formulae:\n%s", output)
+	}
+}
+
+func TestPrintAudit(t *testing.T) {
+	findings := []homebrew.AuditFinding{
+		{Name: "python@3.8", Status: homebrew.AuditDisabled, Version: "3.8.20", DisableDate: "2025-01-01", Reason: "is not supported upstream"},
+		{Name: "node@18", Status: homebrew.AuditScheduled, Version: "18.20.4", DisableDate: "2026-11-01", DaysLeft: 16, Reason: "is not supported upstream"},
+		{Name: "old-app", Cask: true, Status: homebrew.AuditDeprecated, DeprecationDate: "2026-01-01"},
+		{Name: "youtube-dl", Status: homebrew.AuditRenamed, Version: "2021.12.17", Replacement: "yt-dlp"},
+	}
+
+	output := captureOutput(func() {
+		PrintAudit(findings, 30)
+	})
+	for _, want := range []string{"4 total", "disabled", "since 2025-01-01", "disabled on 2026-11-01", "(in 16 days)",
+		"old-app (cask)", "renamed", "→ use yt-dlp instead", "1 disabled, 1 to be disabled within 30 days, 1 deprecated, 1 renamed"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected audit to contain %q:\n%s", want, output)
+		}
+	}
+
+	output = captureOutput(func() {
+		PrintAudit(nil, 30)
+	})
+	if !strings.Contains(output, "No installed package") {
+		t.Errorf("Expected an all-clear message:\n%s", output)
+	}
+}
+
+func TestPrintServices(t *testing.T) {
+	exit := 3
+	services := []homebrew.ServiceInfo{
+		{Name: "redis", Label: "homebrew.redis", Status: homebrew.ServiceStarted, PID: 4242, File: "/home/me/.config/systemd/user/homebrew.redis.service"},
+		{Name: "backup", Label: "homebrew.backup", Status: homebrew.ServiceError, ExitCode: &exit},
+	}
+
+	output := captureOutput(func() {
+		PrintServices(services)
+	})
+	for _, want := range []string{"2 total", "redis", "started", "4242", "homebrew.redis.service", "backup", "error", " 3 "} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected services to contain %q:\n%s", want, output)
+		}
+	}
+
+	info := services[0]
+	info.Definition = &homebrew.ServiceDefinition{
+		Command:     []string{"/opt/redis/bin/redis-server", "/etc/redis.conf"},
+		RunType:     "immediate",
+		Restart:     "always",
+		LogPath:     "/var/log/redis.log",
+		Environment: map[string]string{"LANG": "C"},
+	}
+	output = captureOutput(func() {
+		PrintServiceInfo(&info)
+	})
+	for _, want := range []string{"redis", "homebrew.redis", "4242", "/opt/redis/bin/redis-server /etc/redis.conf",
+		"immediate, restart always", "/var/log/redis.log", "LANG=C"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected service info to contain %q:\n%s", want, output)
+		}
+	}
+}